@@ -0,0 +1,125 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	. "github.com/ForceCLI/force/error"
+	. "github.com/ForceCLI/force/lib"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	profileSaveCmd.Flags().String("connected-app-client-id", "", "Client Id (aka Consumer Key) of the connected app")
+	profileSaveCmd.Flags().StringP("user", "u", "", "username for JWT login")
+	profileSaveCmd.Flags().StringP("key", "k", "", "JWT signing key filename")
+	profileSaveCmd.Flags().String("secret-env", "", "environment `variable` containing the Client Secret")
+	profileSaveCmd.Flags().String("secret-keyring", "", "keyring entry containing the Client Secret, as `service/account`")
+	profileSaveCmd.Flags().StringP("instance", "i", "", "login instance: 'login', 'test', or full instance url")
+	profileSaveCmd.MarkFlagRequired("connected-app-client-id")
+	profileSaveCmd.MarkFlagsMutuallyExclusive("key", "secret-env", "secret-keyring")
+
+	profileCmd.AddCommand(profileSaveCmd)
+	profileCmd.AddCommand(profileListCmd)
+	profileCmd.AddCommand(profileDeleteCmd)
+	loginCmd.AddCommand(profileCmd)
+}
+
+var profileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Manage saved auth profiles for JWT and client credentials logins",
+	Long: `
+Manage saved auth profiles for JWT and client credentials logins.
+
+A profile stores the connected app's client id, the username and signing key
+location for JWT logins, a reference to the client secret for client
+credentials logins, and the login endpoint.  Secrets themselves are never
+saved; they are read from an environment variable or the system keyring each
+time they are needed.
+
+Log in with a profile using "force login --profile <name>".  The login saves
+the profile's key file location or secret reference, so the session is
+re-authenticated when it expires.
+`,
+}
+
+var profileSaveCmd = &cobra.Command{
+	Use:   "save <name>",
+	Short: "Save an auth profile",
+	Example: `
+  force login profile save ci --connected-app-client-id <consumer-key> -u ci@example.com -k jwt.key
+  force login profile save integration --connected-app-client-id <consumer-key> --secret-env SF_CLIENT_SECRET -i my-domain.my.salesforce.com
+  force login profile save integration --connected-app-client-id <consumer-key> --secret-keyring force/integration
+`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		profile := AuthProfile{Endpoint: getEndpoint(cmd)}
+		profile.ClientId, _ = cmd.Flags().GetString("connected-app-client-id")
+		profile.Username, _ = cmd.Flags().GetString("user")
+		profile.KeyFile, _ = cmd.Flags().GetString("key")
+		if env, _ := cmd.Flags().GetString("secret-env"); env != "" {
+			profile.SecretRef = "env:" + env
+		}
+		if entry, _ := cmd.Flags().GetString("secret-keyring"); entry != "" {
+			profile.SecretRef = "keyring:" + entry
+		}
+		if err := SaveAuthProfile(args[0], profile); err != nil {
+			ErrorAndExit(err.Error())
+		}
+	},
+}
+
+var profileListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List saved auth profiles",
+	Args:  cobra.ExactArgs(0),
+	Run: func(cmd *cobra.Command, args []string) {
+		runListAuthProfiles()
+	},
+}
+
+var profileDeleteCmd = &cobra.Command{
+	Use:               "delete <name>",
+	Short:             "Delete a saved auth profile",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeAuthProfiles,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := DeleteAuthProfile(args[0]); err != nil {
+			ErrorAndExit(err.Error())
+		}
+	},
+}
+
+func runListAuthProfiles() {
+	names, err := AuthProfiles()
+	if err != nil {
+		ErrorAndExit(err.Error())
+	}
+	if len(names) == 0 {
+		fmt.Println("No auth profiles")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tFLOW\tCLIENT ID\tCREDENTIAL\tENDPOINT")
+	for _, name := range names {
+		profile, err := LoadAuthProfile(name)
+		if err != nil {
+			ErrorAndExit(err.Error())
+		}
+		flow, credential := "client credentials", profile.SecretRef
+		if profile.IsJWT() {
+			flow, credential = "jwt", fmt.Sprintf("%s (%s)", profile.Username, profile.KeyFile)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", name, flow, profile.ClientId, credential, profile.Endpoint)
+	}
+	w.Flush()
+}
+
+func completeAuthProfiles(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	names, _ := AuthProfiles()
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
@@ -103,38 +103,43 @@ func init() {
 }
 
 var bulkInsertCmd = &cobra.Command{
-	Use:   "insert <object> <file>",
-	Short: "Create records from csv file using Bulk API",
-	Run:   runBulkCmd,
-	Args:  cobra.ExactArgs(2),
+	Use:               "insert <object> <file>",
+	Short:             "Create records from csv file using Bulk API",
+	Run:               runBulkCmd,
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeSObjectThenFile,
 }
 
 var bulkUpdateCmd = &cobra.Command{
-	Use:   "update <object> <file>",
-	Short: "Update records from csv file using Bulk API",
-	Run:   runBulkCmd,
-	Args:  cobra.ExactArgs(2),
+	Use:               "update <object> <file>",
+	Short:             "Update records from csv file using Bulk API",
+	Run:               runBulkCmd,
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeSObjectThenFile,
 }
 
 var bulkUpsertCmd = &cobra.Command{
-	Use:   "upsert -e <External_Id_Field__c> <object> <file>",
-	Short: "Upsert records from csv file using Bulk API",
-	Run:   runBulkCmd,
-	Args:  cobra.ExactArgs(2),
+	Use:               "upsert -e <External_Id_Field__c> <object> <file>",
+	Short:             "Upsert records from csv file using Bulk API",
+	Run:               runBulkCmd,
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeSObjectThenFile,
 }
 
 var bulkDeleteCmd = &cobra.Command{
-	Use:   "delete <object> <file>",
-	Short: "Delete records using Bulk API",
-	Run:   runBulkCmd,
-	Args:  cobra.ExactArgs(2),
+	Use:               "delete <object> <file>",
+	Short:             "Delete records using Bulk API",
+	Run:               runBulkCmd,
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeSObjectThenFile,
 }
 
 var bulkHardDeleteCmd = &cobra.Command{
-	Use:   "hardDelete <object> <file>",
-	Short: "Hard delete records using Bulk API",
-	Run:   runBulkCmd,
-	Args:  cobra.ExactArgs(2),
+	Use:               "hardDelete <object> <file>",
+	Short:             "Hard delete records using Bulk API",
+	Run:               runBulkCmd,
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeSObjectThenFile,
 }
 
 var bulkQueryCmd = &cobra.Command{
@@ -175,7 +180,8 @@ var bulkQueryCmd = &cobra.Command{
 		}
 		displayQueryResults(jobInfo)
 	},
-	Args: cobra.ExactArgs(2),
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeSObjects,
 }
 
 var bulkRetrieveCmd = &cobra.Command{
@@ -77,38 +77,43 @@ var bulk2Cmd = &cobra.Command{
 }
 
 var bulk2InsertCmd = &cobra.Command{
-	Use:   "insert <object> <file>",
-	Short: "Insert records from CSV file using Bulk API 2.0",
-	Args:  cobra.ExactArgs(2),
-	Run:   runBulk2IngestCmd,
+	Use:               "insert <object> <file>",
+	Short:             "Insert records from CSV file using Bulk API 2.0",
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeSObjectThenFile,
+	Run:               runBulk2IngestCmd,
 }
 
 var bulk2UpdateCmd = &cobra.Command{
-	Use:   "update <object> <file>",
-	Short: "Update records from CSV file using Bulk API 2.0",
-	Args:  cobra.ExactArgs(2),
-	Run:   runBulk2IngestCmd,
+	Use:               "update <object> <file>",
+	Short:             "Update records from CSV file using Bulk API 2.0",
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeSObjectThenFile,
+	Run:               runBulk2IngestCmd,
 }
 
 var bulk2UpsertCmd = &cobra.Command{
-	Use:   "upsert -e <External_Id_Field__c> <object> <file>",
-	Short: "Upsert records from CSV file using Bulk API 2.0",
-	Args:  cobra.ExactArgs(2),
-	Run:   runBulk2IngestCmd,
+	Use:               "upsert -e <External_Id_Field__c> <object> <file>",
+	Short:             "Upsert records from CSV file using Bulk API 2.0",
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeSObjectThenFile,
+	Run:               runBulk2IngestCmd,
 }
 
 var bulk2DeleteCmd = &cobra.Command{
-	Use:   "delete <object> <file>",
-	Short: "Delete records using Bulk API 2.0",
-	Args:  cobra.ExactArgs(2),
-	Run:   runBulk2IngestCmd,
+	Use:               "delete <object> <file>",
+	Short:             "Delete records using Bulk API 2.0",
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeSObjectThenFile,
+	Run:               runBulk2IngestCmd,
 }
 
 var bulk2HardDeleteCmd = &cobra.Command{
-	Use:   "hardDelete <object> <file>",
-	Short: "Hard delete records using Bulk API 2.0",
-	Args:  cobra.ExactArgs(2),
-	Run:   runBulk2IngestCmd,
+	Use:               "hardDelete <object> <file>",
+	Short:             "Hard delete records using Bulk API 2.0",
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeSObjectThenFile,
+	Run:               runBulk2IngestCmd,
 }
 
 var bulk2QueryCmd = &cobra.Command{
@@ -0,0 +1,173 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	. "github.com/ForceCLI/force/error"
+	. "github.com/ForceCLI/force/lib"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	compareCmd.Flags().String("from", "", "login `username` of the source org")
+	compareCmd.Flags().String("to", "", "login `username` of the target org")
+	compareCmd.Flags().StringSliceP("type", "t", []string{}, "metadata type to compare (default: all types)")
+	compareCmd.Flags().BoolP("summary", "s", false, "only show the status of each component")
+	compareCmd.Flags().BoolP("unchanged", "u", false, "include unchanged components")
+	compareCmd.Flags().BoolP("warnings", "w", false, "display warnings about metadata that cannot be retrieved")
+	compareCmd.Flags().StringP("output-dir", "d", "", "write package.xml and destructiveChanges.xml that would make the target match the source to `directory`")
+	compareCmd.MarkFlagRequired("from")
+	compareCmd.MarkFlagRequired("to")
+	compareCmd.RegisterFlagCompletionFunc("from", completeLogins)
+	compareCmd.RegisterFlagCompletionFunc("to", completeLogins)
+	compareCmd.RegisterFlagCompletionFunc("type", completeMetadataTypes)
+	RootCmd.AddCommand(compareCmd)
+}
+
+var compareCmd = &cobra.Command{
+	Use:   "compare --from <login> --to <login> [-t type]...",
+	Short: "Compare the metadata in two orgs",
+	Long: `
+Compare the metadata in two orgs.
+
+The components of each type are listed and retrieved from both orgs, and
+compared by content.  Each component is listed as added (only in the source
+org), changed, target-only (only in the target org), or unchanged, along with
+when and by whom it was last modified in each org, followed by unified diffs
+from the target's version to the source's version.
+
+Components installed from managed packages aren't compared.
+
+Use --output-dir to write a package.xml of the components to deploy from the
+source org and a destructiveChanges.xml of the components to delete from the
+target org, which together would make the target match the source.
+
+Exits with status 2 if there are differences.
+`,
+	Example: `
+  force compare --from me@example.com.uat --to me@example.com -t ApexClass -t CustomObject
+  force compare --from uat --to prod -t Flow --summary --output-dir promote
+`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		from, _ := cmd.Flags().GetString("from")
+		to, _ := cmd.Flags().GetString("to")
+		types, _ := cmd.Flags().GetStringSlice("type")
+		summaryOnly, _ := cmd.Flags().GetBool("summary")
+		showUnchanged, _ := cmd.Flags().GetBool("unchanged")
+		showWarnings, _ := cmd.Flags().GetBool("warnings")
+		outputDir, _ := cmd.Flags().GetString("output-dir")
+
+		if _apiVersion != "" {
+			if err := SetApiVersion(_apiVersion); err != nil {
+				ErrorAndExit(err.Error())
+			}
+		}
+		source, err := GetForce(from)
+		if err != nil {
+			ErrorAndExit(err.Error())
+		}
+		target, err := GetForce(to)
+		if err != nil {
+			ErrorAndExit(err.Error())
+		}
+		if len(types) == 0 {
+			types = allMetadataTypes(source)
+		}
+
+		comparison, err := CompareOrgs(source, target, types)
+		if err != nil {
+			ErrorAndExit(err.Error())
+		}
+		if showWarnings {
+			for _, problem := range comparison.Problems {
+				fmt.Fprintln(os.Stderr, problem)
+			}
+		}
+		displayComparison(comparison, summaryOnly, showUnchanged)
+		if outputDir != "" {
+			writePromotionManifests(comparison, outputDir)
+		}
+		if comparison.Different() {
+			os.Exit(diffExitCode)
+		}
+	},
+}
+
+func allMetadataTypes(f *Force) []string {
+	describe, err := f.Metadata.DescribeMetadata()
+	if err != nil {
+		ErrorAndExit(err.Error())
+	}
+	var types []string
+	for _, object := range describe.MetadataObjects {
+		types = append(types, object.XmlName)
+	}
+	return types
+}
+
+func displayComparison(comparison OrgComparison, summaryOnly bool, showUnchanged bool) {
+	for _, c := range comparison.Components {
+		if c.Status == DiffUnchanged && !showUnchanged {
+			continue
+		}
+		fmt.Printf("%-11s %s %s%s\n", c.Status, c.Type, c.FullName, lastModified(c))
+		if summaryOnly {
+			continue
+		}
+		for _, f := range c.Files {
+			fmt.Print(f.Diff)
+		}
+	}
+}
+
+// lastModified describes when and by whom the component was last modified
+// in each org.
+func lastModified(c ComponentComparison) string {
+	describe := func(org string, p *MDFileProperties) string {
+		if p.LastModifedDate.IsZero() {
+			return ""
+		}
+		return fmt.Sprintf("%s: %s by %s", org, p.LastModifedDate.Local().Format("2006-01-02 15:04"), p.LastModifiedByName)
+	}
+	switch {
+	case c.Source != nil && c.Target != nil:
+		source, target := describe("source", c.Source), describe("target", c.Target)
+		if source == "" || target == "" {
+			return ""
+		}
+		return fmt.Sprintf(" (%s; %s)", source, target)
+	case c.Source != nil:
+		if source := describe("source", c.Source); source != "" {
+			return fmt.Sprintf(" (%s)", source)
+		}
+	case c.Target != nil:
+		if target := describe("target", c.Target); target != "" {
+			return fmt.Sprintf(" (%s)", target)
+		}
+	}
+	return ""
+}
+
+func writePromotionManifests(comparison OrgComparison, outputDir string) {
+	packageXml, destructiveChanges, err := comparison.PromotionManifests()
+	if err != nil {
+		ErrorAndExit(err.Error())
+	}
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		ErrorAndExit(err.Error())
+	}
+	if err := os.WriteFile(filepath.Join(outputDir, "package.xml"), packageXml, 0644); err != nil {
+		ErrorAndExit(err.Error())
+	}
+	if destructiveChanges == nil {
+		fmt.Fprintf(os.Stderr, "Wrote package.xml to %s\n", outputDir)
+		return
+	}
+	if err := os.WriteFile(filepath.Join(outputDir, "destructiveChanges.xml"), destructiveChanges, 0644); err != nil {
+		ErrorAndExit(err.Error())
+	}
+	fmt.Fprintf(os.Stderr, "Wrote package.xml and destructiveChanges.xml to %s\n", outputDir)
+}
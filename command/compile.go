@@ -0,0 +1,118 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	. "github.com/ForceCLI/force/error"
+	. "github.com/ForceCLI/force/lib"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	compileCmd.Flags().BoolP("checkonly", "c", false, "compile without saving")
+	compileCmd.Flags().StringP("format", "f", "text", "output format: text, json")
+	RootCmd.AddCommand(compileCmd)
+}
+
+var compileCmd = &cobra.Command{
+	Use:   "compile <files>...",
+	Short: "Save Apex and Visualforce through the Tooling API",
+	Long: `
+Compile and save Apex classes and triggers, and Visualforce pages and
+components, through the Tooling API.  This is faster than deploying them with
+"force push", but can only update components that are already in the org.
+
+Compile errors are printed as file:line:column: message, or with --format
+json, as a JSON object with a list of errors, each with its file, line,
+column, and message.
+
+Exits with status 1 if there are errors.
+`,
+	Example: `
+  force compile src/classes/MyClass.cls
+  force compile --checkonly src/classes/MyClass.cls src/triggers/MyTrigger.trigger
+  force compile -f json src/pages/MyPage.page
+`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		checkOnly, _ := cmd.Flags().GetBool("checkonly")
+		format, _ := cmd.Flags().GetString("format")
+		if format != "text" && format != "json" {
+			ErrorAndExit("--format must be text or json")
+		}
+		runCompile(args, checkOnly, format)
+	},
+}
+
+type compileOutput struct {
+	Success   bool           `json:"success"`
+	CheckOnly bool           `json:"checkOnly"`
+	Errors    []CompileError `json:"errors"`
+}
+
+func runCompile(paths []string, checkOnly bool, format string) {
+	var files []ToolingFile
+	// Errors are reported with the paths given.
+	displayPaths := make(map[string]string)
+	for _, p := range paths {
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			ErrorAndExit(err.Error())
+		}
+		// These components are always directly in their type's directory.
+		metadataPath := filepath.Base(filepath.Dir(abs)) + "/" + filepath.Base(abs)
+		if !IsContainerPath(metadataPath) {
+			ErrorAndExit("%s isn't an Apex class or trigger, or a Visualforce page or component", p)
+		}
+		body, err := os.ReadFile(p)
+		if err != nil {
+			ErrorAndExit(err.Error())
+		}
+		files = append(files, ToolingFile{Path: metadataPath, Body: body})
+		displayPaths[metadataPath] = p
+	}
+
+	result, err := force.ToolingDeploy(files, ToolingDeployOptions{CheckOnly: checkOnly})
+	if err != nil {
+		ErrorAndExit(err.Error())
+	}
+	output := compileOutput{CheckOnly: checkOnly, Errors: []CompileError{}}
+	for _, path := range result.NotFound {
+		output.Errors = append(output.Errors, CompileError{
+			File:    displayPaths[path],
+			Message: "Not in the org.  Use force push to create it.",
+		})
+	}
+	for _, f := range result.Failures {
+		if p, ok := displayPaths[f.FileName]; ok {
+			f.FileName = p
+		}
+		output.Errors = append(output.Errors, NewCompileError(f))
+	}
+	output.Success = len(output.Errors) == 0
+
+	if format == "json" {
+		data, err := json.Marshal(output)
+		if err != nil {
+			ErrorAndExit(err.Error())
+		}
+		fmt.Println(string(data))
+	} else {
+		for _, e := range output.Errors {
+			fmt.Println(e)
+		}
+		if output.Success {
+			verb := "Saved"
+			if checkOnly {
+				verb = "Compiled"
+			}
+			fmt.Printf("%s %d file(s)\n", verb, len(files))
+		}
+	}
+	if !output.Success {
+		os.Exit(1)
+	}
+}
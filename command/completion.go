@@ -0,0 +1,306 @@
+package command
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	forceConfig "github.com/ForceCLI/force/config"
+	. "github.com/ForceCLI/force/lib"
+	"github.com/spf13/cobra"
+)
+
+// Shell completion functions.  Values that require API calls are stored in
+// a local cache so tab completion stays fast.
+
+const completionCacheName = "completion-cache"
+
+var (
+	completionCacheTTL       = 24 * time.Hour
+	completionDeployCacheTTL = time.Minute
+	completionSetupOnce      sync.Once
+)
+
+type completionCacheEntry struct {
+	CachedAt time.Time
+	Values   []string
+}
+
+// completionSetup applies the --config flag, which isn't processed by the
+// root command's PersistentPreRun when generating completions.
+func completionSetup() {
+	completionSetupOnce.Do(func() {
+		if strings.TrimSpace(configName) != "" {
+			_ = useConfig(configName)
+		}
+	})
+}
+
+// completionForce returns the session to use for completions, or nil if there
+// isn't one.  Unlike initializeSession, it never exits.
+func completionForce() *Force {
+	completionSetup()
+	if force != nil {
+		return force
+	}
+	var err error
+	if account != "" {
+		force, err = GetForce(account)
+	} else if force = envSession(); force == nil {
+		var login string
+		if login, err = ActiveLogin(); err == nil && strings.TrimSpace(login) != "" {
+			force, err = GetForce(strings.TrimSpace(login))
+		}
+	}
+	if err != nil {
+		force = nil
+		return nil
+	}
+	if force != nil && _apiVersion != "" {
+		_ = SetApiVersion(_apiVersion)
+	}
+	return force
+}
+
+var unsafeCacheKeyChars = regexp.MustCompile(`[^A-Za-z0-9_.-]`)
+
+func completionCacheDir(f *Force) string {
+	orgId := "default"
+	if f.Credentials != nil && f.Credentials.UserInfo != nil && f.Credentials.UserInfo.OrgId != "" {
+		orgId = f.Credentials.UserInfo.OrgId
+	}
+	return filepath.Join(completionCacheName, orgId)
+}
+
+// cachedCompletions returns the values cached under key, calling fetch to
+// refresh them if they are older than ttl.
+func cachedCompletions(key string, ttl time.Duration, fetch func(*Force) ([]string, error)) []string {
+	f := completionForce()
+	if f == nil {
+		return nil
+	}
+	dir := completionCacheDir(f)
+	key = unsafeCacheKeyChars.ReplaceAllString(key, "_")
+	if data, err := forceConfig.Config.Load(dir, key); err == nil {
+		var entry completionCacheEntry
+		if err := json.Unmarshal([]byte(data), &entry); err == nil && time.Since(entry.CachedAt) < ttl {
+			return entry.Values
+		}
+	}
+	values, err := fetch(f)
+	if err != nil {
+		return nil
+	}
+	entry := completionCacheEntry{CachedAt: time.Now(), Values: values}
+	if data, err := json.Marshal(entry); err == nil {
+		_ = forceConfig.Config.Save(dir, key, string(data))
+	}
+	return values
+}
+
+// filterCompletions returns the values that start with toComplete, ignoring
+// case and any tab-separated description, and excluding values in exclude.
+func filterCompletions(values []string, toComplete string, exclude ...string) []string {
+	excluded := make(map[string]bool)
+	for _, e := range exclude {
+		excluded[strings.ToLower(e)] = true
+	}
+	var matches []string
+	prefix := strings.ToLower(toComplete)
+	for _, v := range values {
+		name := strings.ToLower(strings.SplitN(v, "\t", 2)[0])
+		if excluded[name] {
+			continue
+		}
+		if strings.HasPrefix(name, prefix) {
+			matches = append(matches, v)
+		}
+	}
+	return matches
+}
+
+func sobjectCompletions() []string {
+	return cachedCompletions("sobjects", completionCacheTTL, func(f *Force) ([]string, error) {
+		sobjects, err := f.ListSobjects()
+		if err != nil {
+			return nil, err
+		}
+		var values []string
+		for _, sobject := range sobjects {
+			name, _ := sobject["name"].(string)
+			label, _ := sobject["label"].(string)
+			values = append(values, fmt.Sprintf("%s\t%s", name, label))
+		}
+		sort.Strings(values)
+		return values, nil
+	})
+}
+
+func fieldCompletions(object string) []string {
+	return cachedCompletions("fields-"+object, completionCacheTTL, func(f *Force) ([]string, error) {
+		body, err := f.DescribeSObjectCached(object)
+		if err != nil {
+			return nil, err
+		}
+		describe, err := ParseSObjectDescribe(body)
+		if err != nil {
+			return nil, err
+		}
+		var values []string
+		for _, field := range describe.Fields {
+			values = append(values, fmt.Sprintf("%s\t%s", field.Name, field.TypeDescription()))
+		}
+		sort.Strings(values)
+		return values, nil
+	})
+}
+
+// completeSObjects completes an sobject name as the first argument.
+func completeSObjects(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return filterCompletions(sobjectCompletions(), toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeSObjectThenFile completes an sobject name as the first argument
+// and a file name as the second.
+func completeSObjectThenFile(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveDefault
+	}
+	return completeSObjects(cmd, args, toComplete)
+}
+
+// completeSObjectList completes any number of sobject names.
+func completeSObjectList(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return filterCompletions(sobjectCompletions(), toComplete, args...), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeSObjectFlag completes an sobject name as a flag value.
+func completeSObjectFlag(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return filterCompletions(sobjectCompletions(), toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeFieldName completes an sobject name as the first argument and one
+// of its fields as the second.
+func completeFieldName(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	switch len(args) {
+	case 0:
+		return completeSObjects(cmd, args, toComplete)
+	case 1:
+		return filterCompletions(fieldCompletions(args[0]), toComplete), cobra.ShellCompDirectiveNoFileComp
+	}
+	return nil, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeFieldValues returns a completion function for commands that take
+// an sobject name, followed by positional arguments, followed by
+// <field>:<value> pairs.
+func completeFieldValues(positional int) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) == 0 {
+			return completeSObjects(cmd, args, toComplete)
+		}
+		if len(args) < positional || strings.Contains(toComplete, ":") {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		var used []string
+		for _, arg := range args[positional:] {
+			used = append(used, strings.SplitN(arg, ":", 2)[0])
+		}
+		var values []string
+		for _, v := range filterCompletions(fieldCompletions(args[0]), toComplete, used...) {
+			parts := strings.SplitN(v, "\t", 2)
+			parts[0] += ":"
+			values = append(values, strings.Join(parts, "\t"))
+		}
+		return values, cobra.ShellCompDirectiveNoSpace | cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// completeLogins completes the names of saved logins.
+func completeLogins(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	completionSetup()
+	accounts, err := forceConfig.Config.List("accounts")
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return filterCompletions(accounts, toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeMetadataTypes completes metadata type names.
+func completeMetadataTypes(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	types := cachedCompletions("metadata-types", completionCacheTTL, func(f *Force) ([]string, error) {
+		describe, err := f.Metadata.DescribeMetadata()
+		if err != nil {
+			return nil, err
+		}
+		var values []string
+		for _, obj := range describe.MetadataObjects {
+			values = append(values, obj.XmlName)
+			values = append(values, obj.ChildXmlNames...)
+		}
+		sort.Strings(values)
+		return values, nil
+	})
+	return filterCompletions(types, toComplete, metadataTypes...), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeMetadataNames completes the names of components of the metadata
+// types given with --type.
+func completeMetadataNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var names []string
+	for _, metadataType := range metadataTypes {
+		metadataType := metadataType
+		members := cachedCompletions("metadata-"+metadataType, completionCacheTTL, func(f *Force) ([]string, error) {
+			body, err := f.Metadata.ListMetadata(metadataType)
+			if err != nil {
+				return nil, err
+			}
+			var res struct {
+				Response ListMetadataResponse `xml:"Body>listMetadataResponse"`
+			}
+			if err = xml.Unmarshal(body, &res); err != nil {
+				return nil, err
+			}
+			var values []string
+			for _, result := range res.Response.Result {
+				values = append(values, result.FullName)
+			}
+			sort.Strings(values)
+			return values, nil
+		})
+		names = append(names, members...)
+	}
+	return filterCompletions(names, toComplete, metadataName...), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeDeployIds completes the ids of recent deploys, described by their
+// status and start date.
+func completeDeployIds(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	ids := cachedCompletions("deploys", completionDeployCacheTTL, func(f *Force) ([]string, error) {
+		query := "SELECT Id, Status, StartDate FROM DeployRequest ORDER BY CreatedDate DESC LIMIT 50"
+		result, err := f.Query(query, func(options *QueryOptions) {
+			options.IsTooling = true
+		})
+		if err != nil {
+			return nil, err
+		}
+		var values []string
+		for _, r := range result.Records {
+			id, _ := r["Id"].(string)
+			status, _ := r["Status"].(string)
+			startDate, _ := r["StartDate"].(string)
+			values = append(values, fmt.Sprintf("%s\t%s %s", id, status, startDate))
+		}
+		return values, nil
+	})
+	return filterCompletions(ids, toComplete), cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveKeepOrder
+}
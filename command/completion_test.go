@@ -0,0 +1,59 @@
+package command
+
+import (
+	"reflect"
+	"testing"
+
+	forceConfig "github.com/ForceCLI/force/config"
+	"github.com/spf13/cobra"
+)
+
+func TestFilterCompletions(t *testing.T) {
+	values := []string{"Account\tAccount", "AccountContactRelation\tAccount Contact Relationship", "Contact\tContact"}
+
+	got := filterCompletions(values, "acc", "AccountContactRelation")
+	expected := []string{"Account\tAccount"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+
+	got = filterCompletions(values, "")
+	if !reflect.DeepEqual(got, values) {
+		t.Errorf("Expected %v, got %v", values, got)
+	}
+}
+
+func TestCompleteLogins(t *testing.T) {
+	origConfig := forceConfig.Config
+	if err := forceConfig.UseConfigDirectory(t.TempDir()); err != nil {
+		t.Fatalf("failed to set config directory: %v", err)
+	}
+	t.Cleanup(func() {
+		forceConfig.Config = origConfig
+	})
+	for _, name := range []string{"dev@example.com", "prod@example.com"} {
+		if err := forceConfig.Config.Save("accounts", name, "{}"); err != nil {
+			t.Fatalf("failed to save account: %v", err)
+		}
+	}
+
+	got, directive := completeLogins(RootCmd, nil, "pr")
+	expected := []string{"prod@example.com"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+	if directive != cobra.ShellCompDirectiveNoFileComp {
+		t.Errorf("Expected NoFileComp directive, got %v", directive)
+	}
+}
+
+func TestCompleteFieldValues_skips_positional_arguments(t *testing.T) {
+	complete := completeFieldValues(2)
+	got, directive := complete(recordUpdateCmd, []string{"Account"}, "001")
+	if len(got) != 0 {
+		t.Errorf("Expected no completions for record id, got %v", got)
+	}
+	if directive != cobra.ShellCompDirectiveNoFileComp {
+		t.Errorf("Expected NoFileComp directive, got %v", directive)
+	}
+}
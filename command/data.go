@@ -0,0 +1,115 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	. "github.com/ForceCLI/force/error"
+	. "github.com/ForceCLI/force/lib"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	dataExportCmd.Flags().StringArrayP("query", "q", []string{}, "SOQL query (may be repeated)")
+	dataExportCmd.Flags().StringP("directory", "d", ".", "directory to write data files to")
+	dataExportCmd.Flags().StringP("prefix", "p", "", "prefix for data file names")
+	dataExportCmd.MarkFlagRequired("query")
+
+	dataCmd.AddCommand(dataExportCmd)
+	dataCmd.AddCommand(dataImportCmd)
+	RootCmd.AddCommand(dataCmd)
+}
+
+var dataCmd = &cobra.Command{
+	Use:   "data",
+	Short: "Export and import trees of related records",
+	Long: `
+Export and import trees of related records
+
+Usage:
+
+  force data export --query <soql> [--query <soql>...] [-d <directory>]
+  force data import <plan or data file>...
+`,
+	Example: `
+  force data export -q "SELECT Id, Name, (SELECT Id, LastName FROM Contacts) FROM Account" -d seed
+  force data import seed/plan.json
+`,
+	DisableFlagsInUseLine: true,
+}
+
+var dataExportCmd = &cobra.Command{
+	Use:   "export --query <soql>",
+	Short: "Export records as sObject trees",
+	Long: `Export records as JSON in the sObject Tree format, along with a plan file
+listing the data files in the order in which they should be imported.
+
+Child relationship subqueries are exported as nested records.  Lookups to
+records that are included in the export are replaced with @<referenceId>
+placeholders, so include the Id field in each query.
+`,
+	Example: `
+  force data export -q "SELECT Id, Name, (SELECT Id, LastName FROM Contacts) FROM Account"
+  force data export -q "SELECT Id, Name FROM Account" -q "SELECT Id, Name, AccountId FROM Opportunity" -d seed
+`,
+	Args: cobra.ExactArgs(0),
+	Run: func(cmd *cobra.Command, args []string) {
+		queries, _ := cmd.Flags().GetStringArray("query")
+		directory, _ := cmd.Flags().GetString("directory")
+		prefix, _ := cmd.Flags().GetString("prefix")
+		runDataExport(queries, directory, prefix)
+	},
+}
+
+var dataImportCmd = &cobra.Command{
+	Use:   "import <file>...",
+	Short: "Import sObject trees",
+	Long: `Insert records from plan or data files created by "force data export".
+
+Records are inserted using the sObject Tree API once the records they
+reference have been inserted, and @<referenceId> placeholders are replaced with
+the ids of the new records.  Lookups between records in the same tree are set
+by updating the records after all of the trees have been inserted.
+`,
+	Example: `
+  force data import seed/plan.json
+  force data import Account.json Contact.json
+`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runDataImport(args)
+	},
+}
+
+func runDataExport(queries []string, directory string, prefix string) {
+	export, err := force.ExportRecordTrees(queries)
+	if err != nil {
+		ErrorAndExit(err.Error())
+	}
+	if err := export.Write(directory, prefix); err != nil {
+		ErrorAndExit(err.Error())
+	}
+	for _, entry := range export.Plan(prefix) {
+		count := 0
+		for _, r := range export.Trees[entry.SObject].Records {
+			count += r.Count()
+		}
+		fmt.Printf("Exported %d records to %s\n", count, filepath.Join(directory, entry.Files[0]))
+	}
+	fmt.Printf("Wrote plan to %s\n", filepath.Join(directory, prefix+RecordTreePlanFile))
+}
+
+func runDataImport(files []string) {
+	records, err := LoadRecordTrees(files)
+	if err != nil {
+		ErrorAndExit(err.Error())
+	}
+	ids, err := force.ImportRecordTrees(records, func(sobject string, inserted int) {
+		fmt.Fprintf(os.Stderr, "Inserted %d %s records\n", inserted, sobject)
+	})
+	if err != nil {
+		ErrorAndExit(err.Error())
+	}
+	fmt.Printf("Inserted %d records\n", len(ids))
+}
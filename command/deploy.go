@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"strings"
 	"syscall"
 	"time"
@@ -22,6 +23,12 @@ type deployOutputOptions struct {
 	ignoreCodeCoverageWarnings bool
 	suppressUnexpectedError    bool
 	errorOnTestFailure         bool
+	plan                       bool
+	guard                      string
+	auditObject                string
+	// sourceDir is the directory the metadata is deployed from, used to
+	// locate failures in the sarif, github and gitlab reports.
+	sourceDir string
 }
 
 func defaultDeployOutputOptions() *deployOutputOptions {
@@ -66,6 +73,19 @@ func monitorDeploy(deployId string) (ForceCheckDeploymentStatusResult, error) {
 }
 
 func deploy(force *Force, files ForceMetadataFiles, deployOptions *ForceDeployOptions, outputOptions *deployOutputOptions) error {
+	if outputOptions.plan {
+		plan, err := force.PlanDeploy(files, *deployOptions)
+		if err != nil {
+			return err
+		}
+		displayDeployPlan(plan)
+		return nil
+	}
+	if outputOptions.guard != "" {
+		if err := guardConcurrentModifications(force, files, outputOptions.guard); err != nil {
+			return err
+		}
+	}
 	if outputOptions.quiet {
 		previousLogger := Log
 		var l quietLogger
@@ -82,17 +102,19 @@ func deploy(force *Force, files ForceMetadataFiles, deployOptions *ForceDeployOp
 	stopDeployUponSignal(force, deployId)
 	if outputOptions.interactive {
 		watchDeploy(deployId)
+		// Record the outcome if the deploy finished while being watched.
+		force.Metadata.CheckDeployStatus(deployId)
+		auditDeploy(deployId, outputOptions.auditObject)
 		return nil
 	}
 	result, err := monitorDeploy(deployId)
+	auditDeploy(deployId, outputOptions.auditObject)
 	if err != nil {
 		return err
 	}
 	endTime := time.Now()
 	duration := endTime.Sub(startTime)
 
-	junitOutput := outputOptions.reportFormat == "junit"
-
 	if outputOptions.suppressUnexpectedError {
 		filteredComponentFailures := result.Details.ComponentFailures[:0]
 		for _, f := range result.Details.ComponentFailures {
@@ -105,8 +127,8 @@ func deploy(force *Force, files ForceMetadataFiles, deployOptions *ForceDeployOp
 
 	switch {
 	case outputOptions.quiet:
-	case junitOutput:
-		output, err := result.ToJunit(duration.Seconds())
+	case outputOptions.reportFormat != "text" && outputOptions.reportFormat != "":
+		output, err := deployReport(result, outputOptions, duration)
 		if err != nil {
 			return fmt.Errorf("Failed to generate output: %w", err)
 		}
@@ -156,6 +178,37 @@ func deploy(force *Force, files ForceMetadataFiles, deployOptions *ForceDeployOp
 	return nil
 }
 
+// deployReport formats the deploy result in one of the machine-readable
+// report formats.
+func deployReport(result ForceCheckDeploymentStatusResult, outputOptions *deployOutputOptions, duration time.Duration) (string, error) {
+	switch outputOptions.reportFormat {
+	case "junit":
+		return result.ToJunit(duration.Seconds())
+	case "sarif":
+		return result.ToSarif(reportRoot(outputOptions.sourceDir))
+	case "github":
+		return strings.TrimSuffix(result.ToGitHubAnnotations(reportRoot(outputOptions.sourceDir)), "\n"), nil
+	case "gitlab":
+		return result.ToGitLabCodeQuality(reportRoot(outputOptions.sourceDir))
+	}
+	return "", fmt.Errorf("Unknown report type %s", outputOptions.reportFormat)
+}
+
+// reportRoot returns the source directory relative to the current directory,
+// which is expected to be the root of the repository, so reported paths
+// match the files in pull requests.
+func reportRoot(sourceDir string) string {
+	if sourceDir == "" {
+		return ""
+	}
+	if wd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(wd, sourceDir); err == nil && !strings.HasPrefix(rel, "..") {
+			return rel
+		}
+	}
+	return sourceDir
+}
+
 func stopDeployUponSignal(force *Force, deployId string) {
 	sigs := make(chan os.Signal, 1)
 	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
@@ -178,9 +231,18 @@ func getDeploymentOutputOptions(cmd *cobra.Command) *deployOutputOptions {
 	outputOptions := defaultDeployOutputOptions()
 
 	if reportFormat, err := cmd.Flags().GetString("reporttype"); err == nil {
+		switch reportFormat {
+		case "text", "junit", "sarif", "github", "gitlab":
+		default:
+			ErrorAndExit("--reporttype must be text, junit, sarif, github or gitlab")
+		}
 		outputOptions.reportFormat = reportFormat
 	}
 
+	if auditObject, err := cmd.Flags().GetString("audit-object"); err == nil {
+		outputOptions.auditObject = auditObject
+	}
+
 	if quiet, err := cmd.Flags().GetBool("quiet"); err == nil {
 		outputOptions.quiet = quiet
 	}
@@ -205,6 +267,17 @@ func getDeploymentOutputOptions(cmd *cobra.Command) *deployOutputOptions {
 		outputOptions.errorOnTestFailure = errorOnTestFailure
 	}
 
+	if plan, err := cmd.Flags().GetBool("plan"); err == nil {
+		outputOptions.plan = plan
+	}
+
+	if guard, err := cmd.Flags().GetString("guard"); err == nil {
+		if guard != "" && guard != "warn" && guard != "refuse" {
+			ErrorAndExit("--guard must be warn or refuse")
+		}
+		outputOptions.guard = guard
+	}
+
 	return outputOptions
 }
 
@@ -232,3 +305,73 @@ func getDeploymentOptions(cmd *cobra.Command) ForceDeployOptions {
 	}
 	return deploymentOptions
 }
+
+// displayDeployPlan prints the components a deploy would create, overwrite
+// and delete, and the tests it would run.
+func displayDeployPlan(plan DeployPlan) {
+	section := func(title string, components []DeployPlanComponent) {
+		fmt.Printf("%s (%d)\n", title, len(components))
+		for _, c := range components {
+			line := fmt.Sprintf("  %s %s", c.Type, c.FullName)
+			if c.Existing != nil && !c.Existing.LastModifedDate.IsZero() {
+				line += fmt.Sprintf(" (last modified %s by %s)", c.Existing.LastModifedDate.Local().Format("2006-01-02 15:04"), c.Existing.LastModifiedByName)
+			}
+			if c.ModifiedByOther {
+				line += " [modified by another user]"
+			}
+			fmt.Println(line)
+		}
+		fmt.Println()
+	}
+	section("New components", plan.New)
+	section("Overwritten components", plan.Overwritten)
+	section("Deleted components", plan.Deleted)
+
+	fmt.Printf("Tests (%s): ", plan.TestLevel)
+	switch {
+	case plan.TestLevel == "NoTestRun":
+		fmt.Println("none")
+	case len(plan.Tests) == 0:
+		fmt.Println("none found")
+	default:
+		fmt.Println(len(plan.Tests))
+		for _, test := range plan.Tests {
+			fmt.Printf("  %s\n", test)
+		}
+	}
+	for _, warning := range plan.Warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+	fmt.Println("\nPlan only; nothing was deployed.")
+}
+
+// guardConcurrentModifications checks whether any of the components to be
+// deployed or deleted were changed in the org by someone else since they were
+// last fetched, or can't be checked because their fetch state wasn't
+// recorded.  With the refuse guard, the deploy is stopped if so.
+func guardConcurrentModifications(force *Force, files ForceMetadataFiles, guard string) error {
+	state, err := LoadFetchState(force.Credentials.SessionName())
+	if err != nil {
+		return fmt.Errorf("Could not load fetch state: %w", err)
+	}
+	modified, unchecked, err := force.ModifiedSinceFetch(files, state)
+	if err != nil {
+		return err
+	}
+	for _, c := range unchecked {
+		fmt.Fprintf(os.Stderr, "%s can't be checked for changes in the org because it wasn't fetched with --record-state\n", c)
+	}
+	for _, m := range modified {
+		fmt.Fprintf(os.Stderr, "%s %s was modified by %s at %s, after it was fetched (last modified %s)\n",
+			m.Type, m.FullName, m.Current.LastModifiedByName,
+			m.Current.LastModifedDate.Local().Format("2006-01-02 15:04"),
+			m.Fetched.LastModifiedDate.Local().Format("2006-01-02 15:04"))
+	}
+	if len(modified) > 0 && guard == "refuse" {
+		return fmt.Errorf("%d component(s) were modified in the org since they were fetched.  Fetch them again, or use --guard=warn to deploy anyway.", len(modified))
+	}
+	if len(unchecked) > 0 && guard == "refuse" {
+		return fmt.Errorf("%d component(s) have no recorded fetch state.  Fetch them with --record-state, or use --guard=warn to deploy anyway.", len(unchecked))
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	. "github.com/ForceCLI/force/error"
+	. "github.com/ForceCLI/force/lib"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	deployHistoryCmd.Flags().StringP("user", "u", "", "only deploys by this username")
+	deployHistoryCmd.Flags().String("org", "", "only deploys to the org with this id, or an instance url containing this")
+	deployHistoryCmd.Flags().StringP("branch", "b", "", "only deploys from this git branch")
+	deployHistoryCmd.Flags().String("commit", "", "only deploys from git commits starting with this")
+	deployHistoryCmd.Flags().String("status", "", "only deploys with this status, e.g. Succeeded, Failed or Canceled")
+	deployHistoryCmd.Flags().StringP("component", "c", "", "only deploys of components whose Type/Name contains this")
+	deployHistoryCmd.Flags().String("since", "", "only deploys since this date (YYYY-MM-DD) or duration ago (e.g. 72h)")
+	deployHistoryCmd.Flags().IntP("limit", "n", 20, "show at most this many deploys (0 for all)")
+	deployHistoryCmd.Flags().StringP("format", "f", "text", "output format: text, json")
+	deploysCmd.AddCommand(deployHistoryCmd)
+}
+
+var deployHistoryCmd = &cobra.Command{
+	Use:   "history",
+	Short: "List deploys recorded on this computer",
+	Long: `
+List the deploys started on this computer, such as by push, import and watch,
+most recent first, with the org and user they were deployed as, the git commit
+and branch they were deployed from, the components deployed, and the outcome.
+The outcome of a deploy that was still in progress is recorded when its status
+is next checked, e.g. with "force deploys status".
+
+Use --audit-object with push or import to also record deploys in the org, so
+the history is shared across the team.  The custom object or platform event
+needs the text fields DeployId__c, Username__c, GitCommit__c, GitBranch__c and
+Status__c, the long text fields Components__c and Options__c, and the number
+field DurationSeconds__c.
+`,
+	Example: `
+  force deploys history
+  force deploys history --branch main --status Failed
+  force deploys history --component ApexClass/MyClass --since 2024-01-01
+  force deploys history --org mycompany--uat -f json
+`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		var filter DeployHistoryFilter
+		filter.Username, _ = cmd.Flags().GetString("user")
+		filter.Org, _ = cmd.Flags().GetString("org")
+		filter.Branch, _ = cmd.Flags().GetString("branch")
+		filter.Commit, _ = cmd.Flags().GetString("commit")
+		filter.Status, _ = cmd.Flags().GetString("status")
+		filter.Component, _ = cmd.Flags().GetString("component")
+		filter.Limit, _ = cmd.Flags().GetInt("limit")
+		if since, _ := cmd.Flags().GetString("since"); since != "" {
+			var err error
+			filter.Since, err = parseSince(since, time.Now())
+			if err != nil {
+				ErrorAndExit(err.Error())
+			}
+		}
+		format, _ := cmd.Flags().GetString("format")
+		if format != "text" && format != "json" {
+			ErrorAndExit("--format must be text or json")
+		}
+
+		records, err := LoadDeployHistory()
+		if err != nil {
+			ErrorAndExit(err.Error())
+		}
+		records = FilterDeployHistory(records, filter)
+		if format == "json" {
+			data, err := json.MarshalIndent(records, "", "  ")
+			if err != nil {
+				ErrorAndExit(err.Error())
+			}
+			fmt.Println(string(data))
+			return
+		}
+		if len(records) == 0 {
+			fmt.Println("No deploys recorded.")
+			return
+		}
+		displayDeployHistory(os.Stdout, records)
+	},
+}
+
+// parseSince parses a date, or a duration before now.
+func parseSince(since string, now time.Time) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", since, time.Local); err == nil {
+		return t, nil
+	}
+	if d, err := time.ParseDuration(since); err == nil {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("--since must be a date (YYYY-MM-DD) or a duration (e.g. 72h)")
+}
+
+func displayDeployHistory(out io.Writer, records []DeployRecord) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STARTED\tDEPLOY ID\tUSER\tBRANCH\tCOMMIT\tSTATUS\tCOMPONENTS\tDURATION")
+	for _, r := range records {
+		commit := r.GitCommit
+		if len(commit) > 7 {
+			commit = commit[:7]
+		}
+		if r.GitDirty {
+			commit += "+"
+		}
+		status := r.Status
+		if r.Options.CheckOnly {
+			status += " (check only)"
+		}
+		duration := "-"
+		if r.DurationSeconds > 0 {
+			duration = (time.Duration(r.DurationSeconds * float64(time.Second))).Round(time.Second).String()
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%d\t%s\n",
+			r.StartTime.Local().Format("2006-01-02 15:04"),
+			r.Id,
+			r.Username,
+			valueOrDash(r.GitBranch),
+			valueOrDash(commit),
+			status,
+			len(r.Components)+len(r.DeletedComponents),
+			duration)
+	}
+	w.Flush()
+}
+
+func valueOrDash(s string) string {
+	if strings.TrimSpace(s) == "" {
+		return "-"
+	}
+	return s
+}
+
+// auditDeploy records a deploy in the org as a record of auditObject, if
+// it's set, with its outcome as recorded in the deploy history.
+func auditDeploy(deployId string, auditObject string) {
+	if auditObject == "" {
+		return
+	}
+	record, err := LoadDeployRecord(deployId)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not record deploy %s in the org: %s\n", deployId, err.Error())
+		return
+	}
+	if err := force.AttachDeployRecord(auditObject, record); err != nil {
+		fmt.Fprintf(os.Stderr, "Could not record deploy %s in the org: %s\n", deployId, err.Error())
+	}
+}
@@ -0,0 +1,48 @@
+package command
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/ForceCLI/force/lib"
+)
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.Local)
+	if since, err := parseSince("2024-03-01", now); err != nil || !since.Equal(time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local)) {
+		t.Errorf("Expected March 1, got %v, %v", since, err)
+	}
+	if since, err := parseSince("72h", now); err != nil || !since.Equal(now.Add(-72*time.Hour)) {
+		t.Errorf("Expected 72 hours ago, got %v, %v", since, err)
+	}
+	if _, err := parseSince("last week", now); err == nil {
+		t.Error("Expected an error parsing an invalid --since")
+	}
+}
+
+func TestDisplayDeployHistory(t *testing.T) {
+	var out bytes.Buffer
+	displayDeployHistory(&out, []DeployRecord{{
+		Id:              "0Af000000000001AAA",
+		Username:        "user@example.com",
+		GitBranch:       "main",
+		GitCommit:       "0123456789abcdef",
+		GitDirty:        true,
+		Status:          "Succeeded",
+		Options:         ForceDeployOptions{CheckOnly: true},
+		Components:      []string{"ApexClass/A", "ApexClass/B"},
+		StartTime:       time.Now(),
+		DurationSeconds: 61.2,
+	}})
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected a header and one deploy, got %q", out.String())
+	}
+	for _, expected := range []string{"0Af000000000001AAA", "user@example.com", "main", "0123456+", "Succeeded (check only)", " 2 ", "1m1s"} {
+		if !strings.Contains(lines[1], expected) {
+			t.Errorf("Expected %q in %q", expected, lines[1])
+		}
+	}
+}
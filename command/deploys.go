@@ -36,6 +36,10 @@ func init() {
 	statusDeployCmd.Flags().BoolP("verbose", "v", false, "Show detailed information including component changes")
 	statusDeployCmd.MarkFlagRequired("deploy-id")
 
+	for _, cmd := range []*cobra.Command{cancelDeployCmd, listDeployErrorsCmd, watchDeployCmd, statusDeployCmd} {
+		cmd.RegisterFlagCompletionFunc("deploy-id", completeDeployIds)
+	}
+
 	deploysCmd.AddCommand(listDeploysCmd)
 	deploysCmd.AddCommand(cancelDeployCmd)
 	deploysCmd.AddCommand(listDeployErrorsCmd)
@@ -49,13 +53,15 @@ var deploysCmd = &cobra.Command{
 	Use:   "deploys",
 	Short: "Manage metadata deployments",
 	Long: `
-List and cancel metadata deployments.
+List and cancel metadata deployments, and show the history of deploys made
+from this computer.
 `,
 
 	Example: `
   force deploys list
   force deploys cancel --all
   force deploys cancel -d 0Af000000000000000
+  force deploys history --branch main
 `,
 	DisableFlagsInUseLine: false,
 }
@@ -2,6 +2,10 @@ package command
 
 import (
 	"encoding/xml"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
 
 	. "github.com/ForceCLI/force/error"
 	. "github.com/ForceCLI/force/lib"
@@ -13,9 +17,20 @@ func init() {
 	describeMetadataCmd.Flags().BoolP("json", "j", false, "json output")
 	describeSobjectCmd.Flags().StringP("name", "n", "", "name of sobject")
 	describeSobjectCmd.Flags().BoolP("json", "j", false, "json output")
+	describeSobjectCmd.RegisterFlagCompletionFunc("name", completeSObjectFlag)
+	describeExportCmd.Flags().StringP("output", "o", "data-dictionary", "output directory")
+	describeExportCmd.Flags().StringP("format", "f", "markdown", "output format: markdown, html")
+	describeExportCmd.Flags().StringP("diagram", "d", "mermaid", "entity-relationship diagram format: mermaid, dot")
 
 	describeCmd.AddCommand(describeMetadataCmd)
 	describeCmd.AddCommand(describeSobjectCmd)
+	describeCacheClearCmd.Flags().Bool("all", false, "clear cached describes for all orgs")
+	describeCacheStatusCmd.Flags().Bool("all", false, "show cached describes for all orgs")
+
+	describeCacheCmd.AddCommand(describeCacheClearCmd)
+	describeCacheCmd.AddCommand(describeCacheStatusCmd)
+	describeCmd.AddCommand(describeExportCmd)
+	describeCmd.AddCommand(describeCacheCmd)
 	RootCmd.AddCommand(describeCmd)
 }
 
@@ -52,13 +67,83 @@ object name will retrieve all of the details about the object.
 	},
 }
 
+var describeExportCmd = &cobra.Command{
+	Use:   "export [object...]",
+	Short: "Export a data dictionary",
+	Long: `Generate documentation for SObjects, including fields, types, help text,
+picklist values, relationships, record types, and an entity-relationship
+diagram.  With no objects specified, all custom objects are documented.
+
+Describe results are stored in the local describe cache, so subsequent
+exports only download objects that have changed.
+`,
+	Example: `
+  force describe export
+  force describe export Account Contact Opportunity -f html -o docs/schema
+  force describe export -d dot
+  `,
+	ValidArgsFunction: completeSObjectList,
+	Run: func(cmd *cobra.Command, args []string) {
+		output, _ := cmd.Flags().GetString("output")
+		format, _ := cmd.Flags().GetString("format")
+		diagram, _ := cmd.Flags().GetString("diagram")
+		runDescribeExport(args, output, format, DiagramFormat(diagram))
+	},
+}
+
+var describeCacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the local describe cache",
+	Long: `Manage the local cache of SObject describes.  Describes are cached per org
+and API version, and are revalidated with the org before being used.
+`,
+	Example: `
+  force describe cache status
+  force describe cache clear
+  force describe cache clear --all
+  `,
+	Args: cobra.ExactArgs(0),
+}
+
+var describeCacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove cached describes",
+	Args:  cobra.ExactArgs(0),
+	Run: func(cmd *cobra.Command, args []string) {
+		all, _ := cmd.Flags().GetBool("all")
+		orgId := ""
+		if !all {
+			orgId = describeCacheOrgId()
+		}
+		if err := ClearDescribeCache(orgId); err != nil {
+			ErrorAndExit(err.Error())
+		}
+		fmt.Println("Describe cache cleared")
+	},
+}
+
+var describeCacheStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "List cached describes",
+	Args:  cobra.ExactArgs(0),
+	Run: func(cmd *cobra.Command, args []string) {
+		all, _ := cmd.Flags().GetBool("all")
+		orgId := ""
+		if !all {
+			orgId = describeCacheOrgId()
+		}
+		runDescribeCacheStatus(orgId)
+	},
+}
+
 var describeCmd = &cobra.Command{
-	Use:   "describe (metadata|sobject) [flags]",
+	Use:   "describe (metadata|sobject|export|cache) [flags]",
 	Short: "Describe the types of metadata available in the org",
 	Example: `
   force describe metadata
   force describe metadata -t MatchingRule -j
   force describe sobject -n Account
+  force describe export
   `,
 	Args: cobra.ExactArgs(0),
 }
@@ -106,10 +191,75 @@ func describeSObject(item string, json bool) {
 		}
 	} else {
 		// describe sobject
-		desc, err := force.DescribeSObject(item)
+		desc, err := force.DescribeSObjectCached(item)
 		if err != nil {
 			ErrorAndExit(err.Error())
 		}
 		DisplayForceSobjectDescribe(desc)
 	}
 }
+
+func runDescribeExport(objects []string, output string, format string, diagram DiagramFormat) {
+	switch format {
+	case "markdown", "md", "html":
+	default:
+		ErrorAndExit("unsupported format: %s", format)
+	}
+	switch diagram {
+	case DiagramMermaid, DiagramDot:
+	default:
+		ErrorAndExit("unsupported diagram format: %s", diagram)
+	}
+	if len(objects) == 0 {
+		sobjects, err := force.ListSobjects()
+		if err != nil {
+			ErrorAndExit(err.Error())
+		}
+		for _, sobject := range sobjects {
+			if custom, _ := sobject["custom"].(bool); custom {
+				objects = append(objects, sobject["name"].(string))
+			}
+		}
+	}
+	var describes []SObjectDescribe
+	for _, name := range objects {
+		body, err := force.DescribeSObjectCached(name)
+		if err != nil {
+			ErrorAndExit("Failed to describe %s: %s", name, err.Error())
+		}
+		describe, err := ParseSObjectDescribe(body)
+		if err != nil {
+			ErrorAndExit("Failed to parse describe for %s: %s", name, err.Error())
+		}
+		describes = append(describes, describe)
+	}
+	dictionary := NewDataDictionary(describes, diagram)
+	if err := dictionary.Write(output, format); err != nil {
+		ErrorAndExit(err.Error())
+	}
+	fmt.Printf("Documented %d objects in %s\n", len(describes), output)
+}
+
+func describeCacheOrgId() string {
+	if force.Credentials.UserInfo == nil || force.Credentials.UserInfo.OrgId == "" {
+		ErrorAndExit("Unable to determine org id for the current session")
+	}
+	return force.Credentials.UserInfo.OrgId
+}
+
+func runDescribeCacheStatus(orgId string) {
+	entries, err := DescribeCacheEntries(orgId)
+	if err != nil {
+		ErrorAndExit(err.Error())
+	}
+	if len(entries) == 0 {
+		fmt.Println("No cached describes")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ORG ID\tAPI VERSION\tOBJECT\tCACHED AT")
+	for _, entry := range entries {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", entry.OrgId, entry.ApiVersion, entry.Object, entry.CachedAt.Format(time.RFC3339))
+	}
+	w.Flush()
+}
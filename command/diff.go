@@ -0,0 +1,126 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ForceCLI/force/config"
+	. "github.com/ForceCLI/force/error"
+	. "github.com/ForceCLI/force/lib"
+	"github.com/spf13/cobra"
+)
+
+// diffExitCode is the exit status when differences are found.  Errors exit
+// with status 1.
+const diffExitCode = 2
+
+func init() {
+	diffCmd.Flags().BoolP("summary", "s", false, "only show the status of each component")
+	diffCmd.Flags().BoolP("warnings", "w", false, "display warnings about metadata that cannot be retrieved")
+	RootCmd.AddCommand(diffCmd)
+}
+
+var diffCmd = &cobra.Command{
+	Use:   "diff [paths...]",
+	Short: "Compare local metadata to the org",
+	Long: `
+Compare local metadata to the org.
+
+The org's versions of the local components are retrieved and compared to the
+local files.  XML is normalized before comparing, so differences in
+whitespace and in the order of elements are ignored, except in the order of
+repeated elements, such as picklist values.
+
+Each component is listed as added (only in the local source), changed,
+unchanged, or org-only (only in the org), followed by unified diffs from the
+org's version to the local version.
+
+Exits with status 2 if there are differences, so drift can be detected in CI.
+Without paths, the whole source directory is compared.
+`,
+	Example: `
+  force diff
+  force diff src/classes/MyClass.cls src/objects/Account.object
+  force diff --summary src/aura
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		summaryOnly, _ := cmd.Flags().GetBool("summary")
+		showWarnings, _ := cmd.Flags().GetBool("warnings")
+		if differences := runDiff(args, summaryOnly, showWarnings); differences {
+			os.Exit(diffExitCode)
+		}
+	},
+}
+
+// runDiff compares the local metadata in paths to the org, reporting whether
+// there are any differences.
+func runDiff(paths []string, summaryOnly bool, showWarnings bool) bool {
+	pb := NewPushBuilder()
+	var sourceDir string
+	if len(paths) > 0 {
+		sourceDir = sourceDirFromPaths(paths)
+	}
+	var err error
+	if sourceDir == "" {
+		sourceDir, err = config.GetSourceDir()
+		ExitIfNoSourceDir(err)
+	}
+	pb.Root = sourceDir
+	if len(paths) == 0 {
+		paths = metadataDirectories(sourceDir)
+	}
+	for _, p := range paths {
+		// Aura and LWC components are retrieved as whole bundles.
+		p = replaceComponentWithBundle(p)
+		if err := pb.Add(p); err != nil {
+			ErrorAndExit("Could not add %s: %s", p, err.Error())
+		}
+	}
+	if len(pb.Metadata) == 0 {
+		ErrorAndExit("Nothing to compare")
+	}
+
+	org, problems, err := force.Metadata.RetrieveByPackageXmlContents(pb.PackageXml())
+	if err != nil {
+		ErrorAndExit(err.Error())
+	}
+	if showWarnings {
+		for _, problem := range problems {
+			fmt.Fprintln(os.Stderr, problem)
+		}
+	}
+
+	differences := false
+	for _, d := range DiffMetadataFiles(pb.Files, org) {
+		if d.Status != DiffUnchanged {
+			differences = true
+		}
+		fmt.Printf("%-9s %s %s\n", d.Status, d.Type, d.Name)
+		if summaryOnly {
+			continue
+		}
+		for _, f := range d.Files {
+			fmt.Print(f.Diff)
+		}
+	}
+	return differences
+}
+
+// metadataDirectories returns the metadata type directories in the source
+// directory.
+func metadataDirectories(sourceDir string) []string {
+	entries, err := os.ReadDir(sourceDir)
+	if err != nil {
+		ErrorAndExit(err.Error())
+	}
+	var dirs []string
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		dirs = append(dirs, filepath.Join(sourceDir, entry.Name()))
+	}
+	return dirs
+}
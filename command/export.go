@@ -2,7 +2,6 @@ package command
 
 import (
 	"fmt"
-	"io/ioutil"
 	"os"
 	"path/filepath"
 	"sort"
@@ -17,6 +16,7 @@ import (
 func init() {
 	exportCmd.Flags().BoolP("warnings", "w", false, "display warnings about metadata that cannot be retrieved")
 	exportCmd.Flags().StringSliceP("exclude", "x", []string{}, "exclude metadata type")
+	exportCmd.Flags().BoolP("preserve", "p", false, "keep retrieved zip file on disk, as inbound.zip")
 
 	RootCmd.AddCommand(exportCmd)
 }
@@ -48,11 +48,15 @@ var exportCmd = &cobra.Command{
 		}
 		excludeMetadataNames, _ := cmd.Flags().GetStringSlice("exclude")
 		showWarnings, _ := cmd.Flags().GetBool("warnings")
-		runExport(root, excludeMetadataNames, showWarnings)
+		var options RetrieveOptions
+		if preserve, _ := cmd.Flags().GetBool("preserve"); preserve {
+			options.PreserveZip = "inbound.zip"
+		}
+		runExport(root, excludeMetadataNames, showWarnings, options)
 	},
 }
 
-func runExport(root string, excludeMetadataNames []string, showWarnings bool) {
+func runExport(root string, excludeMetadataNames []string, showWarnings bool, options RetrieveOptions) {
 	sobjects, err := force.ListSobjects()
 	if err != nil {
 		ErrorAndExit(err.Error())
@@ -219,7 +223,7 @@ func runExport(root string, excludeMetadataNames []string, showWarnings bool) {
 		}
 	}
 
-	files, problems, err := force.Metadata.Retrieve(query)
+	problems, err := force.Metadata.RetrieveTo(query, RetrieveToDirectory(root), options)
 	if err != nil {
 		fmt.Printf("Encountered and error with retrieve...\n")
 		ErrorAndExit(err.Error())
@@ -229,16 +233,6 @@ func runExport(root string, excludeMetadataNames []string, showWarnings bool) {
 			fmt.Fprintln(os.Stderr, problem)
 		}
 	}
-	for name, data := range files {
-		file := filepath.Join(root, name)
-		dir := filepath.Dir(file)
-		if err := os.MkdirAll(dir, 0755); err != nil {
-			ErrorAndExit(err.Error())
-		}
-		if err := ioutil.WriteFile(filepath.Join(root, name), data, 0644); err != nil {
-			ErrorAndExit(err.Error())
-		}
-	}
 	fmt.Printf("Exported to %s\n", root)
 }
 
@@ -0,0 +1,221 @@
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+
+	. "github.com/ForceCLI/force/error"
+	. "github.com/ForceCLI/force/lib"
+)
+
+var (
+	accounts       string
+	accountsOutput string
+)
+
+const fanOutConcurrency = 8
+
+// fanOutChildEnv is set in the environment of the commands run for each
+// login.
+const fanOutChildEnv = "FORCE_ACCOUNTS_CHILD"
+
+func init() {
+	RootCmd.PersistentFlags().StringVar(&accounts, "accounts", "", "run the command against each saved login matching a comma-separated list of usernames, patterns, or @groups")
+	RootCmd.PersistentFlags().StringVar(&accountsOutput, "accounts-output", "prefix", "how to show output with --accounts: prefix or group")
+	RootCmd.RegisterFlagCompletionFunc("accounts", completeLogins)
+	RootCmd.RegisterFlagCompletionFunc("accounts-output", cobra.FixedCompletions([]string{"prefix", "group"}, cobra.ShellCompDirectiveNoFileComp))
+}
+
+type fanOutResult struct {
+	Login  string
+	Stdout []byte
+	Stderr []byte
+	Err    error
+}
+
+// runFanOut runs the current command once for each login matching
+// --accounts, each in its own process with --account set, then prints the
+// combined output and exits.  If JSON output was requested with --json or
+// --format json, output that is JSON for every login is merged into a single
+// array with an org field added to each object.
+func runFanOut(cmd *cobra.Command, topLevel *cobra.Command) {
+	if account != "" {
+		ErrorAndExit("Cannot use both --account and --accounts")
+	}
+	switch topLevel.Name() {
+	case "force", "login", "logout", "logins", "active", "completion", "usedxauth", cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd:
+		ErrorAndExit("--accounts cannot be used with %s", cmd.CommandPath())
+	}
+	if accountsOutput != "prefix" && accountsOutput != "group" {
+		ErrorAndExit("--accounts-output must be prefix or group")
+	}
+	logins, err := MatchLogins(accounts)
+	if err != nil {
+		ErrorAndExit(err.Error())
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		ErrorAndExit(err.Error())
+	}
+	args := fanOutArgs(os.Args[1:])
+
+	results := make([]fanOutResult, len(logins))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, fanOutConcurrency)
+	for i, login := range logins {
+		wg.Add(1)
+		go func(i int, login string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			var stdout, stderr bytes.Buffer
+			child := exec.Command(exe, append([]string{"--account=" + login}, args...)...)
+			child.Env = append(os.Environ(), fanOutChildEnv+"=1")
+			child.Stdout = &stdout
+			child.Stderr = &stderr
+			err := child.Run()
+			results[i] = fanOutResult{Login: login, Stdout: stdout.Bytes(), Stderr: stderr.Bytes(), Err: err}
+		}(i, login)
+	}
+	wg.Wait()
+
+	var merged []byte
+	ok := false
+	if fanOutWantsJSON(cmd) {
+		merged, ok = mergeFanOutJSON(results)
+	}
+	if ok {
+		os.Stdout.Write(merged)
+		for _, r := range results {
+			writePrefixed(os.Stderr, r.Login, r.Stderr)
+		}
+	} else {
+		for _, r := range results {
+			if accountsOutput == "group" {
+				fmt.Fprintf(os.Stdout, "=== %s ===\n", r.Login)
+				os.Stdout.Write(r.Stdout)
+				os.Stderr.Write(r.Stderr)
+			} else {
+				writePrefixed(os.Stdout, r.Login, r.Stdout)
+				writePrefixed(os.Stderr, r.Login, r.Stderr)
+			}
+		}
+	}
+
+	var failed []string
+	for _, r := range results {
+		if r.Err != nil {
+			failed = append(failed, r.Login)
+		}
+	}
+	if len(failed) > 0 {
+		ErrorAndExit("Failed for %d of %d logins: %s", len(failed), len(results), strings.Join(failed, ", "))
+	}
+	os.Exit(0)
+}
+
+// fanOutActive reports whether the command is running for multiple logins,
+// either as the parent or as one of the commands run for each login.
+func fanOutActive() bool {
+	return accounts != "" || os.Getenv(fanOutChildEnv) != ""
+}
+
+// fanOutArgs removes the --accounts flags from args so they can be passed to
+// the command run for each login.
+func fanOutArgs(args []string) []string {
+	var result []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			return append(result, args[i:]...)
+		}
+		name := strings.SplitN(arg, "=", 2)[0]
+		if name == "--accounts" || name == "--accounts-output" {
+			if !strings.Contains(arg, "=") {
+				i++
+			}
+			continue
+		}
+		result = append(result, arg)
+	}
+	return result
+}
+
+func writePrefixed(w io.Writer, prefix string, output []byte) {
+	if len(output) == 0 {
+		return
+	}
+	lines := strings.Split(strings.TrimSuffix(string(output), "\n"), "\n")
+	for _, line := range lines {
+		fmt.Fprintf(w, "%s: %s\n", prefix, line)
+	}
+}
+
+// fanOutWantsJSON reports whether cmd was asked for JSON output with --json
+// or --format json.
+func fanOutWantsJSON(cmd *cobra.Command) bool {
+	if flag := cmd.Flags().Lookup("json"); flag != nil && flag.Value.Type() == "bool" && flag.Value.String() == "true" {
+		return true
+	}
+	if flag := cmd.Flags().Lookup("format"); flag != nil {
+		return strings.HasPrefix(strings.ToLower(flag.Value.String()), "json")
+	}
+	return false
+}
+
+// mergeFanOutJSON combines the output of each successful login into a JSON
+// array if every output is a sequence of JSON values.  Objects get an org
+// field; arrays are flattened; other values are wrapped in an object.
+func mergeFanOutJSON(results []fanOutResult) ([]byte, bool) {
+	merged := []interface{}{}
+	found := false
+	for _, r := range results {
+		if r.Err != nil && len(bytes.TrimSpace(r.Stdout)) == 0 {
+			continue
+		}
+		if len(bytes.TrimSpace(r.Stdout)) == 0 {
+			return nil, false
+		}
+		dec := json.NewDecoder(bytes.NewReader(r.Stdout))
+		dec.UseNumber()
+		for {
+			var value interface{}
+			err := dec.Decode(&value)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, false
+			}
+			found = true
+			items := []interface{}{value}
+			if list, ok := value.([]interface{}); ok {
+				items = list
+			}
+			for _, item := range items {
+				if object, ok := item.(map[string]interface{}); ok {
+					object["org"] = r.Login
+					merged = append(merged, object)
+				} else {
+					merged = append(merged, map[string]interface{}{"org": r.Login, "value": item})
+				}
+			}
+		}
+	}
+	if !found {
+		return nil, false
+	}
+	data, err := json.MarshalIndent(merged, "", "  ")
+	if err != nil {
+		return nil, false
+	}
+	return append(data, '\n'), true
+}
@@ -0,0 +1,86 @@
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestFanOutArgsRemovesAccountsFlags(t *testing.T) {
+	args := fanOutArgs([]string{"--accounts", "@prod", "query", "--accounts-output=group", "SELECT Id FROM User", "--", "--accounts"})
+	expected := []string{"query", "SELECT Id FROM User", "--", "--accounts"}
+	if !reflect.DeepEqual(args, expected) {
+		t.Errorf("Expected %v, got %v", expected, args)
+	}
+}
+
+func TestMergeFanOutJSONAddsOrg(t *testing.T) {
+	results := []fanOutResult{
+		{Login: "a@example.com", Stdout: []byte(`[{"Id":"005A"},{"Id":"005B"}]`)},
+		{Login: "b@example.com", Stdout: []byte("{\"Max\":15000}\n{\"Max\":5}\n")},
+		{Login: "c@example.com", Err: errors.New("exit status 1")},
+	}
+	merged, ok := mergeFanOutJSON(results)
+	if !ok {
+		t.Fatalf("Expected JSON output to be merged")
+	}
+	var records []map[string]interface{}
+	if err := json.Unmarshal(merged, &records); err != nil {
+		t.Fatalf("Merged output is not JSON: %v", err)
+	}
+	if len(records) != 4 || records[0]["org"] != "a@example.com" || records[3]["org"] != "b@example.com" {
+		t.Errorf("Unexpected merged output: %s", merged)
+	}
+}
+
+func TestMergeFanOutJSONRejectsText(t *testing.T) {
+	results := []fanOutResult{
+		{Login: "a@example.com", Stdout: []byte(`{"Id":"005A"}`)},
+		{Login: "b@example.com", Stdout: []byte("Id\n005B\n")},
+	}
+	if _, ok := mergeFanOutJSON(results); ok {
+		t.Errorf("Expected text output not to be merged")
+	}
+}
+
+func TestWritePrefixed(t *testing.T) {
+	var buf bytes.Buffer
+	writePrefixed(&buf, "a@example.com", []byte("one\ntwo\n"))
+	if buf.String() != "a@example.com: one\na@example.com: two\n" {
+		t.Errorf("Unexpected output: %q", buf.String())
+	}
+}
+
+func TestFanOutWantsJSON(t *testing.T) {
+	newCmd := func() *cobra.Command {
+		cmd := &cobra.Command{Use: "test"}
+		cmd.Flags().BoolP("json", "j", false, "")
+		cmd.Flags().StringP("format", "f", "csv", "")
+		return cmd
+	}
+	cases := []struct {
+		args     []string
+		expected bool
+	}{
+		{nil, false},
+		{[]string{"--json"}, true},
+		{[]string{"--format", "json-pretty"}, true},
+		{[]string{"-f", "console"}, false},
+	}
+	for _, c := range cases {
+		cmd := newCmd()
+		if err := cmd.ParseFlags(c.args); err != nil {
+			t.Fatal(err)
+		}
+		if got := fanOutWantsJSON(cmd); got != c.expected {
+			t.Errorf("Expected %v for %v, got %v", c.expected, c.args, got)
+		}
+	}
+	if fanOutWantsJSON(&cobra.Command{Use: "plain"}) {
+		t.Errorf("Expected no JSON for command without output flags")
+	}
+}
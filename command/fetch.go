@@ -22,9 +22,12 @@ func init() {
 	fetchCmd.Flags().StringSliceVarP(&metadataTypes, "type", "t", []string{}, "Type of metadata to fetch")
 	fetchCmd.Flags().StringVarP(&targetDirectory, "directory", "d", "", "Use to specify the root directory of your project")
 	fetchCmd.Flags().BoolVarP(&unpack, "unpack", "u", false, "Unpack any static resources")
-	fetchCmd.Flags().BoolVarP(&preserveZip, "preserve", "p", false, "keep zip file on disk")
+	fetchCmd.Flags().BoolVarP(&preserveZip, "preserve", "p", false, "keep retrieved zip file on disk, as inbound.zip or <package>.zip")
 	fetchCmd.Flags().StringP("xml", "x", "", "Package.xml file to use for fetch.")
+	fetchCmd.Flags().BoolVar(&recordFetchState, "record-state", false, "record when fetched components were last modified, for push and import --guard")
 	fetchCmd.MarkFlagsMutuallyExclusive("xml", "type")
+	fetchCmd.RegisterFlagCompletionFunc("type", completeMetadataTypes)
+	fetchCmd.RegisterFlagCompletionFunc("name", completeMetadataNames)
 	RootCmd.AddCommand(fetchCmd)
 }
 
@@ -33,12 +36,18 @@ var fetchCmd = &cobra.Command{
 	Short: "Export specified artifact(s) to a local directory",
 	Long: `
 Export specified artifact(s) to a local directory. Use "package" type to retrieve an unmanaged package.
+
+With --record-state, when each fetched component was last modified in the org
+is recorded in .force/fetched/<login>, so "force push --guard" and "force
+import --guard" can detect components changed in the org by someone else since
+they were fetched.
 `,
 	Example: `
   force fetch -t=CustomObject -n=Book__c -n=Author__c
   force fetch -t Aura -n MyComponent -d /Users/me/Documents/Project/home
   force fetch -t AuraDefinitionBundle -t ApexClass
   force fetch -x myproj/metadata/package.xml
+  force fetch -t ApexClass --record-state
 `,
 	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) > 0 {
@@ -56,11 +65,12 @@ Export specified artifact(s) to a local directory. Use "package" type to retriev
 type metaName = []string
 
 var (
-	metadataTypes   metaName
-	targetDirectory string
-	unpack          bool
-	metadataName    metaName
-	preserveZip     bool
+	metadataTypes    metaName
+	targetDirectory  string
+	unpack           bool
+	metadataName     metaName
+	preserveZip      bool
+	recordFetchState bool
 )
 
 func getWildcardQuery(force *Force, metadataTypes metaName) (query ForceMetadataQuery, err error) {
@@ -101,14 +111,19 @@ func getWildcardQuery(force *Force, metadataTypes metaName) (query ForceMetadata
 }
 
 func runFetchForPackageXml(packageXml string) {
-	files, problems, err := force.Metadata.RetrieveByPackageXml(packageXml)
+	data, err := ioutil.ReadFile(packageXml)
+	if err != nil {
+		ErrorAndExit(err.Error())
+	}
+	unpacker := newFetchUnpacker()
+	problems, err := force.Metadata.RetrieveByPackageXmlContentsTo(data, unpacker.unpackFile, unpacker.retrieveOptions("inbound.zip"))
 	if err != nil {
 		ErrorAndExit(err.Error())
 	}
 	for _, problem := range problems {
 		fmt.Fprintln(os.Stderr, problem)
 	}
-	unpackFiles(files)
+	unpacker.finish()
 }
 
 // Fetch by Type
@@ -120,21 +135,18 @@ func runFetch() {
 		ErrorAndExit("You cannot specify entity names if you specify more than one metadata type.")
 	}
 
-	var files ForceMetadataFiles
 	var problems []string
 	var err error
 
+	unpacker := newFetchUnpacker()
 	if len(metadataTypes) == 1 && strings.ToLower(metadataTypes[0]) == "package" {
-		if len(metadataName) > 0 {
-			for names := range metadataName {
-				files, problems, err = force.Metadata.RetrievePackage(metadataName[names])
-				if err != nil {
-					ErrorAndExit(err.Error())
-				}
-				if preserveZip {
-					os.Rename("inbound.zip", fmt.Sprintf("%s.zip", metadataName[names]))
-				}
+		for _, name := range metadataName {
+			var packageProblems []string
+			packageProblems, err = force.Metadata.RetrievePackageTo(name, unpacker.unpackFile, unpacker.retrieveOptions(fmt.Sprintf("%s.zip", name)))
+			if err != nil {
+				ErrorAndExit(err.Error())
 			}
+			problems = append(problems, packageProblems...)
 		}
 	} else {
 		query := ForceMetadataQuery{}
@@ -150,7 +162,7 @@ func runFetch() {
 				ErrorAndExit(err.Error())
 			}
 		}
-		files, problems, err = force.Metadata.Retrieve(query)
+		problems, err = force.Metadata.RetrieveTo(query, unpacker.unpackFile, unpacker.retrieveOptions("inbound.zip"))
 		if err != nil {
 			ErrorAndExit(err.Error())
 		}
@@ -158,15 +170,34 @@ func runFetch() {
 	for _, problem := range problems {
 		fmt.Fprintln(os.Stderr, problem)
 	}
-	unpackFiles(files)
+	unpacker.finish()
 }
 
-func unpackFiles(files ForceMetadataFiles) {
-	var err error
-	var expandResources bool = unpack
+// fetchUnpacker writes retrieved files to the project directory as they're
+// extracted from the retrieved zip file, and, with --record-state, records
+// when the fetched components were last modified, for push --guard.
+type fetchUnpacker struct {
+	root            string
+	existingPackage bool
+	packageXml      []byte
+	count           int
+	resources       map[string]string
+	state           FetchState
+}
 
-	resourcesMap := make(map[string]string)
+func (u *fetchUnpacker) retrieveOptions(zipName string) RetrieveOptions {
+	var options RetrieveOptions
+	if u.state != nil {
+		options.FileProperties = u.state.Record
+	}
+	if preserveZip {
+		options.PreserveZip = zipName
+	}
+	return options
+}
 
+func newFetchUnpacker() *fetchUnpacker {
+	var err error
 	root := targetDirectory
 	if root == "" {
 		root, err = config.GetSourceDir()
@@ -176,56 +207,79 @@ func unpackFiles(files ForceMetadataFiles) {
 		ErrorAndExit(err.Error())
 	}
 	existingPackage, _ := pathExists(filepath.Join(root, "package.xml"))
+	var state FetchState
+	if recordFetchState {
+		state, err = LoadFetchState(fetchStateUser())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Could not load fetch state: %s\n", err.Error())
+			state = make(FetchState)
+		}
+	}
+	return &fetchUnpacker{root: root, existingPackage: existingPackage, resources: make(map[string]string), state: state}
+}
 
-	if len(files) == 1 {
-		ErrorAndExit("Could not find any objects for " + strings.Join(metadataTypes, ", ") + ". (Is the metadata type correct?)")
+func (u *fetchUnpacker) unpackFile(name string, r io.Reader) error {
+	if name == "package.xml" {
+		// Only written once we know something else was retrieved
+		data, err := io.ReadAll(r)
+		u.packageXml = data
+		return err
 	}
-	for name, data := range files {
-		if !existingPackage || name != "package.xml" {
-			file := filepath.Join(root, name)
-			dir := filepath.Dir(file)
-			if err := os.MkdirAll(dir, 0755); err != nil {
-				ErrorAndExit(err.Error())
-			}
-			if err := ioutil.WriteFile(filepath.Join(root, name), data, 0644); err != nil {
-				ErrorAndExit(err.Error())
-			}
-			isResource := strings.HasSuffix(file, ".resource-meta.xml")
-			//Handle expanding static resources into a "bundle" folder
-			if isResource && expandResources {
-				if string(os.PathSeparator) != "/" {
-					name = strings.Replace(name, "/", string(os.PathSeparator), -1)
-				}
-				pathParts := strings.Split(name, string(os.PathSeparator))
-				resourceName := pathParts[cap(pathParts)-1]
-				resourceExt := strings.Split(resourceName, ".")[1]
-				resourceName = strings.Split(resourceName, ".")[0]
+	if err := WriteRetrievedFile(u.root, name, r); err != nil {
+		return err
+	}
+	u.count++
+	file := filepath.Join(u.root, filepath.FromSlash(name))
+	//Handle expanding static resources into a "bundle" folder
+	if unpack && strings.HasSuffix(file, ".resource-meta.xml") {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		//Check the xml to determine the mime type of the resource
+		// We are looking for application/zip
+		var meta struct {
+			CacheControl string `xml:"cacheControl"`
+			ContentType  string `xml:"contentType"`
+		}
+		xml.Unmarshal(data, &meta)
+		if meta.ContentType == "application/zip" {
+			// this is the meat for a zip file, so add the map
+			resourceName := strings.TrimSuffix(filepath.Base(file), ".resource-meta.xml")
+			u.resources[resourceName] = filepath.Join(filepath.Dir(file), resourceName+".resource")
+		}
+	}
+	return nil
+}
 
-				if resourceExt == "resource-meta" {
-					//Check the xml to determine the mime type of the resource
-					// We are looking for application/zip
-					var meta struct {
-						CacheControl string `xml:"cacheControl"`
-						ContentType  string `xml:"contentType"`
-					}
-					if err = xml.Unmarshal([]byte(data), &meta); err != nil {
-						//return
-					}
-					if meta.ContentType == "application/zip" {
-						// this is the meat for a zip file, so add the map
-						resourcesMap[resourceName] = filepath.Join(filepath.Dir(file), resourceName+".resource")
-					}
-				}
-			}
+func (u *fetchUnpacker) finish() {
+	if u.count == 0 {
+		ErrorAndExit("Could not find any objects for " + strings.Join(metadataTypes, ", ") + ". (Is the metadata type correct?)")
+	}
+	if !u.existingPackage && u.packageXml != nil {
+		if err := ioutil.WriteFile(filepath.Join(u.root, "package.xml"), u.packageXml, 0644); err != nil {
+			ErrorAndExit(err.Error())
 		}
 	}
 
 	// Now we need to see if we have any zips to expand
-	if expandResources && len(resourcesMap) > 0 {
-		unpackResources(resourcesMap)
+	if unpack && len(u.resources) > 0 {
+		unpackResources(u.resources)
 	}
 
-	fmt.Printf("Exported to %s\n", root)
+	if u.state != nil {
+		if err := u.state.Save(fetchStateUser()); err != nil {
+			fmt.Fprintf(os.Stderr, "Could not save fetch state: %s\n", err.Error())
+		}
+	}
+
+	fmt.Printf("Exported to %s\n", u.root)
+}
+
+// fetchStateUser identifies whose fetch state to use.  Components' last
+// modified dates are specific to an org, so state is kept per login.
+func fetchStateUser() string {
+	return force.Credentials.SessionName()
 }
 
 func pathExists(path string) (bool, error) {
@@ -271,14 +325,15 @@ func buildPackageAndFetch(paths []string) {
 		ErrorAndExit("Nothing to fetch")
 	}
 
-	files, problems, err := force.Metadata.RetrieveByPackageXmlContents(packageXml)
+	unpacker := newFetchUnpacker()
+	problems, err := force.Metadata.RetrieveByPackageXmlContentsTo(packageXml, unpacker.unpackFile, unpacker.retrieveOptions("inbound.zip"))
 	if err != nil {
 		ErrorAndExit(err.Error())
 	}
 	for _, problem := range problems {
 		fmt.Fprintln(os.Stderr, problem)
 	}
-	unpackFiles(files)
+	unpacker.finish()
 }
 
 func unpackResources(resourceMap map[string]string) {
@@ -55,6 +55,7 @@ var fieldListCmd = &cobra.Command{
 	Use:                   "list <object>",
 	Short:                 "List SObject fields",
 	Args:                  cobra.ExactArgs(1),
+	ValidArgsFunction:     completeSObjects,
 	DisableFlagsInUseLine: true,
 	Run: func(cmd *cobra.Command, args []string) {
 		runFieldList(args[0])
@@ -83,6 +84,7 @@ Supported field options include:
   force field create Contact Phone:phone helpText:"Primary contact phone number"
 `,
 	Args:                  cobra.MinimumNArgs(2),
+	ValidArgsFunction:     completeSObjects,
 	DisableFlagsInUseLine: true,
 	Run: func(cmd *cobra.Command, args []string) {
 		runFieldCreate(args)
@@ -93,6 +95,7 @@ var fieldDeleteCmd = &cobra.Command{
 	Use:                   "delete <object> <field>",
 	Short:                 "Delete SObject field",
 	Args:                  cobra.ExactArgs(2),
+	ValidArgsFunction:     completeFieldName,
 	DisableFlagsInUseLine: true,
 	Run: func(cmd *cobra.Command, args []string) {
 		runFieldDelete(args[0], args[1])
@@ -114,7 +117,7 @@ var fieldTypeCmd = &cobra.Command{
 }
 
 func runFieldList(object string) {
-	sobject, err := force.GetSobject(object)
+	sobject, err := force.GetSobjectCached(object)
 	if err != nil {
 		ErrorAndExit(err.Error())
 	}
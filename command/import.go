@@ -3,7 +3,6 @@ package command
 import (
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"os"
 	"os/user"
 	"path/filepath"
@@ -32,11 +31,15 @@ func init() {
 	importCmd.Flags().BoolP("quiet", "q", false, "only output failures")
 	importCmd.Flags().BoolP("interactive", "I", false, "interactive mode")
 	importCmd.Flags().CountP("verbose", "v", "give more verbose output")
-	importCmd.Flags().StringP("reporttype", "f", "text", "report type format (text or junit)")
+	importCmd.Flags().StringP("reporttype", "f", "text", "report type format (text, junit, sarif, github or gitlab)")
 
 	importCmd.Flags().StringP("directory", "d", "src", "relative path to package.xml")
 	importCmd.Flags().Bool("smart-flow-version", false, "enable smart flow versioning (auto-select new version and prune inactive flows)")
 
+	importCmd.Flags().Bool("plan", false, "show the components that would be created, overwritten and deleted, and the tests that would run, without deploying")
+	importCmd.Flags().String("guard", "", "refuse to deploy components modified in the org by someone else since they were fetched with --record-state, or never fetched (--guard=warn to only warn)")
+	importCmd.Flags().Lookup("guard").NoOptDefVal = "refuse"
+	importCmd.Flags().String("audit-object", "", "also record the deploy in the org as a record of this custom object or platform event; see force deploys history")
 	importCmd.Flags().BoolP("erroronfailure", "E", true, "exit with an error code if any tests fail")
 
 	RootCmd.AddCommand(importCmd)
@@ -97,23 +100,7 @@ func sourceDir(cmd *cobra.Command) string {
 
 func runImport(root string, options ForceDeployOptions, displayOptions *deployOutputOptions, smartFlowVersion bool) {
 	_ = smartFlowVersion
-	files := make(ForceMetadataFiles)
-	if _, err := os.Stat(filepath.Join(root, "package.xml")); os.IsNotExist(err) {
-		ErrorAndExit(" \n" + filepath.Join(root, "package.xml") + "\ndoes not exist")
-	}
-
-	err := filepath.Walk(root, func(path string, f os.FileInfo, err error) error {
-		if f.Mode().IsRegular() {
-			if f.Name() != ".DS_Store" {
-				data, err := ioutil.ReadFile(path)
-				if err != nil {
-					ErrorAndExit(err.Error())
-				}
-				files[strings.Replace(path, fmt.Sprintf("%s%s", root, string(os.PathSeparator)), "", -1)] = data
-			}
-		}
-		return nil
-	})
+	files, err := ReadMetadataDirectory(root)
 	if err != nil {
 		ErrorAndExit(err.Error())
 	}
@@ -132,8 +119,9 @@ func runImport(root string, options ForceDeployOptions, displayOptions *deployOu
 			ErrorAndExit(err2.Error())
 		}
 	}
+	displayOptions.sourceDir = root
 	err = deploy(force, files, &options, displayOptions)
-	if err == nil && displayOptions.reportFormat == "text" && !displayOptions.quiet {
+	if err == nil && displayOptions.reportFormat == "text" && !displayOptions.quiet && !displayOptions.plan {
 		fmt.Printf("Imported from %s\n", root)
 	}
 	if err != nil && (!errors.Is(err, testFailureError) || displayOptions.errorOnTestFailure) {
@@ -1,6 +1,7 @@
 package command
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 
@@ -97,6 +98,10 @@ type logEvent struct {
 }
 
 func tailLogs() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go force.KeepSessionFresh(ctx)
+
 	client := bayeux.NewClient(force)
 	msgs := make(chan *bayeux.Message)
 	if err := client.Subscribe("/systemTopic/Logging", msgs); err != nil {
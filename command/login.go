@@ -389,6 +389,8 @@ logged in system. non-production server to login to (values are 'pre',
 'test', or full instance url`)
 	loginCmd.Flags().IntP("port", "P", 3835, "port for local OAuth callback server")
 	loginCmd.Flags().Bool("device-flow", false, "use OAuth Device Flow (for headless environments)")
+	loginCmd.Flags().String("profile", "", "log in using a saved auth `profile`")
+	loginCmd.RegisterFlagCompletionFunc("profile", completeAuthProfiles)
 
 	scratchCmd.Flags().String("username", "", "username for scratch org user")
 	scratchCmd.Flags().Var(
@@ -414,6 +416,8 @@ logged in system. non-production server to login to (values are 'pre',
 		"release",
 		"Salesforce release for scratch org: preview (next release) or previous")
 	scratchCmd.Flags().Int("duration", 7, "number of days before the scratch org expires (1-30)")
+	scratchCmd.Flags().String("definition-file", "", "scratch org definition file (project-scratch-def.json format)")
+	scratchCmd.MarkFlagFilename("definition-file", "json")
 
 	loginCmd.AddCommand(scratchCmd)
 	RootCmd.AddCommand(loginCmd)
@@ -424,6 +428,12 @@ var scratchCmd = &cobra.Command{
 	Short: "Create scratch org and log in",
 	Long: `Create scratch org and log in
 
+The org shape can be read from a definition file in the project-scratch-def.json
+format using --definition-file.  Features, products, and settings given as
+flags are added to those in the definition file, and --edition, --release,
+and --username override it.  The definition's settings are deployed as
+Settings metadata after the org is created.
+
 Available Features:
   AccountingSubledgerGrowthEdition    - Enables Accounting Subledger Growth Edition
   AccountingSubledgerUser             - Enables Accounting Subledger user licenses
@@ -604,17 +614,33 @@ Examples:
   force login scratch --product revenuecloud
   force login scratch --release preview
   force login scratch --release previous
-  force login scratch --duration 14`,
+  force login scratch --duration 14
+  force login scratch --definition-file config/project-scratch-def.json
+  force login scratch --definition-file config/project-scratch-def.json --product fsc`,
 	Run: func(cmd *cobra.Command, args []string) {
-		scratchUser, _ := cmd.Flags().GetString("username")
+		var def ScratchOrgDefinition
+		if definitionFile, _ := cmd.Flags().GetString("definition-file"); definitionFile != "" {
+			var err error
+			def, err = LoadScratchOrgDefinition(definitionFile)
+			if err != nil {
+				ErrorAndExit(err.Error())
+			}
+		}
+		if scratchUser, _ := cmd.Flags().GetString("username"); scratchUser != "" {
+			def.Username = scratchUser
+		}
+		if def.Edition == "" || cmd.Flags().Changed("edition") {
+			def.Edition = ScratchEditionIds[selectedEdition][0]
+		}
+		if cmd.Flags().Changed("release") {
+			def.Release = ScratchReleaseIds[selectedRelease][0]
+		}
 		scratchNamespace, _ := cmd.Flags().GetString("namespace")
 		quantities, _ := cmd.Flags().GetStringToString("quantity")
-		allFeatures := expandProductsToFeatures(selectedProducts, selectedFeatures, quantities)
-		edition := ScratchEditionIds[selectedEdition][0]
+		def.Features = append(def.Features, expandProductsToFeatures(selectedProducts, selectedFeatures, quantities)...)
 		allSettings := expandProductsToSettings(selectedProducts, selectedSettings)
-		release := ScratchReleaseIds[selectedRelease][0]
 		duration, _ := cmd.Flags().GetInt("duration")
-		scratchLogin(scratchUser, allFeatures, edition, allSettings, scratchNamespace, release, duration)
+		scratchLogin(def, allSettings, scratchNamespace, duration)
 	},
 }
 
@@ -623,7 +649,13 @@ var loginCmd = &cobra.Command{
 	Short: "Log into Salesforce and store a session token",
 	Long: `Log into Salesforce and store a session token.  By default, OAuth is
 used and a refresh token will be stored as well.  The refresh token is used
-to get a new session token automatically when needed.`,
+to get a new session token automatically when needed.
+
+JWT logins save the location of the signing key so they can also be
+refreshed.  Use "force login profile" to save JWT and client credentials
+settings, including a reference to the client secret, so logins using the
+profile can be refreshed too.  Use "force login status" to see how each
+saved login is refreshed.`,
 	Example: `
     force login
     force login -i test
@@ -634,12 +666,18 @@ to get a new session token automatically when needed.`,
     force login -i my-domain.my.salesforce.com -s[kipLogin]
     force login --connected-app-client-id <my-consumer-key> -u user@example.com -key jwt.key
     force login --connected-app-client-id <my-consumer-key> --connected-app-client-secret <my-consumer-secret>
+    force login --profile ci
     force login -P 8080
     force login --device-flow
     force login scratch
 `,
 	Args: cobra.MaximumNArgs(0),
 	Run: func(cmd *cobra.Command, args []string) {
+		if profile, _ := cmd.Flags().GetString("profile"); profile != "" {
+			selectApiVersion(cmd)
+			profileLogin(profile)
+			return
+		}
 		connectedAppClientId, _ := cmd.Flags().GetString("connected-app-client-id")
 		if connectedAppClientId != "" {
 			ClientId = connectedAppClientId
@@ -863,12 +901,16 @@ func expandProductsToSettings(products []ScratchProduct, settings []ScratchSetti
 	return uniqueSettings
 }
 
-func scratchLogin(scratchUser string, features []string, edition string, settings []string, namespace string, release string, duration int) {
+func scratchLogin(def ScratchOrgDefinition, settings []string, namespace string, duration int) {
 	if force == nil {
 		ErrorAndExit("You must be logged into a Dev Hub org to authenticate as a scratch org user.")
 	}
+	definitionSettings, err := def.SettingsMetadata()
+	if err != nil {
+		ErrorAndExit(err.Error())
+	}
 	fmt.Fprintln(os.Stderr, "Creating new Scratch Org...")
-	scratchOrgId, err := force.CreateScratchOrgWithDuration(scratchUser, features, edition, settings, namespace, release, duration)
+	scratchOrgId, err := force.CreateScratchOrgFromDefinition(def, namespace, duration)
 	if err != nil {
 		ErrorAndExit(err.Error())
 	}
@@ -898,6 +940,13 @@ func scratchLogin(scratchUser string, features []string, edition string, setting
 			ErrorAndExit(fmt.Sprintf("Settings deployment failed: %s", err.Error()))
 		}
 	}
+	if len(def.Settings) > 0 {
+		fmt.Fprintln(os.Stderr, "Deploying settings from definition file...")
+		err = scratchForce.DeploySettingsFiles(definitionSettings)
+		if err != nil {
+			ErrorAndExit(fmt.Sprintf("Settings deployment failed: %s", err.Error()))
+		}
+	}
 }
 
 func oauthLogin(endpoint string, skipLogin bool, port int) {
@@ -925,18 +974,21 @@ func oauthDeviceFlowLogin(endpoint string, skipLogin bool, port int) {
 }
 
 func jwtLogin(endpoint, username, keyfile string) {
-	assertion, err := JwtAssertionForEndpoint(endpoint, username, keyfile, ClientId)
+	_, err := ForceLoginAtEndpointAndSaveJWTWithKey(endpoint, username, keyfile, ClientId, os.Stdout)
 	if err != nil {
 		ErrorAndExit(err.Error())
 	}
-	_, err = ForceLoginAtEndpointAndSaveJWT(endpoint, assertion, os.Stdout)
+}
+
+func clientCredentialsLogin(endpoint, clientId, clientSecret string) {
+	_, err := ForceLoginAtEndpointAndSaveClientCredentials(endpoint, clientId, clientSecret, os.Stdout)
 	if err != nil {
 		ErrorAndExit(err.Error())
 	}
 }
 
-func clientCredentialsLogin(endpoint, clientId, clientSecret string) {
-	_, err := ForceLoginAtEndpointAndSaveClientCredentials(endpoint, clientId, clientSecret, os.Stdout)
+func profileLogin(name string) {
+	_, err := ForceLoginAndSaveWithAuthProfile(name, os.Stdout)
 	if err != nil {
 		ErrorAndExit(err.Error())
 	}
@@ -6,8 +6,10 @@ import (
 	"os"
 	"strings"
 	"text/tabwriter"
+	"time"
 
 	. "github.com/ForceCLI/force/config"
+	. "github.com/ForceCLI/force/error"
 	. "github.com/ForceCLI/force/lib"
 	"github.com/spf13/cobra"
 )
@@ -16,6 +18,16 @@ func init() {
 	loginsCmd.Flags().StringP("org-id", "o", "", "filter by org id")
 	loginsCmd.Flags().StringP("user-id", "i", "", "filter by user id")
 	loginsCmd.Flags().Bool("sfdx", false, "include SFDX logins")
+	loginsCmd.Flags().StringArrayP("tag", "t", []string{}, "filter by tag (can be specified multiple times)")
+	loginsCmd.Flags().StringP("env", "e", "", "filter by environment")
+	loginsCmd.RegisterFlagCompletionFunc("env", cobra.FixedCompletions(LoginEnvironments, cobra.ShellCompDirectiveNoFileComp))
+
+	loginsTagCmd.Flags().StringP("env", "e", "", "set the environment: "+strings.Join(LoginEnvironments, ", ")+", or none")
+	loginsTagCmd.RegisterFlagCompletionFunc("env", cobra.FixedCompletions(append(LoginEnvironments, "none"), cobra.ShellCompDirectiveNoFileComp))
+
+	loginsCmd.AddCommand(loginsTagCmd)
+	loginsCmd.AddCommand(loginsUntagCmd)
+	loginsCmd.AddCommand(loginsPolicyCmd)
 	RootCmd.AddCommand(loginsCmd)
 }
 
@@ -24,15 +36,139 @@ type accountFilter func(ForceSession) bool
 var loginsCmd = &cobra.Command{
 	Use:   "logins",
 	Short: "List force.com logins used",
+	Long: `
+List saved logins with their instance, environment, tags, when they were last
+used, and when their access token expires.  The expiry assumes the default
+two-hour session timeout.
+
+Logins without an environment label are shown as sandbox or scratch when
+their URL identifies them as such.  Use "force logins tag" to set labels.
+`,
 	Example: `
   force logins
+  force logins --tag payments --env production
 `,
 	Run: func(cmd *cobra.Command, args []string) {
 		showSFDX, _ := cmd.Flags().GetBool("sfdx")
-		runLogins(filters(cmd), showSFDX)
+		runLogins(filters(cmd), metadataFilters(cmd), showSFDX)
+	},
+}
+
+var loginsTagCmd = &cobra.Command{
+	Use:   "tag <login> [tag]...",
+	Short: "Add tags or an environment label to a saved login",
+	Long: `
+Add free-form tags, such as a team or customer, to a saved login, and
+optionally label its environment.  Destructive operations against logins
+labeled production are subject to the login's production policy; see
+"force logins policy".
+
+Tagged logins can be selected with --accounts @<tag>.
+`,
+	Example: `
+  force logins tag admin@acme.com payments acme --env production
+  force logins tag ci@acme.com.uat --env sandbox
+`,
+	Args:              cobra.MinimumNArgs(1),
+	ValidArgsFunction: completeLogins,
+	Run: func(cmd *cobra.Command, args []string) {
+		env, _ := cmd.Flags().GetString("env")
+		if len(args) == 1 && !cmd.Flags().Changed("env") {
+			ErrorAndExit("Specify tags or --env")
+		}
+		runTagLogin(args[0], args[1:], env, cmd.Flags().Changed("env"))
+	},
+}
+
+var loginsUntagCmd = &cobra.Command{
+	Use:               "untag <login> <tag>...",
+	Short:             "Remove tags from a saved login",
+	Args:              cobra.MinimumNArgs(2),
+	ValidArgsFunction: completeLogins,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := UntagLogin(args[0], args[1:]); err != nil {
+			ErrorAndExit(err.Error())
+		}
 	},
 }
 
+var loginsPolicyCmd = &cobra.Command{
+	Use:   "policy <login> [policy]",
+	Short: "Show or set the production safety policy of a saved login",
+	Long: `
+Show or set what happens before a destructive operation, such as deleting
+records or objects, a bulk hardDelete, or a deploy with destructiveChanges,
+against a production org.  The policy applies to logins labeled production,
+and to unlabeled logins when the org is not a sandbox or scratch org.  Logins
+labeled as another environment are not checked.  The default policy is
+confirm.
+
+Policies:
+  confirm     ask for confirmation showing the org name
+  type-name   require the org name to be typed
+  refuse      never run destructive operations
+  allow       run destructive operations without asking
+
+Without a terminal to confirm on, confirm and type-name refuse the operation
+unless --yes is given or FORCE_CONFIRM_PRODUCTION is set.  Use "default" to
+restore the default policy.
+`,
+	Example: `
+  force logins policy admin@acme.com
+  force logins policy admin@acme.com type-name
+`,
+	Args:              cobra.RangeArgs(1, 2),
+	ValidArgsFunction: completeLogins,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 1 {
+			fmt.Println(LoginProductionPolicy(args[0]))
+			return
+		}
+		policy := args[1]
+		if policy == "default" {
+			policy = ""
+		}
+		if err := SetLoginProductionPolicy(args[0], policy); err != nil {
+			ErrorAndExit(err.Error())
+		}
+	},
+}
+
+func runTagLogin(account string, tags []string, env string, setEnv bool) {
+	if len(tags) > 0 {
+		if err := TagLogin(account, tags); err != nil {
+			ErrorAndExit(err.Error())
+		}
+	}
+	if setEnv {
+		if env == "none" {
+			env = ""
+		}
+		if err := SetLoginEnvironment(account, env); err != nil {
+			ErrorAndExit(err.Error())
+		}
+	}
+}
+
+// metadataFilters returns the filters on login tags and environment.
+func metadataFilters(cmd *cobra.Command) []func(string, ForceSession) bool {
+	var filters []func(string, ForceSession) bool
+	tags, _ := cmd.Flags().GetStringArray("tag")
+	for _, tag := range tags {
+		tag := tag
+		filters = append(filters, func(account string, _ ForceSession) bool {
+			return LoadLoginMetadata(account).HasTag(tag)
+		})
+	}
+	env, _ := cmd.Flags().GetString("env")
+	if env != "" {
+		filters = append(filters, func(account string, creds ForceSession) bool {
+			return strings.EqualFold(LoginEnvironment(account, creds), env)
+		})
+	}
+	return filters
+}
+
 func filters(cmd *cobra.Command) []accountFilter {
 	var filters []accountFilter
 	orgId, _ := cmd.Flags().GetString("org-id")
@@ -63,7 +199,7 @@ func filters(cmd *cobra.Command) []accountFilter {
 	return filters
 }
 
-func runLogins(filters []accountFilter, includeSFDX bool) {
+func runLogins(filters []accountFilter, metadataFilters []func(string, ForceSession) bool, includeSFDX bool) {
 	active, _ := ActiveLogin()
 	accounts, _ := Config.List("accounts")
 	var sfdxAuths []SFDXAuth
@@ -81,6 +217,10 @@ func runLogins(filters []accountFilter, includeSFDX bool) {
 	}
 	w := new(tabwriter.Writer)
 	w.Init(os.Stdout, 1, 0, 1, ' ', 0)
+	fmt.Fprintln(w, "LOGIN\tINSTANCE\tENV\tTAGS\tLAST USED\tTOKEN EXPIRES")
+	if len(metadataFilters) > 0 {
+		includeSFDX = false
+	}
 
 ACCOUNTS:
 	for _, account := range accounts {
@@ -96,8 +236,15 @@ ACCOUNTS:
 					continue ACCOUNTS
 				}
 			}
+			for _, f := range metadataFilters {
+				if !f(account, creds) {
+					continue ACCOUNTS
+				}
+			}
 
-			var banner = fmt.Sprintf("\t%s", creds.InstanceUrl)
+			metadata := LoadLoginMetadata(account)
+			var banner = fmt.Sprintf("\t%s\t%s\t%s\t%s\t%s", creds.InstanceUrl, LoginEnvironment(account, creds),
+				strings.Join(metadata.Tags, ","), formatLastUsed(metadata.LastUsed), formatTokenExpiry(creds))
 			if account == active {
 				account = fmt.Sprintf("\x1b[31;1m%s (active)\x1b[0m", account)
 			} else {
@@ -137,9 +284,27 @@ ACCOUNTS:
 			if strings.TrimSpace(instance) == "" {
 				instance = auth.LoginUrl
 			}
-			fmt.Fprintf(w, "%s\t%s\n", name, instance)
+			fmt.Fprintf(w, "%s\t%s\t\t\t\t%s\n", name, instance, formatTokenExpiry(session))
 		}
 	}
 	fmt.Fprintln(w)
 	w.Flush()
 }
+
+func formatLastUsed(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+func formatTokenExpiry(creds ForceSession) string {
+	expiry, ok := creds.TokenExpiry()
+	if !ok {
+		return ""
+	}
+	if expiry.Before(time.Now()) {
+		return "expired"
+	}
+	return expiry.Local().Format("2006-01-02 15:04")
+}
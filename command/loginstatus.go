@@ -0,0 +1,73 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	. "github.com/ForceCLI/force/error"
+	. "github.com/ForceCLI/force/lib"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	loginCmd.AddCommand(loginStatusCmd)
+}
+
+var loginStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show how each saved login's session is refreshed",
+	Long: `
+Show how each saved login's session is refreshed and when its access token
+is expected to expire.  Expiry assumes the default two-hour session timeout.
+
+Sessions that can be refreshed are refreshed automatically shortly before
+they expire.  Logins that can't be refreshed, such as SOAP username and
+password logins, need to be logged in again once their token expires.
+`,
+	Example: `
+  force login status
+`,
+	Args: cobra.ExactArgs(0),
+	Run: func(cmd *cobra.Command, args []string) {
+		runLoginStatus()
+	},
+}
+
+func runLoginStatus() {
+	statuses, err := SavedSessionStatuses()
+	if err != nil {
+		ErrorAndExit(err.Error())
+	}
+	if len(statuses) == 0 {
+		ErrorAndExit("No saved logins")
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "LOGIN\tREFRESH\tTOKEN EXPIRES\tSTATUS")
+	for _, s := range statuses {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", s.Login, s.RefreshCapability, formatSessionExpiry(s.Expiry), sessionStatusMessage(s))
+	}
+	w.Flush()
+}
+
+func formatSessionExpiry(expiry time.Time) string {
+	if expiry.IsZero() {
+		return "unknown"
+	}
+	return expiry.Local().Format("2006-01-02 15:04")
+}
+
+func sessionStatusMessage(s SessionStatus) string {
+	canRefresh := s.RefreshCapability != RefreshCapabilityNone
+	switch {
+	case canRefresh && s.Expired():
+		return "expired, will refresh"
+	case canRefresh:
+		return "ok"
+	case s.Expired():
+		return "expired, run force login"
+	default:
+		return "cannot refresh"
+	}
+}
@@ -31,11 +31,7 @@ func runLogout() {
 		ErrorAndExit("No logins, so a username cannot be assumed.")
 	}
 	username := force.Credentials.UserInfo.UserName
-	Config.Delete("accounts", username)
-	if active, _ := Config.Load("current", "account"); active == username {
-		Config.Delete("current", "account")
-		SetActiveLoginDefault()
-	}
+	DeleteLogin(username)
 	if runtime.GOOS == "windows" {
 		cmd := exec.Command("title", account)
 		cmd.Run()
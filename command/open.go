@@ -48,7 +48,7 @@ func runOpen(startUrl string) {
 	if err != nil {
 		ErrorAndExit(err.Error())
 	}
-	openUrl := fmt.Sprintf("%s/secur/frontdoor.jsp?sid=%s", force.Credentials.InstanceUrl, force.Credentials.AccessToken)
+	openUrl := fmt.Sprintf("%s/secur/frontdoor.jsp?sid=%s", force.InstanceUrl(), force.AccessToken())
 	if startUrl != "" {
 		openUrl = fmt.Sprintf("%s&retURL=%s", openUrl, url.QueryEscape(startUrl))
 	}
@@ -0,0 +1,116 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	. "github.com/ForceCLI/force/error"
+	. "github.com/ForceCLI/force/lib"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	orgSetupCmd.Flags().Bool("restart", false, "run all steps, ignoring steps finished by earlier runs")
+
+	orgCmd.AddCommand(orgSetupCmd)
+	RootCmd.AddCommand(orgCmd)
+}
+
+var orgCmd = &cobra.Command{
+	Use:                   "org",
+	Short:                 "Manage the active org",
+	DisableFlagsInUseLine: true,
+}
+
+var orgSetupCmd = &cobra.Command{
+	Use:   "setup <plan file>",
+	Short: "Set up an org from a declarative plan",
+	Long: `
+Set up an org by running the steps in a YAML plan file.  Each step has a
+name, a type, and optionally a list of steps it depends on.  Steps run after
+the steps they depend on, and otherwise in the order they are listed.
+
+Step types:
+  package         install a package by namespace and version, or by id (04t...)
+  deploy          deploy a metadata directory containing package.xml
+  permissionsets  assign permission sets to a user (default: current user)
+  data            load a CSV file using Bulk API 2.0
+  apex            execute anonymous Apex from a file or inline code
+
+The steps finished in each org are recorded, so running the plan again after
+a failure resumes with the failed step.  A finished step runs again if its
+definition in the plan changes.  Use --restart to run every step.
+
+Example plan:
+
+  name: dev-org
+  steps:
+    - name: base-package
+      type: package
+      namespace: acme
+      version: "2.1"
+    - name: source
+      type: deploy
+      path: src
+      dependsOn: [base-package]
+    - name: perms
+      type: permissionsets
+      permissionSets: [Acme_Admin]
+      dependsOn: [source]
+    - name: accounts
+      type: data
+      object: Account
+      operation: upsert
+      externalId: Acme_Id__c
+      file: data/accounts.csv
+      dependsOn: [source]
+    - name: init
+      type: apex
+      file: scripts/init.apex
+      dependsOn: [accounts, perms]
+
+Paths are relative to the plan file.
+`,
+	Example: `
+  force org setup config/setup.yaml
+  force org setup config/setup.yaml --restart
+`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		restart, _ := cmd.Flags().GetBool("restart")
+		runOrgSetup(args[0], restart)
+	},
+}
+
+func runOrgSetup(path string, restart bool) {
+	plan, err := LoadOrgSetupPlan(path)
+	if err != nil {
+		ErrorAndExit(err.Error())
+	}
+	if restart {
+		if err := force.ClearOrgSetupState(plan); err != nil {
+			ErrorAndExit(err.Error())
+		}
+	}
+	results, runErr := force.RunOrgSetupPlan(plan, func(step OrgSetupStep) {
+		fmt.Fprintf(os.Stderr, "Running %s (%s)...\n", step.Name, step.Type)
+	})
+	var total time.Duration
+	w := tabwriter.NewWriter(os.Stdout, 1, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STEP\tTYPE\tSTATUS\tDURATION")
+	for _, result := range results {
+		duration := ""
+		if result.Status == OrgSetupStepDone || result.Status == OrgSetupStepFailed {
+			duration = result.Duration.Round(time.Millisecond).String()
+			total += result.Duration
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", result.Step, result.Type, result.Status, duration)
+	}
+	fmt.Fprintf(w, "TOTAL\t\t\t%s\n", total.Round(time.Millisecond))
+	w.Flush()
+	if runErr != nil {
+		ErrorAndExit(runErr.Error())
+	}
+}
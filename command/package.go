@@ -4,6 +4,7 @@ import (
 	"archive/zip"
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
@@ -12,6 +13,7 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"text/tabwriter"
 	"time"
 
 	. "github.com/ForceCLI/force/error"
@@ -24,6 +26,9 @@ func init() {
 	packageInstallCmd.Flags().StringP("password", "p", "", "password for package")
 	packageInstallCmd.Flags().StringP("package-version-id", "i", "", "Package version ID (04t) to install via Tooling API")
 
+	packageSyncCmd.Flags().BoolP("dry-run", "n", false, "show the changes without making them")
+	packageSyncCmd.Flags().Bool("uninstall", false, "uninstall packages that are not in the manifest")
+
 	packageUninstallCmd.Flags().StringP("package-version-id", "i", "", "Subscriber Package Version ID (04t) to uninstall (required)")
 	packageUninstallCmd.MarkFlagRequired("package-version-id")
 
@@ -42,7 +47,7 @@ func init() {
 	packageVersionCreateCmd.Flags().StringP("version-description", "d", "", "Version description (optional, defaults to version-number)")
 	packageVersionCreateCmd.Flags().StringP("ancestor-id", "", "", "Ancestor version ID (optional)")
 	packageVersionCreateCmd.Flags().BoolP("no-ancestor", "", false, "Explicitly set ancestorId to an empty string")
-	packageVersionCreateCmd.Flags().StringArrayP("dependency", "", []string{}, "Subscriber Package Version ID (04t) dependency (can be specified multiple times)")
+	packageVersionCreateCmd.Flags().StringArrayP("dependency", "", []string{}, "Dependency as a Subscriber Package Version ID (04t) or <package name or namespace>@<version>, e.g. MyPackage@1.4.LATEST (can be specified multiple times)")
 	packageVersionCreateCmd.Flags().StringP("tag", "", "", "Tag to set on the Package2VersionCreateRequest")
 	packageVersionCreateCmd.Flags().BoolP("skip-validation", "s", false, "Skip validation")
 	packageVersionCreateCmd.Flags().BoolP("async-validation", "y", false, "Async validation")
@@ -50,12 +55,15 @@ func init() {
 	packageVersionCreateCmd.MarkFlagRequired("version-number")
 	packageVersionCreateCmd.MarkFlagsMutuallyExclusive("ancestor-id", "no-ancestor")
 
-	packageVersionReleaseCmd.Flags().StringP("version-id", "v", "", "Package Version ID (required)")
+	packageVersionReleaseCmd.Flags().StringP("version-id", "v", "", "Package Version ID (05i) or Subscriber Package Version ID (04t) (required)")
 	packageVersionReleaseCmd.MarkFlagRequired("version-id")
 
 	packageVersionGetCmd.Flags().StringP("version-id", "v", "", "Package Version ID (required)")
 	packageVersionGetCmd.MarkFlagRequired("version-id")
 
+	packageVersionReportCmd.Flags().StringP("version-id", "v", "", "Package Version ID (05i) or Subscriber Package Version ID (04t) (required)")
+	packageVersionReportCmd.MarkFlagRequired("version-id")
+
 	packageVersionListCmd.Flags().StringP("package-id", "i", "", "Package ID (optional, filter by package)")
 	packageVersionListCmd.Flags().StringP("namespace", "", "", "Package namespace (alternative to --package-id)")
 	packageVersionListCmd.Flags().BoolP("released", "r", false, "Show only released versions")
@@ -68,9 +76,11 @@ func init() {
 	packageVersionCmd.AddCommand(packageVersionCreateCmd)
 	packageVersionCmd.AddCommand(packageVersionReleaseCmd)
 	packageVersionCmd.AddCommand(packageVersionGetCmd)
+	packageVersionCmd.AddCommand(packageVersionReportCmd)
 	packageVersionCmd.AddCommand(packageVersionListCmd)
 	packageCmd.AddCommand(packageInstallCmd)
 	packageCmd.AddCommand(packageUninstallCmd)
+	packageCmd.AddCommand(packageSyncCmd)
 	packageCmd.AddCommand(packageVersionCmd)
 	RootCmd.AddCommand(packageCmd)
 }
@@ -147,6 +157,46 @@ var packageInstallCmd = &cobra.Command{
 	},
 }
 
+var packageSyncCmd = &cobra.Command{
+	Use:   "sync <manifest>",
+	Short: "Install or upgrade packages to match a manifest",
+	Long: `
+Install or upgrade the packages listed in a manifest file that are missing or
+out of date in the org.  Packages are installed after the packages they depend
+on.  Installed packages newer than the manifest requires are left alone.
+
+The manifest is a YAML or JSON file:
+
+  packages:
+    - name: Core
+      id: 04t000000000001
+    - name: Sales
+      id: 04t000000000002
+      password: secret
+      activateRSS: true
+    - namespace: legacy
+      version: "3.2"
+      dependsOn: [Core]
+
+Packages given by 04t id are installed with the Tooling API, and their
+dependencies on other packages in the manifest are found automatically.
+Packages given by namespace and version are installed with the Metadata API,
+and dependencies on them must be declared with dependsOn.  Their version must
+be a version number such as 3.2, not LATEST or RELEASED.
+`,
+	Example: `
+  force package sync packages.yaml --dry-run
+  force package sync packages.yaml
+  force package sync packages.yaml --uninstall
+`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		uninstall, _ := cmd.Flags().GetBool("uninstall")
+		runSyncPackages(args[0], dryRun, uninstall)
+	},
+}
+
 var packageUninstallCmd = &cobra.Command{
 	Use:   "uninstall",
 	Short: "Uninstall a 2GP package",
@@ -182,9 +232,15 @@ var packageVersionCreateCmd = &cobra.Command{
 }
 
 var packageVersionReleaseCmd = &cobra.Command{
-	Use:   "release",
-	Short: "Release a package version",
-	Args:  cobra.NoArgs,
+	Use:     "release",
+	Aliases: []string{"promote"},
+	Short:   "Release a package version",
+	Long: `
+Release (promote) a package version so it can be installed in production
+orgs.  The version must have been created with validation, must meet the code
+coverage requirement, and must not depend on beta versions of other packages.
+`,
+	Args: cobra.NoArgs,
 	Run: func(cmd *cobra.Command, args []string) {
 		versionId, _ := cmd.Flags().GetString("version-id")
 		runReleasePackageVersion(versionId)
@@ -201,6 +257,16 @@ var packageVersionGetCmd = &cobra.Command{
 	},
 }
 
+var packageVersionReportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Show package version status, code coverage, and dependencies",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		versionId, _ := cmd.Flags().GetString("version-id")
+		runReportPackageVersion(versionId)
+	},
+}
+
 var packageVersionListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List package versions",
@@ -288,22 +354,12 @@ func runListPackages() {
 }
 
 func runListInstalledPackages() {
-	// Query InstalledSubscriberPackage to get installed packages
-	query := "SELECT Id, SubscriberPackage.Name, SubscriberPackage.NamespacePrefix, " +
-		"SubscriberPackageVersion.Id, SubscriberPackageVersion.Name, " +
-		"SubscriberPackageVersion.MajorVersion, SubscriberPackageVersion.MinorVersion, " +
-		"SubscriberPackageVersion.PatchVersion, SubscriberPackageVersion.BuildNumber " +
-		"FROM InstalledSubscriberPackage " +
-		"ORDER BY SubscriberPackage.Name"
-
-	result, err := force.Query(query, func(options *lib.QueryOptions) {
-		options.IsTooling = true
-	})
+	packages, err := force.ListInstalledPackages()
 	if err != nil {
 		ErrorAndExit("Failed to query installed packages: " + err.Error())
 	}
 
-	if len(result.Records) == 0 {
+	if len(packages) == 0 {
 		fmt.Println("No installed packages found")
 		return
 	}
@@ -311,163 +367,96 @@ func runListInstalledPackages() {
 	fmt.Printf("%-18s %-30s %-15s %-18s %-20s %-10s\n", "ID", "Package Name", "Namespace", "Version ID", "Version Name", "Version")
 	fmt.Println(strings.Repeat("-", 120))
 
-	for _, record := range result.Records {
-		id := ""
-		if recordId, ok := record["Id"].(string); ok {
-			id = recordId
+	for _, p := range packages {
+		packageName := p.Name
+		if len(packageName) > 30 {
+			packageName = packageName[:27] + "..."
 		}
-
-		packageName := ""
-		namespace := ""
-		if subscriberPackage, ok := record["SubscriberPackage"].(map[string]interface{}); ok {
-			if name, ok := subscriberPackage["Name"].(string); ok {
-				packageName = name
-				if len(packageName) > 30 {
-					packageName = packageName[:27] + "..."
-				}
-			}
-			if ns, ok := subscriberPackage["NamespacePrefix"]; ok && ns != nil {
-				namespace = ns.(string)
-			}
+		versionName := p.VersionName
+		if len(versionName) > 20 {
+			versionName = versionName[:17] + "..."
 		}
-
-		versionId := ""
-		versionName := ""
 		versionNumber := ""
-		if subscriberPackageVersion, ok := record["SubscriberPackageVersion"].(map[string]interface{}); ok {
-			if vId, ok := subscriberPackageVersion["Id"].(string); ok {
-				versionId = vId
-			}
-			if vName, ok := subscriberPackageVersion["Name"].(string); ok {
-				versionName = vName
-				if len(versionName) > 20 {
-					versionName = versionName[:17] + "..."
-				}
-			}
-			// Construct version number from individual components
-			major := subscriberPackageVersion["MajorVersion"]
-			minor := subscriberPackageVersion["MinorVersion"]
-			patch := subscriberPackageVersion["PatchVersion"]
-			build := subscriberPackageVersion["BuildNumber"]
-			if major != nil && minor != nil && patch != nil && build != nil {
-				versionNumber = fmt.Sprintf("%v.%v.%v.%v", major, minor, patch, build)
-			}
+		if p.VersionId != "" {
+			versionNumber = p.VersionNumber()
 		}
 
 		fmt.Printf("%-18s %-30s %-15s %-18s %-20s %-10s\n",
-			id, packageName, namespace, versionId, versionName, versionNumber)
+			p.Id, packageName, p.Namespace, p.VersionId, versionName, versionNumber)
 	}
 }
 
-func runInstallPackage(packageNamespace string, version string, password string, activateRSS bool) {
-	if err := force.Metadata.InstallPackageWithRSS(packageNamespace, version, password, activateRSS); err != nil {
+func runSyncPackages(path string, dryRun bool, uninstall bool) {
+	manifest, err := lib.LoadPackageManifest(path)
+	if err != nil {
 		ErrorAndExit(err.Error())
 	}
-	fmt.Println("Package installed")
-}
-
-func runInstallPackageById(packageVersionId string, password string, activateRSS bool) {
-	// Validate that the ID starts with 04t (SubscriberPackageVersion prefix)
-	if !strings.HasPrefix(packageVersionId, "04t") {
-		ErrorAndExit("Invalid package version ID. Must be a Subscriber Package Version ID (04t)")
+	actions, err := force.PlanPackageSync(manifest, uninstall)
+	if err != nil {
+		ErrorAndExit(err.Error())
 	}
 
-	// Create the PackageInstallRequest using Tooling API
-	attrs := map[string]string{
-		"SubscriberPackageVersionKey": packageVersionId,
-		"EnableRss":                   fmt.Sprintf("%t", activateRSS),
-		"NameConflictResolution":      "Block",
-		"SecurityType":                "None",
+	changes := 0
+	w := tabwriter.NewWriter(os.Stdout, 1, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PACKAGE\tINSTALLED\tREQUIRED\tACTION")
+	for _, action := range actions {
+		installed := ""
+		if action.Installed != nil {
+			installed = action.Installed.VersionNumber()
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", action.Label(), installed, action.Required, action.Action)
+		switch action.Action {
+		case lib.PackageSyncInstall, lib.PackageSyncUpgrade, lib.PackageSyncUninstall:
+			changes++
+		}
 	}
+	w.Flush()
 
-	if password != "" {
-		attrs["Password"] = password
+	if changes == 0 {
+		fmt.Println("Packages are up to date")
+		return
 	}
-
-	fmt.Printf("Installing package version: %s\n", packageVersionId)
-
-	result, err := force.CreateToolingRecord("PackageInstallRequest", attrs)
+	if dryRun {
+		fmt.Printf("%d changes needed\n", changes)
+		return
+	}
+	err = force.ApplyPackageSync(actions, func(action lib.PackageSyncAction, status string) {
+		fmt.Fprintf(os.Stderr, "%s %s: %s\n", action.Action, action.Label(), status)
+	})
 	if err != nil {
-		ErrorAndExit("Failed to create package install request: " + err.Error())
+		ErrorAndExit(err.Error())
 	}
+	fmt.Printf("Applied %d changes\n", changes)
+}
 
-	requestId := result.Id
-	if requestId == "" {
-		ErrorAndExit("Failed to get request ID from response")
+func runInstallPackage(packageNamespace string, version string, password string, activateRSS bool) {
+	if err := force.Metadata.InstallPackageWithRSS(packageNamespace, version, password, activateRSS); err != nil {
+		ErrorAndExit(err.Error())
 	}
-
-	fmt.Printf("Package install request submitted: %s\n", requestId)
-
-	// Poll for completion
-	pollPackageInstallStatus(requestId)
+	fmt.Println("Package installed")
 }
 
-func pollPackageInstallStatus(requestId string) {
-	query := fmt.Sprintf("SELECT Id, Status, Errors, CreatedDate FROM PackageInstallRequest WHERE Id = '%s'", requestId)
-
-	for i := 0; i < 240; i++ { // Poll for up to 20 minutes (5 seconds * 240)
-		time.Sleep(5 * time.Second)
-
-		result, err := force.Query(query, func(options *lib.QueryOptions) {
-			options.IsTooling = true
-		})
-		if err != nil {
-			fmt.Printf("Error querying status: %s\n", err.Error())
-			continue
-		}
-
-		if len(result.Records) == 0 {
+func runInstallPackageById(packageVersionId string, password string, activateRSS bool) {
+	fmt.Printf("Installing package version: %s\n", packageVersionId)
+	err := force.InstallPackageVersion(packageVersionId, password, activateRSS, func(p lib.PackageRequestProgress) {
+		switch {
+		case p.Submitted:
+			fmt.Printf("Package install request submitted: %s\n", p.RequestId)
+		case errors.Is(p.Err, lib.ErrPackageRequestNotFound):
 			fmt.Println("No records found")
-			continue
-		}
-
-		record := result.Records[0]
-		status := record["Status"].(string)
-
-		fmt.Printf("Status: %s\n", status)
-
-		if status == "SUCCESS" {
-			fmt.Println("Package installed successfully")
-			return
-		} else if status == "ERROR" {
-			var errorMessages []string
-			if errors, ok := record["Errors"]; ok && errors != nil {
-				// Errors field is typically a JSON array within the response
-				if errorsMap, ok := errors.(map[string]interface{}); ok {
-					if errorArray, ok := errorsMap["errors"].([]interface{}); ok {
-						for _, err := range errorArray {
-							if errMap, ok := err.(map[string]interface{}); ok {
-								if message, ok := errMap["message"].(string); ok {
-									errorMessages = append(errorMessages, message)
-								}
-							}
-						}
-					}
-				} else if errorsArray, ok := errors.([]interface{}); ok {
-					for _, err := range errorsArray {
-						if errMap, ok := err.(map[string]interface{}); ok {
-							if message, ok := errMap["message"].(string); ok {
-								errorMessages = append(errorMessages, message)
-							}
-						}
-					}
-				}
-			}
-
-			if len(errorMessages) > 0 {
-				ErrorAndExit("Package installation failed with errors:\n" + strings.Join(errorMessages, "\n"))
-			} else {
-				ErrorAndExit("Package installation failed with status: ERROR")
+		case p.Err != nil:
+			fmt.Printf("Error querying status: %s\n", p.Err.Error())
+		default:
+			fmt.Printf("Status: %s\n", p.Status)
+			if p.Status != "SUCCESS" && p.Status != "ERROR" && p.Status != "IN_PROGRESS" {
+				fmt.Printf("Unexpected status: %s. Continuing to poll...\n", p.Status)
 			}
-		} else if status == "IN_PROGRESS" {
-			// Continue polling
-		} else {
-			// Handle other statuses if any
-			fmt.Printf("Unexpected status: %s. Continuing to poll...\n", status)
 		}
+	})
+	if err != nil {
+		ErrorAndExit(err.Error())
 	}
-
-	ErrorAndExit("Package installation timed out after 20 minutes")
+	fmt.Println("Package installed successfully")
 }
 
 func runUninstallPackage(packageVersionId string) {
@@ -510,68 +499,25 @@ func runUninstallPackage(packageVersionId string) {
 	fmt.Printf("Uninstalling package: %s (%s)\n", packageName, versionName)
 	fmt.Printf("Package Version ID: %s\n", packageVersionId)
 
-	// Create the SubscriberPackageVersionUninstallRequest using Tooling API
-	attrs := map[string]string{
-		"SubscriberPackageVersionId": packageVersionId,
-	}
-
-	requestResult, err := force.CreateToolingRecord("SubscriberPackageVersionUninstallRequest", attrs)
-	if err != nil {
-		ErrorAndExit("Failed to create package uninstall request: " + err.Error())
-	}
-
-	requestId := requestResult.Id
-	if requestId == "" {
-		ErrorAndExit("Failed to get request ID from response")
-	}
-
-	fmt.Printf("Package uninstall request submitted: %s\n", requestId)
-
-	// Poll for completion
-	pollPackageUninstallStatus(requestId)
-}
-
-func pollPackageUninstallStatus(requestId string) {
-	query := fmt.Sprintf("SELECT Id, Status FROM SubscriberPackageVersionUninstallRequest WHERE Id = '%s'", requestId)
-
-	for i := 0; i < 240; i++ { // Poll for up to 20 minutes (5 seconds * 240)
-		time.Sleep(5 * time.Second)
-
-		result, err := force.Query(query, func(options *lib.QueryOptions) {
-			options.IsTooling = true
-		})
-		if err != nil {
-			fmt.Printf("Error querying status: %s\n", err.Error())
-			continue
-		}
-
-		if len(result.Records) == 0 {
+	err = force.UninstallPackageVersion(packageVersionId, func(p lib.PackageRequestProgress) {
+		switch {
+		case p.Submitted:
+			fmt.Printf("Package uninstall request submitted: %s\n", p.RequestId)
+		case errors.Is(p.Err, lib.ErrPackageRequestNotFound):
 			fmt.Println("No records found")
-			continue
-		}
-
-		record := result.Records[0]
-		status := ""
-		if statusValue, ok := record["Status"].(string); ok {
-			status = statusValue
-		}
-
-		fmt.Printf("Status: %s\n", status)
-
-		if status == "Success" {
-			fmt.Println("Package uninstalled successfully")
-			return
-		} else if status == "Error" {
-			ErrorAndExit("Package uninstall failed with status: Error")
-		} else if status == "InProgress" {
-			// Continue polling
-		} else {
-			// Handle other statuses if any
-			fmt.Printf("Status: %s, continuing to poll...\n", status)
+		case p.Err != nil:
+			fmt.Printf("Error querying status: %s\n", p.Err.Error())
+		default:
+			fmt.Printf("Status: %s\n", p.Status)
+			if p.Status != "Success" && p.Status != "Error" && p.Status != "InProgress" {
+				fmt.Printf("Status: %s, continuing to poll...\n", p.Status)
+			}
 		}
+	})
+	if err != nil {
+		ErrorAndExit(err.Error())
 	}
-
-	ErrorAndExit("Package uninstall timed out after 20 minutes")
+	fmt.Println("Package uninstalled successfully")
 }
 
 func runCreatePackageVersion(path string, packageId string, namespace string, versionNumber string,
@@ -638,10 +584,15 @@ func runCreatePackageVersion(path string, packageId string, namespace string, ve
 		}
 	}
 
-	for _, dependency := range dependencies {
-		if !strings.HasPrefix(dependency, "04t") {
-			ErrorAndExit(fmt.Sprintf("Invalid dependency ID: %s. Must be a Subscriber Package Version ID (04t)", dependency))
+	for i, dependency := range dependencies {
+		id, err := force.ResolvePackageDependency(dependency)
+		if err != nil {
+			ErrorAndExit("Failed to resolve dependency: " + err.Error())
+		}
+		if id != dependency {
+			fmt.Printf("Resolved dependency %s to %s\n", dependency, id)
 		}
+		dependencies[i] = id
 	}
 
 	// Create package2-descriptor.json
@@ -906,17 +857,88 @@ func pollPackageVersionStatus(requestId string) string {
 }
 
 func runReleasePackageVersion(versionId string) {
-	// Update the Package2Version record to set IsReleased = true using Tooling API
-	updateData := map[string]string{
-		"IsReleased": "true",
+	version, err := force.PromotePackageVersion(versionId)
+	if err != nil {
+		ErrorAndExit("Failed to release package version: " + err.Error())
 	}
 
-	err := force.UpdateToolingRecord("Package2Version", versionId, updateData)
+	fmt.Printf("Package version released successfully: %s %s (%s)\n", version.PackageName, version.VersionNumber(), version.SubscriberPackageVersionId)
+}
+
+func runReportPackageVersion(versionId string) {
+	version, err := force.GetPackageVersion(versionId)
 	if err != nil {
-		ErrorAndExit("Failed to release package version: " + err.Error())
+		ErrorAndExit(err.Error())
 	}
 
-	fmt.Printf("Package version released successfully: %s\n", versionId)
+	fmt.Printf("Package: %s\n", version.PackageName)
+	if version.Namespace != "" {
+		fmt.Printf("Namespace: %s\n", version.Namespace)
+	}
+	fmt.Printf("Version: %s\n", version.VersionNumber())
+	fmt.Printf("Package Version ID: %s\n", version.Id)
+	fmt.Printf("Subscriber Package Version ID: %s\n", version.SubscriberPackageVersionId)
+	fmt.Printf("Released: %t\n", version.IsReleased)
+	if version.ReleaseVersion != "" {
+		fmt.Printf("Release Version: %s\n", version.ReleaseVersion)
+	}
+	if version.BuildDurationInSeconds > 0 {
+		fmt.Printf("Build Duration: %s\n", time.Duration(version.BuildDurationInSeconds)*time.Second)
+	}
+	if version.ValidationSkipped {
+		fmt.Println("Validation: skipped")
+	} else {
+		fmt.Println("Validation: performed")
+	}
+	if version.CodeCoverage != nil {
+		result := "failed"
+		if version.HasPassedCodeCoverageCheck {
+			result = "passed"
+		}
+		fmt.Printf("Code Coverage: %.0f%% (%s)\n", *version.CodeCoverage, result)
+	} else {
+		fmt.Println("Code Coverage: not calculated")
+	}
+
+	if version.SubscriberPackageVersionId == "" {
+		return
+	}
+	tree, err := force.PackageDependencyTree(version.SubscriberPackageVersionId)
+	if err != nil {
+		ErrorAndExit("Failed to get dependencies: " + err.Error())
+	}
+	fmt.Println("Dependencies:")
+	if len(tree.Dependencies) == 0 {
+		fmt.Println("  (none)")
+	}
+	printPackageDependencies(tree.Dependencies, "  ")
+
+	if !version.IsReleased {
+		problems := lib.PackageVersionPromotionProblems(version)
+		for _, beta := range tree.BetaDependencies() {
+			problems = append(problems, fmt.Sprintf("depends on beta version %s %s", beta.PackageName, beta.VersionNumber))
+		}
+		if len(problems) == 0 {
+			fmt.Println("Promotable: yes")
+		} else {
+			fmt.Printf("Promotable: no (%s)\n", strings.Join(problems, "; "))
+		}
+	}
+}
+
+func printPackageDependencies(dependencies []*lib.PackageDependencyNode, indent string) {
+	for _, d := range dependencies {
+		name := d.PackageName
+		if d.Namespace != "" {
+			name = fmt.Sprintf("%s (%s)", name, d.Namespace)
+		}
+		beta := ""
+		if d.IsBeta {
+			beta = " [beta]"
+		}
+		fmt.Printf("%s%s %s %s%s\n", indent, name, d.VersionNumber, d.SubscriberPackageVersionId, beta)
+		printPackageDependencies(d.Dependencies, indent+"  ")
+	}
 }
 
 func runGetPackageVersion(versionId string) {
@@ -0,0 +1,24 @@
+package command
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+
+	. "github.com/ForceCLI/force/lib"
+)
+
+func init() {
+	Prompt = promptTerminal
+}
+
+// promptTerminal asks the user a question on the terminal, failing if
+// stdin isn't a terminal so scripts can't confirm by accident.
+func promptTerminal(question string) (string, error) {
+	if stat, err := os.Stdin.Stat(); err != nil || stat.Mode()&os.ModeCharDevice == 0 {
+		return "", errors.New("confirmation is required, but stdin is not a terminal")
+	}
+	fmt.Fprint(os.Stderr, question)
+	return bufio.NewReader(os.Stdin).ReadString('\n')
+}
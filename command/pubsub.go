@@ -2,6 +2,7 @@ package command
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"os"
 
@@ -76,6 +77,9 @@ converting nested structures like {"string": "value"} to just "value".`,
 			replayPreset = proto.ReplayPreset_EARLIEST
 		}
 		parseChanges, _ := cmd.Flags().GetBool("changes")
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go force.KeepSessionFresh(ctx)
 		err := pubsub.Subscribe(force, args[0], replayId, replayPreset, parseChanges)
 		if err != nil {
 			ErrorAndExit(err.Error())
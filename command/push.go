@@ -30,13 +30,17 @@ func init() {
 	pushCmd.Flags().BoolP("quiet", "q", false, "only output failures")
 	pushCmd.Flags().CountP("verbose", "v", "give more verbose output")
 	pushCmd.Flags().BoolP("interactive", "I", false, "interactive mode")
-	pushCmd.Flags().String("reporttype", "text", "report type format (text or junit)")
+	pushCmd.Flags().String("reporttype", "text", "report type format (text, junit, sarif, github or gitlab)")
 
 	// Ways to push
 	pushCmd.Flags().StringSliceP("filepath", "f", []string{}, "Path to resource(s)")
 	pushCmd.Flags().StringSliceP("type", "t", []string{}, "Metatdata type")
 	pushCmd.Flags().StringSliceP("name", "n", []string{}, "name of metadata object")
 	pushCmd.Flags().StringSlice("test", []string{}, "Test(s) to run")
+	pushCmd.Flags().Bool("plan", false, "show the components that would be created, overwritten and deleted, and the tests that would run, without deploying")
+	pushCmd.Flags().String("guard", "", "refuse to deploy components modified in the org by someone else since they were fetched with --record-state, or never fetched (--guard=warn to only warn)")
+	pushCmd.Flags().Lookup("guard").NoOptDefVal = "refuse"
+	pushCmd.Flags().String("audit-object", "", "also record the deploy in the org as a record of this custom object or platform event; see force deploys history")
 	pushCmd.Flags().Bool("smart-flow-version", false, "enable smart flow versioning (auto-select new version and prune inactive flows)")
 	RootCmd.AddCommand(pushCmd)
 }
@@ -57,6 +61,7 @@ File path can be specified as - to read from stdin; see examples
   force push -checkonly -test MyClass_Test metadata/classes/MyClass.cls
   force push -n MyApex -n MyObject__c
   git diff HEAD^ --name-only --diff-filter=ACM | force push -f -
+  force push -c --reporttype github metadata/classes/MyClass.cls
 `,
 	DisableFlagsInUseLine: false,
 	Run: func(cmd *cobra.Command, args []string) {
@@ -166,6 +171,7 @@ func pushByPaths(resourcePaths []string, deployOptions *ForceDeployOptions, disp
 		ExitIfNoSourceDir(err)
 	}
 	pb.Root = sourceDir
+	displayOptions.sourceDir = sourceDir
 	for _, p := range resourcePaths {
 		f, err := os.Stat(p)
 		if err != nil {
@@ -209,6 +215,7 @@ func pushByMetadataType(metadataType string, metadataNames []string, deployOptio
 	sourceDir, err := config.GetSourceDir()
 	ExitIfNoSourceDir(err)
 	pb.Root = sourceDir
+	displayOptions.sourceDir = sourceDir
 	if len(metadataNames) == 0 {
 		err = pb.AddMetadataType(metadataType)
 		if err != nil {
@@ -249,6 +256,7 @@ func pushMetadataTypes(metadataTypes []string, deployOptions *ForceDeployOptions
 	sourceDir, err := config.GetSourceDir()
 	ExitIfNoSourceDir(err)
 	pb.Root = sourceDir
+	displayOptions.sourceDir = sourceDir
 
 	for _, metadataType := range metadataTypes {
 		err = pb.AddMetadataType(metadataType)
@@ -1,7 +1,11 @@
 package command
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"sort"
 	"strings"
 
 	. "github.com/ForceCLI/force/error"
@@ -11,6 +15,10 @@ import (
 
 func init() {
 	recordDeleteCmd.Flags().BoolP("tooling", "t", false, "delete using object record")
+	recordBatchCmd.Flags().BoolP("all-or-none", "A", false, "roll back all records in a request if any fail")
+	for _, cmd := range []*cobra.Command{recordCreateCmd, recordUpdateCmd, recordUpsertCmd} {
+		cmd.Flags().Bool("check-fields", false, "check that the fields exist on the object before sending the record")
+	}
 
 	recordCmd.AddCommand(recordGetCmd)
 	recordCmd.AddCommand(recordCreateCmd)
@@ -19,6 +27,7 @@ func init() {
 	recordCmd.AddCommand(recordDeleteCmd)
 	recordCmd.AddCommand(recordMergeCmd)
 	recordCmd.AddCommand(recordUndeleteCmd)
+	recordCmd.AddCommand(recordBatchCmd)
 	RootCmd.AddCommand(recordCmd)
 }
 
@@ -35,6 +44,7 @@ Usage:
   force record get <object> <extid>:<value>
 `,
 	Args:                  cobra.ExactArgs(2),
+	ValidArgsFunction:     completeSObjects,
 	DisableFlagsInUseLine: true,
 	Run: func(cmd *cobra.Command, args []string) {
 		runRecordGet(args[0], args[1])
@@ -45,11 +55,13 @@ var recordCreateCmd = &cobra.Command{
 	Use:                   "create <object> [<field>:<value>...]",
 	Short:                 "Create new record",
 	Args:                  cobra.MinimumNArgs(1),
+	ValidArgsFunction:     completeFieldValues(1),
 	DisableFlagsInUseLine: true,
 	Run: func(cmd *cobra.Command, args []string) {
 		object := args[0]
 		fields := args[1:]
-		runRecordCreate(object, fields)
+		checkFields, _ := cmd.Flags().GetBool("check-fields")
+		runRecordCreate(object, fields, checkFields)
 	},
 }
 
@@ -57,12 +69,14 @@ var recordUpdateCmd = &cobra.Command{
 	Use:                   "update <object> <id> [<field>:<value>...]",
 	Short:                 "Update record",
 	Args:                  cobra.MinimumNArgs(2),
+	ValidArgsFunction:     completeFieldValues(2),
 	DisableFlagsInUseLine: true,
 	Run: func(cmd *cobra.Command, args []string) {
 		object := args[0]
 		id := args[1]
 		fields := args[2:]
-		runRecordUpdate(object, id, fields)
+		checkFields, _ := cmd.Flags().GetBool("check-fields")
+		runRecordUpdate(object, id, fields, checkFields)
 	},
 }
 
@@ -84,12 +98,14 @@ Usage:
   force record upsert Contact Email:john@example.com FirstName:John LastName:Doe
 `,
 	Args:                  cobra.MinimumNArgs(2),
+	ValidArgsFunction:     completeFieldValues(1),
 	DisableFlagsInUseLine: true,
 	Run: func(cmd *cobra.Command, args []string) {
 		object := args[0]
 		extIdPair := args[1]
 		fields := args[2:]
-		runRecordUpsert(object, extIdPair, fields)
+		checkFields, _ := cmd.Flags().GetBool("check-fields")
+		runRecordUpsert(object, extIdPair, fields, checkFields)
 	},
 }
 
@@ -97,6 +113,7 @@ var recordDeleteCmd = &cobra.Command{
 	Use:                   "delete <object> <id>",
 	Short:                 "Delete record",
 	Args:                  cobra.ExactArgs(2),
+	ValidArgsFunction:     completeSObjects,
 	DisableFlagsInUseLine: true,
 	Run: func(cmd *cobra.Command, args []string) {
 		if tooling, _ := cmd.Flags().GetBool("tooling"); tooling {
@@ -111,6 +128,7 @@ var recordMergeCmd = &cobra.Command{
 	Use:                   "merge <object> <masterId> <duplicateId>",
 	Short:                 "Merge records",
 	Args:                  cobra.ExactArgs(3),
+	ValidArgsFunction:     completeSObjects,
 	DisableFlagsInUseLine: true,
 	Run: func(cmd *cobra.Command, args []string) {
 		object := args[0]
@@ -130,6 +148,38 @@ var recordUndeleteCmd = &cobra.Command{
 	},
 }
 
+var recordBatchCmd = &cobra.Command{
+	Use:   "batch <file>",
+	Short: "Create, update, upsert, or delete records in batches",
+	Long: `
+Create, update, upsert, or delete records listed in an NDJSON file, one
+operation per line.  Use - to read operations from standard input.
+
+Operations are sent using the sObject Collections API in batches of up to 200
+records, with up to 5 batches per composite request.  A result is written
+for each operation, in the order of the input.
+
+With --all-or-none, all of the records in a composite request are rolled back
+if any of them fail.
+
+Operation format:
+
+  {"operation": "create", "sobject": "Account", "fields": {"Name": "Acme"}}
+  {"operation": "update", "sobject": "Account", "id": "001...", "fields": {"Phone": "555-0100"}}
+  {"operation": "upsert", "sobject": "Account", "externalIdField": "Ext_Id__c", "fields": {"Ext_Id__c": "A1"}}
+  {"operation": "delete", "id": "001..."}
+`,
+	Example: `
+  force record batch operations.ndjson
+  force record batch --all-or-none - < operations.ndjson
+`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		allOrNone, _ := cmd.Flags().GetBool("all-or-none")
+		runRecordBatch(args[0], allOrNone)
+	},
+}
+
 var recordCmd = &cobra.Command{
 	Use:   "record <command> [<args>]",
 	Short: "Create, modify, or view records",
@@ -147,6 +197,7 @@ Usage:
   force record delete <object> <id>
   force record merge <object> <masterId> <duplicateId>
   force record undelete <id>
+  force record batch <file>
 `,
 	Example: `
   force record get User 00Ei0000000000
@@ -158,6 +209,7 @@ Usage:
   force record delete User 00Ei0000000000
   force record merge Contact 0033c00002YDNNWAA5 0033c00002YDPqkAAH
   force record undelete 0033c00002YDNNWAA5
+  force record batch operations.ndjson
 `,
 }
 
@@ -170,8 +222,11 @@ func runRecordGet(sobject, id string) {
 	}
 }
 
-func runRecordCreate(object string, fields []string) {
+func runRecordCreate(object string, fields []string, checkFields bool) {
 	attrs := parseArgumentAttrs(fields)
+	if checkFields {
+		validateRecordFields(object, attrs)
+	}
 	id, err, emessages := force.CreateRecord(object, attrs)
 	if err != nil {
 		if len(emessages) > 0 {
@@ -182,8 +237,11 @@ func runRecordCreate(object string, fields []string) {
 	fmt.Printf("Record created: %s\n", id)
 }
 
-func runRecordUpdate(object string, id string, fields []string) {
+func runRecordUpdate(object string, id string, fields []string, checkFields bool) {
 	attrs := parseArgumentAttrs(fields)
+	if checkFields {
+		validateRecordFields(object, attrs)
+	}
 	err := force.UpdateRecord(object, id, attrs)
 	if err != nil {
 		ErrorAndExit("Failed to update record: %s", err.Error())
@@ -191,7 +249,7 @@ func runRecordUpdate(object string, id string, fields []string) {
 	fmt.Println("Record updated")
 }
 
-func runRecordUpsert(object string, extIdPair string, fields []string) {
+func runRecordUpsert(object string, extIdPair string, fields []string, checkFields bool) {
 	split := strings.SplitN(extIdPair, ":", 2)
 	if len(split) != 2 {
 		ErrorAndExit("Invalid external ID format. Use <extid>:<value>")
@@ -200,6 +258,9 @@ func runRecordUpsert(object string, extIdPair string, fields []string) {
 	extIdValue := split[1]
 
 	attrs := parseArgumentAttrs(fields)
+	if checkFields {
+		validateRecordFields(object, attrs)
+	}
 	result, err := force.UpsertRecord(object, extIdField, extIdValue, attrs)
 	if err != nil {
 		ErrorAndExit("Failed to upsert record: %s", err.Error())
@@ -244,6 +305,39 @@ func runRecordDelete(object, id string) {
 	fmt.Println("Record deleted")
 }
 
+func runRecordBatch(file string, allOrNone bool) {
+	var in io.Reader = os.Stdin
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			ErrorAndExit(err.Error())
+		}
+		defer f.Close()
+		in = f
+	}
+	ops, err := ReadRecordOperations(in)
+	if err != nil {
+		ErrorAndExit("Failed to read operations: %s", err.Error())
+	}
+	results, err := force.ExecuteRecordOperations(ops, allOrNone, func(done int) {
+		fmt.Fprintf(os.Stderr, "Processed %d of %d operations\n", done, len(ops))
+	})
+	if err != nil {
+		ErrorAndExit(err.Error())
+	}
+	failed := 0
+	enc := json.NewEncoder(os.Stdout)
+	for _, r := range results {
+		if !r.Success {
+			failed++
+		}
+		enc.Encode(r)
+	}
+	if failed > 0 {
+		ErrorAndExit("%d of %d operations failed", failed, len(results))
+	}
+}
+
 func runToolingRecordDelete(object, id string) {
 	err := force.DeleteToolingRecord(object, id)
 	if err != nil {
@@ -252,6 +346,37 @@ func runToolingRecordDelete(object, id string) {
 	fmt.Println("Record deleted")
 }
 
+// validateRecordFields exits with an error if any of the fields do not exist
+// on the object.  If the object can't be described, the fields are sent to the
+// API unchecked.
+func validateRecordFields(object string, attrs map[string]string) {
+	body, err := force.DescribeSObjectCached(object)
+	if err != nil {
+		return
+	}
+	describe, err := ParseSObjectDescribe(body)
+	if err != nil {
+		return
+	}
+	if unknown := unknownFields(describe, attrs); len(unknown) > 0 {
+		ErrorAndExit("Unknown fields on %s: %s", object, strings.Join(unknown, ", "))
+	}
+}
+
+func unknownFields(describe SObjectDescribe, attrs map[string]string) (unknown []string) {
+	known := make(map[string]bool)
+	for _, f := range describe.Fields {
+		known[strings.ToLower(f.Name)] = true
+	}
+	for name := range attrs {
+		if !known[strings.ToLower(name)] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	return
+}
+
 func parseArgumentAttrs(pairs []string) (parsed map[string]string) {
 	parsed = make(map[string]string)
 	for _, pair := range pairs {
@@ -2,6 +2,8 @@ package command
 
 import (
 	"testing"
+
+	. "github.com/ForceCLI/force/lib"
 )
 
 func TestParseArgumentAttrs(t *testing.T) {
@@ -56,3 +58,29 @@ func TestParseArgumentAttrs(t *testing.T) {
 		})
 	}
 }
+
+func TestUnknownFields(t *testing.T) {
+	describe := SObjectDescribe{
+		Name: "Account",
+		Fields: []SObjectFieldDescribe{
+			{Name: "Name"},
+			{Name: "Industry"},
+		},
+	}
+	attrs := map[string]string{
+		"name":       "Acme",
+		"Industry":   "Technology",
+		"Revenue__c": "100",
+		"Bogus":      "x",
+	}
+	unknown := unknownFields(describe, attrs)
+	expected := []string{"Bogus", "Revenue__c"}
+	if len(unknown) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, unknown)
+	}
+	for i := range expected {
+		if unknown[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected, unknown)
+		}
+	}
+}
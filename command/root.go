@@ -33,13 +33,21 @@ func init() {
 	RootCmd.PersistentFlags().StringVarP(&account, "account", "a", "", "account `username` to use")
 	RootCmd.PersistentFlags().StringVar(&configName, "config", "", "config directory to use (default: .force)")
 	RootCmd.PersistentFlags().StringVarP(&_apiVersion, "apiversion", "V", "", "API version to use")
+	RootCmd.PersistentFlags().BoolVar(&ConfirmDestructiveOperations, "yes", false, "confirm destructive operations in production orgs without prompting (or set FORCE_CONFIRM_PRODUCTION)")
+	RootCmd.RegisterFlagCompletionFunc("account", completeLogins)
 
 	RootCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
 		initializeConfig()
+		if os.Getenv("FORCE_CONFIRM_PRODUCTION") != "" {
+			ConfirmDestructiveOperations = true
+		}
 		current := cmd
 		for current.Parent() != nil && current.Parent() != RootCmd {
 			current = current.Parent()
 		}
+		if accounts != "" {
+			runFanOut(cmd, current)
+		}
 		isLoginScratch := current.Name() == "login" && cmd.Name() == "scratch"
 		if isLoginScratch && account != "" {
 			if err := SetActiveLogin(account); err != nil {
@@ -47,7 +55,7 @@ func init() {
 			}
 		}
 		switch current.Name() {
-		case "force", "completion", "usedxauth", "logins":
+		case "force", "completion", "usedxauth", "logins", "compare", cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd:
 		case "login":
 			if isLoginScratch {
 				initializeSession()
@@ -70,45 +78,54 @@ var RootCmd = &cobra.Command{
 
 func initializeConfig() {
 	if configName != "" {
-		customConfig := strings.TrimSpace(configName)
-		if customConfig == "" {
+		if strings.TrimSpace(configName) == "" {
 			return
 		}
-
-		isPath := strings.ContainsAny(customConfig, string(os.PathSeparator))
-		if !isPath && strings.ContainsAny(customConfig, "/\\") {
-			isPath = true
+		if err := useConfig(configName); err != nil {
+			ErrorAndExit(err.Error())
 		}
-		if strings.HasPrefix(customConfig, "~") {
-			isPath = true
+		out := os.Stdout
+		if fanOutActive() {
+			// Keep stdout for the output that's combined across logins.
+			out = os.Stderr
 		}
-		if strings.HasPrefix(customConfig, ".") {
-			if customConfig == "." || customConfig == ".." {
+		fmt.Fprintln(out, "Setting config to", forceConfig.Config.GlobalRoot())
+	}
+}
+
+// useConfig switches to the config directory or base name given by
+// customConfig.
+func useConfig(customConfig string) error {
+	customConfig = strings.TrimSpace(customConfig)
+
+	isPath := strings.ContainsAny(customConfig, string(os.PathSeparator))
+	if !isPath && strings.ContainsAny(customConfig, "/\\") {
+		isPath = true
+	}
+	if strings.HasPrefix(customConfig, "~") {
+		isPath = true
+	}
+	if strings.HasPrefix(customConfig, ".") {
+		if customConfig == "." || customConfig == ".." {
+			isPath = true
+		} else if len(customConfig) > 1 {
+			next := customConfig[1]
+			if next == '/' || next == '\\' {
 				isPath = true
-			} else if len(customConfig) > 1 {
-				next := customConfig[1]
-				if next == '/' || next == '\\' {
-					isPath = true
-				}
 			}
 		}
-		if !isPath {
-			if vol := filepath.VolumeName(customConfig); vol != "" && len(customConfig) > len(vol) {
-				isPath = true
-			}
+	}
+	if !isPath {
+		if vol := filepath.VolumeName(customConfig); vol != "" && len(customConfig) > len(vol) {
+			isPath = true
 		}
+	}
 
-		var err error
-		if isPath {
-			err = forceConfig.UseConfigDirectory(customConfig)
-		} else {
-			forceConfig.UseConfigBase(customConfig)
-		}
-		if err != nil {
-			ErrorAndExit(err.Error())
-		}
-		fmt.Println("Setting config to", forceConfig.Config.GlobalRoot())
+	if isPath {
+		return forceConfig.UseConfigDirectory(customConfig)
 	}
+	forceConfig.UseConfigBase(customConfig)
+	return nil
 }
 
 func envSession() *Force {
@@ -127,14 +144,21 @@ func envSession() *Force {
 
 func initializeSession() {
 	var err error
-	if account != "" {
-		force, err = GetForce(account)
+	login := account
+	if login != "" {
+		force, err = GetForce(login)
 	} else if force = envSession(); force == nil {
 		force, err = ActiveForce()
+		if err == nil {
+			login, _ = ActiveLogin()
+		}
 	}
 	if err != nil {
 		ErrorAndExit(err.Error())
 	}
+	if login = strings.TrimSpace(login); login != "" {
+		TouchLogin(login)
+	}
 	if _apiVersion != "" {
 		err := SetApiVersion(_apiVersion)
 		if err != nil {
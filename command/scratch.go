@@ -0,0 +1,168 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	. "github.com/ForceCLI/force/config"
+	. "github.com/ForceCLI/force/error"
+	. "github.com/ForceCLI/force/lib"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	scratchListCmd.Flags().Bool("all", false, "include scratch orgs created by all Dev Hub users")
+	scratchDeleteCmd.Flags().Bool("keep-login", false, "don't remove the saved login for the scratch org")
+	scratchOpenCmd.Flags().StringP("start", "s", "", "relative URL to open")
+
+	scratchOrgCmd.AddCommand(scratchListCmd)
+	scratchOrgCmd.AddCommand(scratchDeleteCmd)
+	scratchOrgCmd.AddCommand(scratchOpenCmd)
+	RootCmd.AddCommand(scratchOrgCmd)
+}
+
+var scratchOrgCmd = &cobra.Command{
+	Use:   "scratch",
+	Short: "Manage scratch orgs",
+	Long: `
+Manage the scratch orgs created from a Dev Hub.  Except for open, the active
+login must be a Dev Hub org.  Use "force login scratch" to create scratch orgs.
+
+Scratch orgs can be identified by username, org id, or ScratchOrgInfo id.
+`,
+	Example: `
+  force scratch list
+  force scratch delete test-abc123@example.com
+  force scratch open test-abc123@example.com
+`,
+	DisableFlagsInUseLine: true,
+}
+
+var scratchListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List active scratch orgs",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		all, _ := cmd.Flags().GetBool("all")
+		runScratchList(all)
+	},
+}
+
+var scratchDeleteCmd = &cobra.Command{
+	Use:               "delete <scratch org>...",
+	Short:             "Delete scratch orgs and their saved logins",
+	Args:              cobra.MinimumNArgs(1),
+	ValidArgsFunction: completeLogins,
+	Run: func(cmd *cobra.Command, args []string) {
+		keepLogin, _ := cmd.Flags().GetBool("keep-login")
+		runScratchDelete(args, keepLogin)
+	},
+}
+
+var scratchOpenCmd = &cobra.Command{
+	Use:   "open <scratch org>",
+	Short: "Open a browser window, logged into a scratch org",
+	Long: `
+Open a browser window, logged into a scratch org using its saved login.  If
+the scratch org is given by org id, the Dev Hub is used to look up its
+username.
+`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeLogins,
+	Run: func(cmd *cobra.Command, args []string) {
+		startUrl, _ := cmd.Flags().GetString("start")
+		runScratchOpen(args[0], startUrl)
+	},
+}
+
+func savedLogins() map[string]bool {
+	saved := make(map[string]bool)
+	accounts, _ := Config.List("accounts")
+	for _, account := range accounts {
+		saved[strings.ToLower(account)] = true
+	}
+	return saved
+}
+
+func runScratchList(all bool) {
+	orgs, err := force.ListScratchOrgs(all)
+	if err != nil {
+		ErrorAndExit(err.Error())
+	}
+	if len(orgs) == 0 {
+		fmt.Println("No active scratch orgs")
+		return
+	}
+	saved := savedLogins()
+	w := tabwriter.NewWriter(os.Stdout, 1, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "USERNAME\tORG ID\tEDITION\tEXPIRES\tCREATED BY\tSAVED LOGIN")
+	for _, org := range orgs {
+		login := ""
+		if saved[strings.ToLower(org.SignupUsername)] {
+			login = "yes"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", org.SignupUsername, org.OrgId, org.Edition, org.ExpirationDate, org.CreatedBy, login)
+	}
+	w.Flush()
+}
+
+func runScratchDelete(identifiers []string, keepLogin bool) {
+	orgs, err := force.ListScratchOrgs(true)
+	if err != nil {
+		ErrorAndExit(err.Error())
+	}
+	saved := savedLogins()
+	var failed []string
+	for _, identifier := range identifiers {
+		var org *ScratchOrgInfo
+		for i := range orgs {
+			if orgs[i].Matches(identifier) {
+				org = &orgs[i]
+				break
+			}
+		}
+		if org == nil {
+			fmt.Fprintf(os.Stderr, "No active scratch org found for %s\n", identifier)
+			failed = append(failed, identifier)
+			continue
+		}
+		if err := force.DeleteScratchOrg(*org); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to delete %s: %s\n", org.SignupUsername, err.Error())
+			failed = append(failed, identifier)
+			continue
+		}
+		fmt.Printf("Deleted scratch org %s\n", org.SignupUsername)
+		if !keepLogin && saved[strings.ToLower(org.SignupUsername)] {
+			if err := DeleteLogin(org.SignupUsername); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to remove saved login for %s: %s\n", org.SignupUsername, err.Error())
+			} else {
+				fmt.Printf("Removed saved login for %s\n", org.SignupUsername)
+			}
+		}
+	}
+	if len(failed) > 0 {
+		ErrorAndExit("Failed to delete %s", strings.Join(failed, ", "))
+	}
+}
+
+func runScratchOpen(identifier string, startUrl string) {
+	username := identifier
+	if !savedLogins()[strings.ToLower(identifier)] {
+		if force == nil {
+			ErrorAndExit("No saved login found for %s", identifier)
+		}
+		org, err := force.FindScratchOrg(identifier)
+		if err != nil {
+			ErrorAndExit(err.Error())
+		}
+		username = org.SignupUsername
+	}
+	scratchForce, err := GetForce(username)
+	if err != nil {
+		ErrorAndExit(err.Error())
+	}
+	force = scratchForce
+	runOpen(startUrl)
+}
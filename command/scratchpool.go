@@ -0,0 +1,182 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	. "github.com/ForceCLI/force/error"
+	. "github.com/ForceCLI/force/lib"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	scratchPoolCreateCmd.Flags().IntP("count", "n", 0, "number of orgs to create (default: pool size)")
+	scratchPoolFetchCmd.Flags().String("auth-field", DefaultScratchPoolAuthField, "ScratchOrgInfo field holding pool org credentials")
+
+	scratchPoolCmd.AddCommand(scratchPoolCreateCmd)
+	scratchPoolCmd.AddCommand(scratchPoolReplenishCmd)
+	scratchPoolCmd.AddCommand(scratchPoolFetchCmd)
+	scratchPoolCmd.AddCommand(scratchPoolStatusCmd)
+	scratchOrgCmd.AddCommand(scratchPoolCmd)
+}
+
+var scratchPoolCmd = &cobra.Command{
+	Use:   "pool",
+	Short: "Manage pools of ready-to-use scratch orgs",
+	Long: `
+Manage pools of scratch orgs that are created and set up ahead of time, so CI
+jobs can claim one instead of waiting for a new org.  The active login must be
+a Dev Hub org.
+
+A pool is configured in a JSON file:
+
+  {
+    "name": "ci",
+    "definitionFile": "config/project-scratch-def.json",
+    "size": 5,
+    "duration": 2,
+    "setup": {
+      "deploy": ["src"],
+      "packages": [{"id": "04t..."}],
+      "apex": ["scripts/setup.apex"]
+    }
+  }
+
+Each org's pool and state are stored in the Description of its ScratchOrgInfo
+record.  The credentials used to log into pool orgs are stored in a custom
+Long Text Area field on ScratchOrgInfo, which must be created in the Dev Hub.
+The field is Pool_Auth__c unless the pool sets authField.
+`,
+	Example: `
+  force scratch pool create pools/ci.json
+  force scratch pool replenish pools/ci.json
+  force scratch pool fetch ci
+  force scratch pool status
+`,
+	DisableFlagsInUseLine: true,
+}
+
+var scratchPoolCreateCmd = &cobra.Command{
+	Use:   "create <pool file>",
+	Short: "Add new orgs to a pool",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		count, _ := cmd.Flags().GetInt("count")
+		runScratchPoolCreate(args[0], count)
+	},
+}
+
+var scratchPoolReplenishCmd = &cobra.Command{
+	Use:   "replenish <pool file>",
+	Short: "Create enough orgs to bring a pool up to its size",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runScratchPoolReplenish(args[0])
+	},
+}
+
+var scratchPoolFetchCmd = &cobra.Command{
+	Use:   "fetch <pool>",
+	Short: "Claim an org from a pool and log into it",
+	Long: `
+Claim an available org from a pool and log into it.  The new login becomes
+the active login, and its username is written to standard output.  Claims
+are safe to make from several jobs at once; each org is claimed by only one.
+`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		authField, _ := cmd.Flags().GetString("auth-field")
+		runScratchPoolFetch(args[0], authField)
+	},
+}
+
+var scratchPoolStatusCmd = &cobra.Command{
+	Use:   "status [pool]",
+	Short: "Show the number of orgs in each pool by state",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		pool := ""
+		if len(args) > 0 {
+			pool = args[0]
+		}
+		runScratchPoolStatus(pool)
+	},
+}
+
+func loadScratchPool(path string) ScratchPool {
+	pool, err := LoadScratchPool(path)
+	if err != nil {
+		ErrorAndExit(err.Error())
+	}
+	return pool
+}
+
+func createScratchPoolOrgs(pool ScratchPool, count int) {
+	added, err := force.CreateScratchPoolOrgs(pool, count, func(msg string) {
+		fmt.Fprintln(os.Stderr, msg)
+	})
+	fmt.Printf("Added %d scratch orgs to pool %s\n", added, pool.Name)
+	if err != nil {
+		ErrorAndExit(err.Error())
+	}
+}
+
+func runScratchPoolCreate(path string, count int) {
+	pool := loadScratchPool(path)
+	if count <= 0 {
+		count = pool.Size
+	}
+	createScratchPoolOrgs(pool, count)
+}
+
+func runScratchPoolReplenish(path string) {
+	pool := loadScratchPool(path)
+	orgs, err := force.ListScratchPoolOrgs(pool.Name, "")
+	if err != nil {
+		ErrorAndExit(err.Error())
+	}
+	shortfall := pool.Shortfall(orgs)
+	if shortfall == 0 {
+		fmt.Printf("Pool %s is full\n", pool.Name)
+		return
+	}
+	createScratchPoolOrgs(pool, shortfall)
+}
+
+func runScratchPoolFetch(pool string, authField string) {
+	session, org, err := force.ClaimScratchPoolOrg(pool, authField)
+	if err != nil {
+		ErrorAndExit(err.Error())
+	}
+	username, err := ForceSaveLogin(session, os.Stderr)
+	if err != nil {
+		ErrorAndExit("Claimed %s, but could not save login: %s", org.SignupUsername, err.Error())
+	}
+	fmt.Println(username)
+}
+
+func runScratchPoolStatus(pool string) {
+	orgs, err := force.ListScratchPoolOrgs(pool, "")
+	if err != nil {
+		ErrorAndExit(err.Error())
+	}
+	status := ScratchPoolStatus(orgs)
+	if len(status) == 0 {
+		fmt.Println("No scratch org pools")
+		return
+	}
+	var pools []string
+	for name := range status {
+		pools = append(pools, name)
+	}
+	sort.Strings(pools)
+	w := tabwriter.NewWriter(os.Stdout, 1, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "POOL\tAVAILABLE\tPROVISIONING\tCLAIMED")
+	for _, name := range pools {
+		counts := status[name]
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\n", name, counts[ScratchPoolAvailable], counts[ScratchPoolProvisioning], counts[ScratchPoolClaimed])
+	}
+	w.Flush()
+}
@@ -0,0 +1,255 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ForceCLI/force/config"
+	. "github.com/ForceCLI/force/error"
+	. "github.com/ForceCLI/force/lib"
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	watchCmd.Flags().Duration("debounce", 500*time.Millisecond, "wait until files have stopped changing for `duration` before pushing")
+	watchCmd.Flags().Bool("metadata-api", false, "always deploy through the Metadata API instead of saving through the Tooling API")
+	RootCmd.AddCommand(watchCmd)
+}
+
+var watchCmd = &cobra.Command{
+	Use:   "watch [paths...]",
+	Short: "Push metadata to the org as it changes",
+	Long: `
+Watch the source directory, or the given paths within it, and push changed
+components to the org as they're saved.
+
+Changes are collected until files stop changing for the debounce period, then
+only the changed components are pushed.  Existing Apex classes and triggers,
+Visualforce pages and components, and Aura and Lightning web component files
+are saved through the Tooling API, which is faster than a Metadata API deploy.
+Other metadata, and new components, are deployed through the Metadata API.
+
+Failures are printed as file:line:column: problem.  Deleted files aren't
+removed from the org.
+`,
+	Example: `
+  force watch
+  force watch src/classes src/lwc
+  force watch --debounce 2s --metadata-api
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		debounce, _ := cmd.Flags().GetDuration("debounce")
+		metadataApiOnly, _ := cmd.Flags().GetBool("metadata-api")
+		runWatch(args, debounce, !metadataApiOnly)
+	},
+}
+
+func runWatch(paths []string, debounce time.Duration, useTooling bool) {
+	var sourceDir string
+	if len(paths) > 0 {
+		sourceDir = sourceDirFromPaths(paths)
+	}
+	var err error
+	if sourceDir == "" {
+		sourceDir, err = config.GetSourceDir()
+		ExitIfNoSourceDir(err)
+	}
+	if len(paths) == 0 {
+		paths = []string{sourceDir}
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		ErrorAndExit(err.Error())
+	}
+	defer watcher.Close()
+	for _, p := range paths {
+		if err := watchTree(watcher, p); err != nil {
+			ErrorAndExit(err.Error())
+		}
+	}
+	fmt.Printf("Watching %s for changes\n", strings.Join(paths, ", "))
+
+	changed := make(map[string]bool)
+	timer := time.NewTimer(debounce)
+	timer.Stop()
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op == fsnotify.Chmod || ignoredWatchPath(event.Name) {
+				continue
+			}
+			if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+				if event.Op&fsnotify.Create != 0 {
+					watchTree(watcher, event.Name)
+				}
+				continue
+			}
+			changed[event.Name] = true
+			timer.Reset(debounce)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			fmt.Fprintln(os.Stderr, err)
+		case <-timer.C:
+			var files []string
+			for f := range changed {
+				files = append(files, f)
+			}
+			changed = make(map[string]bool)
+			pushChanges(sourceDir, files, useTooling)
+		}
+	}
+}
+
+// watchTree watches a directory and its subdirectories, since fsnotify
+// doesn't watch recursively.
+func watchTree(watcher *fsnotify.Watcher, root string) error {
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if path != root && ignoredWatchPath(path) {
+			return filepath.SkipDir
+		}
+		return watcher.Add(path)
+	})
+}
+
+// ignoredWatchPath reports whether a path is a hidden file or directory, or
+// an editor's temporary file, whose changes shouldn't be pushed.
+func ignoredWatchPath(path string) bool {
+	name := filepath.Base(path)
+	return strings.HasPrefix(name, ".") ||
+		strings.HasPrefix(name, "#") ||
+		strings.HasSuffix(name, "~") ||
+		strings.HasSuffix(name, ".swp") ||
+		strings.HasSuffix(name, ".tmp") ||
+		name == "__tests__" ||
+		name == "node_modules"
+}
+
+// pushChanges pushes the components of the changed files, saving what it
+// can through the Tooling API and deploying the rest.
+func pushChanges(sourceDir string, files []string, useTooling bool) {
+	var toolingFiles []ToolingFile
+	var deployPaths []string
+	for _, f := range files {
+		if _, err := os.Stat(f); err != nil {
+			fmt.Fprintf(os.Stderr, "Ignoring deleted %s\n", f)
+			continue
+		}
+		rel, err := filepath.Rel(sourceDir, f)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+		if !useTooling || !IsToolingPath(rel) {
+			deployPaths = append(deployPaths, f)
+			continue
+		}
+		body, err := os.ReadFile(f)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			continue
+		}
+		toolingFiles = append(toolingFiles, ToolingFile{Path: rel, Body: body})
+	}
+
+	if len(toolingFiles) > 0 {
+		start := time.Now()
+		result, err := force.ToolingDeploy(toolingFiles, ToolingDeployOptions{})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		} else {
+			for _, path := range result.NotFound {
+				deployPaths = append(deployPaths, filepath.Join(sourceDir, filepath.FromSlash(path)))
+			}
+			printWatchResult(sourceDir, len(toolingFiles)-len(result.NotFound), result.Failures, "Saved", time.Since(start))
+		}
+	}
+	if len(deployPaths) > 0 {
+		deployChanges(sourceDir, deployPaths)
+	}
+}
+
+// deployChanges deploys the changed files' components through the Metadata
+// API.
+func deployChanges(sourceDir string, paths []string) {
+	start := time.Now()
+	pb := NewPushBuilder()
+	pb.Root = sourceDir
+	added := make(map[string]bool)
+	for _, p := range paths {
+		// Aura and LWC components are deployed as whole bundles.
+		p = replaceComponentWithBundle(p)
+		if added[p] {
+			continue
+		}
+		added[p] = true
+		if err := pb.Add(p); err != nil {
+			fmt.Fprintf(os.Stderr, "Could not add %s: %s\n", p, err.Error())
+		}
+	}
+	if len(pb.Metadata) == 0 {
+		return
+	}
+	deployId, err := force.Metadata.StartDeploy(pb.ForceMetadataFiles(), ForceDeployOptions{SinglePackage: true})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	result, err := monitorDeploy(deployId)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	if result.ErrorMessage != "" {
+		fmt.Fprintln(os.Stderr, result.ErrorMessage)
+	}
+	printWatchResult(sourceDir, result.NumberComponentsDeployed, result.Details.ComponentFailures, "Deployed", time.Since(start))
+}
+
+func printWatchResult(sourceDir string, count int, failures []ComponentFailure, verb string, duration time.Duration) {
+	for _, f := range failures {
+		fmt.Fprintln(os.Stderr, formatComponentFailure(sourceDir, f))
+	}
+	if len(failures) > 0 {
+		fmt.Printf("%s %d component(s) with %d failure(s) in %s\n", verb, count, len(failures), duration.Round(time.Millisecond))
+		return
+	}
+	fmt.Printf("%s %d component(s) in %s\n", verb, count, duration.Round(time.Millisecond))
+}
+
+// formatComponentFailure formats a failure as file:line:column: problem, so
+// editors and terminals can link to it.
+func formatComponentFailure(sourceDir string, f ComponentFailure) string {
+	if f.FileName != "" {
+		f.FileName = displayPath(sourceDir, f.FileName)
+	}
+	return NewCompileError(f).String()
+}
+
+// displayPath returns the path of a file in the source directory, relative
+// to the current directory if it's within it.
+func displayPath(sourceDir string, name string) string {
+	path := filepath.Join(sourceDir, filepath.FromSlash(strings.TrimPrefix(name, "unpackaged/")))
+	if wd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(wd, path); err == nil && !strings.HasPrefix(rel, "..") {
+			return rel
+		}
+	}
+	return path
+}
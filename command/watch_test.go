@@ -0,0 +1,40 @@
+package command
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/ForceCLI/force/lib"
+)
+
+func TestFormatComponentFailure(t *testing.T) {
+	wd, _ := os.Getwd()
+	sourceDir := filepath.Join(wd, "src")
+	cases := []struct {
+		failure  ComponentFailure
+		expected string
+	}{
+		{ComponentFailure{FileName: "classes/A.cls", LineNumber: 3, ColumnNumber: 7, Problem: "Missing ';'"}, filepath.Join("src", "classes", "A.cls") + ":3:7: Missing ';'"},
+		{ComponentFailure{FileName: "unpackaged/objects/B.object", LineNumber: 10, Problem: "Bad field"}, filepath.Join("src", "objects", "B.object") + ":10: Bad field"},
+		{ComponentFailure{FullName: "Cmp", Problem: "Invalid"}, "Cmp: Invalid"},
+	}
+	for _, c := range cases {
+		if actual := formatComponentFailure(sourceDir, c.failure); actual != c.expected {
+			t.Errorf("Expected %q, got %q", c.expected, actual)
+		}
+	}
+}
+
+func TestIgnoredWatchPath(t *testing.T) {
+	for _, path := range []string{"src/.git", "src/classes/.A.cls.swp", "src/classes/A.cls~", "src/lwc/c/__tests__", "src/classes/#A.cls#"} {
+		if !ignoredWatchPath(path) {
+			t.Errorf("Expected %s to be ignored", path)
+		}
+	}
+	for _, path := range []string{"src/classes/A.cls", "src/lwc/c/c.js", "src/aura/Cmp"} {
+		if ignoredWatchPath(path) {
+			t.Errorf("Expected %s not to be ignored", path)
+		}
+	}
+}
@@ -140,6 +140,14 @@ func (m *Manager) LoadGlobal(name, key string) (string, error) {
 	return m.Load(name, key)
 }
 
+func (m *Manager) LoadLocal(name, key string) (string, error) {
+	path, err := m.localPath(name, key)
+	if err != nil {
+		return "", err
+	}
+	return m.readFile(path)
+}
+
 func (m *Manager) LoadLocalOrGlobal(name, key string) (string, error) {
 	if path, err := m.localPath(name, key); err == nil {
 		if body, err := m.readFile(path); err == nil {
@@ -12,7 +12,7 @@ import (
 )
 
 func (f *Force) UserInfo() (userinfo UserInfo, err error) {
-	url := fmt.Sprintf("%s/services/oauth2/userinfo", f.Credentials.InstanceUrl)
+	url := fmt.Sprintf("%s/services/oauth2/userinfo", f.InstanceUrl())
 	login, err := f.makeHttpRequestSync(NewRequest("GET").AbsoluteUrl(url))
 	if err != nil {
 		return
@@ -215,6 +215,8 @@ func (f *Force) UpdateCredentials(creds ForceSession) {
 
 // CopyCredentialAuthFields copies auth fields from creds into the receiver's Credentials.
 func (f *Force) CopyCredentialAuthFields(creds *ForceSession) {
+	credentialsMutex.Lock()
+	defer credentialsMutex.Unlock()
 	f.Credentials.AccessToken = creds.AccessToken
 	f.Credentials.IssuedAt = creds.IssuedAt
 	f.Credentials.InstanceUrl = creds.InstanceUrl
@@ -356,6 +358,20 @@ func SetActiveLoginDefault() (account string) {
 	return
 }
 
+// DeleteLogin removes a saved login.  If it was the active login, another
+// saved login becomes active.
+func DeleteLogin(account string) (err error) {
+	if err = Config.Delete("accounts", account); err != nil {
+		return
+	}
+	Config.Delete("login-metadata", account)
+	if active, _ := Config.Load("current", "account"); active == account {
+		Config.Delete("current", "account")
+		SetActiveLoginDefault()
+	}
+	return
+}
+
 func SetActiveLogin(account string) (err error) {
 	err = Config.Save("current", "account", account)
 	return
@@ -0,0 +1,153 @@
+package lib
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/ForceCLI/force/config"
+)
+
+// AuthProfile holds what's needed to log in, and to log in again when the
+// session expires, using the JWT bearer flow or the client credentials flow.
+// Secrets are never stored in a profile, only a reference to them; see
+// ResolveSecretRef.
+type AuthProfile struct {
+	ClientId  string `json:"clientId"`
+	Username  string `json:"username,omitempty"`
+	KeyFile   string `json:"keyFile,omitempty"`
+	SecretRef string `json:"secretRef,omitempty"`
+	Endpoint  string `json:"endpoint"`
+}
+
+// IsJWT reports whether the profile logs in using the JWT bearer flow.
+func (p AuthProfile) IsJWT() bool {
+	return p.KeyFile != ""
+}
+
+func (p AuthProfile) validate() error {
+	switch {
+	case p.ClientId == "":
+		return errors.New("A client id is required")
+	case p.Endpoint == "":
+		return errors.New("An endpoint is required")
+	case p.KeyFile != "" && p.SecretRef != "":
+		return errors.New("Specify either a key file or a secret, not both")
+	case p.KeyFile != "" && p.Username == "":
+		return errors.New("A username is required to log in with a key file")
+	case p.KeyFile == "" && p.SecretRef == "":
+		return errors.New("A key file or a secret is required")
+	}
+	if p.SecretRef != "" {
+		if _, _, err := parseSecretRef(p.SecretRef); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SaveAuthProfile saves a named auth profile, replacing any existing profile
+// with the same name.
+func SaveAuthProfile(name string, profile AuthProfile) error {
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("Invalid profile name %q", name)
+	}
+	if profile.KeyFile != "" {
+		keyFile, err := filepath.Abs(profile.KeyFile)
+		if err != nil {
+			return err
+		}
+		profile.KeyFile = keyFile
+	}
+	if err := profile.validate(); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(profile, "", "  ")
+	if err != nil {
+		return err
+	}
+	return Config.Save("auth-profiles", name, string(data))
+}
+
+// LoadAuthProfile returns the named auth profile.
+func LoadAuthProfile(name string) (profile AuthProfile, err error) {
+	data, err := Config.Load("auth-profiles", name)
+	if err != nil {
+		return profile, fmt.Errorf("Unknown auth profile %s", name)
+	}
+	if err = json.Unmarshal([]byte(data), &profile); err != nil {
+		return profile, fmt.Errorf("Invalid auth profile %s: %w", name, err)
+	}
+	return profile, nil
+}
+
+// AuthProfiles returns the names of the saved auth profiles.
+func AuthProfiles() ([]string, error) {
+	return Config.List("auth-profiles")
+}
+
+func DeleteAuthProfile(name string) error {
+	if _, err := Config.Load("auth-profiles", name); err != nil {
+		return fmt.Errorf("Unknown auth profile %s", name)
+	}
+	return Config.Delete("auth-profiles", name)
+}
+
+// ForceLoginAndSaveWithAuthProfile logs in using the named auth profile and
+// saves the login along with the profile's key file or secret reference, so
+// the session can be re-authenticated when it expires.
+func ForceLoginAndSaveWithAuthProfile(name string, output *os.File) (username string, err error) {
+	profile, err := LoadAuthProfile(name)
+	if err != nil {
+		return
+	}
+	if profile.IsJWT() {
+		return ForceLoginAtEndpointAndSaveJWTWithKey(profile.Endpoint, profile.Username, profile.KeyFile, profile.ClientId, output)
+	}
+	return ForceLoginAtEndpointAndSaveClientCredentialsWithSecretRef(profile.Endpoint, profile.ClientId, profile.SecretRef, output)
+}
+
+func parseSecretRef(ref string) (kind string, value string, err error) {
+	kind, value, ok := strings.Cut(ref, ":")
+	if !ok || value == "" || (kind != "env" && kind != "keyring") {
+		return "", "", fmt.Errorf("Invalid secret reference %q: use env:NAME or keyring:SERVICE/ACCOUNT", ref)
+	}
+	if kind == "keyring" && !strings.Contains(value, "/") {
+		return "", "", fmt.Errorf("Invalid secret reference %q: use keyring:SERVICE/ACCOUNT", ref)
+	}
+	return kind, value, nil
+}
+
+// ResolveSecretRef returns the secret a reference refers to.  References
+// take the form env:NAME, for the value of the NAME environment variable, or
+// keyring:SERVICE/ACCOUNT, for a password stored in the macOS keychain or,
+// on Linux, the Secret Service keyring.
+func ResolveSecretRef(ref string) (string, error) {
+	kind, value, err := parseSecretRef(ref)
+	if err != nil {
+		return "", err
+	}
+	if kind == "env" {
+		secret := os.Getenv(value)
+		if secret == "" {
+			return "", fmt.Errorf("Environment variable %s is not set", value)
+		}
+		return secret, nil
+	}
+	service, account, _ := strings.Cut(value, "/")
+	return keyringSecret(service, account)
+}
+
+func keyringSecret(service, account string) (string, error) {
+	secret, err := lookupKeychainSecret(service, account, "service", service, "account", account)
+	if errors.Is(err, errKeychainSecretNotFound) || (err == nil && secret == "") {
+		return "", fmt.Errorf("Secret %s/%s not found in keyring", service, account)
+	}
+	if err != nil {
+		return "", fmt.Errorf("Unable to read secret %s/%s from keyring: %w", service, account, err)
+	}
+	return secret, nil
+}
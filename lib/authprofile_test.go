@@ -0,0 +1,123 @@
+package lib
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestSaveAuthProfile(t *testing.T) {
+	cleanup := setupTestConfig(t)
+	defer cleanup()
+
+	invalid := []AuthProfile{
+		{Endpoint: "https://login.salesforce.com", SecretRef: "env:SECRET"},
+		{ClientId: "id", Endpoint: "https://login.salesforce.com"},
+		{ClientId: "id", Endpoint: "https://login.salesforce.com", KeyFile: "jwt.key"},
+		{ClientId: "id", Endpoint: "https://login.salesforce.com", Username: "ci@acme.com", KeyFile: "jwt.key", SecretRef: "env:SECRET"},
+		{ClientId: "id", Endpoint: "https://login.salesforce.com", SecretRef: "plaintext"},
+		{ClientId: "id", Endpoint: "https://login.salesforce.com", SecretRef: "keyring:force"},
+	}
+	for _, profile := range invalid {
+		if err := SaveAuthProfile("bad", profile); err == nil {
+			t.Errorf("Expected error saving %+v", profile)
+		}
+	}
+
+	jwtProfile := AuthProfile{ClientId: "id", Endpoint: "https://test.salesforce.com", Username: "ci@acme.com", KeyFile: "jwt.key"}
+	if err := SaveAuthProfile("ci", jwtProfile); err != nil {
+		t.Fatalf("SaveAuthProfile returned error: %v", err)
+	}
+	saved, err := LoadAuthProfile("ci")
+	if err != nil {
+		t.Fatalf("LoadAuthProfile returned error: %v", err)
+	}
+	if !saved.IsJWT() || !filepath.IsAbs(saved.KeyFile) {
+		t.Errorf("Expected JWT profile with absolute key path, got %+v", saved)
+	}
+
+	SaveAuthProfile("integration", AuthProfile{ClientId: "id", Endpoint: "https://login.salesforce.com", SecretRef: "keyring:force/integration"})
+	if names, _ := AuthProfiles(); !reflect.DeepEqual(names, []string{"ci", "integration"}) {
+		t.Errorf("Unexpected profiles: %v", names)
+	}
+	if err := DeleteAuthProfile("ci"); err != nil {
+		t.Errorf("DeleteAuthProfile returned error: %v", err)
+	}
+	if _, err := LoadAuthProfile("ci"); err == nil {
+		t.Errorf("Expected deleted profile to be gone")
+	}
+}
+
+func TestResolveKeyringSecretRef(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("secret-tool lookup is only used on linux")
+	}
+	script := filepath.Join(t.TempDir(), "secret-tool")
+	os.WriteFile(script, []byte("#!/bin/sh\n[ \"$*\" = \"lookup service force account integration\" ] && echo s3cret\n"), 0755)
+	t.Setenv(secretToolEnvVar, script)
+
+	if secret, err := ResolveSecretRef("keyring:force/integration"); err != nil || secret != "s3cret" {
+		t.Errorf("Unexpected secret %q, %v", secret, err)
+	}
+	if _, err := ResolveSecretRef("keyring:force/missing"); err == nil {
+		t.Errorf("Expected error for missing keyring entry")
+	}
+}
+
+func TestLoginWithAuthProfileCanRefresh(t *testing.T) {
+	cleanup := setupTestConfig(t)
+	defer cleanup()
+
+	originalGetUserInfo := getUserInfoFn
+	getUserInfoFn = stubUserInfo("integration@acme.com")
+	defer func() { getUserInfoFn = originalGetUserInfo }()
+
+	tokens := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("grant_type") != "client_credentials" || r.Form.Get("client_secret") != "s3cret" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": "invalid_client", "error_description": "invalid client credentials"}`))
+			return
+		}
+		tokens++
+		fmt.Fprintf(w, `{"access_token": "token-%d", "instance_url": "https://acme.my.salesforce.com", "issued_at": "1704164645000"}`, tokens)
+	}))
+	defer server.Close()
+
+	t.Setenv("FORCE_TEST_CLIENT_SECRET", "s3cret")
+	SaveAuthProfile("integration", AuthProfile{ClientId: "id", Endpoint: server.URL, SecretRef: "env:FORCE_TEST_CLIENT_SECRET"})
+
+	output, _ := os.Create(filepath.Join(t.TempDir(), "output"))
+	defer output.Close()
+	username, err := ForceLoginAndSaveWithAuthProfile("integration", output)
+	if err != nil {
+		t.Fatalf("ForceLoginAndSaveWithAuthProfile returned error: %v", err)
+	}
+	creds, err := GetAccountCredentials(username)
+	if err != nil {
+		t.Fatalf("GetAccountCredentials returned error: %v", err)
+	}
+	if creds.SessionOptions.ClientSecretRef != "env:FORCE_TEST_CLIENT_SECRET" || creds.RefreshCapability() != RefreshCapabilityClientCredentials {
+		t.Fatalf("Expected login to be refreshable with the profile's secret, got %+v", creds.SessionOptions)
+	}
+
+	f := NewForce(&creds)
+	if err := f.RefreshSession(); err != nil {
+		t.Fatalf("RefreshSession returned error: %v", err)
+	}
+	if f.Credentials.AccessToken != "token-2" {
+		t.Errorf("Expected refreshed token, got %s", f.Credentials.AccessToken)
+	}
+
+	t.Setenv("FORCE_TEST_CLIENT_SECRET", "wrong")
+	if err := f.RefreshSession(); err == nil || !strings.Contains(err.Error(), "invalid client credentials") {
+		t.Errorf("Expected invalid client credentials error, got %v", err)
+	}
+}
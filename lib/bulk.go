@@ -116,11 +116,16 @@ func (f *Force) CreateBulkJobWithContext(ctx context.Context, jobInfo JobInfo, r
 }
 
 func (f *Force) CreateBulkJob(jobInfo JobInfo, requestOptions ...func(*http.Request)) (JobInfo, error) {
+	if strings.EqualFold(jobInfo.Operation, "hardDelete") {
+		if err := f.CheckDestructiveOperation(fmt.Sprintf("hard delete %s records", jobInfo.Object)); err != nil {
+			return JobInfo{}, err
+		}
+	}
 	xmlbody, err := internal.XmlMarshal(jobInfo)
 	if err != nil {
 		return JobInfo{}, fmt.Errorf("Could not create job request: %s", err.Error())
 	}
-	url := fmt.Sprintf("%s/services/async/%s/job", f.Credentials.InstanceUrl, apiVersionNumber)
+	url := fmt.Sprintf("%s/services/async/%s/job", f.InstanceUrl(), apiVersionNumber)
 	body, err := f.httpPostPatchWithRetry(url, string(xmlbody), ContentTypeXml, HttpMethodPost, requestOptions...)
 	if err != nil {
 		if fault, ok := err.(LoginFault); ok && fault.ExceptionCode == "InvalidEntity" {
@@ -159,7 +164,7 @@ func (f *Force) CloseBulkJob(jobId string) (JobInfo, error) {
 	if err != nil {
 		return JobInfo{}, err
 	}
-	url := fmt.Sprintf("%s/services/async/%s/job/%s", f.Credentials.InstanceUrl, apiVersionNumber, jobId)
+	url := fmt.Sprintf("%s/services/async/%s/job/%s", f.InstanceUrl(), apiVersionNumber, jobId)
 	body, err := f.httpPostPatchWithRetry(url, string(xmlbody), ContentTypeXml, HttpMethodPost)
 	if err != nil {
 		return JobInfo{}, err
@@ -172,7 +177,7 @@ func (f *Force) CloseBulkJob(jobId string) (JobInfo, error) {
 }
 
 func (f *Force) GetBulkJobs() ([]JobInfo, error) {
-	url := fmt.Sprintf("%s/services/async/%s/jobs", f.Credentials.InstanceUrl, apiVersionNumber)
+	url := fmt.Sprintf("%s/services/async/%s/jobs", f.InstanceUrl(), apiVersionNumber)
 	resp, err := f.httpGetBulk(url)
 	if err != nil {
 		return nil, err
@@ -206,7 +211,7 @@ func (f *Force) BulkQueryWithContext(ctx context.Context, soql string, jobId str
 }
 
 func (f *Force) BulkQuery(soql string, jobId string, contentType string, requestOptions ...func(*http.Request)) (BatchInfo, error) {
-	url := fmt.Sprintf("%s/services/async/%s/job/%s/batch", f.Credentials.InstanceUrl, apiVersionNumber, jobId)
+	url := fmt.Sprintf("%s/services/async/%s/job/%s/batch", f.InstanceUrl(), apiVersionNumber, jobId)
 
 	jct, err := JobInfo{ContentType: contentType}.JobContentType()
 	if err != nil {
@@ -231,7 +236,7 @@ func (f *Force) AddBatchToJob(content string, job JobInfo) (BatchInfo, error) {
 	if err != nil {
 		return BatchInfo{}, err
 	}
-	url := fmt.Sprintf("%s/services/async/%s/job/%s/batch", f.Credentials.InstanceUrl, apiVersionNumber, job.Id)
+	url := fmt.Sprintf("%s/services/async/%s/job/%s/batch", f.InstanceUrl(), apiVersionNumber, job.Id)
 	body, err := f.httpPostPatchWithRetry(url, content, httpContentTypeForJobContentType[jct], HttpMethodPost)
 	if err != nil {
 		return BatchInfo{}, err
@@ -261,7 +266,7 @@ func (f *Force) GetBatchInfoWithContext(ctx context.Context, jobId string, batch
 }
 
 func (f *Force) GetBatchInfo(jobId string, batchId string) (BatchInfo, error) {
-	url := fmt.Sprintf("%s/services/async/%s/job/%s/batch/%s", f.Credentials.InstanceUrl, apiVersionNumber, jobId, batchId)
+	url := fmt.Sprintf("%s/services/async/%s/job/%s/batch/%s", f.InstanceUrl(), apiVersionNumber, jobId, batchId)
 
 	resp, err := f.httpGetBulk(url)
 	if err != nil {
@@ -296,7 +301,7 @@ func (f *Force) GetBatchesWithContext(ctx context.Context, jobId string) (result
 }
 
 func (f *Force) GetBatches(jobId string) (result []BatchInfo, err error) {
-	url := fmt.Sprintf("%s/services/async/%s/job/%s/batch", f.Credentials.InstanceUrl, apiVersionNumber, jobId)
+	url := fmt.Sprintf("%s/services/async/%s/job/%s/batch", f.InstanceUrl(), apiVersionNumber, jobId)
 	resp, err := f.httpGetBulk(url)
 	if err != nil {
 		return nil, err
@@ -335,7 +340,7 @@ func (f *Force) GetJobInfoWithContext(ctx context.Context, jobId string) (JobInf
 }
 
 func (f *Force) GetJobInfo(jobId string) (JobInfo, error) {
-	url := fmt.Sprintf("%s/services/async/%s/job/%s", f.Credentials.InstanceUrl, apiVersionNumber, jobId)
+	url := fmt.Sprintf("%s/services/async/%s/job/%s", f.InstanceUrl(), apiVersionNumber, jobId)
 	resp, err := f.httpGetBulk(url)
 	if err != nil {
 		return JobInfo{}, err
@@ -348,7 +353,7 @@ func (f *Force) GetJobInfo(jobId string) (JobInfo, error) {
 }
 
 func (f *Force) RetrieveBulkQueryResultList(job JobInfo, batchId string) ([]byte, error) {
-	url := fmt.Sprintf("%s/services/async/%s/job/%s/batch/%s/result", f.Credentials.InstanceUrl, apiVersionNumber, job.Id, batchId)
+	url := fmt.Sprintf("%s/services/async/%s/job/%s/batch/%s/result", f.InstanceUrl(), apiVersionNumber, job.Id, batchId)
 	ct, err := job.HttpContentType()
 	if err != nil {
 		return nil, err
@@ -374,7 +379,7 @@ func (f *Force) RetrieveBulkQueryWithContext(ctx context.Context, jobId string,
 }
 
 func (f *Force) RetrieveBulkQuery(jobId string, batchId string) ([]byte, error) {
-	url := fmt.Sprintf("%s/services/async/%s/job/%s/batch/%s/result", f.Credentials.InstanceUrl, apiVersionNumber, jobId, batchId)
+	url := fmt.Sprintf("%s/services/async/%s/job/%s/batch/%s/result", f.InstanceUrl(), apiVersionNumber, jobId, batchId)
 	resp, err := f.httpGetBulk(url)
 	if err != nil {
 		return nil, err
@@ -383,7 +388,7 @@ func (f *Force) RetrieveBulkQuery(jobId string, batchId string) ([]byte, error)
 }
 
 func (f *Force) RetrieveBulkRequest(jobId string, batchId string) ([]byte, error) {
-	url := fmt.Sprintf("%s/services/async/%s/job/%s/batch/%s/request", f.Credentials.InstanceUrl, apiVersionNumber, jobId, batchId)
+	url := fmt.Sprintf("%s/services/async/%s/job/%s/batch/%s/request", f.InstanceUrl(), apiVersionNumber, jobId, batchId)
 	resp, err := f.httpGetBulk(url)
 	if err != nil {
 		return nil, err
@@ -392,7 +397,7 @@ func (f *Force) RetrieveBulkRequest(jobId string, batchId string) ([]byte, error
 }
 
 func (f *Force) RetrieveBulkQueryResults(jobId string, batchId string, resultId string) ([]byte, error) {
-	url := fmt.Sprintf("%s/services/async/%s/job/%s/batch/%s/result/%s", f.Credentials.InstanceUrl, apiVersionNumber, jobId, batchId, resultId)
+	url := fmt.Sprintf("%s/services/async/%s/job/%s/batch/%s/result/%s", f.InstanceUrl(), apiVersionNumber, jobId, batchId, resultId)
 	resp, err := f.httpGetBulk(url)
 	if err != nil {
 		return nil, err
@@ -401,7 +406,7 @@ func (f *Force) RetrieveBulkQueryResults(jobId string, batchId string, resultId
 }
 
 func (f *Force) RetrieveBulkJobQueryResults(job JobInfo, batchId string, resultId string) ([]byte, error) {
-	url := fmt.Sprintf("%s/services/async/%s/job/%s/batch/%s/result/%s", f.Credentials.InstanceUrl, apiVersionNumber, job.Id, batchId, resultId)
+	url := fmt.Sprintf("%s/services/async/%s/job/%s/batch/%s/result/%s", f.InstanceUrl(), apiVersionNumber, job.Id, batchId, resultId)
 	ct, err := job.HttpContentType()
 	if err != nil {
 		return nil, err
@@ -426,7 +431,7 @@ func (f *Force) RetrieveBulkJobQueryResultsWithCallbackWithContext(ctx context.C
 }
 
 func (f *Force) RetrieveBulkJobQueryResultsWithCallback(job JobInfo, batchId string, resultId string, callback HttpCallback) error {
-	url := fmt.Sprintf("%s/services/async/%s/job/%s/batch/%s/result/%s", f.Credentials.InstanceUrl, apiVersionNumber, job.Id, batchId, resultId)
+	url := fmt.Sprintf("%s/services/async/%s/job/%s/batch/%s/result/%s", f.InstanceUrl(), apiVersionNumber, job.Id, batchId, resultId)
 	ct, err := job.HttpContentType()
 	if err != nil {
 		return err
@@ -443,7 +448,7 @@ func (f *Force) RetrieveBulkJobQueryResultsAndSend(job JobInfo, batchId string,
 
 func (f *Force) RetrieveBulkBatchResults(jobId string, batchId string) (BatchResult, error) {
 	var result BatchResult
-	url := fmt.Sprintf("%s/services/async/%s/job/%s/batch/%s/result", f.Credentials.InstanceUrl, apiVersionNumber, jobId, batchId)
+	url := fmt.Sprintf("%s/services/async/%s/job/%s/batch/%s/result", f.InstanceUrl(), apiVersionNumber, jobId, batchId)
 	resp, err := f.httpGetBulk(url)
 	if err != nil {
 		return result, err
@@ -158,12 +158,12 @@ type Bulk2QueryResults struct {
 
 // bulk2IngestUrl returns the URL for the Bulk API 2.0 ingest endpoint
 func (f *Force) bulk2IngestUrl() string {
-	return fmt.Sprintf("%s/services/data/%s/jobs/ingest", f.Credentials.InstanceUrl, apiVersion)
+	return fmt.Sprintf("%s/services/data/%s/jobs/ingest", f.InstanceUrl(), apiVersion)
 }
 
 // bulk2QueryUrl returns the URL for the Bulk API 2.0 query endpoint
 func (f *Force) bulk2QueryUrl() string {
-	return fmt.Sprintf("%s/services/data/%s/jobs/query", f.Credentials.InstanceUrl, apiVersion)
+	return fmt.Sprintf("%s/services/data/%s/jobs/query", f.InstanceUrl(), apiVersion)
 }
 
 // CreateBulk2IngestJob creates a new Bulk API 2.0 ingest job
@@ -171,6 +171,11 @@ func (f *Force) CreateBulk2IngestJob(request Bulk2IngestJobRequest) (Bulk2Ingest
 	if request.ContentType == "" {
 		request.ContentType = "CSV"
 	}
+	if strings.EqualFold(string(request.Operation), string(Bulk2OperationHardDelete)) {
+		if err := f.CheckDestructiveOperation(fmt.Sprintf("hard delete %s records", request.Object)); err != nil {
+			return Bulk2IngestJobInfo{}, err
+		}
+	}
 	url := f.bulk2IngestUrl()
 	body, err := json.Marshal(request)
 	if err != nil {
@@ -367,7 +372,7 @@ func (f *Force) GetBulk2IngestJobs() ([]Bulk2IngestJobInfo, error) {
 
 	jobs := result.Records
 	for !result.Done && result.NextRecordsUrl != "" {
-		nextUrl := fmt.Sprintf("%s%s", f.Credentials.InstanceUrl, result.NextRecordsUrl)
+		nextUrl := fmt.Sprintf("%s%s", f.InstanceUrl(), result.NextRecordsUrl)
 		req := NewRequest("GET").
 			AbsoluteUrl(nextUrl).
 			WithContent(ContentTypeJson).
@@ -692,7 +697,7 @@ func (f *Force) GetBulk2QueryJobs() ([]Bulk2QueryJobInfo, error) {
 
 	jobs := result.Records
 	for !result.Done && result.NextRecordsUrl != "" {
-		nextUrl := fmt.Sprintf("%s%s", f.Credentials.InstanceUrl, result.NextRecordsUrl)
+		nextUrl := fmt.Sprintf("%s%s", f.InstanceUrl(), result.NextRecordsUrl)
 		req := NewRequest("GET").
 			AbsoluteUrl(nextUrl).
 			WithContent(ContentTypeJson).
@@ -0,0 +1,468 @@
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SObjectDescribe is the subset of an sobject describe result used to
+// document an object.
+type SObjectDescribe struct {
+	Name               string                 `json:"name"`
+	Label              string                 `json:"label"`
+	LabelPlural        string                 `json:"labelPlural"`
+	KeyPrefix          string                 `json:"keyPrefix"`
+	Custom             bool                   `json:"custom"`
+	Fields             []SObjectFieldDescribe `json:"fields"`
+	ChildRelationships []ChildRelationship    `json:"childRelationships"`
+	RecordTypeInfos    []RecordTypeInfo       `json:"recordTypeInfos"`
+}
+
+type SObjectFieldDescribe struct {
+	Name              string          `json:"name"`
+	Label             string          `json:"label"`
+	Type              string          `json:"type"`
+	Length            int             `json:"length"`
+	Precision         int             `json:"precision"`
+	Scale             int             `json:"scale"`
+	Custom            bool            `json:"custom"`
+	Nillable          bool            `json:"nillable"`
+	Unique            bool            `json:"unique"`
+	ExternalId        bool            `json:"externalId"`
+	Calculated        bool            `json:"calculated"`
+	CascadeDelete     bool            `json:"cascadeDelete"`
+	InlineHelpText    string          `json:"inlineHelpText"`
+	ReferenceTo       []string        `json:"referenceTo"`
+	RelationshipName  string          `json:"relationshipName"`
+	RelationshipOrder *int            `json:"relationshipOrder"`
+	PicklistValues    []PicklistEntry `json:"picklistValues"`
+}
+
+type PicklistEntry struct {
+	Value        string `json:"value"`
+	Label        string `json:"label"`
+	Active       bool   `json:"active"`
+	DefaultValue bool   `json:"defaultValue"`
+}
+
+type ChildRelationship struct {
+	ChildSObject     string `json:"childSObject"`
+	Field            string `json:"field"`
+	RelationshipName string `json:"relationshipName"`
+	CascadeDelete    bool   `json:"cascadeDelete"`
+}
+
+type RecordTypeInfo struct {
+	Name                     string `json:"name"`
+	DeveloperName            string `json:"developerName"`
+	RecordTypeId             string `json:"recordTypeId"`
+	Active                   bool   `json:"active"`
+	Available                bool   `json:"available"`
+	DefaultRecordTypeMapping bool   `json:"defaultRecordTypeMapping"`
+	Master                   bool   `json:"master"`
+}
+
+func ParseSObjectDescribe(body string) (describe SObjectDescribe, err error) {
+	err = json.Unmarshal([]byte(body), &describe)
+	return
+}
+
+// IsMasterDetail returns true if the field is the detail side of a
+// master-detail relationship.
+func (f SObjectFieldDescribe) IsMasterDetail() bool {
+	return f.Type == "reference" && f.RelationshipOrder != nil
+}
+
+// TypeDescription returns a readable description of the field's type,
+// including length, precision, and reference targets where relevant.
+func (f SObjectFieldDescribe) TypeDescription() string {
+	switch f.Type {
+	case "string", "textarea", "encryptedstring", "url", "email", "phone":
+		if f.Length > 0 {
+			return fmt.Sprintf("%s(%d)", f.Type, f.Length)
+		}
+	case "double", "currency", "percent", "int":
+		if f.Precision > 0 {
+			return fmt.Sprintf("%s(%d, %d)", f.Type, f.Precision, f.Scale)
+		}
+	case "reference":
+		kind := "Lookup"
+		if f.IsMasterDetail() {
+			kind = "Master-Detail"
+		}
+		return fmt.Sprintf("%s(%s)", kind, strings.Join(f.ReferenceTo, ", "))
+	}
+	return f.Type
+}
+
+type DiagramFormat string
+
+const (
+	DiagramMermaid DiagramFormat = "mermaid"
+	DiagramDot     DiagramFormat = "dot"
+)
+
+// DataDictionary renders documentation for a set of sobject describes.
+type DataDictionary struct {
+	Objects []SObjectDescribe
+	Diagram DiagramFormat
+}
+
+func NewDataDictionary(objects []SObjectDescribe, diagram DiagramFormat) DataDictionary {
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].Name < objects[j].Name
+	})
+	return DataDictionary{Objects: objects, Diagram: diagram}
+}
+
+type relationshipEdge struct {
+	from         string
+	to           string
+	field        string
+	masterDetail bool
+}
+
+// edges returns the relationships between documented objects.  References to
+// objects outside the dictionary are left out of the diagram.
+func (d DataDictionary) edges() []relationshipEdge {
+	included := make(map[string]bool)
+	for _, o := range d.Objects {
+		included[o.Name] = true
+	}
+	var edges []relationshipEdge
+	for _, o := range d.Objects {
+		for _, f := range o.Fields {
+			if f.Type != "reference" {
+				continue
+			}
+			for _, target := range f.ReferenceTo {
+				if !included[target] {
+					continue
+				}
+				edges = append(edges, relationshipEdge{
+					from:         o.Name,
+					to:           target,
+					field:        f.Name,
+					masterDetail: f.IsMasterDetail(),
+				})
+			}
+		}
+	}
+	return edges
+}
+
+// MermaidDiagram returns an entity-relationship diagram in Mermaid syntax.
+func (d DataDictionary) MermaidDiagram() string {
+	var sb strings.Builder
+	sb.WriteString("erDiagram\n")
+	for _, o := range d.Objects {
+		fmt.Fprintf(&sb, "    %s {\n", o.Name)
+		for _, f := range o.Fields {
+			switch {
+			case f.Name == "Id":
+				fmt.Fprintf(&sb, "        %s %s PK\n", f.Type, f.Name)
+			case f.Type == "reference":
+				fmt.Fprintf(&sb, "        %s %s FK\n", f.Type, f.Name)
+			}
+		}
+		sb.WriteString("    }\n")
+	}
+	for _, e := range d.edges() {
+		cardinality := "}o--o|"
+		if e.masterDetail {
+			cardinality = "}o--||"
+		}
+		fmt.Fprintf(&sb, "    %s %s %s : %q\n", e.from, cardinality, e.to, e.field)
+	}
+	return sb.String()
+}
+
+// DotDiagram returns an entity-relationship diagram in Graphviz DOT syntax.
+func (d DataDictionary) DotDiagram() string {
+	var sb strings.Builder
+	sb.WriteString("digraph DataDictionary {\n")
+	sb.WriteString("    rankdir=LR;\n")
+	sb.WriteString("    node [shape=box];\n")
+	for _, o := range d.Objects {
+		fmt.Fprintf(&sb, "    %q [label=%q];\n", o.Name, fmt.Sprintf("%s\n(%s)", o.Label, o.Name))
+	}
+	for _, e := range d.edges() {
+		style := "dashed"
+		if e.masterDetail {
+			style = "solid"
+		}
+		fmt.Fprintf(&sb, "    %q -> %q [label=%q, style=%s];\n", e.from, e.to, e.field, style)
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.ReplaceAll(s, "\n", "<br>")
+}
+
+func picklistValueNames(values []PicklistEntry) []string {
+	var names []string
+	for _, v := range values {
+		if v.Active {
+			names = append(names, v.Value)
+		}
+	}
+	return names
+}
+
+// ObjectMarkdown returns the Markdown documentation page for a single object.
+func (d DataDictionary) ObjectMarkdown(o SObjectDescribe) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# %s (%s)\n\n", o.Label, o.Name)
+	fmt.Fprintf(&sb, "[Back to index](index.md)\n\n")
+	if o.KeyPrefix != "" {
+		fmt.Fprintf(&sb, "Key prefix: `%s`\n\n", o.KeyPrefix)
+	}
+
+	sb.WriteString("## Fields\n\n")
+	sb.WriteString("| Name | Label | Type | Required | Help Text | Picklist Values |\n")
+	sb.WriteString("| --- | --- | --- | --- | --- | --- |\n")
+	for _, f := range o.Fields {
+		required := ""
+		if !f.Nillable && !f.Calculated && f.Type != "boolean" {
+			required = "Yes"
+		}
+		fmt.Fprintf(&sb, "| %s | %s | %s | %s | %s | %s |\n",
+			markdownCell(f.Name),
+			markdownCell(f.Label),
+			markdownCell(f.TypeDescription()),
+			required,
+			markdownCell(f.InlineHelpText),
+			markdownCell(strings.Join(picklistValueNames(f.PicklistValues), ", ")))
+	}
+
+	var references []SObjectFieldDescribe
+	for _, f := range o.Fields {
+		if f.Type == "reference" {
+			references = append(references, f)
+		}
+	}
+	if len(references) > 0 || len(o.ChildRelationships) > 0 {
+		sb.WriteString("\n## Relationships\n\n")
+		sb.WriteString("| Field | Relationship Name | Related Object | Direction |\n")
+		sb.WriteString("| --- | --- | --- | --- |\n")
+		for _, f := range references {
+			fmt.Fprintf(&sb, "| %s | %s | %s | Parent |\n",
+				f.Name, f.RelationshipName, d.objectLinks(f.ReferenceTo, ".md"))
+		}
+		for _, c := range o.ChildRelationships {
+			if c.RelationshipName == "" {
+				continue
+			}
+			fmt.Fprintf(&sb, "| %s | %s | %s | Child |\n",
+				c.Field, c.RelationshipName, d.objectLinks([]string{c.ChildSObject}, ".md"))
+		}
+	}
+
+	if len(o.RecordTypeInfos) > 0 {
+		sb.WriteString("\n## Record Types\n\n")
+		sb.WriteString("| Name | Developer Name | Id | Active | Default |\n")
+		sb.WriteString("| --- | --- | --- | --- | --- |\n")
+		for _, r := range o.RecordTypeInfos {
+			fmt.Fprintf(&sb, "| %s | %s | %s | %t | %t |\n",
+				markdownCell(r.Name), r.DeveloperName, r.RecordTypeId, r.Active, r.DefaultRecordTypeMapping)
+		}
+	}
+	return sb.String()
+}
+
+func (d DataDictionary) includes(name string) bool {
+	for _, o := range d.Objects {
+		if o.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (d DataDictionary) objectLinks(names []string, ext string) string {
+	var links []string
+	for _, name := range names {
+		if !d.includes(name) {
+			links = append(links, name)
+			continue
+		}
+		if ext == ".html" {
+			links = append(links, fmt.Sprintf(`<a href="%s.html">%s</a>`, name, name))
+		} else {
+			links = append(links, fmt.Sprintf("[%s](%s.md)", name, name))
+		}
+	}
+	return strings.Join(links, ", ")
+}
+
+// IndexMarkdown returns the Markdown index page listing all objects, along
+// with the entity-relationship diagram.
+func (d DataDictionary) IndexMarkdown() string {
+	var sb strings.Builder
+	sb.WriteString("# Data Dictionary\n\n")
+	sb.WriteString("| Object | Label | Fields |\n")
+	sb.WriteString("| --- | --- | --- |\n")
+	for _, o := range d.Objects {
+		fmt.Fprintf(&sb, "| [%s](%s.md) | %s | %d |\n", o.Name, o.Name, markdownCell(o.Label), len(o.Fields))
+	}
+	sb.WriteString("\n## Entity Relationship Diagram\n\n")
+	switch d.Diagram {
+	case DiagramDot:
+		sb.WriteString("See [erd.dot](erd.dot).\n")
+	default:
+		sb.WriteString("```mermaid\n")
+		sb.WriteString(d.MermaidDiagram())
+		sb.WriteString("```\n")
+	}
+	return sb.String()
+}
+
+const htmlHeader = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>%s</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+</style>
+</head>
+<body>
+`
+
+const htmlFooter = `</body>
+</html>
+`
+
+func htmlCell(s string) string {
+	return strings.ReplaceAll(html.EscapeString(s), "\n", "<br>")
+}
+
+// ObjectHTML returns the HTML documentation page for a single object.
+func (d DataDictionary) ObjectHTML(o SObjectDescribe) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, htmlHeader, html.EscapeString(o.Name))
+	fmt.Fprintf(&sb, "<h1>%s (%s)</h1>\n", htmlCell(o.Label), htmlCell(o.Name))
+	sb.WriteString(`<p><a href="index.html">Back to index</a></p>` + "\n")
+	if o.KeyPrefix != "" {
+		fmt.Fprintf(&sb, "<p>Key prefix: <code>%s</code></p>\n", htmlCell(o.KeyPrefix))
+	}
+
+	sb.WriteString("<h2>Fields</h2>\n<table>\n")
+	sb.WriteString("<tr><th>Name</th><th>Label</th><th>Type</th><th>Required</th><th>Help Text</th><th>Picklist Values</th></tr>\n")
+	for _, f := range o.Fields {
+		required := ""
+		if !f.Nillable && !f.Calculated && f.Type != "boolean" {
+			required = "Yes"
+		}
+		fmt.Fprintf(&sb, "<tr><td>%s</td><td>%s</td><td>%s</td><td>%s</td><td>%s</td><td>%s</td></tr>\n",
+			htmlCell(f.Name),
+			htmlCell(f.Label),
+			htmlCell(f.TypeDescription()),
+			required,
+			htmlCell(f.InlineHelpText),
+			htmlCell(strings.Join(picklistValueNames(f.PicklistValues), ", ")))
+	}
+	sb.WriteString("</table>\n")
+
+	hasRelationships := len(o.ChildRelationships) > 0
+	for _, f := range o.Fields {
+		if f.Type == "reference" {
+			hasRelationships = true
+		}
+	}
+	if hasRelationships {
+		sb.WriteString("<h2>Relationships</h2>\n<table>\n")
+		sb.WriteString("<tr><th>Field</th><th>Relationship Name</th><th>Related Object</th><th>Direction</th></tr>\n")
+		for _, f := range o.Fields {
+			if f.Type != "reference" {
+				continue
+			}
+			fmt.Fprintf(&sb, "<tr><td>%s</td><td>%s</td><td>%s</td><td>Parent</td></tr>\n",
+				htmlCell(f.Name), htmlCell(f.RelationshipName), d.objectLinks(f.ReferenceTo, ".html"))
+		}
+		for _, c := range o.ChildRelationships {
+			if c.RelationshipName == "" {
+				continue
+			}
+			fmt.Fprintf(&sb, "<tr><td>%s</td><td>%s</td><td>%s</td><td>Child</td></tr>\n",
+				htmlCell(c.Field), htmlCell(c.RelationshipName), d.objectLinks([]string{c.ChildSObject}, ".html"))
+		}
+		sb.WriteString("</table>\n")
+	}
+
+	if len(o.RecordTypeInfos) > 0 {
+		sb.WriteString("<h2>Record Types</h2>\n<table>\n")
+		sb.WriteString("<tr><th>Name</th><th>Developer Name</th><th>Id</th><th>Active</th><th>Default</th></tr>\n")
+		for _, r := range o.RecordTypeInfos {
+			fmt.Fprintf(&sb, "<tr><td>%s</td><td>%s</td><td>%s</td><td>%t</td><td>%t</td></tr>\n",
+				htmlCell(r.Name), htmlCell(r.DeveloperName), htmlCell(r.RecordTypeId), r.Active, r.DefaultRecordTypeMapping)
+		}
+		sb.WriteString("</table>\n")
+	}
+	sb.WriteString(htmlFooter)
+	return sb.String()
+}
+
+// IndexHTML returns the HTML index page listing all objects, along with the
+// entity-relationship diagram.
+func (d DataDictionary) IndexHTML() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, htmlHeader, "Data Dictionary")
+	sb.WriteString("<h1>Data Dictionary</h1>\n<table>\n")
+	sb.WriteString("<tr><th>Object</th><th>Label</th><th>Fields</th></tr>\n")
+	for _, o := range d.Objects {
+		fmt.Fprintf(&sb, `<tr><td><a href="%s.html">%s</a></td><td>%s</td><td>%d</td></tr>`+"\n",
+			html.EscapeString(o.Name), htmlCell(o.Name), htmlCell(o.Label), len(o.Fields))
+	}
+	sb.WriteString("</table>\n<h2>Entity Relationship Diagram</h2>\n")
+	switch d.Diagram {
+	case DiagramDot:
+		sb.WriteString(`<p>See <a href="erd.dot">erd.dot</a>.</p>` + "\n")
+	default:
+		fmt.Fprintf(&sb, "<pre class=\"mermaid\">\n%s</pre>\n", html.EscapeString(d.MermaidDiagram()))
+		sb.WriteString(`<script type="module">import mermaid from "https://cdn.jsdelivr.net/npm/mermaid@10/dist/mermaid.esm.min.mjs"; mermaid.initialize({ startOnLoad: true });</script>` + "\n")
+	}
+	sb.WriteString(htmlFooter)
+	return sb.String()
+}
+
+// Write writes the data dictionary to dir in the given format, either
+// "markdown" or "html".
+func (d DataDictionary) Write(dir string, format string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	var ext string
+	var index func() string
+	var page func(SObjectDescribe) string
+	switch format {
+	case "markdown", "md":
+		ext, index, page = ".md", d.IndexMarkdown, d.ObjectMarkdown
+	case "html":
+		ext, index, page = ".html", d.IndexHTML, d.ObjectHTML
+	default:
+		return fmt.Errorf("unsupported format: %s", format)
+	}
+	for _, o := range d.Objects {
+		if err := os.WriteFile(filepath.Join(dir, o.Name+ext), []byte(page(o)), 0644); err != nil {
+			return err
+		}
+	}
+	if d.Diagram == DiagramDot {
+		if err := os.WriteFile(filepath.Join(dir, "erd.dot"), []byte(d.DotDiagram()), 0644); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(filepath.Join(dir, "index"+ext), []byte(index()), 0644)
+}
@@ -0,0 +1,103 @@
+package lib
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const invoiceDescribe = `{
+	"name": "Invoice__c",
+	"label": "Invoice",
+	"custom": true,
+	"keyPrefix": "a01",
+	"fields": [
+		{"name": "Id", "label": "Record ID", "type": "id", "nillable": false},
+		{"name": "Account__c", "label": "Account", "type": "reference", "referenceTo": ["Account"], "relationshipName": "Account__r", "relationshipOrder": 0, "nillable": false},
+		{"name": "Owner__c", "label": "Owner", "type": "reference", "referenceTo": ["User"], "relationshipName": "Owner__r", "nillable": true},
+		{"name": "Status__c", "label": "Status", "type": "picklist", "nillable": true, "inlineHelpText": "Current | state",
+			"picklistValues": [{"value": "Open", "active": true}, {"value": "Old", "active": false}, {"value": "Paid", "active": true}]}
+	],
+	"childRelationships": [
+		{"childSObject": "Invoice_Line__c", "field": "Invoice__c", "relationshipName": "Lines__r", "cascadeDelete": true}
+	],
+	"recordTypeInfos": [
+		{"name": "Master", "developerName": "Master", "recordTypeId": "012000000000000AAA", "active": true, "defaultRecordTypeMapping": true, "master": true}
+	]
+}`
+
+const accountDescribe = `{
+	"name": "Account",
+	"label": "Account",
+	"fields": [{"name": "Id", "label": "Account ID", "type": "id"}]
+}`
+
+func testDataDictionary(t *testing.T, diagram DiagramFormat) DataDictionary {
+	var objects []SObjectDescribe
+	for _, body := range []string{invoiceDescribe, accountDescribe} {
+		o, err := ParseSObjectDescribe(body)
+		if err != nil {
+			t.Fatalf("failed to parse describe: %v", err)
+		}
+		objects = append(objects, o)
+	}
+	return NewDataDictionary(objects, diagram)
+}
+
+func TestDataDictionary_MermaidDiagram_includes_relationships_between_documented_objects(t *testing.T) {
+	d := testDataDictionary(t, DiagramMermaid)
+	diagram := d.MermaidDiagram()
+
+	if !strings.Contains(diagram, `Invoice__c }o--|| Account : "Account__c"`) {
+		t.Errorf("expected master-detail relationship to Account, got:\n%s", diagram)
+	}
+	if strings.Contains(diagram, "User") {
+		t.Errorf("expected relationship to undocumented User object to be omitted, got:\n%s", diagram)
+	}
+}
+
+func TestDataDictionary_DotDiagram(t *testing.T) {
+	d := testDataDictionary(t, DiagramDot)
+	diagram := d.DotDiagram()
+
+	if !strings.Contains(diagram, `"Invoice__c" -> "Account" [label="Account__c", style=solid];`) {
+		t.Errorf("expected edge to Account, got:\n%s", diagram)
+	}
+}
+
+func TestDataDictionary_ObjectMarkdown(t *testing.T) {
+	d := testDataDictionary(t, DiagramMermaid)
+	page := d.ObjectMarkdown(d.Objects[1])
+
+	expected := []string{
+		"# Invoice (Invoice__c)",
+		"| Status__c | Status | picklist |  | Current \\| state | Open, Paid |",
+		"| Account__c | Account | Master-Detail(Account) | Yes |",
+		"| Account__c | Account__r | [Account](Account.md) | Parent |",
+		"| Owner__c | Owner__r | User | Parent |",
+		"| Invoice__c | Lines__r | Invoice_Line__c | Child |",
+		"| Master | Master | 012000000000000AAA | true | true |",
+	}
+	for _, e := range expected {
+		if !strings.Contains(page, e) {
+			t.Errorf("expected page to contain %q, got:\n%s", e, page)
+		}
+	}
+}
+
+func TestDataDictionary_Write(t *testing.T) {
+	dir := t.TempDir()
+	d := testDataDictionary(t, DiagramDot)
+	if err := d.Write(dir, "html"); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	for _, name := range []string{"index.html", "Account.html", "Invoice__c.html", "erd.dot"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("expected %s to be written: %v", name, err)
+		}
+	}
+	if err := d.Write(dir, "pdf"); err == nil {
+		t.Errorf("expected error for unsupported format")
+	}
+}
@@ -0,0 +1,333 @@
+package lib
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	. "github.com/ForceCLI/force/config"
+)
+
+// DeployRecord is the local record of a deploy: who started it, against
+// which org, from which git commit, what it deployed, and how it went.
+type DeployRecord struct {
+	Id                string             `json:"id"`
+	InstanceUrl       string             `json:"instanceUrl"`
+	OrgId             string             `json:"orgId,omitempty"`
+	Username          string             `json:"username"`
+	Options           ForceDeployOptions `json:"options"`
+	GitCommit         string             `json:"gitCommit,omitempty"`
+	GitBranch         string             `json:"gitBranch,omitempty"`
+	GitDirty          bool               `json:"gitDirty,omitempty"`
+	Components        []string           `json:"components"`
+	DeletedComponents []string           `json:"deletedComponents,omitempty"`
+	StartTime         time.Time          `json:"startTime"`
+	DurationSeconds   float64            `json:"durationSeconds,omitempty"`
+	Status            string             `json:"status"`
+	Success           bool               `json:"success"`
+	ComponentErrors   int                `json:"componentErrors,omitempty"`
+	TestErrors        int                `json:"testErrors,omitempty"`
+	ErrorMessage      string             `json:"errorMessage,omitempty"`
+}
+
+// DeployHistoryFilter selects deploy records.  Empty fields match every
+// record.
+type DeployHistoryFilter struct {
+	// Username and Org match case-insensitively; Org matches the org id
+	// or any part of the instance url.
+	Username string
+	Org      string
+	Branch   string
+	// Commit matches a prefix of the git commit.
+	Commit string
+	Status string
+	// Component matches any part of a deployed or deleted component's
+	// Type/Name, case-insensitively.
+	Component string
+	Since     time.Time
+	Limit     int
+}
+
+const deployHistoryDir = "deployhistory"
+
+// DeployStatusUnknown is the status of a recorded deploy whose status
+// couldn't be checked.
+const DeployStatusUnknown = "Unknown"
+
+// NewDeployRecord starts the record of a deploy of files, started at start,
+// with the git state of the current directory.
+func (f *Force) NewDeployRecord(deployId string, files ForceMetadataFiles, options ForceDeployOptions, start time.Time) (DeployRecord, error) {
+	r := DeployRecord{
+		Id:          deployId,
+		InstanceUrl: f.InstanceUrl(),
+		Options:     options,
+		StartTime:   start,
+		Status:      "InProgress",
+	}
+	if f.Credentials.UserInfo != nil {
+		r.Username = f.Credentials.UserInfo.UserName
+		r.OrgId = f.Credentials.UserInfo.OrgId
+	}
+	r.GitCommit, r.GitBranch, r.GitDirty = gitState()
+	deployed, deleted, err := deployManifests(files)
+	if err != nil {
+		return r, err
+	}
+	r.Components = componentNames(deployed)
+	r.DeletedComponents = componentNames(deleted)
+	return r, nil
+}
+
+func componentNames(members map[string][]string) []string {
+	names := []string{}
+	for t, typeMembers := range members {
+		for _, name := range typeMembers {
+			names = append(names, t+"/"+name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// gitState returns the commit and branch checked out in the current
+// directory, and whether there are uncommitted changes.  They're empty if
+// it's not a git repository.
+func gitState() (commit string, branch string, dirty bool) {
+	git := func(args ...string) string {
+		out, err := exec.Command("git", args...).Output()
+		if err != nil {
+			return ""
+		}
+		return strings.TrimSpace(string(out))
+	}
+	commit = git("rev-parse", "HEAD")
+	if commit == "" {
+		return "", "", false
+	}
+	branch = git("rev-parse", "--abbrev-ref", "HEAD")
+	dirty = git("status", "--porcelain") != ""
+	return
+}
+
+// Complete records the outcome of the deploy, which finished at end.
+func (r *DeployRecord) Complete(result ForceCheckDeploymentStatusResult, end time.Time) {
+	r.DurationSeconds = end.Sub(r.StartTime).Round(time.Millisecond).Seconds()
+	r.Status = result.Status
+	r.Success = result.Success
+	r.ComponentErrors = len(result.Details.ComponentFailures)
+	r.TestErrors = len(result.Details.RunTestResult.TestFailures)
+	r.ErrorMessage = result.ErrorMessage
+}
+
+// recordDeployStart records a deploy of zipfile, started at start, in the
+// deploy history.  Failing to record the deploy doesn't stop it.
+func (fm *ForceMetadata) recordDeployStart(deployId string, zipfile []byte, options ForceDeployOptions, start time.Time) {
+	files, err := zipMetadataFiles(zipfile)
+	if err == nil {
+		var record DeployRecord
+		record, err = fm.Force.NewDeployRecord(deployId, files, options, start)
+		if err == nil {
+			err = record.Save()
+		}
+	}
+	if err != nil {
+		Log.Info(fmt.Sprintf("Could not record deploy %s: %s", deployId, err.Error()))
+	}
+}
+
+// recordDeployStatus records the outcome of a recorded deploy once it's
+// done, or that its status couldn't be checked.
+func recordDeployStatus(deployId string, result ForceCheckDeploymentStatusResult, statusErr error) {
+	record, err := LoadDeployRecord(deployId)
+	if err != nil {
+		return
+	}
+	switch {
+	case statusErr != nil && record.Status == "InProgress":
+		record.Status = DeployStatusUnknown
+		record.ErrorMessage = "Could not get deploy status: " + statusErr.Error()
+	case statusErr == nil && result.Done && record.Status != result.Status:
+		end := result.CompletedDate
+		if end.IsZero() {
+			end = time.Now()
+		}
+		record.Complete(result, end)
+	default:
+		return
+	}
+	if err := record.Save(); err != nil {
+		Log.Info(fmt.Sprintf("Could not record deploy %s: %s", deployId, err.Error()))
+	}
+}
+
+// zipMetadataFiles reads the files in a deploy zip file, keyed by their path
+// relative to its package.xml.
+func zipMetadataFiles(zipfile []byte) (ForceMetadataFiles, error) {
+	r, err := zip.NewReader(bytes.NewReader(zipfile), int64(len(zipfile)))
+	if err != nil {
+		return nil, err
+	}
+	root := ""
+	for _, f := range r.File {
+		if path.Base(f.Name) == "package.xml" {
+			root = path.Dir(f.Name)
+			break
+		}
+	}
+	files := make(ForceMetadataFiles)
+	for _, f := range r.File {
+		name, err := filepath.Rel(root, f.Name)
+		if err != nil || strings.HasPrefix(name, "..") || f.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		files[filepath.ToSlash(name)] = data
+	}
+	return files, nil
+}
+
+// LoadDeployRecord loads the record of a deploy from the deploy history.
+func LoadDeployRecord(deployId string) (DeployRecord, error) {
+	var r DeployRecord
+	data, err := Config.Load(deployHistoryDir, deployId)
+	if err != nil {
+		return r, err
+	}
+	if err := json.Unmarshal([]byte(data), &r); err != nil {
+		return r, fmt.Errorf("Could not parse deploy record %s: %w", deployId, err)
+	}
+	return r, nil
+}
+
+// Save saves the record in the deploy history, replacing any earlier record
+// of the same deploy.
+func (r DeployRecord) Save() error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return Config.Save(deployHistoryDir, r.Id, string(data))
+}
+
+// LoadDeployHistory loads the deploy history, most recent first.
+func LoadDeployHistory() ([]DeployRecord, error) {
+	ids, err := Config.List(deployHistoryDir)
+	if err != nil {
+		return nil, err
+	}
+	records := []DeployRecord{}
+	for _, id := range ids {
+		data, err := Config.Load(deployHistoryDir, id)
+		if err != nil {
+			return nil, err
+		}
+		var r DeployRecord
+		if err := json.Unmarshal([]byte(data), &r); err != nil {
+			return nil, fmt.Errorf("Could not parse deploy record %s: %w", id, err)
+		}
+		records = append(records, r)
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].StartTime.After(records[j].StartTime)
+	})
+	return records, nil
+}
+
+// FilterDeployHistory returns the records that match the filter, up to its
+// limit.
+func FilterDeployHistory(records []DeployRecord, filter DeployHistoryFilter) []DeployRecord {
+	matched := []DeployRecord{}
+	for _, r := range records {
+		if filter.Limit > 0 && len(matched) >= filter.Limit {
+			break
+		}
+		if filter.matches(r) {
+			matched = append(matched, r)
+		}
+	}
+	return matched
+}
+
+func (filter DeployHistoryFilter) matches(r DeployRecord) bool {
+	if filter.Username != "" && !strings.EqualFold(filter.Username, r.Username) {
+		return false
+	}
+	if filter.Org != "" && !sameId(filter.Org, r.OrgId) && !strings.Contains(strings.ToLower(r.InstanceUrl), strings.ToLower(filter.Org)) {
+		return false
+	}
+	if filter.Branch != "" && filter.Branch != r.GitBranch {
+		return false
+	}
+	if filter.Commit != "" && (r.GitCommit == "" || !strings.HasPrefix(r.GitCommit, strings.ToLower(filter.Commit))) {
+		return false
+	}
+	if filter.Status != "" && !strings.EqualFold(filter.Status, r.Status) {
+		return false
+	}
+	if !filter.Since.IsZero() && r.StartTime.Before(filter.Since) {
+		return false
+	}
+	if filter.Component != "" {
+		component := strings.ToLower(filter.Component)
+		found := false
+		for _, c := range append(append([]string{}, r.Components...), r.DeletedComponents...) {
+			if strings.Contains(strings.ToLower(c), component) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// AttachDeployRecord saves the record in the org as a record of sobject, a
+// custom object or platform event, so the deploy history is shared.  The
+// sobject needs the text fields DeployId__c, Username__c, GitCommit__c,
+// GitBranch__c, Status__c, and the long text fields Components__c and
+// Options__c.  DurationSeconds__c is a number.
+func (f *Force) AttachDeployRecord(sobject string, r DeployRecord) error {
+	options, err := json.Marshal(r.Options)
+	if err != nil {
+		return err
+	}
+	components := append([]string{}, r.Components...)
+	for _, c := range r.DeletedComponents {
+		components = append(components, "-"+c)
+	}
+	_, err, messages := f.CreateRecord(sobject, map[string]string{
+		"DeployId__c":        r.Id,
+		"Username__c":        r.Username,
+		"GitCommit__c":       r.GitCommit,
+		"GitBranch__c":       r.GitBranch,
+		"Status__c":          r.Status,
+		"Components__c":      strings.Join(components, "\n"),
+		"Options__c":         string(options),
+		"DurationSeconds__c": fmt.Sprintf("%.3f", r.DurationSeconds),
+	})
+	if err != nil {
+		if len(messages) > 0 {
+			return fmt.Errorf("Could not create %s: %s", sobject, messages[0].Message)
+		}
+		return fmt.Errorf("Could not create %s: %w", sobject, err)
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package lib
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDeployRecordSaveAndLoad(t *testing.T) {
+	cleanup := setupTestConfig(t)
+	defer cleanup()
+
+	force := &Force{Credentials: &ForceSession{
+		InstanceUrl: "https://example.my.salesforce.com",
+		UserInfo:    &UserInfo{UserName: "user@example.com", OrgId: "00D000000000001AAA"},
+	}}
+	files := ForceMetadataFiles{
+		"package.xml":            []byte(`<Package><types><members>B</members><members>A</members><name>ApexClass</name></types></Package>`),
+		"destructiveChanges.xml": []byte(`<Package><types><members>Old</members><name>ApexClass</name></types></Package>`),
+	}
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	record, err := force.NewDeployRecord("0Af000000000001AAA", files, ForceDeployOptions{CheckOnly: true}, start)
+	if err != nil {
+		t.Fatalf("NewDeployRecord returned error: %v", err)
+	}
+	if expected := []string{"ApexClass/A", "ApexClass/B"}; !reflect.DeepEqual(record.Components, expected) {
+		t.Errorf("Expected components %v, got %v", expected, record.Components)
+	}
+	if expected := []string{"ApexClass/Old"}; !reflect.DeepEqual(record.DeletedComponents, expected) {
+		t.Errorf("Expected deleted components %v, got %v", expected, record.DeletedComponents)
+	}
+	if record.Username != "user@example.com" || record.OrgId != "00D000000000001AAA" || record.Status != "InProgress" {
+		t.Errorf("Unexpected record %+v", record)
+	}
+	if err := record.Save(); err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+
+	var result ForceCheckDeploymentStatusResult
+	result.Status = "Failed"
+	result.Details.ComponentFailures = []ComponentFailure{{FullName: "A"}}
+	record.Complete(result, start.Add(90*time.Second))
+	if err := record.Save(); err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+	older := DeployRecord{Id: "0Af000000000000AAA", StartTime: start.Add(-time.Hour), Status: "Succeeded"}
+	if err := older.Save(); err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+
+	records, err := LoadDeployHistory()
+	if err != nil {
+		t.Fatalf("LoadDeployHistory returned error: %v", err)
+	}
+	if len(records) != 2 || records[0].Id != record.Id || records[1].Id != older.Id {
+		t.Fatalf("Expected the two deploys, most recent first, got %+v", records)
+	}
+	if r := records[0]; r.Status != "Failed" || r.DurationSeconds != 90 || r.ComponentErrors != 1 || !r.Options.CheckOnly {
+		t.Errorf("Expected the completed deploy, got %+v", r)
+	}
+}
+
+func TestLoadDeployHistoryEmpty(t *testing.T) {
+	cleanup := setupTestConfig(t)
+	defer cleanup()
+
+	records, err := LoadDeployHistory()
+	if err != nil || len(records) != 0 {
+		t.Errorf("Expected no deploys, got %v, %v", records, err)
+	}
+}
+
+func TestFilterDeployHistory(t *testing.T) {
+	now := time.Now()
+	records := []DeployRecord{
+		{Id: "1", Username: "a@example.com", InstanceUrl: "https://co--uat.my.salesforce.com", OrgId: "00D000000000001AAA", GitBranch: "main", GitCommit: "abc123", Status: "Succeeded", Components: []string{"ApexClass/Foo"}, StartTime: now},
+		{Id: "2", Username: "b@example.com", InstanceUrl: "https://co.my.salesforce.com", OrgId: "00D000000000002AAA", GitBranch: "feature", GitCommit: "def456", Status: "Failed", DeletedComponents: []string{"CustomObject/Bar__c"}, StartTime: now.Add(-48 * time.Hour)},
+		{Id: "3", Username: "a@example.com", InstanceUrl: "https://co.my.salesforce.com", Status: "Succeeded", StartTime: now.Add(-72 * time.Hour)},
+	}
+	cases := []struct {
+		filter   DeployHistoryFilter
+		expected []string
+	}{
+		{DeployHistoryFilter{}, []string{"1", "2", "3"}},
+		{DeployHistoryFilter{Limit: 2}, []string{"1", "2"}},
+		{DeployHistoryFilter{Username: "A@example.com"}, []string{"1", "3"}},
+		{DeployHistoryFilter{Org: "uat"}, []string{"1"}},
+		{DeployHistoryFilter{Org: "00D000000000002"}, []string{"2"}},
+		{DeployHistoryFilter{Branch: "feature"}, []string{"2"}},
+		{DeployHistoryFilter{Commit: "ABC"}, []string{"1"}},
+		{DeployHistoryFilter{Status: "failed"}, []string{"2"}},
+		{DeployHistoryFilter{Component: "bar__c"}, []string{"2"}},
+		{DeployHistoryFilter{Since: now.Add(-49 * time.Hour)}, []string{"1", "2"}},
+		{DeployHistoryFilter{Username: "a@example.com", Limit: 1}, []string{"1"}},
+	}
+	for _, c := range cases {
+		var ids []string
+		for _, r := range FilterDeployHistory(records, c.filter) {
+			ids = append(ids, r.Id)
+		}
+		if !reflect.DeepEqual(ids, c.expected) {
+			t.Errorf("%+v: expected %v, got %v", c.filter, c.expected, ids)
+		}
+	}
+}
+
+func TestAttachDeployRecord(t *testing.T) {
+	var path string
+	var attrs map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		json.NewDecoder(r.Body).Decode(&attrs)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id": "e00000000000001AAA", "success": true, "errors": []}`))
+	}))
+	defer server.Close()
+
+	force := &Force{Credentials: &ForceSession{InstanceUrl: server.URL, AccessToken: "test-token"}}
+	err := force.AttachDeployRecord("Deploy_Audit__e", DeployRecord{
+		Id:                "0Af000000000001AAA",
+		Username:          "user@example.com",
+		GitCommit:         "abc123",
+		Status:            "Succeeded",
+		Components:        []string{"ApexClass/A"},
+		DeletedComponents: []string{"ApexClass/Old"},
+		Options:           ForceDeployOptions{TestLevel: "RunLocalTests"},
+		DurationSeconds:   12.5,
+	})
+	if err != nil {
+		t.Fatalf("AttachDeployRecord returned error: %v", err)
+	}
+	if !strings.HasSuffix(path, "/sobjects/Deploy_Audit__e") {
+		t.Errorf("Expected a Deploy_Audit__e to be created, got %s", path)
+	}
+	if attrs["DeployId__c"] != "0Af000000000001AAA" || attrs["GitCommit__c"] != "abc123" || attrs["Components__c"] != "ApexClass/A\n-ApexClass/Old" || attrs["DurationSeconds__c"] != "12.500" {
+		t.Errorf("Unexpected fields %v", attrs)
+	}
+	if attrs["Options__c"] != `{"testLevel":"RunLocalTests"}` {
+		t.Errorf("Unexpected options %s", attrs["Options__c"])
+	}
+}
+
+func TestDeployIsRecorded(t *testing.T) {
+	cleanup := setupTestConfig(t)
+	defer cleanup()
+
+	status := `<done>false</done><status>InProgress</status>`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		switch {
+		case strings.Contains(string(body), "checkDeployStatus"):
+			if status == "" {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.Write([]byte(`<Envelope><Body><checkDeployStatusResponse><result><id>0Af000000000001AAA</id>` + status +
+				`</result></checkDeployStatusResponse></Body></Envelope>`))
+		default:
+			w.Write([]byte(`<Envelope><Body><deployResponse><result><id>0Af000000000001AAA</id></result></deployResponse></Body></Envelope>`))
+		}
+	}))
+	defer server.Close()
+
+	force := NewForce(&ForceSession{
+		InstanceUrl: server.URL,
+		AccessToken: "token",
+		UserInfo:    &UserInfo{UserName: "user@example.com"},
+	})
+	files := ForceMetadataFiles{"package.xml": []byte(`<Package><types><members>A</members><name>ApexClass</name></types></Package>`)}
+	deployId, err := force.Metadata.StartDeploy(files, ForceDeployOptions{})
+	if err != nil {
+		t.Fatalf("StartDeploy returned error: %v", err)
+	}
+	record, err := LoadDeployRecord(deployId)
+	if err != nil || record.Status != "InProgress" || !reflect.DeepEqual(record.Components, []string{"ApexClass/A"}) {
+		t.Fatalf("Expected deploy to be recorded as in progress, got %+v, %v", record, err)
+	}
+
+	status = ""
+	force.Metadata.CheckDeployStatus(deployId)
+	if record, _ = LoadDeployRecord(deployId); record.Status != DeployStatusUnknown {
+		t.Errorf("Expected deploy whose status couldn't be checked to be recorded as unknown, got %s", record.Status)
+	}
+
+	status = `<done>true</done><status>Succeeded</status><success>true</success>`
+	force.Metadata.CheckDeployStatus(deployId)
+	if record, _ = LoadDeployRecord(deployId); record.Status != "Succeeded" || !record.Success {
+		t.Errorf("Expected deploy to be recorded as succeeded, got %+v", record)
+	}
+}
@@ -0,0 +1,249 @@
+package lib
+
+import (
+	"encoding/xml"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// DeployPlanComponent is a component a deploy would create, overwrite or
+// delete.  Existing is the component as it is in the org, if it exists.
+type DeployPlanComponent struct {
+	Type     string
+	FullName string
+	Existing *MDFileProperties
+	// ModifiedByOther is set if the component was last modified by another
+	// user, whose change the deploy would overwrite or delete.
+	ModifiedByOther bool
+}
+
+// DeployPlan describes what a deploy would change, without deploying.
+type DeployPlan struct {
+	New         []DeployPlanComponent
+	Overwritten []DeployPlanComponent
+	Deleted     []DeployPlanComponent
+	TestLevel   string
+	Tests       []string
+	Warnings    []string
+}
+
+// PlanDeploy compares the components in a deploy's package.xml and
+// destructive changes to the components in the org, as reported by
+// listMetadata, and works out which tests would run.  Nothing is deployed.
+func (f *Force) PlanDeploy(files ForceMetadataFiles, options ForceDeployOptions) (plan DeployPlan, err error) {
+	deployed, deleted, err := deployManifests(files)
+	if err != nil {
+		return
+	}
+	existing, err := f.Metadata.orgComponents(deployed, deleted)
+	if err != nil {
+		return
+	}
+	userId := ""
+	if f.Credentials != nil && f.Credentials.UserInfo != nil {
+		userId = f.Credentials.UserInfo.UserId
+	}
+	component := func(t string, name string) DeployPlanComponent {
+		c := DeployPlanComponent{Type: t, FullName: name}
+		if p, ok := existing[componentKey(t, name)]; ok {
+			c.Existing = &p
+			c.ModifiedByOther = userId != "" && p.LastModifiedById != "" && !sameId(p.LastModifiedById, userId)
+		}
+		return c
+	}
+
+	for _, t := range sortedKeys(deployed) {
+		for _, name := range deployed[t] {
+			c := component(t, name)
+			if c.Existing == nil {
+				plan.New = append(plan.New, c)
+			} else {
+				plan.Overwritten = append(plan.Overwritten, c)
+			}
+		}
+	}
+	for _, t := range sortedKeys(deleted) {
+		for _, name := range deleted[t] {
+			c := component(t, name)
+			if c.Existing == nil {
+				plan.Warnings = append(plan.Warnings, fmt.Sprintf("%s %s is to be deleted but isn't in the org", t, name))
+			}
+			plan.Deleted = append(plan.Deleted, c)
+		}
+	}
+
+	err = f.planTests(&plan, files, options, deployed)
+	return
+}
+
+// planTests works out which tests would run under the deploy's test level.
+func (f *Force) planTests(plan *DeployPlan, files ForceMetadataFiles, options ForceDeployOptions, deployed map[string][]string) error {
+	production := false
+	if org, err := f.safetyOrg(); err == nil {
+		production = org.Production
+	}
+	_, hasClasses := deployed["ApexClass"]
+	_, hasTriggers := deployed["ApexTrigger"]
+	hasApex := hasClasses || hasTriggers
+
+	plan.TestLevel = options.TestLevel
+	if plan.TestLevel == "" {
+		plan.TestLevel = "NoTestRun"
+	}
+	if plan.TestLevel == "NoTestRun" && production && hasApex {
+		// Production deploys that include Apex run local tests by default.
+		if options.TestLevel != "" {
+			plan.Warnings = append(plan.Warnings, "NoTestRun can't be used when deploying Apex to a production org; local tests will run")
+		}
+		plan.TestLevel = "RunLocalTests"
+	}
+	switch plan.TestLevel {
+	case "RunSpecifiedTests":
+		for _, test := range options.RunTests {
+			if test != "" {
+				plan.Tests = append(plan.Tests, test)
+			}
+		}
+	case "RunLocalTests", "RunAllTestsInOrg":
+		tests, err := f.localTestClasses(files)
+		if err != nil {
+			return fmt.Errorf("Could not find test classes: %w", err)
+		}
+		plan.Tests = tests
+		if plan.TestLevel == "RunAllTestsInOrg" {
+			plan.Warnings = append(plan.Warnings, "Tests in installed managed packages will also run")
+		}
+	}
+	return nil
+}
+
+// localTestClasses returns the names of the test classes that aren't from
+// managed packages, in the org and in the files to be deployed.
+func (f *Force) localTestClasses(files ForceMetadataFiles) ([]string, error) {
+	result, err := f.Query("SELECT Name, Body FROM ApexClass WHERE NamespacePrefix = null")
+	if err != nil {
+		return nil, err
+	}
+	tests := make(map[string]bool)
+	for _, record := range result.Records {
+		name, _ := record["Name"].(string)
+		body, _ := record["Body"].(string)
+		if isTestClass(body) {
+			tests[name] = true
+		}
+	}
+	for path, data := range files {
+		path = filepath.ToSlash(path)
+		if !strings.HasPrefix(path, "classes/") || !strings.HasSuffix(path, ".cls") {
+			continue
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(path, "classes/"), ".cls")
+		if isTestClass(string(data)) {
+			tests[name] = true
+		} else {
+			// The deploy replaces the org's version of the class.
+			delete(tests, name)
+		}
+	}
+	return sortedKeys(tests), nil
+}
+
+func isTestClass(body string) bool {
+	return strings.Contains(strings.ToLower(body), "@istest")
+}
+
+// deployManifests returns the members of each type in a deploy's
+// package.xml, with wildcards expanded from the files being deployed, and in
+// its destructive changes.
+func deployManifests(files ForceMetadataFiles) (deployed map[string][]string, deleted map[string][]string, err error) {
+	deployed, err = packageMembers(files, "package.xml")
+	if err != nil {
+		return
+	}
+	for t, members := range deployed {
+		deployed[t] = expandWildcard(files, t, members)
+	}
+	deleted = make(map[string][]string)
+	for _, manifest := range []string{"destructiveChangesPre.xml", "destructiveChanges.xml", "destructiveChangesPost.xml"} {
+		members, err := packageMembers(files, manifest)
+		if err != nil {
+			return nil, nil, err
+		}
+		for t, names := range members {
+			deleted[t] = append(deleted[t], names...)
+		}
+	}
+	return
+}
+
+// orgComponents lists the components in the org of the types being deployed
+// or deleted, keyed by componentKey.
+func (fm *ForceMetadata) orgComponents(deployed map[string][]string, deleted map[string][]string) (map[string]MDFileProperties, error) {
+	typeSet := make(map[string]bool)
+	for t := range deployed {
+		typeSet[t] = true
+	}
+	for t := range deleted {
+		typeSet[t] = true
+	}
+	inventory, err := fm.ListMetadataInventory(sortedKeys(typeSet))
+	if err != nil {
+		return nil, fmt.Errorf("Could not list metadata in org: %w", err)
+	}
+	existing := make(map[string]MDFileProperties)
+	for _, p := range inventory {
+		existing[componentKey(p.Type, p.FullName)] = p
+	}
+	return existing, nil
+}
+
+// packageMembers returns the members of each type in a manifest, or nothing
+// if the manifest isn't in files.
+func packageMembers(files ForceMetadataFiles, manifest string) (map[string][]string, error) {
+	members := make(map[string][]string)
+	data, ok := files[manifest]
+	if !ok {
+		return members, nil
+	}
+	var p Package
+	if err := xml.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("Could not parse %s: %w", manifest, err)
+	}
+	for _, t := range p.Types {
+		members[t.Name] = append(members[t.Name], t.Members...)
+	}
+	return members, nil
+}
+
+// expandWildcard replaces a wildcard member with the components of the type
+// found in files.
+func expandWildcard(files ForceMetadataFiles, metadataType string, members []string) []string {
+	names := make(map[string]bool)
+	for _, m := range members {
+		if m != "*" {
+			names[m] = true
+			continue
+		}
+		for path := range files {
+			if t, name := componentForPath(filepath.ToSlash(path)); t == metadataType {
+				names[name] = true
+			}
+		}
+	}
+	return sortedKeys(names)
+}
+
+// componentKey identifies a component.  Metadata names aren't case
+// sensitive.
+func componentKey(metadataType string, name string) string {
+	return strings.ToLower(metadataType + "/" + name)
+}
+
+// sameId compares 15 or 18 character Salesforce ids.
+func sameId(a string, b string) bool {
+	if len(a) >= 15 && len(b) >= 15 {
+		return a[:15] == b[:15]
+	}
+	return a == b
+}
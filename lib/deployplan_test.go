@@ -0,0 +1,119 @@
+package lib
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const planPackageXml = `<?xml version="1.0" encoding="UTF-8"?>
+<Package xmlns="http://soap.sforce.com/2006/04/metadata">
+    <types>
+        <members>*</members>
+        <name>ApexClass</name>
+    </types>
+    <types>
+        <members>Account.Score__c</members>
+        <name>CustomField</name>
+    </types>
+    <version>62.0</version>
+</Package>`
+
+const planDestructiveXml = `<?xml version="1.0" encoding="UTF-8"?>
+<Package xmlns="http://soap.sforce.com/2006/04/metadata">
+    <types>
+        <members>Obsolete</members>
+        <members>Missing</members>
+        <name>ApexClass</name>
+    </types>
+</Package>`
+
+func TestPlanDeploy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "/query") {
+			w.Write([]byte(`{"totalSize": 2, "done": true, "records": [
+				{"Name": "ExistingTest", "Body": "@IsTest private class ExistingTest {}"},
+				{"Name": "Existing", "Body": "@isTest public class Existing {}"}
+			]}`))
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		var results string
+		if strings.Contains(string(body), "<type>ApexClass</type>") {
+			results += `<result><fullName>existing</fullName><fileName>classes/Existing.cls</fileName><type>ApexClass</type>` +
+				`<lastModifiedById>005000000000002AAA</lastModifiedById><lastModifiedByName>Bob</lastModifiedByName>` +
+				`<lastModifiedDate>2026-01-02T03:04:05.000Z</lastModifiedDate></result>` +
+				`<result><fullName>Obsolete</fullName><fileName>classes/Obsolete.cls</fileName><type>ApexClass</type>` +
+				`<lastModifiedById>005000000000001</lastModifiedById></result>`
+		}
+		w.Write([]byte(`<Envelope><Body><listMetadataResponse>` + results + `</listMetadataResponse></Body></Envelope>`))
+	}))
+	defer server.Close()
+
+	force := NewForce(&ForceSession{
+		InstanceUrl: server.URL,
+		AccessToken: "test-token",
+		UserInfo:    &UserInfo{UserId: "005000000000001AAA"},
+	})
+	force.org = &safetyOrg{Name: "Test", Production: false}
+	files := ForceMetadataFiles{
+		"package.xml":            []byte(planPackageXml),
+		"destructiveChanges.xml": []byte(planDestructiveXml),
+		"classes/Existing.cls":   []byte("public class Existing {}"),
+		"classes/NewTest.cls":    []byte("@isTest class NewTest {}"),
+		"objects/Account.object": []byte("<CustomObject/>"),
+	}
+	plan, err := force.PlanDeploy(files, ForceDeployOptions{TestLevel: "RunLocalTests"})
+	if err != nil {
+		t.Fatalf("PlanDeploy returned error: %v", err)
+	}
+	names := func(components []DeployPlanComponent) []string {
+		var result []string
+		for _, c := range components {
+			result = append(result, c.Type+" "+c.FullName)
+		}
+		return result
+	}
+	if expected := []string{"ApexClass NewTest", "CustomField Account.Score__c"}; !reflect.DeepEqual(names(plan.New), expected) {
+		t.Errorf("Expected new %v, got %v", expected, names(plan.New))
+	}
+	if expected := []string{"ApexClass Existing"}; !reflect.DeepEqual(names(plan.Overwritten), expected) {
+		t.Fatalf("Expected overwritten %v, got %v", expected, names(plan.Overwritten))
+	}
+	if c := plan.Overwritten[0]; !c.ModifiedByOther || c.Existing.LastModifiedByName != "Bob" {
+		t.Errorf("Expected Existing to be flagged as modified by Bob, got %+v", c)
+	}
+	if expected := []string{"ApexClass Obsolete", "ApexClass Missing"}; !reflect.DeepEqual(names(plan.Deleted), expected) {
+		t.Errorf("Expected deleted %v, got %v", expected, names(plan.Deleted))
+	}
+	if plan.Deleted[0].ModifiedByOther {
+		t.Errorf("Expected Obsolete, last modified by the current user, not to be flagged")
+	}
+	// Existing is no longer a test once deployed; NewTest is.
+	if expected := []string{"ExistingTest", "NewTest"}; !reflect.DeepEqual(plan.Tests, expected) {
+		t.Errorf("Expected tests %v, got %v", expected, plan.Tests)
+	}
+	if len(plan.Warnings) != 1 || !strings.Contains(plan.Warnings[0], "Missing") {
+		t.Errorf("Expected a warning about deleting Missing, got %v", plan.Warnings)
+	}
+}
+
+func TestPlanTestsOnProduction(t *testing.T) {
+	force := &Force{org: &safetyOrg{Name: "Prod", Production: true}}
+	deployed := map[string][]string{"CustomObject": {"Account"}}
+
+	var plan DeployPlan
+	force.planTests(&plan, ForceMetadataFiles{}, ForceDeployOptions{TestLevel: "NoTestRun"}, deployed)
+	if plan.TestLevel != "NoTestRun" || len(plan.Warnings) != 0 {
+		t.Errorf("Expected no tests without Apex, got %+v", plan)
+	}
+
+	plan = DeployPlan{}
+	force.planTests(&plan, ForceMetadataFiles{}, ForceDeployOptions{TestLevel: "RunSpecifiedTests", RunTests: []string{""}}, deployed)
+	if plan.TestLevel != "RunSpecifiedTests" || len(plan.Tests) != 0 {
+		t.Errorf("Expected an empty set of specified tests, got %+v", plan)
+	}
+}
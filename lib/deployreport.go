@@ -0,0 +1,303 @@
+package lib
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// deployProblem is a component or test failure from a deploy, located in the
+// local source files where possible.
+type deployProblem struct {
+	Rule    string
+	Title   string
+	Path    string
+	Line    int
+	Column  int
+	Message string
+	Warning bool
+}
+
+const (
+	componentFailureRule = "ComponentFailure"
+	testFailureRule      = "TestFailure"
+)
+
+// text returns the problem's message, prefixed with its component or test.
+func (p deployProblem) text() string {
+	if p.Title == "" {
+		return p.Message
+	}
+	return p.Title + ": " + p.Message
+}
+
+// stackTraceFrame matches a frame of an Apex stack trace, such as
+// "Class.MyTest.testMethod: line 12, column 1".
+var stackTraceFrame = regexp.MustCompile(`^Class\.([\w.]+): line (\d+), column (\d+)`)
+
+// problems returns the deploy's component and test failures.  root is the
+// local directory the metadata was deployed from, which is prepended to the
+// failures' paths.
+func (r ForceCheckDeploymentStatusResult) problems(root string) []deployProblem {
+	root = filepath.ToSlash(root)
+	var problems []deployProblem
+	for _, f := range r.Details.ComponentFailures {
+		p := deployProblem{
+			Rule:    componentFailureRule,
+			Title:   strings.TrimSpace(f.ComponentType + " " + f.FullName),
+			Line:    f.LineNumber,
+			Column:  f.ColumnNumber,
+			Message: f.Problem,
+			Warning: f.ProblemType == "Warning",
+		}
+		if f.FileName != "" {
+			p.Path = path.Join(root, strings.TrimPrefix(f.FileName, "unpackaged/"))
+		}
+		problems = append(problems, p)
+	}
+	for _, f := range r.Details.RunTestResult.TestFailures {
+		p := deployProblem{
+			Rule:    testFailureRule,
+			Title:   f.Name + "." + f.MethodName,
+			Message: f.Message,
+		}
+		if f.StackTrace != "" {
+			p.Message += "\n" + f.StackTrace
+		}
+		// Test failures are reported in the test class, if it's in the
+		// source directory.
+		className := f.Name[strings.LastIndex(f.Name, ".")+1:]
+		classPath := path.Join(root, "classes", className+".cls")
+		if _, err := os.Stat(filepath.FromSlash(classPath)); err == nil {
+			p.Path = classPath
+			p.Line, p.Column = testFailureLocation(className, f.StackTrace)
+		}
+		problems = append(problems, p)
+	}
+	return problems
+}
+
+// testFailureLocation returns the line and column of the first frame of the
+// stack trace in the test class, or zeros if there is none.
+func testFailureLocation(className string, stackTrace string) (int, int) {
+	for _, frame := range strings.Split(stackTrace, "\n") {
+		m := stackTraceFrame.FindStringSubmatch(strings.TrimSpace(frame))
+		if m == nil {
+			continue
+		}
+		// The frame is the class, optionally with a namespace before it
+		// and inner classes after it, followed by the method.
+		names := strings.Split(m[1], ".")
+		for _, name := range names[:len(names)-1] {
+			if strings.EqualFold(name, className) {
+				line, _ := strconv.Atoi(m[2])
+				column, _ := strconv.Atoi(m[3])
+				return line, column
+			}
+		}
+	}
+	return 0, 0
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationUri string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	Id               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleId    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	Uri string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+// ToSarif formats the deploy's component and test failures as a SARIF 2.1.0
+// log, for code scanning tools.  root is the directory the metadata was
+// deployed from, relative to the root of the repository.
+func (r ForceCheckDeploymentStatusResult) ToSarif(root string) (string, error) {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "force",
+			InformationUri: "https://github.com/ForceCLI/force",
+			Rules: []sarifRule{
+				{Id: componentFailureRule, ShortDescription: sarifMessage{Text: "Component failed to deploy"}},
+				{Id: testFailureRule, ShortDescription: sarifMessage{Text: "Apex test failed"}},
+			},
+		}},
+		Results: []sarifResult{},
+	}
+	for _, p := range r.problems(root) {
+		result := sarifResult{
+			RuleId:  p.Rule,
+			Level:   "error",
+			Message: sarifMessage{Text: p.text()},
+		}
+		if p.Warning {
+			result.Level = "warning"
+		}
+		if p.Path != "" {
+			location := sarifLocation{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{Uri: p.Path},
+			}}
+			if p.Line > 0 {
+				location.PhysicalLocation.Region = &sarifRegion{StartLine: p.Line, StartColumn: p.Column}
+			}
+			result.Locations = []sarifLocation{location}
+		}
+		run.Results = append(run.Results, result)
+	}
+	output, err := json.MarshalIndent(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	}, "", "  ")
+	if err != nil {
+		return "", errors.Wrap(err, "Unable to format result for sarif")
+	}
+	return string(output), nil
+}
+
+// ToGitHubAnnotations formats the deploy's component and test failures as
+// GitHub Actions workflow commands, which annotate the files in pull
+// requests.  root is the directory the metadata was deployed from, relative
+// to the root of the repository.
+func (r ForceCheckDeploymentStatusResult) ToGitHubAnnotations(root string) string {
+	var b strings.Builder
+	for _, p := range r.problems(root) {
+		command := "error"
+		if p.Warning {
+			command = "warning"
+		}
+		var properties []string
+		if p.Path != "" {
+			properties = append(properties, "file="+escapeGitHubProperty(p.Path))
+			if p.Line > 0 {
+				properties = append(properties, fmt.Sprintf("line=%d", p.Line))
+			}
+			if p.Column > 0 {
+				properties = append(properties, fmt.Sprintf("col=%d", p.Column))
+			}
+		}
+		if p.Title != "" {
+			properties = append(properties, "title="+escapeGitHubProperty(p.Title))
+		}
+		if len(properties) > 0 {
+			command += " " + strings.Join(properties, ",")
+		}
+		fmt.Fprintf(&b, "::%s::%s\n", command, escapeGitHubData(p.Message))
+	}
+	return b.String()
+}
+
+func escapeGitHubData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+func escapeGitHubProperty(s string) string {
+	return strings.NewReplacer(":", "%3A", ",", "%2C").Replace(escapeGitHubData(s))
+}
+
+type codeQualityIssue struct {
+	Description string              `json:"description"`
+	CheckName   string              `json:"check_name"`
+	Fingerprint string              `json:"fingerprint"`
+	Severity    string              `json:"severity"`
+	Location    codeQualityLocation `json:"location"`
+}
+
+type codeQualityLocation struct {
+	Path  string           `json:"path"`
+	Lines codeQualityLines `json:"lines"`
+}
+
+type codeQualityLines struct {
+	Begin int `json:"begin"`
+}
+
+// ToGitLabCodeQuality formats the deploy's component and test failures as a
+// GitLab Code Quality report.  root is the directory the metadata was
+// deployed from, relative to the root of the repository.  Failures without a
+// file are reported against the package.xml in root.
+func (r ForceCheckDeploymentStatusResult) ToGitLabCodeQuality(root string) (string, error) {
+	issues := []codeQualityIssue{}
+	for _, p := range r.problems(root) {
+		issue := codeQualityIssue{
+			Description: p.text(),
+			CheckName:   p.Rule,
+			Severity:    "major",
+			Location: codeQualityLocation{
+				Path:  p.Path,
+				Lines: codeQualityLines{Begin: p.Line},
+			},
+		}
+		if p.Warning {
+			issue.Severity = "minor"
+		}
+		if issue.Location.Path == "" {
+			issue.Location.Path = path.Join(filepath.ToSlash(root), "package.xml")
+		}
+		if issue.Location.Lines.Begin == 0 {
+			issue.Location.Lines.Begin = 1
+		}
+		sum := sha1.Sum([]byte(strings.Join([]string{p.Rule, issue.Location.Path, p.Title, p.Message}, "\x00")))
+		issue.Fingerprint = hex.EncodeToString(sum[:])
+		issues = append(issues, issue)
+	}
+	output, err := json.MarshalIndent(issues, "", "  ")
+	if err != nil {
+		return "", errors.Wrap(err, "Unable to format result for gitlab")
+	}
+	return string(output), nil
+}
@@ -0,0 +1,156 @@
+package lib
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testDeployResult() ForceCheckDeploymentStatusResult {
+	var r ForceCheckDeploymentStatusResult
+	r.Details.ComponentFailures = []ComponentFailure{
+		{ComponentType: "ApexClass", FullName: "A", FileName: "unpackaged/classes/A.cls", LineNumber: 3, ColumnNumber: 7, Problem: "Missing ';' at 'x'", ProblemType: "Error"},
+		{ComponentType: "CustomObject", FullName: "Foo__c", FileName: "objects/Foo__c.object", Problem: "Deprecated", ProblemType: "Warning"},
+		{Problem: "No package.xml found"},
+	}
+	r.Details.RunTestResult.TestFailures = []TestFailure{
+		{Name: "ATest", MethodName: "testIt", Message: "System.AssertException: Assertion Failed", StackTrace: "Class.A.run: line 5, column 1\nClass.ATest.testIt: line 12, column 3"},
+		{Name: "ns.Missing", MethodName: "testIt", Message: "Failed"},
+	}
+	return r
+}
+
+func testDeployRoot(t *testing.T) string {
+	root := t.TempDir()
+	os.MkdirAll(filepath.Join(root, "classes"), 0755)
+	os.WriteFile(filepath.Join(root, "classes", "ATest.cls"), []byte("@isTest class ATest {}"), 0644)
+	return root
+}
+
+func TestDeployProblems(t *testing.T) {
+	root := testDeployRoot(t)
+	problems := testDeployResult().problems(root)
+	if len(problems) != 5 {
+		t.Fatalf("Expected 5 problems, got %+v", problems)
+	}
+	if p := problems[0]; p.Path != filepath.ToSlash(root)+"/classes/A.cls" || p.Line != 3 || p.Column != 7 || p.Warning {
+		t.Errorf("Unexpected component failure %+v", p)
+	}
+	if p := problems[1]; !p.Warning {
+		t.Errorf("Expected a warning, got %+v", p)
+	}
+	if p := problems[2]; p.Path != "" || p.Message != "No package.xml found" {
+		t.Errorf("Unexpected component failure without a file %+v", p)
+	}
+	if p := problems[3]; p.Path != filepath.ToSlash(root)+"/classes/ATest.cls" || p.Line != 12 || p.Column != 3 || p.Rule != testFailureRule {
+		t.Errorf("Expected the test failure in ATest.cls on line 12, got %+v", p)
+	}
+	if p := problems[4]; p.Path != "" || p.Line != 0 {
+		t.Errorf("Expected no location for a test class not in the source directory, got %+v", p)
+	}
+}
+
+func TestTestFailureLocation(t *testing.T) {
+	cases := []struct {
+		stackTrace   string
+		line, column int
+	}{
+		{"Class.ATest.testIt: line 12, column 3", 12, 3},
+		{"Class.ns.ATest.testIt: line 4, column 1", 4, 1},
+		{"Class.ATest.Inner.run: line 30, column 5\nClass.ATest.testIt: line 12, column 3", 30, 5},
+		{"Class.Other.run: line 5, column 1", 0, 0},
+		{"", 0, 0},
+	}
+	for _, c := range cases {
+		line, column := testFailureLocation("ATest", c.stackTrace)
+		if line != c.line || column != c.column {
+			t.Errorf("%q: expected %d:%d, got %d:%d", c.stackTrace, c.line, c.column, line, column)
+		}
+	}
+}
+
+func TestToSarif(t *testing.T) {
+	output, err := testDeployResult().ToSarif("src")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var log sarifLog
+	if err := json.Unmarshal([]byte(output), &log); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 || len(log.Runs[0].Results) != 5 {
+		t.Fatalf("Unexpected SARIF log %s", output)
+	}
+	first := log.Runs[0].Results[0]
+	location := first.Locations[0].PhysicalLocation
+	if first.Level != "error" || location.ArtifactLocation.Uri != "src/classes/A.cls" || location.Region.StartLine != 3 || location.Region.StartColumn != 7 {
+		t.Errorf("Unexpected result %+v", first)
+	}
+	if second := log.Runs[0].Results[1]; second.Level != "warning" || second.Locations[0].PhysicalLocation.Region != nil {
+		t.Errorf("Expected a warning without a region, got %+v", second)
+	}
+	if third := log.Runs[0].Results[2]; len(third.Locations) != 0 {
+		t.Errorf("Expected no location, got %+v", third)
+	}
+}
+
+func TestToGitHubAnnotations(t *testing.T) {
+	root := testDeployRoot(t)
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	os.Chdir(root)
+	lines := strings.Split(strings.TrimSuffix(testDeployResult().ToGitHubAnnotations("."), "\n"), "\n")
+	expected := []string{
+		"::error file=classes/A.cls,line=3,col=7,title=ApexClass A::Missing ';' at 'x'",
+		"::warning file=objects/Foo__c.object,title=CustomObject Foo__c::Deprecated",
+		"::error::No package.xml found",
+		"::error file=classes/ATest.cls,line=12,col=3,title=ATest.testIt::System.AssertException: Assertion Failed%0AClass.A.run: line 5, column 1%0AClass.ATest.testIt: line 12, column 3",
+		"::error title=ns.Missing.testIt::Failed",
+	}
+	if len(lines) != len(expected) {
+		t.Fatalf("Expected %d annotations, got %q", len(expected), lines)
+	}
+	for i := range expected {
+		if lines[i] != expected[i] {
+			t.Errorf("Expected %q, got %q", expected[i], lines[i])
+		}
+	}
+}
+
+func TestEscapeGitHubProperty(t *testing.T) {
+	if s := escapeGitHubProperty("a:b,c%d\ne"); s != "a%3Ab%2Cc%25d%0Ae" {
+		t.Errorf("Unexpected escaping %q", s)
+	}
+}
+
+func TestToGitLabCodeQuality(t *testing.T) {
+	output, err := testDeployResult().ToGitLabCodeQuality("src")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var issues []codeQualityIssue
+	if err := json.Unmarshal([]byte(output), &issues); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if len(issues) != 5 {
+		t.Fatalf("Expected 5 issues, got %s", output)
+	}
+	if i := issues[0]; i.Location.Path != "src/classes/A.cls" || i.Location.Lines.Begin != 3 || i.Severity != "major" || i.CheckName != componentFailureRule {
+		t.Errorf("Unexpected issue %+v", i)
+	}
+	if i := issues[1]; i.Severity != "minor" || i.Location.Lines.Begin != 1 {
+		t.Errorf("Expected a minor issue on line 1, got %+v", i)
+	}
+	if i := issues[2]; i.Location.Path != "src/package.xml" {
+		t.Errorf("Expected an issue without a file to be reported against package.xml, got %+v", i)
+	}
+	seen := make(map[string]bool)
+	for _, i := range issues {
+		if i.Fingerprint == "" || seen[i.Fingerprint] {
+			t.Errorf("Expected unique fingerprints, got %s", output)
+		}
+		seen[i.Fingerprint] = true
+	}
+}
@@ -0,0 +1,160 @@
+package lib
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	. "github.com/ForceCLI/force/config"
+)
+
+const describeCacheName = "describe-cache"
+
+// DescribeCacheEntry is an sobject describe stored in the local describe
+// cache.  Entries are keyed by org id, API version, and object name, and are
+// revalidated using the ETag returned by Salesforce.
+type DescribeCacheEntry struct {
+	OrgId      string
+	ApiVersion string
+	Object     string
+	ETag       string
+	CachedAt   time.Time
+	Body       string `json:",omitempty"`
+}
+
+func describeCacheDir(orgId, version string) string {
+	return filepath.Join(describeCacheName, orgId, version)
+}
+
+func (f *Force) describeCacheOrgId() string {
+	if f.Credentials == nil || f.Credentials.UserInfo == nil {
+		return ""
+	}
+	return f.Credentials.UserInfo.OrgId
+}
+
+func loadDescribeCacheEntry(orgId, version, objecttype string) (entry DescribeCacheEntry, err error) {
+	data, err := Config.Load(describeCacheDir(orgId, version), objecttype+".json")
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal([]byte(data), &entry)
+	return
+}
+
+func saveDescribeCacheEntry(entry DescribeCacheEntry) error {
+	body, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return Config.Save(describeCacheDir(entry.OrgId, entry.ApiVersion), entry.Object+".json", string(body))
+}
+
+// DescribeSObjectCached returns the describe for objecttype, using the local
+// describe cache when the org reports that the object has not changed.  If the
+// org id is unknown, such as for sessions from environment variables, the
+// cache is bypassed.
+func (f *Force) DescribeSObjectCached(objecttype string) (result string, err error) {
+	orgId := f.describeCacheOrgId()
+	if orgId == "" {
+		return f.DescribeSObject(objecttype)
+	}
+	cached, cacheErr := loadDescribeCacheEntry(orgId, apiVersion, objecttype)
+	etag := ""
+	if cacheErr == nil && cached.Body != "" {
+		etag = cached.ETag
+	}
+	res, err := f.DescribeSObjectWithETag(objecttype, etag)
+	if err != nil {
+		return
+	}
+	if res.NotModified && etag != "" {
+		return cached.Body, nil
+	}
+	entry := DescribeCacheEntry{
+		OrgId:      orgId,
+		ApiVersion: apiVersion,
+		Object:     objecttype,
+		ETag:       res.ETag,
+		CachedAt:   time.Now(),
+		Body:       res.Body,
+	}
+	if err := saveDescribeCacheEntry(entry); err != nil {
+		Log.Info("Failed to update describe cache: " + err.Error())
+	}
+	return res.Body, nil
+}
+
+// GetSobjectCached is like GetSobject, but uses the local describe cache.
+func (f *Force) GetSobjectCached(name string) (sobject ForceSobject, err error) {
+	body, err := f.DescribeSObjectCached(name)
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal([]byte(body), &sobject)
+	return
+}
+
+// DescribeCacheEntries lists the entries in the local describe cache, without
+// their describe bodies.  If orgId is empty, entries for all orgs are
+// returned.
+func DescribeCacheEntries(orgId string) (entries []DescribeCacheEntry, err error) {
+	root := filepath.Join(Config.GlobalRoot(), describeCacheName)
+	orgIds := []string{orgId}
+	if orgId == "" {
+		if orgIds, err = listDirs(root); err != nil {
+			return
+		}
+	}
+	for _, org := range orgIds {
+		var versions []string
+		if versions, err = listDirs(filepath.Join(root, org)); err != nil {
+			return
+		}
+		for _, version := range versions {
+			var files []string
+			if files, err = Config.List(describeCacheDir(org, version)); err != nil {
+				return
+			}
+			for _, file := range files {
+				entry, loadErr := loadDescribeCacheEntry(org, version, strings.TrimSuffix(file, ".json"))
+				if loadErr != nil {
+					continue
+				}
+				entry.Body = ""
+				entries = append(entries, entry)
+			}
+		}
+	}
+	return
+}
+
+// ClearDescribeCache removes the cached describes for orgId.  If orgId is
+// empty, the cache is cleared for all orgs.
+func ClearDescribeCache(orgId string) error {
+	dir := filepath.Join(Config.GlobalRoot(), describeCacheName)
+	if orgId != "" {
+		dir = filepath.Join(dir, orgId)
+	}
+	return os.RemoveAll(dir)
+}
+
+func listDirs(dir string) (names []string, err error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
@@ -0,0 +1,63 @@
+package lib
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDescribeSObjectCached_revalidates_with_etag(t *testing.T) {
+	cleanup := setupTestConfig(t)
+	defer cleanup()
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(`{"name":"Account","fields":[{"name":"Id"}]}`))
+	}))
+	defer server.Close()
+
+	force := &Force{
+		Credentials: &ForceSession{
+			InstanceUrl: server.URL,
+			AccessToken: "test-token",
+			UserInfo:    &UserInfo{OrgId: "00D000000000001"},
+		},
+	}
+
+	first, err := force.DescribeSObjectCached("Account")
+	if err != nil {
+		t.Fatalf("DescribeSObjectCached returned error: %v", err)
+	}
+	second, err := force.DescribeSObjectCached("Account")
+	if err != nil {
+		t.Fatalf("DescribeSObjectCached returned error: %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("Expected 2 requests, got %d", requests)
+	}
+	if first != second {
+		t.Errorf("Expected cached describe %q, got %q", first, second)
+	}
+
+	entries, err := DescribeCacheEntries("00D000000000001")
+	if err != nil {
+		t.Fatalf("DescribeCacheEntries returned error: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Object != "Account" || entries[0].ETag != `"v1"` {
+		t.Errorf("Unexpected cache entries: %+v", entries)
+	}
+
+	if err := ClearDescribeCache(""); err != nil {
+		t.Fatalf("ClearDescribeCache returned error: %v", err)
+	}
+	entries, _ = DescribeCacheEntries("")
+	if len(entries) != 0 {
+		t.Errorf("Expected cache to be empty, got %+v", entries)
+	}
+}
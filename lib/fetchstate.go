@@ -0,0 +1,112 @@
+package lib
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	. "github.com/ForceCLI/force/config"
+)
+
+// FetchedComponent is the state of a component in the org when it was last
+// fetched.
+type FetchedComponent struct {
+	LastModifiedDate   time.Time `json:"lastModifiedDate"`
+	LastModifiedByName string    `json:"lastModifiedByName,omitempty"`
+}
+
+// FetchState records the components fetched from an org, keyed by
+// componentKey.  It's kept per user in the local .force directory.
+type FetchState map[string]FetchedComponent
+
+// ConcurrentModification is a component that was changed in the org by
+// someone else after it was last fetched.
+type ConcurrentModification struct {
+	Type     string
+	FullName string
+	Fetched  FetchedComponent
+	Current  MDFileProperties
+}
+
+// LoadFetchState loads the fetch state recorded for username, which is
+// empty if nothing has been fetched.
+func LoadFetchState(username string) (FetchState, error) {
+	state := make(FetchState)
+	data, err := Config.LoadLocal("fetched", username)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal([]byte(data), &state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// Save saves the fetch state for username.
+func (s FetchState) Save(username string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return Config.SaveLocal("fetched", username, string(data))
+}
+
+// Record records the state of retrieved components, as reported by
+// RetrieveOptions.FileProperties.
+func (s FetchState) Record(properties []MDFileProperties) {
+	for _, p := range properties {
+		if p.Type == "" || p.Type == "Package" {
+			continue
+		}
+		s[componentKey(p.Type, p.FullName)] = FetchedComponent{
+			LastModifiedDate:   p.LastModifedDate,
+			LastModifiedByName: p.LastModifiedByName,
+		}
+	}
+}
+
+// ModifiedSinceFetch returns the components to be deployed or deleted that
+// were modified in the org after they were last fetched, by a user other
+// than the current one.  Components in the org that have no recorded fetch
+// state can't be checked, and are returned as unchecked, as Type/Name.
+func (f *Force) ModifiedSinceFetch(files ForceMetadataFiles, state FetchState) (modified []ConcurrentModification, unchecked []string, err error) {
+	deployed, deleted, err := deployManifests(files)
+	if err != nil {
+		return nil, nil, err
+	}
+	existing, err := f.Metadata.orgComponents(deployed, deleted)
+	if err != nil {
+		return nil, nil, err
+	}
+	userId := ""
+	if f.Credentials != nil && f.Credentials.UserInfo != nil {
+		userId = f.Credentials.UserInfo.UserId
+	}
+	for _, members := range []map[string][]string{deployed, deleted} {
+		for _, t := range sortedKeys(members) {
+			for _, name := range members[t] {
+				key := componentKey(t, name)
+				current, ok := existing[key]
+				if !ok {
+					continue
+				}
+				fetched, ok := state[key]
+				if !ok {
+					unchecked = append(unchecked, t+"/"+name)
+					continue
+				}
+				if !current.LastModifedDate.After(fetched.LastModifiedDate) {
+					continue
+				}
+				if userId != "" && sameId(current.LastModifiedById, userId) {
+					continue
+				}
+				modified = append(modified, ConcurrentModification{Type: t, FullName: name, Fetched: fetched, Current: current})
+			}
+		}
+	}
+	return modified, unchecked, nil
+}
@@ -0,0 +1,76 @@
+package lib
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFetchStateSaveAndLoad(t *testing.T) {
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	os.Chdir(t.TempDir())
+
+	state, err := LoadFetchState("me@example.com")
+	if err != nil || len(state) != 0 {
+		t.Fatalf("Expected empty state before fetching, got %v, %v", state, err)
+	}
+	modified := time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC)
+	state.Record([]MDFileProperties{
+		{Type: "ApexClass", FullName: "A", LastModifedDate: modified, LastModifiedByName: "Jane"},
+		{Type: "Package", FullName: "package.xml"},
+	})
+	if err := state.Save("me@example.com"); err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+	loaded, err := LoadFetchState("me@example.com")
+	if err != nil {
+		t.Fatalf("LoadFetchState returned error: %v", err)
+	}
+	if len(loaded) != 1 || !loaded[componentKey("ApexClass", "A")].LastModifiedDate.Equal(modified) {
+		t.Errorf("Unexpected state: %v", loaded)
+	}
+}
+
+func TestModifiedSinceFetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<Envelope><Body><listMetadataResponse>` +
+			`<result><fullName>Admin</fullName><type>ApexClass</type><lastModifiedById>005000000000002AAA</lastModifiedById>` +
+			`<lastModifiedByName>Bob</lastModifiedByName><lastModifiedDate>2026-03-05T00:00:00.000Z</lastModifiedDate></result>` +
+			`<result><fullName>Mine</fullName><type>ApexClass</type><lastModifiedById>005000000000001AAA</lastModifiedById>` +
+			`<lastModifiedDate>2026-03-05T00:00:00.000Z</lastModifiedDate></result>` +
+			`<result><fullName>Unchanged</fullName><type>ApexClass</type><lastModifiedById>005000000000002AAA</lastModifiedById>` +
+			`<lastModifiedDate>2026-03-01T00:00:00.000Z</lastModifiedDate></result>` +
+			`<result><fullName>Unfetched</fullName><type>ApexClass</type><lastModifiedById>005000000000002AAA</lastModifiedById>` +
+			`<lastModifiedDate>2026-03-05T00:00:00.000Z</lastModifiedDate></result>` +
+			`</listMetadataResponse></Body></Envelope>`))
+	}))
+	defer server.Close()
+
+	force := NewForce(&ForceSession{
+		InstanceUrl: server.URL,
+		AccessToken: "test-token",
+		UserInfo:    &UserInfo{UserId: "005000000000001AAA"},
+	})
+	fetched := FetchedComponent{LastModifiedDate: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)}
+	state := FetchState{
+		componentKey("ApexClass", "Admin"):     fetched,
+		componentKey("ApexClass", "Mine"):      fetched,
+		componentKey("ApexClass", "Unchanged"): fetched,
+	}
+	files := ForceMetadataFiles{"package.xml": []byte(`<Package><types><members>Admin</members><members>Mine</members>` +
+		`<members>Unchanged</members><members>Unfetched</members><members>New</members><name>ApexClass</name></types></Package>`)}
+	modified, unchecked, err := force.ModifiedSinceFetch(files, state)
+	if err != nil {
+		t.Fatalf("ModifiedSinceFetch returned error: %v", err)
+	}
+	if len(modified) != 1 || modified[0].FullName != "Admin" || modified[0].Current.LastModifiedByName != "Bob" {
+		t.Errorf("Expected only Admin to be modified by someone else, got %+v", modified)
+	}
+	if !reflect.DeepEqual(unchecked, []string{"ApexClass/Unfetched"}) {
+		t.Errorf("Expected only Unfetched to be unchecked, got %v", unchecked)
+	}
+}
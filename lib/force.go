@@ -52,9 +52,11 @@ const (
 )
 
 const (
-	RefreshUnavailable = iota
-	RefreshOauth       = iota
-	RefreshSFDX        = iota
+	RefreshUnavailable       = iota
+	RefreshOauth             = iota
+	RefreshSFDX              = iota
+	RefreshJWT               = iota
+	RefreshClientCredentials = iota
 )
 
 type RefreshMethod int
@@ -64,6 +66,10 @@ type Force struct {
 	Metadata    *ForceMetadata
 	Partner     *ForcePartner
 	retrier     *HttpRetrier
+	org         *safetyOrg
+	// refreshFailedAt is when refreshing the session before it expired
+	// last failed.
+	refreshFailedAt time.Time
 }
 
 type UserInfo struct {
@@ -86,6 +92,12 @@ type SessionOptions struct {
 	//
 	// Note that RefreshMethod implementations use Force.UpdateCredentials.
 	RefreshFunc func(*Force) error `json:"-"`
+	// JwtKeyFile is the signing key used to re-authenticate JWT sessions.
+	JwtKeyFile string `json:",omitempty"`
+	// ClientSecretRef refers to the secret used to re-authenticate client
+	// credentials sessions; see ResolveSecretRef.  The secret itself is
+	// never saved.
+	ClientSecretRef string `json:",omitempty"`
 }
 
 type OAuthError struct {
@@ -567,7 +579,7 @@ func ForceLoginAtEndpoint(endpoint string) (creds ForceSession, err error) {
 }
 
 func (f *Force) GetCodeCoverage(classId string, className string) (err error) {
-	url := fmt.Sprintf("%s/services/data/%s/query/?q=Select+Id+From+ApexClass+Where+Name+=+'%s'", f.Credentials.InstanceUrl, apiVersion, className)
+	url := fmt.Sprintf("%s/services/data/%s/query/?q=Select+Id+From+ApexClass+Where+Name+=+'%s'", f.InstanceUrl(), apiVersion, className)
 
 	body, err := f.makeHttpRequestSync(NewRequest("GET").AbsoluteUrl(url))
 	if err != nil {
@@ -581,7 +593,7 @@ func (f *Force) GetCodeCoverage(classId string, className string) (err error) {
 	}
 
 	classId = result.Records[0]["Id"].(string)
-	url = fmt.Sprintf("%s/services/data/%s/tooling/query/?q=Select+Coverage,+NumLinesCovered,+NumLinesUncovered,+ApexTestClassId,+ApexClassorTriggerId+From+ApexCodeCoverage+Where+ApexClassorTriggerId='%s'", f.Credentials.InstanceUrl, apiVersion, classId)
+	url = fmt.Sprintf("%s/services/data/%s/tooling/query/?q=Select+Coverage,+NumLinesCovered,+NumLinesUncovered,+ApexTestClassId,+ApexClassorTriggerId+From+ApexCodeCoverage+Where+ApexClassorTriggerId='%s'", f.InstanceUrl(), apiVersion, classId)
 
 	body, err = f.makeHttpRequestSync(NewRequest("GET").AbsoluteUrl(url))
 	if err != nil {
@@ -600,13 +612,16 @@ func (f *Force) GetCodeCoverage(classId string, className string) (err error) {
 }
 
 func (f *Force) DeleteDataPipeline(id string) (err error) {
-	url := fmt.Sprintf("%s/services/data/%s/tooling/sobjects/DataPipeline/%s", f.Credentials.InstanceUrl, apiVersion, id)
+	if err = f.CheckDestructiveOperation(fmt.Sprintf("delete DataPipeline %s", id)); err != nil {
+		return
+	}
+	url := fmt.Sprintf("%s/services/data/%s/tooling/sobjects/DataPipeline/%s", f.InstanceUrl(), apiVersion, id)
 	_, err = f.httpDelete(url)
 	return
 }
 
 func (f *Force) UpdateDataPipeline(id string, masterLabel string, scriptContent string) (err error) {
-	url := fmt.Sprintf("%s/services/data/%s/tooling/sobjects/DataPipeline/%s", f.Credentials.InstanceUrl, apiVersion, id)
+	url := fmt.Sprintf("%s/services/data/%s/tooling/sobjects/DataPipeline/%s", f.InstanceUrl(), apiVersion, id)
 	attrs := make(map[string]string)
 	attrs["MasterLabel"] = masterLabel
 	attrs["ScriptContent"] = scriptContent
@@ -616,7 +631,7 @@ func (f *Force) UpdateDataPipeline(id string, masterLabel string, scriptContent
 }
 
 func (f *Force) CreateDataPipeline(name string, masterLabel string, apiVersionNumber string, scriptContent string, scriptType string) (result ForceCreateRecordResult, err error, emessages []ForceError) {
-	aurl := fmt.Sprintf("%s/services/data/%s/tooling/sobjects/DataPipeline", f.Credentials.InstanceUrl, apiVersion)
+	aurl := fmt.Sprintf("%s/services/data/%s/tooling/sobjects/DataPipeline", f.InstanceUrl(), apiVersion)
 
 	attrs := make(map[string]string)
 	attrs["DeveloperName"] = name
@@ -636,7 +651,7 @@ func (f *Force) CreateDataPipeline(name string, masterLabel string, apiVersionNu
 }
 
 func (f *Force) CreateDataPipelineJob(id string) (result ForceCreateRecordResult, err error, emessages []ForceError) {
-	aurl := fmt.Sprintf("%s/services/data/%s/tooling/sobjects/DataPipelineJob", f.Credentials.InstanceUrl, apiVersion)
+	aurl := fmt.Sprintf("%s/services/data/%s/tooling/sobjects/DataPipelineJob", f.InstanceUrl(), apiVersion)
 
 	attrs := make(map[string]string)
 	attrs["DataPipelineId"] = id
@@ -657,7 +672,7 @@ func (f *Force) GetDataPipeline(name string) (results ForceQueryResult, err erro
 }
 
 func (f *Force) QueryDataPipeline(soql string) (results ForceQueryResult, err error) {
-	aurl := fmt.Sprintf("%s/services/data/%s/tooling/query?q=%s", f.Credentials.InstanceUrl, apiVersion,
+	aurl := fmt.Sprintf("%s/services/data/%s/tooling/query?q=%s", f.InstanceUrl(), apiVersion,
 		url.QueryEscape(soql))
 
 	body, err := f.makeHttpRequestSync(NewRequest("GET").AbsoluteUrl(aurl))
@@ -670,7 +685,7 @@ func (f *Force) QueryDataPipeline(soql string) (results ForceQueryResult, err er
 }
 
 func (f *Force) QueryDataPipelineJob(soql string) (results ForceQueryResult, err error) {
-	aurl := fmt.Sprintf("%s/services/data/%s/tooling/query?q=%s", f.Credentials.InstanceUrl, apiVersion,
+	aurl := fmt.Sprintf("%s/services/data/%s/tooling/query?q=%s", f.InstanceUrl(), apiVersion,
 		url.QueryEscape(soql))
 
 	body, err := f.makeHttpRequestSync(NewRequest("GET").AbsoluteUrl(aurl))
@@ -689,7 +704,7 @@ func (f *Force) GetAuraBundles() (bundles AuraDefinitionBundleResult, definition
 }
 
 func (f *Force) GetAuraBundleDefinitions() (definitions AuraDefinitionBundleResult, err error) {
-	aurl := fmt.Sprintf("%s/services/data/%s/tooling/query?q=%s", f.Credentials.InstanceUrl, apiVersion,
+	aurl := fmt.Sprintf("%s/services/data/%s/tooling/query?q=%s", f.InstanceUrl(), apiVersion,
 		url.QueryEscape("SELECT Id, Source, AuraDefinitionBundleId, DefType, Format FROM AuraDefinition"))
 
 	body, err := f.makeHttpRequestSync(NewRequest("GET").AbsoluteUrl(aurl))
@@ -713,7 +728,7 @@ func (f *Force) GetMoreAuraBundleDefinitions(definitions *AuraDefinitionBundleRe
 	for !isDone {
 
 		moreDefs := new(AuraDefinitionBundleResult)
-		aurl := fmt.Sprintf("%s%s", f.Credentials.InstanceUrl, nextRecordsUrl)
+		aurl := fmt.Sprintf("%s%s", f.InstanceUrl(), nextRecordsUrl)
 
 		body, err := f.makeHttpRequestSync(NewRequest("GET").AbsoluteUrl(aurl))
 		if err != nil {
@@ -735,7 +750,7 @@ func (f *Force) GetMoreAuraBundleDefinitions(definitions *AuraDefinitionBundleRe
 }
 
 func (f *Force) GetAuraBundlesList() (bundles AuraDefinitionBundleResult, err error) {
-	aurl := fmt.Sprintf("%s/services/data/%s/tooling/query?q=%s", f.Credentials.InstanceUrl, apiVersion,
+	aurl := fmt.Sprintf("%s/services/data/%s/tooling/query?q=%s", f.InstanceUrl(), apiVersion,
 		url.QueryEscape("SELECT Id, DeveloperName, NamespacePrefix, ApiVersion, Description FROM AuraDefinitionBundle"))
 	body, err := f.makeHttpRequestSync(NewRequest("GET").AbsoluteUrl(aurl))
 	if err != nil {
@@ -759,7 +774,7 @@ func (f *Force) GetAuraBundle(bundleName string) (bundles AuraDefinitionBundleRe
 func (f *Force) GetAuraBundleByName(bundleName string) (bundles AuraDefinitionBundleResult, err error) {
 	criteria := fmt.Sprintf(" Where DeveloperName = '%s'", bundleName)
 
-	aurl := fmt.Sprintf("%s/services/data/%s/tooling/query?q=%s", f.Credentials.InstanceUrl, apiVersion,
+	aurl := fmt.Sprintf("%s/services/data/%s/tooling/query?q=%s", f.InstanceUrl(), apiVersion,
 		url.QueryEscape(fmt.Sprintf("SELECT Id, DeveloperName, NamespacePrefix, ApiVersion, Description FROM AuraDefinitionBundle%s", criteria)))
 
 	body, err := f.makeHttpRequestSync(NewRequest("GET").AbsoluteUrl(aurl))
@@ -772,7 +787,7 @@ func (f *Force) GetAuraBundleByName(bundleName string) (bundles AuraDefinitionBu
 }
 
 func (f *Force) GetAuraBundleDefinition(id string) (definitions AuraDefinitionBundleResult, err error) {
-	aurl := fmt.Sprintf("%s/services/data/%s/tooling/query?q=%s", f.Credentials.InstanceUrl, apiVersion,
+	aurl := fmt.Sprintf("%s/services/data/%s/tooling/query?q=%s", f.InstanceUrl(), apiVersion,
 		url.QueryEscape(fmt.Sprintf("SELECT Id, Source, AuraDefinitionBundleId, DefType, Format FROM AuraDefinition WHERE AuraDefinitionBundleId = '%s'", id)))
 
 	body, err := f.makeHttpRequestSync(NewRequest("GET").AbsoluteUrl(aurl))
@@ -785,7 +800,7 @@ func (f *Force) GetAuraBundleDefinition(id string) (definitions AuraDefinitionBu
 }
 
 func (f *Force) CreateAuraBundle(bundleName string) (result ForceCreateRecordResult, err error, emessages []ForceError) {
-	aurl := fmt.Sprintf("%s/services/data/%s/tooling/sobjects/AuraDefinitionBundle", f.Credentials.InstanceUrl, apiVersion)
+	aurl := fmt.Sprintf("%s/services/data/%s/tooling/sobjects/AuraDefinitionBundle", f.InstanceUrl(), apiVersion)
 	attrs := make(map[string]string)
 	attrs["DeveloperName"] = bundleName
 	attrs["Description"] = "An Aura Bundle"
@@ -801,7 +816,7 @@ func (f *Force) CreateAuraBundle(bundleName string) (result ForceCreateRecordRes
 }
 
 func (f *Force) CreateAuraComponent(attrs map[string]string) (result ForceCreateRecordResult, err error, emessages []ForceError) {
-	aurl := fmt.Sprintf("%s/services/data/%s/tooling/sobjects/AuraDefinition", f.Credentials.InstanceUrl, apiVersion)
+	aurl := fmt.Sprintf("%s/services/data/%s/tooling/sobjects/AuraDefinition", f.InstanceUrl(), apiVersion)
 	body, err, emessages := f.httpPost(aurl, attrs)
 	if err != nil {
 		return
@@ -812,7 +827,7 @@ func (f *Force) CreateAuraComponent(attrs map[string]string) (result ForceCreate
 }
 
 func (f *Force) ListSobjects() (sobjects []ForceSobject, err error) {
-	url := fmt.Sprintf("%s/services/data/%s/sobjects", f.Credentials.InstanceUrl, apiVersion)
+	url := fmt.Sprintf("%s/services/data/%s/sobjects", f.InstanceUrl(), apiVersion)
 	body, err := f.makeHttpRequestSync(NewRequest("GET").AbsoluteUrl(url))
 	if err != nil {
 		return
@@ -824,7 +839,7 @@ func (f *Force) ListSobjects() (sobjects []ForceSobject, err error) {
 }
 
 func (f *Force) GetSobject(name string) (sobject ForceSobject, err error) {
-	url := fmt.Sprintf("%s/services/data/%s/sobjects/%s/describe", f.Credentials.InstanceUrl, apiVersion, name)
+	url := fmt.Sprintf("%s/services/data/%s/sobjects/%s/describe", f.InstanceUrl(), apiVersion, name)
 	body, err := f.makeHttpRequestSync(NewRequest("GET").AbsoluteUrl(url))
 	if err != nil {
 		return
@@ -925,7 +940,7 @@ func (f *Force) Query(qs string, options ...func(*QueryOptions)) (ForceQueryResu
 func (f *Force) QueryOptions() []query.Option {
 	instUrl := ""
 	if f.Credentials != nil {
-		instUrl = f.Credentials.InstanceUrl
+		instUrl = f.InstanceUrl()
 	}
 	return []query.Option{
 		query.HttpGet(func(url string) ([]byte, error) {
@@ -979,7 +994,7 @@ func (f *Force) Get(url string) (object ForceRecord, err error) {
 
 func (f *Force) GetLimits() (result map[string]ForceLimit, err error) {
 
-	url := fmt.Sprintf("%s/services/data/%s/limits", f.Credentials.InstanceUrl, apiVersion)
+	url := fmt.Sprintf("%s/services/data/%s/limits", f.InstanceUrl(), apiVersion)
 	body, err := f.makeHttpRequestSync(NewRequest("GET").AbsoluteUrl(url))
 	if err != nil {
 		return
@@ -990,7 +1005,7 @@ func (f *Force) GetLimits() (result map[string]ForceLimit, err error) {
 }
 
 func (f *Force) GetPasswordStatus(id string) (result ForcePasswordStatusResult, err error) {
-	url := fmt.Sprintf("%s/services/data/%s/sobjects/User/%s/password", f.Credentials.InstanceUrl, apiVersion, id)
+	url := fmt.Sprintf("%s/services/data/%s/sobjects/User/%s/password", f.InstanceUrl(), apiVersion, id)
 	body, err := f.makeHttpRequestSync(NewRequest("GET").AbsoluteUrl(url))
 	if err != nil {
 		return
@@ -1000,7 +1015,7 @@ func (f *Force) GetPasswordStatus(id string) (result ForcePasswordStatusResult,
 }
 
 func (f *Force) ResetPassword(id string) (result ForcePasswordResetResult, err error) {
-	url := fmt.Sprintf("%s/services/data/%s/sobjects/User/%s/password", f.Credentials.InstanceUrl, apiVersion, id)
+	url := fmt.Sprintf("%s/services/data/%s/sobjects/User/%s/password", f.InstanceUrl(), apiVersion, id)
 	body, err := f.httpDelete(url)
 	if err != nil {
 		return
@@ -1010,7 +1025,7 @@ func (f *Force) ResetPassword(id string) (result ForcePasswordResetResult, err e
 }
 
 func (f *Force) ChangePassword(id string, attrs map[string]string) (result string, err error, emessages []ForceError) {
-	url := fmt.Sprintf("%s/services/data/%s/sobjects/User/%s/password", f.Credentials.InstanceUrl, apiVersion, id)
+	url := fmt.Sprintf("%s/services/data/%s/sobjects/User/%s/password", f.InstanceUrl(), apiVersion, id)
 	_, err, emessages = f.httpPost(url, attrs)
 	return
 }
@@ -1019,9 +1034,9 @@ func (f *Force) GetRecord(sobject, id string) (object ForceRecord, err error) {
 	fields := strings.Split(id, ":")
 	var url string
 	if len(fields) == 1 {
-		url = fmt.Sprintf("%s/services/data/%s/sobjects/%s/%s", f.Credentials.InstanceUrl, apiVersion, sobject, id)
+		url = fmt.Sprintf("%s/services/data/%s/sobjects/%s/%s", f.InstanceUrl(), apiVersion, sobject, id)
 	} else {
-		url = fmt.Sprintf("%s/services/data/%s/sobjects/%s/%s/%s", f.Credentials.InstanceUrl, apiVersion, sobject, fields[0], fields[1])
+		url = fmt.Sprintf("%s/services/data/%s/sobjects/%s/%s/%s", f.InstanceUrl(), apiVersion, sobject, fields[0], fields[1])
 	}
 
 	body, err := f.makeHttpRequestSync(NewRequest("GET").AbsoluteUrl(url))
@@ -1033,7 +1048,7 @@ func (f *Force) GetRecord(sobject, id string) (object ForceRecord, err error) {
 }
 
 func (f *Force) CreateRecord(sobject string, attrs map[string]string) (id string, err error, emessages []ForceError) {
-	url := fmt.Sprintf("%s/services/data/%s/sobjects/%s", f.Credentials.InstanceUrl, apiVersion, sobject)
+	url := fmt.Sprintf("%s/services/data/%s/sobjects/%s", f.InstanceUrl(), apiVersion, sobject)
 	body, err, emessages := f.httpPost(url, attrs)
 	var result ForceCreateRecordResult
 	json.Unmarshal(body, &result)
@@ -1044,7 +1059,7 @@ func (f *Force) CreateRecord(sobject string, attrs map[string]string) (id string
 func (f *Force) QueryProfile(fields ...string) (results ForceQueryResult, err error) {
 
 	url := fmt.Sprintf("%s/services/data/%s/tooling/query?q=Select+%s+From+Profile+Where+Id='%s'",
-		f.Credentials.InstanceUrl,
+		f.InstanceUrl(),
 		apiVersion,
 		strings.Join(fields, ","),
 		f.Credentials.UserInfo.ProfileId)
@@ -1058,7 +1073,7 @@ func (f *Force) QueryProfile(fields ...string) (results ForceQueryResult, err er
 }
 
 func (f *Force) QueryTraceFlags() (results ForceQueryResult, err error) {
-	url := fmt.Sprintf("%s/services/data/%s/tooling/query/?q=Select+Id,+DebugLevel.DeveloperName,++ApexCode,+ApexProfiling,+Callout,+CreatedDate,+Database,+ExpirationDate,+System,+TracedEntity.Name,+Validation,+Visualforce,+Workflow+From+TraceFlag+Order+By+ExpirationDate,TracedEntity.Name", f.Credentials.InstanceUrl, apiVersion)
+	url := fmt.Sprintf("%s/services/data/%s/tooling/query/?q=Select+Id,+DebugLevel.DeveloperName,++ApexCode,+ApexProfiling,+Callout,+CreatedDate,+Database,+ExpirationDate,+System,+TracedEntity.Name,+Validation,+Visualforce,+Workflow+From+TraceFlag+Order+By+ExpirationDate,TracedEntity.Name", f.InstanceUrl(), apiVersion)
 	body, err := f.makeHttpRequestSync(NewRequest("GET").AbsoluteUrl(url))
 	if err != nil {
 		return
@@ -1068,7 +1083,7 @@ func (f *Force) QueryTraceFlags() (results ForceQueryResult, err error) {
 }
 
 func (f *Force) QueryDefaultDebugLevel() (id string, err error) {
-	url := fmt.Sprintf("%s/services/data/%s/tooling/query/?q=Select+Id+From+DebugLevel+Where+DeveloperName+=+'Force_CLI'", f.Credentials.InstanceUrl, apiVersion)
+	url := fmt.Sprintf("%s/services/data/%s/tooling/query/?q=Select+Id+From+DebugLevel+Where+DeveloperName+=+'Force_CLI'", f.InstanceUrl(), apiVersion)
 	body, err := f.makeHttpRequestSync(NewRequest("GET").AbsoluteUrl(url))
 	if err != nil {
 		return
@@ -1086,7 +1101,7 @@ func (f *Force) DefaultDebugLevel() (id string, err error, emessages []ForceErro
 	if err != nil || id != "" {
 		return
 	}
-	url := fmt.Sprintf("%s/services/data/%s/tooling/sobjects/DebugLevel", f.Credentials.InstanceUrl, apiVersion)
+	url := fmt.Sprintf("%s/services/data/%s/tooling/sobjects/DebugLevel", f.InstanceUrl(), apiVersion)
 
 	// The log levels are currently hard-coded to a useful level of logging
 	// without hitting the maximum log size of 2MB in most cases, hopefully.
@@ -1120,7 +1135,7 @@ func (f *Force) StartTrace(userId ...string) (result ForceCreateRecordResult, er
 	if err != nil {
 		return
 	}
-	url := fmt.Sprintf("%s/services/data/%s/tooling/sobjects/TraceFlag", f.Credentials.InstanceUrl, apiVersion)
+	url := fmt.Sprintf("%s/services/data/%s/tooling/sobjects/TraceFlag", f.InstanceUrl(), apiVersion)
 
 	attrs := make(map[string]string)
 	attrs["DebugLevelId"] = debugLevel
@@ -1142,7 +1157,7 @@ func (f *Force) StartTrace(userId ...string) (result ForceCreateRecordResult, er
 }
 
 func (f *Force) RetrieveLog(logId string) (result string, err error) {
-	url := fmt.Sprintf("%s/services/data/%s/tooling/sobjects/ApexLog/%s/Body", f.Credentials.InstanceUrl, apiVersion, logId)
+	url := fmt.Sprintf("%s/services/data/%s/tooling/sobjects/ApexLog/%s/Body", f.InstanceUrl(), apiVersion, logId)
 	body, err := f.makeHttpRequestSync(NewRequest("GET").AbsoluteUrl(url))
 	result = string(body)
 	return
@@ -1150,7 +1165,7 @@ func (f *Force) RetrieveLog(logId string) (result string, err error) {
 
 // ExecuteAnonymousTooling executes anonymous Apex code using the Tooling API
 func (f *Force) ExecuteAnonymousTooling(apex string) (result ExecuteAnonymousResult, err error) {
-	url := fmt.Sprintf("%s/services/data/%s/tooling/executeAnonymous/?anonymousBody=%s", f.Credentials.InstanceUrl, apiVersion, url.QueryEscape(apex))
+	url := fmt.Sprintf("%s/services/data/%s/tooling/executeAnonymous/?anonymousBody=%s", f.InstanceUrl(), apiVersion, url.QueryEscape(apex))
 	body, err := f.makeHttpRequestSync(NewRequest("GET").AbsoluteUrl(url))
 	if err != nil {
 		return
@@ -1171,7 +1186,7 @@ type ExecuteAnonymousResult struct {
 }
 
 func (f *Force) QueryLogs() (results ForceQueryResult, err error) {
-	url := fmt.Sprintf("%s/services/data/%s/tooling/query/?q=Select+Id,+Application,+DurationMilliseconds,+Location,+LogLength,+LogUser.Name,+Operation,+Request,StartTime,+Status+From+ApexLog+Order+By+StartTime", f.Credentials.InstanceUrl, apiVersion)
+	url := fmt.Sprintf("%s/services/data/%s/tooling/query/?q=Select+Id,+Application,+DurationMilliseconds,+Location,+LogLength,+LogUser.Name,+Operation,+Request,StartTime,+Status+From+ApexLog+Order+By+StartTime", f.InstanceUrl(), apiVersion)
 	body, err := f.makeHttpRequestSync(NewRequest("GET").AbsoluteUrl(url))
 	if err != nil {
 		return
@@ -1181,7 +1196,7 @@ func (f *Force) QueryLogs() (results ForceQueryResult, err error) {
 }
 
 func (f *Force) RetrieveEventLogFile(elfId string) (result string, err error) {
-	url := fmt.Sprintf("%s/services/data/%s/sobjects/EventLogFile/%s/LogFile", f.Credentials.InstanceUrl, apiVersion, elfId)
+	url := fmt.Sprintf("%s/services/data/%s/sobjects/EventLogFile/%s/LogFile", f.InstanceUrl(), apiVersion, elfId)
 	body, err := f.makeHttpRequestSync(NewRequest("GET").AbsoluteUrl(url))
 	if err != nil {
 		return
@@ -1214,9 +1229,9 @@ func (f *Force) QueryEventLogFiles() (results ForceQueryResult, err error) {
 		ErrorAndExit(e.Error())
 	}
 	if f.useHourlyLogs() && currApi >= 37.0 {
-		url = fmt.Sprintf("%s/services/data/%s/query/?q=Select+Id,+LogDate,+EventType,+LogFileLength,+Sequence,+Interval+FROM+EventLogFile+ORDER+BY+LogDate+DESC,+EventType,+Sequence,+Interval", f.Credentials.InstanceUrl, apiVersion)
+		url = fmt.Sprintf("%s/services/data/%s/query/?q=Select+Id,+LogDate,+EventType,+LogFileLength,+Sequence,+Interval+FROM+EventLogFile+ORDER+BY+LogDate+DESC,+EventType,+Sequence,+Interval", f.InstanceUrl(), apiVersion)
 	} else {
-		url = fmt.Sprintf("%s/services/data/%s/query/?q=Select+Id,+LogDate,+EventType,+LogFileLength+FROM+EventLogFile+ORDER+BY+LogDate+DESC,+EventType", f.Credentials.InstanceUrl, apiVersion)
+		url = fmt.Sprintf("%s/services/data/%s/query/?q=Select+Id,+LogDate,+EventType,+LogFileLength+FROM+EventLogFile+ORDER+BY+LogDate+DESC,+EventType", f.InstanceUrl(), apiVersion)
 	}
 	body, err := f.makeHttpRequestSync(NewRequest("GET").AbsoluteUrl(url))
 	if err != nil {
@@ -1227,19 +1242,38 @@ func (f *Force) QueryEventLogFiles() (results ForceQueryResult, err error) {
 }
 
 func (f *Force) UpdateAuraComponent(source map[string]string, id string) (err error) {
-	url := fmt.Sprintf("%s/services/data/%s/tooling/sobjects/AuraDefinition/%s", f.Credentials.InstanceUrl, apiVersion, id)
+	url := fmt.Sprintf("%s/services/data/%s/tooling/sobjects/AuraDefinition/%s", f.InstanceUrl(), apiVersion, id)
 	_, err = f.httpPatch(url, source)
 	return
 }
 
+// diagnosticToolingObjects are Tooling API objects, such as debug logs and
+// trace flags, that are deleted without the production safety check.
+var diagnosticToolingObjects = map[string]bool{
+	"apexlog":    true,
+	"traceflag":  true,
+	"debuglevel": true,
+}
+
 func (f *Force) DeleteToolingRecord(objecttype string, id string) (err error) {
-	url := fmt.Sprintf("%s/services/data/%s/tooling/sobjects/%s/%s", f.Credentials.InstanceUrl, apiVersion, objecttype, id)
+	if !diagnosticToolingObjects[strings.ToLower(objecttype)] {
+		if err = f.CheckDestructiveOperation(fmt.Sprintf("delete %s %s", objecttype, id)); err != nil {
+			return
+		}
+	}
+	return f.deleteToolingRecord(objecttype, id)
+}
+
+// deleteToolingRecord deletes a Tooling API record without the production
+// safety check, for temporary records such as a MetadataContainer.
+func (f *Force) deleteToolingRecord(objecttype string, id string) (err error) {
+	url := fmt.Sprintf("%s/services/data/%s/tooling/sobjects/%s/%s", f.InstanceUrl(), apiVersion, objecttype, id)
 	_, err = f.httpDelete(url)
 	return
 }
 
 func (f *Force) CreateToolingRecord(objecttype string, attrs map[string]string) (result ForceCreateRecordResult, err error) {
-	aurl := fmt.Sprintf("%s/services/data/%s/tooling/sobjects/%s", f.Credentials.InstanceUrl, apiVersion, objecttype)
+	aurl := fmt.Sprintf("%s/services/data/%s/tooling/sobjects/%s", f.InstanceUrl(), apiVersion, objecttype)
 	body, err, _ := f.httpPost(aurl, attrs)
 
 	if err != nil {
@@ -1251,13 +1285,13 @@ func (f *Force) CreateToolingRecord(objecttype string, attrs map[string]string)
 }
 
 func (f *Force) UpdateToolingRecord(objecttype string, id string, attrs map[string]string) (err error) {
-	url := fmt.Sprintf("%s/services/data/%s/tooling/sobjects/%s/%s", f.Credentials.InstanceUrl, apiVersion, objecttype, id)
+	url := fmt.Sprintf("%s/services/data/%s/tooling/sobjects/%s/%s", f.InstanceUrl(), apiVersion, objecttype, id)
 	_, err = f.httpPatch(url, attrs)
 	return
 }
 
 func (f *Force) CreateToolingRecordMultipart(objecttype string, body []byte, contentType string) (result ForceCreateRecordResult, err error) {
-	aurl := fmt.Sprintf("%s/services/data/%s/tooling/sobjects/%s", f.Credentials.InstanceUrl, apiVersion, objecttype)
+	aurl := fmt.Sprintf("%s/services/data/%s/tooling/sobjects/%s", f.InstanceUrl(), apiVersion, objecttype)
 
 	req := NewRequest("POST").
 		AbsoluteUrl(aurl).
@@ -1274,7 +1308,7 @@ func (f *Force) CreateToolingRecordMultipart(objecttype string, body []byte, con
 }
 
 func (f *Force) DescribeSObject(objecttype string) (result string, err error) {
-	url := fmt.Sprintf("%s/services/data/%s/sobjects/%s/describe", f.Credentials.InstanceUrl, apiVersion, objecttype)
+	url := fmt.Sprintf("%s/services/data/%s/sobjects/%s/describe", f.InstanceUrl(), apiVersion, objecttype)
 	body, err := f.makeHttpRequestSync(NewRequest("GET").AbsoluteUrl(url))
 	if err != nil {
 		return
@@ -1292,7 +1326,7 @@ type DescribeSObjectResponse struct {
 
 // DescribeSObjectWithETag fetches SObject describe information with ETag support for conditional requests
 func (f *Force) DescribeSObjectWithETag(objecttype string, ifNoneMatch string) (result *DescribeSObjectResponse, err error) {
-	url := fmt.Sprintf("%s/services/data/%s/sobjects/%s/describe", f.Credentials.InstanceUrl, apiVersion, objecttype)
+	url := fmt.Sprintf("%s/services/data/%s/sobjects/%s/describe", f.InstanceUrl(), apiVersion, objecttype)
 
 	req := NewRequest("GET").AbsoluteUrl(url).ReadResponseBody()
 
@@ -1339,16 +1373,16 @@ func (f *Force) UpdateRecord(sobject string, id string, attrs map[string]string)
 	fields := strings.Split(id, ":")
 	var url string
 	if len(fields) == 1 {
-		url = fmt.Sprintf("%s/services/data/%s/sobjects/%s/%s", f.Credentials.InstanceUrl, apiVersion, sobject, id)
+		url = fmt.Sprintf("%s/services/data/%s/sobjects/%s/%s", f.InstanceUrl(), apiVersion, sobject, id)
 	} else {
-		url = fmt.Sprintf("%s/services/data/%s/sobjects/%s/%s/%s", f.Credentials.InstanceUrl, apiVersion, sobject, fields[0], fields[1])
+		url = fmt.Sprintf("%s/services/data/%s/sobjects/%s/%s/%s", f.InstanceUrl(), apiVersion, sobject, fields[0], fields[1])
 	}
 	_, err = f.httpPatch(url, attrs)
 	return
 }
 
 func (f *Force) UpsertRecord(sobject string, externalIdField string, externalIdValue string, attrs map[string]string) (result ForceUpsertResult, err error) {
-	url := fmt.Sprintf("%s/services/data/%s/sobjects/%s/%s/%s", f.Credentials.InstanceUrl, apiVersion, sobject, externalIdField, externalIdValue)
+	url := fmt.Sprintf("%s/services/data/%s/sobjects/%s/%s/%s", f.InstanceUrl(), apiVersion, sobject, externalIdField, externalIdValue)
 	body, err := f.httpPatch(url, attrs)
 	if err != nil {
 		return
@@ -1367,7 +1401,10 @@ func (f *Force) UpsertRecord(sobject string, externalIdField string, externalIdV
 }
 
 func (f *Force) DeleteRecord(sobject string, id string) (err error) {
-	url := fmt.Sprintf("%s/services/data/%s/sobjects/%s/%s", f.Credentials.InstanceUrl, apiVersion, sobject, id)
+	if err = f.CheckDestructiveOperation(fmt.Sprintf("delete %s %s", sobject, id)); err != nil {
+		return
+	}
+	url := fmt.Sprintf("%s/services/data/%s/sobjects/%s/%s", f.InstanceUrl(), apiVersion, sobject, id)
 	_, err = f.httpDelete(url)
 	return
 }
@@ -1386,7 +1423,7 @@ func (f *Force) Whoami() (me ForceRecord, err error) {
 
 // Prepend https schema and instance to URL
 func (f *Force) qualifyUrl(url string) string {
-	return fmt.Sprintf("%s/%s", f.Credentials.InstanceUrl, strings.TrimLeft(url, "/"))
+	return fmt.Sprintf("%s/%s", f.InstanceUrl(), strings.TrimLeft(url, "/"))
 }
 
 func (f *Force) GetAbsoluteBytes(url string) (result []byte, err error) {
@@ -1471,7 +1508,7 @@ func (f *Force) PutREST(url string, content string) (result string, err error) {
 }
 
 func (f *Force) getForceResult(url string) (results ForceQueryResult, err error) {
-	furl := fmt.Sprintf("%s%s", f.Credentials.InstanceUrl, url)
+	furl := fmt.Sprintf("%s%s", f.InstanceUrl(), url)
 	body, err := f.makeHttpRequestSync(NewRequest("GET").AbsoluteUrl(furl))
 	if err != nil {
 		return
@@ -1481,8 +1518,8 @@ func (f *Force) getForceResult(url string) (results ForceQueryResult, err error)
 }
 
 func (f *Force) setHttpInputAuth(input *httpRequestInput) *httpRequestInput {
-	input.Headers["X-SFDC-Session"] = f.Credentials.AccessToken
-	input.Headers["Authorization"] = fmt.Sprintf("Bearer %s", f.Credentials.AccessToken)
+	input.Headers["X-SFDC-Session"] = f.AccessToken()
+	input.Headers["Authorization"] = fmt.Sprintf("Bearer %s", f.AccessToken())
 	return input
 }
 
@@ -1622,6 +1659,7 @@ func summarizeErrorBody(body []byte) string {
 
 func (f *Force) httpPostPatchWithRetry(url string, rbody string, contenttype ContentType, method HttpMethod, requestOptions ...func(*http.Request)) ([]byte, error) {
 	retrier := f.GetRetrier()
+	f.refreshExpiringSession()
 
 	for {
 		res, err := f.httpPostPatch(url, rbody, contenttype, method, requestOptions...)
@@ -1659,8 +1697,8 @@ func (f *Force) httpPostPatch(url string, rbody string, contenttype ContentType,
 		option(req)
 	}
 
-	req.Header.Add("X-SFDC-Session", f.Credentials.AccessToken)
-	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", f.Credentials.AccessToken))
+	req.Header.Add("X-SFDC-Session", f.AccessToken())
+	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", f.AccessToken()))
 	req.Header.Add("Content-Type", string(contenttype))
 
 	res, err := doRequest(req)
@@ -1693,6 +1731,7 @@ func (f *Force) readResponseBody(res *http.Response) ([]byte, error) {
 }
 
 func (f *Force) httpPost(url string, attrs map[string]string) (body []byte, err error, emessages []ForceError) {
+	f.refreshExpiringSession()
 	body, err, emessages = f.httpPostAttributes(url, attrs)
 	if err == SessionExpiredError {
 		err = f.RefreshSession()
@@ -1710,7 +1749,7 @@ func (f *Force) httpPostAttributes(url string, attrs map[string]string) (body []
 	if err != nil {
 		return
 	}
-	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", f.Credentials.AccessToken))
+	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", f.AccessToken()))
 	req.Header.Add("Content-Type", "application/json")
 	res, err := doRequest(req)
 	if err != nil {
@@ -1741,6 +1780,7 @@ func (f *Force) httpPostAttributes(url string, attrs map[string]string) (body []
 }
 
 func (f *Force) httpPatch(url string, attrs map[string]string) (body []byte, err error) {
+	f.refreshExpiringSession()
 	body, err = f.httpPatchAttributes(url, attrs)
 	if err == SessionExpiredError {
 		err = f.RefreshSession()
@@ -1758,7 +1798,7 @@ func (f *Force) httpPatchAttributes(url string, attrs map[string]string) (body [
 	if err != nil {
 		return
 	}
-	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", f.Credentials.AccessToken))
+	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", f.AccessToken()))
 	req.Header.Add("Content-Type", "application/json")
 	res, err := doRequest(req)
 	if err != nil {
@@ -1788,6 +1828,7 @@ func (f *Force) httpPatchAttributes(url string, attrs map[string]string) (body [
 }
 
 func (f *Force) httpDelete(url string) (body []byte, err error) {
+	f.refreshExpiringSession()
 	body, err = f.httpDeleteUrl(url)
 	if err == SessionExpiredError {
 		err = f.RefreshSession()
@@ -1804,7 +1845,7 @@ func (f *Force) httpDeleteUrl(url string) (body []byte, err error) {
 	if err != nil {
 		return
 	}
-	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", f.Credentials.AccessToken))
+	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", f.AccessToken()))
 	res, err := doRequest(req)
 	if err != nil {
 		return
@@ -1836,7 +1877,7 @@ func (result *ForceQueryResult) Update(other ForceQueryResult, force *Force) {
 	result.Done = other.Done
 	result.Records = append(result.Records, other.Records...)
 	result.TotalSize = len(result.Records)
-	result.NextRecordsUrl = fmt.Sprintf("%s%s", force.Credentials.InstanceUrl, other.NextRecordsUrl)
+	result.NextRecordsUrl = fmt.Sprintf("%s%s", force.InstanceUrl(), other.NextRecordsUrl)
 }
 
 func startLocalHttpServer(ch chan ForceSession) (port int, err error) {
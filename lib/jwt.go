@@ -7,6 +7,7 @@ import (
 	"io"
 	"net/url"
 	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -102,6 +103,29 @@ func ForceLoginAtEndpointAndSaveJWT(endpoint string, assertion string, output *o
 	return
 }
 
+// ForceLoginAtEndpointAndSaveJWTWithKey logs in with a JWT signed by
+// keyfile and saves the login along with the key's location, so the session
+// can be re-authenticated when it expires.
+func ForceLoginAtEndpointAndSaveJWTWithKey(endpoint string, username string, keyfile string, clientId string, output *os.File) (name string, err error) {
+	keyfile, err = filepath.Abs(keyfile)
+	if err != nil {
+		return
+	}
+	assertion, err := JwtAssertionForEndpoint(endpoint, username, keyfile, clientId)
+	if err != nil {
+		return
+	}
+	creds, err := JWTLoginAtEndpoint(endpoint, assertion)
+	if err != nil {
+		return
+	}
+	creds.ClientId = clientId
+	creds.SessionOptions.RefreshMethod = RefreshJWT
+	creds.SessionOptions.JwtKeyFile = keyfile
+	name, err = ForceSaveLogin(creds, output)
+	return
+}
+
 func ClientCredentialsLoginAtEndpoint(endpoint string, clientId string, clientSecret string) (creds ForceSession, err error) {
 	attrs := url.Values{}
 	attrs.Set("grant_type", "client_credentials")
@@ -141,7 +165,7 @@ func ClientCredentialsLoginAtEndpoint(endpoint string, clientId string, clientSe
 	err = json.Unmarshal(body, &creds)
 	creds.SessionOptions = &SessionOptions{}
 	creds.EndpointUrl = endpoint
-	creds.ClientId = ClientId
+	creds.ClientId = clientId
 	return
 }
 
@@ -154,6 +178,25 @@ func ForceLoginAtEndpointAndSaveClientCredentials(endpoint string, clientId stri
 	return
 }
 
+// ForceLoginAtEndpointAndSaveClientCredentialsWithSecretRef logs in using
+// the client credentials flow with the secret secretRef refers to, and saves
+// the login along with the reference, so the session can be
+// re-authenticated when it expires.
+func ForceLoginAtEndpointAndSaveClientCredentialsWithSecretRef(endpoint string, clientId string, secretRef string, output *os.File) (name string, err error) {
+	clientSecret, err := ResolveSecretRef(secretRef)
+	if err != nil {
+		return
+	}
+	creds, err := ClientCredentialsLoginAtEndpoint(endpoint, clientId, clientSecret)
+	if err != nil {
+		return
+	}
+	creds.SessionOptions.RefreshMethod = RefreshClientCredentials
+	creds.SessionOptions.ClientSecretRef = secretRef
+	name, err = ForceSaveLogin(creds, output)
+	return
+}
+
 func PasswordFlowLoginAtEndpoint(endpoint string, clientId string, clientSecret string, username string, password string) (creds ForceSession, err error) {
 	attrs := url.Values{}
 	attrs.Set("grant_type", "password")
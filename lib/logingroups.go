@@ -0,0 +1,94 @@
+package lib
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	. "github.com/ForceCLI/force/config"
+)
+
+// SavedLogins returns the usernames of the saved logins.
+func SavedLogins() ([]string, error) {
+	accounts, err := Config.List("accounts")
+	if err != nil {
+		return nil, err
+	}
+	var logins []string
+	for _, account := range accounts {
+		if !strings.HasPrefix(account, ".") {
+			logins = append(logins, account)
+		}
+	}
+	return logins, nil
+}
+
+// LoginGroup returns the usernames and patterns in a login group.  Groups are
+// stored in the groups config directory, either in the project or globally,
+// in a file named for the group with one username or pattern per line.
+func LoginGroup(name string) ([]string, error) {
+	data, err := Config.LoadLocalOrGlobal("groups", name)
+	if err != nil {
+		return nil, fmt.Errorf("Unknown login group %s", name)
+	}
+	var patterns []string
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			patterns = append(patterns, line)
+		}
+	}
+	return patterns, nil
+}
+
+// MatchLogins returns the saved logins selected by spec, a comma-separated
+// list of usernames, glob patterns such as *@example.com, and login groups
+// or tags written as @name.
+func MatchLogins(spec string) ([]string, error) {
+	logins, err := SavedLogins()
+	if err != nil {
+		return nil, err
+	}
+	var patterns []string
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		switch {
+		case item == "":
+		case strings.HasPrefix(item, "@"):
+			group, _ := LoginGroup(item[1:])
+			for _, login := range logins {
+				if LoadLoginMetadata(login).HasTag(item[1:]) {
+					group = append(group, login)
+				}
+			}
+			if len(group) == 0 {
+				return nil, fmt.Errorf("No login group or tagged logins found for %s", item)
+			}
+			patterns = append(patterns, group...)
+		default:
+			patterns = append(patterns, item)
+		}
+	}
+	if len(patterns) == 0 {
+		return nil, fmt.Errorf("No logins given")
+	}
+	for _, pattern := range patterns {
+		if _, err := path.Match(strings.ToLower(pattern), ""); err != nil {
+			return nil, fmt.Errorf("Invalid login pattern %q", pattern)
+		}
+	}
+
+	var matched []string
+	for _, login := range logins {
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(strings.ToLower(pattern), strings.ToLower(login)); ok {
+				matched = append(matched, login)
+				break
+			}
+		}
+	}
+	if len(matched) == 0 {
+		return nil, fmt.Errorf("No saved logins match %s", spec)
+	}
+	return matched, nil
+}
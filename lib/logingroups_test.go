@@ -0,0 +1,41 @@
+package lib
+
+import (
+	"reflect"
+	"testing"
+
+	forceConfig "github.com/ForceCLI/force/config"
+)
+
+func TestMatchLogins(t *testing.T) {
+	cleanup := setupTestConfig(t)
+	defer cleanup()
+
+	for _, login := range []string{"admin@acme.com", "ci@acme.com.uat", "dev@other.com"} {
+		forceConfig.Config.Save("accounts", login, "{}")
+	}
+	forceConfig.Config.Save("groups", "uat", "# UAT sandboxes\n*.uat\n")
+
+	cases := map[string][]string{
+		"*@acme.com":                {"admin@acme.com"},
+		"@uat":                      {"ci@acme.com.uat"},
+		"DEV@other.com, @uat":       {"ci@acme.com.uat", "dev@other.com"},
+		"*@acme.com*,dev@other.com": {"admin@acme.com", "ci@acme.com.uat", "dev@other.com"},
+	}
+	for spec, expected := range cases {
+		logins, err := MatchLogins(spec)
+		if err != nil {
+			t.Errorf("MatchLogins(%q) returned error: %v", spec, err)
+			continue
+		}
+		if !reflect.DeepEqual(logins, expected) {
+			t.Errorf("MatchLogins(%q) = %v, expected %v", spec, logins, expected)
+		}
+	}
+
+	for _, spec := range []string{"@missing", "nobody@example.com", "["} {
+		if _, err := MatchLogins(spec); err == nil {
+			t.Errorf("Expected error for %q", spec)
+		}
+	}
+}
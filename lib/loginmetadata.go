@@ -0,0 +1,191 @@
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	. "github.com/ForceCLI/force/config"
+)
+
+// Login environments
+const (
+	EnvironmentProduction = "production"
+	EnvironmentSandbox    = "sandbox"
+	EnvironmentScratch    = "scratch"
+	EnvironmentDeveloper  = "developer"
+)
+
+var LoginEnvironments = []string{EnvironmentProduction, EnvironmentSandbox, EnvironmentScratch, EnvironmentDeveloper}
+
+// DefaultSessionLifetime is the default session timeout for an org, used to
+// estimate when an access token expires.
+const DefaultSessionLifetime = 2 * time.Hour
+
+// LoginMetadata is information about a saved login that isn't part of its
+// session, so it's kept when the login is refreshed or replaced.
+type LoginMetadata struct {
+	Environment string    `json:"environment,omitempty"`
+	Tags        []string  `json:"tags,omitempty"`
+	LastUsed    time.Time `json:"lastUsed,omitempty"`
+
+	ProductionPolicy string `json:"productionPolicy,omitempty"`
+}
+
+// LoadLoginMetadata returns the metadata for a saved login.
+func LoadLoginMetadata(account string) (m LoginMetadata) {
+	if data, err := Config.Load("login-metadata", account); err == nil {
+		json.Unmarshal([]byte(data), &m)
+	}
+	return
+}
+
+func SaveLoginMetadata(account string, m LoginMetadata) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return Config.Save("login-metadata", account, string(data))
+}
+
+func updateLoginMetadata(account string, update func(*LoginMetadata) error) error {
+	if _, err := Config.Load("accounts", account); err != nil {
+		return fmt.Errorf("No saved login found for %s", account)
+	}
+	m := LoadLoginMetadata(account)
+	if err := update(&m); err != nil {
+		return err
+	}
+	return SaveLoginMetadata(account, m)
+}
+
+// HasTag reports whether the login has the tag, ignoring case.
+func (m LoginMetadata) HasTag(tag string) bool {
+	for _, t := range m.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// TagLogin adds tags to a saved login.
+func TagLogin(account string, tags []string) error {
+	return updateLoginMetadata(account, func(m *LoginMetadata) error {
+		for _, tag := range tags {
+			tag = strings.TrimSpace(tag)
+			if tag == "" || strings.ContainsAny(tag, ", ") {
+				return fmt.Errorf("Invalid tag %q: tags cannot be empty or contain spaces or commas", tag)
+			}
+			if !m.HasTag(tag) {
+				m.Tags = append(m.Tags, tag)
+			}
+		}
+		sort.Strings(m.Tags)
+		return nil
+	})
+}
+
+// UntagLogin removes tags from a saved login.
+func UntagLogin(account string, tags []string) error {
+	return updateLoginMetadata(account, func(m *LoginMetadata) error {
+		var kept []string
+		for _, t := range m.Tags {
+			remove := false
+			for _, tag := range tags {
+				if strings.EqualFold(t, tag) {
+					remove = true
+					break
+				}
+			}
+			if !remove {
+				kept = append(kept, t)
+			}
+		}
+		m.Tags = kept
+		return nil
+	})
+}
+
+// SetLoginEnvironment labels a saved login with its environment.  An empty
+// environment removes the label.
+func SetLoginEnvironment(account string, environment string) error {
+	environment = strings.ToLower(environment)
+	if environment == "prod" {
+		environment = EnvironmentProduction
+	}
+	valid := environment == ""
+	for _, e := range LoginEnvironments {
+		valid = valid || e == environment
+	}
+	if !valid {
+		return fmt.Errorf("Invalid environment %q: must be one of %s", environment, strings.Join(LoginEnvironments, ", "))
+	}
+	return updateLoginMetadata(account, func(m *LoginMetadata) error {
+		m.Environment = environment
+		return nil
+	})
+}
+
+// TouchLogin records that a saved login was used.
+func TouchLogin(account string) {
+	m := LoadLoginMetadata(account)
+	m.LastUsed = time.Now()
+	SaveLoginMetadata(account, m)
+}
+
+// LoginEnvironment returns the environment label of a saved login.  If the
+// login hasn't been labeled, sandboxes and scratch orgs are recognized by
+// their URLs; otherwise, the environment is unknown.
+func LoginEnvironment(account string, creds ForceSession) string {
+	if m := LoadLoginMetadata(account); m.Environment != "" {
+		return m.Environment
+	}
+	return inferLoginEnvironment(creds)
+}
+
+func inferLoginEnvironment(creds ForceSession) string {
+	host := ""
+	if u, err := url.Parse(creds.InstanceUrl); err == nil {
+		host = strings.ToLower(u.Host)
+	}
+	switch {
+	case strings.Contains(host, ".scratch."):
+		return EnvironmentScratch
+	case strings.Contains(host, ".sandbox.") || strings.Contains(host, "--"):
+		return EnvironmentSandbox
+	case strings.Contains(host, ".develop."):
+		return EnvironmentDeveloper
+	case creds.ForceEndpoint == EndpointTest:
+		return EnvironmentSandbox
+	}
+	return ""
+}
+
+// IsProductionLogin reports whether a saved login is labeled as production.
+func IsProductionLogin(account string) bool {
+	return LoadLoginMetadata(account).Environment == EnvironmentProduction
+}
+
+// TokenIssuedAt returns when the session's access token was issued, if known.
+func (creds ForceSession) TokenIssuedAt() (time.Time, bool) {
+	ms, err := strconv.ParseInt(creds.IssuedAt, 10, 64)
+	if err != nil || ms <= 0 {
+		return time.Time{}, false
+	}
+	return time.UnixMilli(ms), true
+}
+
+// TokenExpiry estimates when the session's access token expires, assuming
+// the default session timeout.
+func (creds ForceSession) TokenExpiry() (time.Time, bool) {
+	issued, ok := creds.TokenIssuedAt()
+	if !ok {
+		return time.Time{}, false
+	}
+	return issued.Add(DefaultSessionLifetime), true
+}
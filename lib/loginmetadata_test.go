@@ -0,0 +1,92 @@
+package lib
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	forceConfig "github.com/ForceCLI/force/config"
+)
+
+func TestTagLogin(t *testing.T) {
+	cleanup := setupTestConfig(t)
+	defer cleanup()
+
+	forceConfig.Config.Save("accounts", "admin@acme.com", "{}")
+	forceConfig.Config.Save("accounts", "dev@acme.com", "{}")
+
+	if err := TagLogin("admin@acme.com", []string{"team-a", "customer-x", "team-a"}); err != nil {
+		t.Fatalf("TagLogin returned error: %v", err)
+	}
+	if tags := LoadLoginMetadata("admin@acme.com").Tags; !reflect.DeepEqual(tags, []string{"customer-x", "team-a"}) {
+		t.Errorf("Unexpected tags: %v", tags)
+	}
+	if err := UntagLogin("admin@acme.com", []string{"TEAM-A"}); err != nil {
+		t.Fatalf("UntagLogin returned error: %v", err)
+	}
+	if tags := LoadLoginMetadata("admin@acme.com").Tags; !reflect.DeepEqual(tags, []string{"customer-x"}) {
+		t.Errorf("Unexpected tags after untag: %v", tags)
+	}
+
+	if err := TagLogin("admin@acme.com", []string{"a,b"}); err == nil {
+		t.Errorf("Expected error for tag containing a comma")
+	}
+	if err := TagLogin("nobody@acme.com", []string{"team-a"}); err == nil {
+		t.Errorf("Expected error tagging unknown login")
+	}
+
+	logins, err := MatchLogins("@customer-x")
+	if err != nil || !reflect.DeepEqual(logins, []string{"admin@acme.com"}) {
+		t.Errorf("MatchLogins(@customer-x) = %v, %v", logins, err)
+	}
+}
+
+func TestSetLoginEnvironment(t *testing.T) {
+	cleanup := setupTestConfig(t)
+	defer cleanup()
+
+	forceConfig.Config.Save("accounts", "admin@acme.com", "{}")
+
+	if err := SetLoginEnvironment("admin@acme.com", "bogus"); err == nil {
+		t.Errorf("Expected error for invalid environment")
+	}
+	if err := SetLoginEnvironment("admin@acme.com", "prod"); err != nil {
+		t.Fatalf("SetLoginEnvironment returned error: %v", err)
+	}
+	if !IsProductionLogin("admin@acme.com") {
+		t.Errorf("Expected login to be labeled production")
+	}
+	if err := SetLoginEnvironment("admin@acme.com", ""); err != nil {
+		t.Fatalf("SetLoginEnvironment returned error: %v", err)
+	}
+	if IsProductionLogin("admin@acme.com") {
+		t.Errorf("Expected production label to be removed")
+	}
+}
+
+func TestInferLoginEnvironment(t *testing.T) {
+	cases := map[string]string{
+		"https://acme--uat.sandbox.my.salesforce.com":               EnvironmentSandbox,
+		"https://acme--uat.my.salesforce.com":                       EnvironmentSandbox,
+		"https://power-dream-1234-dev-ed.scratch.my.salesforce.com": EnvironmentScratch,
+		"https://acme-dev-ed.develop.my.salesforce.com":             EnvironmentDeveloper,
+		"https://acme.my.salesforce.com":                            "",
+	}
+	for instanceUrl, expected := range cases {
+		if env := inferLoginEnvironment(ForceSession{InstanceUrl: instanceUrl}); env != expected {
+			t.Errorf("inferLoginEnvironment(%s) = %q, expected %q", instanceUrl, env, expected)
+		}
+	}
+}
+
+func TestTokenExpiry(t *testing.T) {
+	issued := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	creds := ForceSession{IssuedAt: "1704164645000"}
+	expiry, ok := creds.TokenExpiry()
+	if !ok || !expiry.Equal(issued.Add(DefaultSessionLifetime)) {
+		t.Errorf("Unexpected token expiry: %v, %v", expiry, ok)
+	}
+	if _, ok := (ForceSession{}).TokenExpiry(); ok {
+		t.Errorf("Expected unknown expiry without issued at time")
+	}
+}
@@ -31,8 +31,6 @@ type BigObject struct {
 
 var AlreadyCompletedError = errors.New("Deployment already completed")
 
-var preserveZip bool
-
 func (bo *BigObject) ToXml() string {
 	soap := `<?xml version="1.0" encoding="UTF-8"?>
 		<CustomObject xmlns="http://soap.sforce.com/2006/04/metadata">
@@ -228,17 +226,17 @@ type ForceMetadata struct {
 }
 
 type ForceDeployOptions struct {
-	XMLName           xml.Name `xml:"deployOptions"`
-	AllowMissingFiles bool     `xml:"allowMissingFiles"`
-	AutoUpdatePackage bool     `xml:"autoUpdatePackage"`
-	CheckOnly         bool     `xml:"checkOnly"`
-	IgnoreWarnings    bool     `xml:"ignoreWarnings"`
-	PerformRetrieve   bool     `xml:"performRetrieve"`
-	PurgeOnDelete     bool     `xml:"purgeOnDelete"`
-	RollbackOnError   bool     `xml:"rollbackOnError"`
-	TestLevel         string   `xml:"testLevel,omitempty"`
-	RunTests          []string `xml:"runTests"`
-	SinglePackage     bool     `xml:"singlePackage"`
+	XMLName           xml.Name `xml:"deployOptions" json:"-"`
+	AllowMissingFiles bool     `xml:"allowMissingFiles" json:"allowMissingFiles,omitempty"`
+	AutoUpdatePackage bool     `xml:"autoUpdatePackage" json:"autoUpdatePackage,omitempty"`
+	CheckOnly         bool     `xml:"checkOnly" json:"checkOnly,omitempty"`
+	IgnoreWarnings    bool     `xml:"ignoreWarnings" json:"ignoreWarnings,omitempty"`
+	PerformRetrieve   bool     `xml:"performRetrieve" json:"performRetrieve,omitempty"`
+	PurgeOnDelete     bool     `xml:"purgeOnDelete" json:"purgeOnDelete,omitempty"`
+	RollbackOnError   bool     `xml:"rollbackOnError" json:"rollbackOnError,omitempty"`
+	TestLevel         string   `xml:"testLevel,omitempty" json:"testLevel,omitempty"`
+	RunTests          []string `xml:"runTests" json:"runTests,omitempty"`
+	SinglePackage     bool     `xml:"singlePackage" json:"singlePackage,omitempty"`
 }
 
 /*
@@ -721,13 +719,17 @@ func (fm *ForceMetadata) GetStatus(id string) (status MetadataDeployStatus, err
 }
 
 func (fm *ForceMetadata) CheckStatus(id string) error {
+	return fm.checkStatus(id, "")
+}
+
+func (fm *ForceMetadata) checkStatus(id string, prefix string) error {
 	for {
 		status, err := fm.GetStatus(id)
 		switch {
 		case err != nil:
 			return err
 		case !status.Done:
-			Log.Info(fmt.Sprintf("Not done yet: %s  Will check again in five seconds.", status.State))
+			Log.Info(fmt.Sprintf("%sNot done yet: %s  Will check again in five seconds.", prefix, status.State))
 			time.Sleep(5000 * time.Millisecond)
 		case status.State == "Error":
 			return errors.New(status.Message)
@@ -766,7 +768,12 @@ func (fm *ForceMetadata) CancelDeploy(id string) (ForceCancelDeployResult, error
 	return cancelResult, nil
 }
 
+// CheckDeployStatus gets the status of a deploy.  The outcome of a deploy
+// recorded in the deploy history is recorded once it's done.
 func (fm *ForceMetadata) CheckDeployStatus(id string) (results ForceCheckDeploymentStatusResult, err error) {
+	defer func() {
+		recordDeployStatus(id, results, err)
+	}()
 	body, err := fm.soapExecute("checkDeployStatus", fmt.Sprintf("<id>%s</id><includeDetails>true</includeDetails>", id))
 	if err != nil {
 		return
@@ -786,37 +793,16 @@ func (fm *ForceMetadata) CheckDeployStatus(id string) (results ForceCheckDeploym
 }
 
 func (fm *ForceMetadata) CheckRetrieveStatus(id string) (files ForceMetadataFiles, problems []string, err error) {
-	body, err := fm.soapExecute("checkRetrieveStatus", fmt.Sprintf("<id>%s</id>", id))
-	if err != nil {
-		return
-	}
-	var status struct {
-		ZipFile  string   `xml:"Body>checkRetrieveStatusResponse>result>zipFile"`
-		Problems []string `xml:"Body>checkRetrieveStatusResponse>result>messages>problem"`
-	}
-	if err = xml.Unmarshal(body, &status); err != nil {
-		return
-	}
-	data, err := base64.StdEncoding.DecodeString(status.ZipFile)
-	if err != nil {
-		return
-	}
-	problems = status.Problems
+	files = make(ForceMetadataFiles)
+	problems, err = fm.CheckRetrieveStatusTo(id, collectRetrievedFiles(files), RetrieveOptions{})
+	return
+}
 
-	zipfiles, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
-	if preserveZip == true {
-		ioutil.WriteFile("inbound.zip", data, 0644)
-	}
-	if err != nil {
-		return
-	}
-	files = make(map[string][]byte)
-	for _, file := range zipfiles.File {
-		fd, _ := file.Open()
-		defer fd.Close()
-		data, _ := ioutil.ReadAll(fd)
-		files[file.Name] = data
-	}
+// CheckRetrieveStatusTo calls handler with each file retrieved by a
+// completed retrieve.  The zip file is streamed to disk rather than held in
+// memory.
+func (fm *ForceMetadata) CheckRetrieveStatusTo(id string, handler RetrieveFileHandler, options RetrieveOptions) (problems []string, err error) {
+	problems, _, err = fm.extractRetrieve(id, options, handler)
 	return
 }
 
@@ -1048,6 +1034,9 @@ func (fm *ForceMetadata) CreateCustomField(object, field, typ string, options ma
 }
 
 func (fm *ForceMetadata) DeleteCustomField(object, field string) (err error) {
+	if err = fm.Force.CheckDestructiveOperation(fmt.Sprintf("delete field %s.%s", object, field)); err != nil {
+		return
+	}
 	soap := `
 		<metadata xsi:type="CustomField" xmlns:cmd="http://soap.sforce.com/2006/04/metadata">
 			<fullName>%s.%s</fullName>
@@ -1155,6 +1144,9 @@ func (fm *ForceMetadata) CreateCustomObject(object string) (err error) {
 }
 
 func (fm *ForceMetadata) DeleteCustomObject(object string) (err error) {
+	if err = fm.Force.CheckDestructiveOperation(fmt.Sprintf("delete object %s", object)); err != nil {
+		return
+	}
 	soap := `
 		<metadata xsi:type="CustomObject" xmlns:cmd="http://soap.sforce.com/2006/04/metadata">
 			<fullName>%s</fullName>
@@ -1210,6 +1202,34 @@ func (fm *ForceMetadata) MakeZipWithOptions(files ForceMetadataFiles, options Fo
 	return
 }
 
+// ReadMetadataDirectory reads the files in a metadata directory, such as
+// one containing a package.xml, keyed by their path relative to root.
+func ReadMetadataDirectory(root string) (files ForceMetadataFiles, err error) {
+	if _, err = os.Stat(filepath.Join(root, "package.xml")); os.IsNotExist(err) {
+		return nil, fmt.Errorf("%s does not exist", filepath.Join(root, "package.xml"))
+	}
+	files = make(ForceMetadataFiles)
+	err = filepath.Walk(root, func(path string, f os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !f.Mode().IsRegular() || f.Name() == ".DS_Store" {
+			return nil
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		files[rel] = data
+		return nil
+	})
+	return
+}
+
 // Deploy metadata and wait unti deploy is complete, then return results
 func (fm *ForceMetadata) Deploy(files ForceMetadataFiles, options ForceDeployOptions) (results ForceCheckDeploymentStatusResult, err error) {
 	zipfile, err := fm.MakeZip(files)
@@ -1256,6 +1276,12 @@ func deploySoapBody(zipfile []byte, options ForceDeployOptions) string {
 }
 
 func (fm *ForceMetadata) startDeployZipFile(zipfile []byte, options ForceDeployOptions) (string, error) {
+	if operation := destructiveDeployOperation(zipfile, options); operation != "" {
+		if err := fm.Force.CheckDestructiveOperation(operation); err != nil {
+			return "", err
+		}
+	}
+	start := time.Now()
 	body, err := fm.soapExecute("deploy", deploySoapBody(zipfile, options))
 	if err != nil {
 		return "", err
@@ -1267,6 +1293,7 @@ func (fm *ForceMetadata) startDeployZipFile(zipfile []byte, options ForceDeployO
 	if err = xml.Unmarshal(body, &status); err != nil {
 		return "", err
 	}
+	fm.recordDeployStart(status.Id, zipfile, options, start)
 	return status.Id, nil
 }
 
@@ -1303,64 +1330,39 @@ func (fm *ForceMetadata) RetrieveByPackageXml(package_xml string) (ForceMetadata
 }
 
 func (fm *ForceMetadata) RetrieveByPackageXmlContents(data []byte) (files ForceMetadataFiles, problems []string, err error) {
-	soap := `
-		<retrieveRequest>
-			<apiVersion>%s</apiVersion>
-			<unpackaged>
-				%s
-			</unpackaged>
-		</retrieveRequest>
-	`
-
-	type types struct {
-		Name    string   `xml:"name"`
-		Members []string `xml:"members"`
-	}
+	files = make(ForceMetadataFiles)
+	problems, err = fm.RetrieveByPackageXmlContentsTo(data, collectRetrievedFiles(files), RetrieveOptions{})
+	return
+}
 
+// RetrieveByPackageXmlContentsTo retrieves the metadata in a package.xml,
+// calling handler with each retrieved file.
+func (fm *ForceMetadata) RetrieveByPackageXmlContentsTo(data []byte, handler RetrieveFileHandler, options RetrieveOptions) (problems []string, err error) {
 	var pxml struct {
-		Results []types `xml:"types"`
+		Types []retrieveType `xml:"types"`
 	}
-
 	xml.Unmarshal(data, &pxml)
+	return fm.retrieveQuery(pxml.Types, handler, options)
+}
 
-	xml_types := ""
-	for _, p_xml := range pxml.Results {
-		xml, err := xml.MarshalIndent(p_xml, " ", "    ")
-		if err != nil {
-			ErrorAndExit(err.Error())
-		}
-		xml_types += fmt.Sprintf("%s\n", xml)
-	}
-
-	body, err := fm.soapExecute("retrieve", fmt.Sprintf(soap, apiVersionNumber, xml_types))
-	if err != nil {
-		return
-	}
-	var status struct {
-		Id string `xml:"Body>retrieveResponse>result>id"`
-	}
-	if err = xml.Unmarshal(body, &status); err != nil {
-		return
-	}
-
-	if err = fm.CheckStatus(status.Id); err != nil {
-		return
-	}
-	raw_files, problems, err := fm.CheckRetrieveStatus(status.Id)
-	if err != nil {
-		return
-	}
+// Retrieve the metadata in query.  Large retrieves are split into several
+// smaller ones, whose files are merged.
+func (fm *ForceMetadata) Retrieve(query ForceMetadataQuery) (files ForceMetadataFiles, problems []string, err error) {
 	files = make(ForceMetadataFiles)
-	for raw_name, data := range raw_files {
-		name := strings.Replace(raw_name, "unpackaged/", "", -1)
-		files[name] = data
-	}
-
+	problems, err = fm.RetrieveTo(query, collectRetrievedFiles(files), RetrieveOptions{})
 	return
 }
 
-func (fm *ForceMetadata) Retrieve(query ForceMetadataQuery) (files ForceMetadataFiles, problems []string, err error) {
+// RetrieveTo retrieves the metadata in query, calling handler with each
+// retrieved file as it's extracted, so large retrieves can be written out
+// without holding them in memory.
+func (fm *ForceMetadata) RetrieveTo(query ForceMetadataQuery, handler RetrieveFileHandler, options RetrieveOptions) (problems []string, err error) {
+	return fm.retrieveQuery(retrieveTypes(query), handler, options)
+}
 
+// retrieveUnpackaged retrieves the types in a single retrieve, prefixing its
+// progress messages with prefix.  It returns the number of files retrieved.
+func (fm *ForceMetadata) retrieveUnpackaged(types []retrieveType, prefix string, options RetrieveOptions, handler RetrieveFileHandler) (problems []string, count int, err error) {
 	soap := `
 		<retrieveRequest>
 			<apiVersion>%s</apiVersion>
@@ -1369,24 +1371,16 @@ func (fm *ForceMetadata) Retrieve(query ForceMetadataQuery) (files ForceMetadata
 			</unpackaged>
 		</retrieveRequest>
 	`
-	soapType := `
-		<types>
-			<name>%s</name>
-			%s
-		</types>
-	`
-	soapTypeMembers := `<members>%s</members>`
-	types := ""
-	for _, element := range query {
-		members := ""
-		for _, member := range element.Members {
-			members += fmt.Sprintf(soapTypeMembers, member)
-		}
-		for _, atype := range element.Name {
-			types += fmt.Sprintf(soapType, atype, members)
+	xmlTypes := ""
+	for _, t := range types {
+		typeXml, err := t.xml()
+		if err != nil {
+			return nil, 0, err
 		}
+		xmlTypes += fmt.Sprintf("%s\n", typeXml)
 	}
-	body, err := fm.soapExecute("retrieve", fmt.Sprintf(soap, apiVersionNumber, types))
+
+	body, err := fm.soapExecute("retrieve", fmt.Sprintf(soap, apiVersionNumber, xmlTypes))
 	if err != nil {
 		return
 	}
@@ -1397,22 +1391,21 @@ func (fm *ForceMetadata) Retrieve(query ForceMetadataQuery) (files ForceMetadata
 		return
 	}
 
-	if err = fm.CheckStatus(status.Id); err != nil {
-		return
-	}
-	raw_files, problems, err := fm.CheckRetrieveStatus(status.Id)
-	if err != nil {
+	if err = fm.checkStatus(status.Id, prefix); err != nil {
 		return
 	}
+	return fm.extractRetrieve(status.Id, options, stripUnpackaged(handler))
+}
+
+func (fm *ForceMetadata) RetrievePackage(packageName string) (files ForceMetadataFiles, problems []string, err error) {
 	files = make(ForceMetadataFiles)
-	for raw_name, data := range raw_files {
-		name := strings.Replace(raw_name, "unpackaged/", "", -1)
-		files[name] = data
-	}
+	problems, err = fm.RetrievePackageTo(packageName, collectRetrievedFiles(files), RetrieveOptions{})
 	return
 }
 
-func (fm *ForceMetadata) RetrievePackage(packageName string) (files ForceMetadataFiles, problems []string, err error) {
+// RetrievePackageTo retrieves a package, calling handler with each
+// retrieved file.
+func (fm *ForceMetadata) RetrievePackageTo(packageName string, handler RetrieveFileHandler, options RetrieveOptions) (problems []string, err error) {
 	soap := `
 		<retrieveRequest>
 			<apiVersion>%s</apiVersion>
@@ -1433,16 +1426,7 @@ func (fm *ForceMetadata) RetrievePackage(packageName string) (files ForceMetadat
 	if err = fm.CheckStatus(status.Id); err != nil {
 		return
 	}
-	raw_files, problems, err := fm.CheckRetrieveStatus(status.Id)
-	if err != nil {
-		return
-	}
-	files = make(ForceMetadataFiles)
-	for raw_name, data := range raw_files {
-		name := strings.Replace(raw_name, fmt.Sprintf("unpackaged%s", string(os.PathSeparator)), "", -1)
-		files[name] = data
-	}
-	return
+	return fm.CheckRetrieveStatusTo(status.Id, stripUnpackaged(handler), options)
 }
 
 func (fm *ForceMetadata) ListMetadata(query string) (res []byte, err error) {
@@ -1478,8 +1462,9 @@ func (fm *ForceMetadata) ListConnectedApps() (apps ForceConnectedApps, err error
 }
 
 func (fm *ForceMetadata) soapExecute(action, query string) (response []byte, err error) {
-	url := fmt.Sprintf("%s/services/Soap/m/%s", fm.Force.Credentials.InstanceUrl, fm.ApiVersion)
-	soap := NewSoap(url, "http://soap.sforce.com/2006/04/metadata", fm.Force.Credentials.AccessToken)
+	fm.Force.refreshExpiringSession()
+	url := fmt.Sprintf("%s/services/Soap/m/%s", fm.Force.InstanceUrl(), fm.ApiVersion)
+	soap := NewSoap(url, "http://soap.sforce.com/2006/04/metadata", fm.Force.AccessToken())
 	response, err = soap.Execute(action, query)
 	if err == SessionExpiredError {
 		err = fm.Force.RefreshSession()
@@ -0,0 +1,252 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Component diff statuses, from the point of view of pushing the local
+// source to the org.
+const (
+	DiffAdded     = "added"
+	DiffChanged   = "changed"
+	DiffUnchanged = "unchanged"
+	DiffOrgOnly   = "org-only"
+)
+
+// FileDiff compares a local file to the org's version of it.  Diff is a
+// unified diff from the org's version to the local version, after both have
+// been normalized.
+type FileDiff struct {
+	Path   string
+	Status string
+	Diff   string
+}
+
+// ComponentDiff compares the files making up a metadata component.
+type ComponentDiff struct {
+	Type   string
+	Name   string
+	Status string
+	Files  []FileDiff
+}
+
+var manifestFile = regexp.MustCompile(`^(package|destructiveChanges(Pre|Post)?)\.xml$`)
+
+// DiffMetadataFiles compares local metadata files to the files retrieved
+// from the org, grouping the differences by component.  Manifests, such as
+// package.xml, aren't compared.
+func DiffMetadataFiles(local ForceMetadataFiles, org ForceMetadataFiles) []ComponentDiff {
+	localFiles := make(map[string][]byte)
+	for name, data := range local {
+		localFiles[filepath.ToSlash(name)] = data
+	}
+	paths := make(map[string]bool)
+	for name := range localFiles {
+		paths[name] = true
+	}
+	for name := range org {
+		paths[name] = true
+	}
+
+	var keys []string
+	components := make(map[string]*ComponentDiff)
+	for path := range paths {
+		if manifestFile.MatchString(path) {
+			continue
+		}
+		f := FileDiff{Path: path}
+		localData, inLocal := localFiles[path]
+		orgData, inOrg := org[path]
+		f.Diff = diffFileVersions("org/"+path, orgData, inOrg, "local/"+path, localData, inLocal)
+		switch {
+		case !inOrg:
+			f.Status = DiffAdded
+		case !inLocal:
+			f.Status = DiffOrgOnly
+		case f.Diff == "":
+			f.Status = DiffUnchanged
+		default:
+			f.Status = DiffChanged
+		}
+		metadataType, name := componentForPath(path)
+		key := metadataType + "/" + name
+		c, ok := components[key]
+		if !ok {
+			c = &ComponentDiff{Type: metadataType, Name: name}
+			components[key] = c
+			keys = append(keys, key)
+		}
+		c.Files = append(c.Files, f)
+	}
+
+	sort.Strings(keys)
+	var diffs []ComponentDiff
+	for _, key := range keys {
+		c := components[key]
+		sort.Slice(c.Files, func(i, j int) bool { return c.Files[i].Path < c.Files[j].Path })
+		c.Status = c.Files[0].Status
+		for _, f := range c.Files[1:] {
+			if f.Status != c.Status {
+				c.Status = DiffChanged
+			}
+		}
+		diffs = append(diffs, *c)
+	}
+	return diffs
+}
+
+// diffFileVersions returns a unified diff between the normalized versions of
+// a file, or an empty string if they're the same.  A missing version is
+// compared as an empty /dev/null.
+func diffFileVersions(fromName string, from []byte, inFrom bool, toName string, to []byte, inTo bool) string {
+	var fromText, toText string
+	if inFrom {
+		fromText = normalizeMetadata(from)
+	} else {
+		fromName = "/dev/null"
+	}
+	if inTo {
+		toText = normalizeMetadata(to)
+	} else {
+		toName = "/dev/null"
+	}
+	return UnifiedDiff(fromName, toName, fromText, toText)
+}
+
+// componentForPath returns the metadata type and name of the component a
+// file belongs to, based on its slash-separated path within the metadata
+// directory.
+func componentForPath(path string) (metadataType string, name string) {
+	parts := strings.Split(path, "/")
+	metadataType = parts[0]
+	var bundle bool
+	for _, mp := range metapaths {
+		if mp.path == parts[0] {
+			metadataType = mp.name
+			bundle = mp.onlyFolder
+			break
+		}
+	}
+	if len(parts) == 1 {
+		return metadataType, path
+	}
+	if bundle {
+		return metadataType, parts[1]
+	}
+	name = strings.TrimSuffix(strings.Join(parts[1:], "/"), "-meta.xml")
+	return metadataType, strings.TrimSuffix(name, filepath.Ext(name))
+}
+
+// normalizeMetadata returns the contents of a metadata file in a form in
+// which insignificant differences are removed.  XML is re-indented and each
+// element's children are ordered by name, keeping the order of repeated
+// elements, such as picklist values, in which order matters.  Line endings
+// are normalized in other files.
+func normalizeMetadata(data []byte) string {
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("<")) {
+		return text
+	}
+	root, err := parseXmlNode(data)
+	if err != nil {
+		return text
+	}
+	var out strings.Builder
+	out.WriteString(xml.Header)
+	root.write(&out, "")
+	return out.String()
+}
+
+// xmlEscaper escapes text without escaping line breaks, so multi-line values
+// are diffed line by line.
+var xmlEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+
+type xmlNode struct {
+	name     string
+	attrs    []string
+	text     string
+	children []*xmlNode
+}
+
+// xmlName returns a name as written, with its namespace prefix.
+func xmlName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
+
+func parseXmlNode(data []byte) (*xmlNode, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	var stack []*xmlNode
+	var root *xmlNode
+	for {
+		// Raw tokens keep the namespace prefixes as written.
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			n := &xmlNode{name: xmlName(t.Name)}
+			for _, a := range t.Attr {
+				n.attrs = append(n.attrs, xmlName(a.Name)+`="`+xmlEscaper.Replace(a.Value)+`"`)
+			}
+			sort.Strings(n.attrs)
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, n)
+			} else {
+				root = n
+			}
+			stack = append(stack, n)
+		case xml.EndElement:
+			if len(stack) == 0 {
+				return nil, io.ErrUnexpectedEOF
+			}
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text += string(t)
+			}
+		}
+	}
+	if root == nil || len(stack) > 0 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return root, nil
+}
+
+func (n *xmlNode) write(out *strings.Builder, indent string) {
+	out.WriteString(indent + "<" + n.name)
+	for _, a := range n.attrs {
+		out.WriteString(" " + a)
+	}
+	if len(n.children) == 0 {
+		if n.text == "" {
+			out.WriteString("/>\n")
+			return
+		}
+		out.WriteString(">" + xmlEscaper.Replace(n.text) + "</" + n.name + ">\n")
+		return
+	}
+	out.WriteString(">\n")
+	if text := strings.TrimSpace(n.text); text != "" {
+		out.WriteString(indent + "    " + xmlEscaper.Replace(text) + "\n")
+	}
+	children := append([]*xmlNode{}, n.children...)
+	sort.SliceStable(children, func(i, j int) bool { return children[i].name < children[j].name })
+	for _, c := range children {
+		c.write(out, indent+"    ")
+	}
+	out.WriteString(indent + "</" + n.name + ">\n")
+}
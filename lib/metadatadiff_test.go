@@ -0,0 +1,138 @@
+package lib
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	a := "one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\nnine\nten\neleven\n"
+	b := "one\ntwo\nthree\nFOUR\nfive\nsix\nseven\neight\nnine\nten\neleven\ntwelve\n"
+	expected := `--- a
++++ b
+@@ -1,7 +1,7 @@
+ one
+ two
+ three
+-four
++FOUR
+ five
+ six
+ seven
+@@ -9,3 +9,4 @@
+ nine
+ ten
+ eleven
++twelve
+`
+	if diff := UnifiedDiff("a", "b", a, b); diff != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, diff)
+	}
+	if diff := UnifiedDiff("a", "b", a, a); diff != "" {
+		t.Errorf("Expected no diff, got %s", diff)
+	}
+}
+
+func TestUnifiedDiffFromEmpty(t *testing.T) {
+	expected := "--- /dev/null\n+++ b\n@@ -0,0 +1,2 @@\n+one\n+two\n"
+	if diff := UnifiedDiff("/dev/null", "b", "", "one\ntwo\n"); diff != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, diff)
+	}
+}
+
+func TestNormalizeMetadataXml(t *testing.T) {
+	a := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<CustomObject xmlns="http://soap.sforce.com/2006/04/metadata">
+    <label>Book</label>
+    <fields><fullName>Title__c</fullName><type>Text</type></fields>
+    <fields><fullName>Author__c</fullName><type>Text</type></fields>
+</CustomObject>`)
+	b := []byte("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\r\n<CustomObject xmlns=\"http://soap.sforce.com/2006/04/metadata\">\r\n" +
+		"  <fields>\r\n    <type>Text</type>\r\n    <fullName>Title__c</fullName>\r\n  </fields>\r\n" +
+		"  <fields>\r\n    <fullName>Author__c</fullName>\r\n    <type>Text</type>\r\n  </fields>\r\n" +
+		"  <label>Book</label>\r\n</CustomObject>\r\n")
+	if normalizeMetadata(a) != normalizeMetadata(b) {
+		t.Errorf("Expected the same normalized XML, got:\n%s\n%s", normalizeMetadata(a), normalizeMetadata(b))
+	}
+
+	// The order of repeated elements is significant.
+	reordered := []byte(`<CustomObject xmlns="http://soap.sforce.com/2006/04/metadata">
+    <fields><fullName>Author__c</fullName><type>Text</type></fields>
+    <fields><fullName>Title__c</fullName><type>Text</type></fields>
+    <label>Book</label>
+</CustomObject>`)
+	if normalizeMetadata(a) == normalizeMetadata(reordered) {
+		t.Errorf("Expected reordered fields to differ")
+	}
+}
+
+func TestNormalizeMetadataText(t *testing.T) {
+	if text := normalizeMetadata([]byte("public class A {\r\n}\r\n")); text != "public class A {\n}\n" {
+		t.Errorf("Unexpected normalized text: %q", text)
+	}
+}
+
+func TestComponentForPath(t *testing.T) {
+	cases := []struct {
+		path, metadataType, name string
+	}{
+		{"classes/A.cls", "ApexClass", "A"},
+		{"classes/A.cls-meta.xml", "ApexClass", "A"},
+		{"aura/MyCmp/MyCmpController.js", "AuraDefinitionBundle", "MyCmp"},
+		{"reports/Sales/Pipeline.report", "Report", "Sales/Pipeline"},
+	}
+	for _, c := range cases {
+		metadataType, name := componentForPath(c.path)
+		if metadataType != c.metadataType || name != c.name {
+			t.Errorf("%s: expected %s %s, got %s %s", c.path, c.metadataType, c.name, metadataType, name)
+		}
+	}
+}
+
+func TestDiffMetadataFiles(t *testing.T) {
+	local := ForceMetadataFiles{
+		"package.xml":            []byte("<Package/>"),
+		"classes/A.cls":          []byte("public class A {}\n"),
+		"classes/A.cls-meta.xml": []byte("<ApexClass><status>Active</status></ApexClass>"),
+		"classes/B.cls":          []byte("public class B { }\n"),
+		"classes/C.cls":          []byte("public class C {}\n"),
+		"aura/Cmp/Cmp.cmp":       []byte("<aura:component/>"),
+	}
+	org := ForceMetadataFiles{
+		"package.xml":            []byte("<Package><types/></Package>"),
+		"classes/A.cls":          []byte("public class A {}\r\n"),
+		"classes/A.cls-meta.xml": []byte("<ApexClass>\n  <status>Active</status>\n</ApexClass>\n"),
+		"classes/B.cls":          []byte("public class B {}\n"),
+		"aura/Cmp/Cmp.cmp":       []byte("<aura:component/>"),
+		"aura/Cmp/CmpHelper.js":  []byte("({})"),
+	}
+	diffs := DiffMetadataFiles(local, org)
+	statuses := make(map[string]string)
+	for _, d := range diffs {
+		statuses[d.Type+" "+d.Name] = d.Status
+	}
+	expected := map[string]string{
+		"ApexClass A":              DiffUnchanged,
+		"ApexClass B":              DiffChanged,
+		"ApexClass C":              DiffAdded,
+		"AuraDefinitionBundle Cmp": DiffChanged,
+	}
+	if len(statuses) != len(expected) {
+		t.Errorf("Expected %v, got %v", expected, statuses)
+	}
+	for component, status := range expected {
+		if statuses[component] != status {
+			t.Errorf("Expected %s to be %s, got %s", component, status, statuses[component])
+		}
+	}
+	for _, d := range diffs {
+		if d.Type == "AuraDefinitionBundle" {
+			if len(d.Files) != 2 || d.Files[1].Status != DiffOrgOnly {
+				t.Errorf("Expected the helper to be org-only: %+v", d.Files)
+			}
+		}
+		if d.Name == "B" && !strings.Contains(d.Files[0].Diff, "-public class B {}\n+public class B { }\n") {
+			t.Errorf("Unexpected diff: %s", d.Files[0].Diff)
+		}
+	}
+}
@@ -0,0 +1,297 @@
+package lib
+
+import (
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DiffTargetOnly is the status of a component found only in the target org
+// of a comparison.  Components only in the source org are DiffAdded.
+const DiffTargetOnly = "target-only"
+
+// folderListTypes maps foldered metadata types to the types listMetadata
+// uses for their folders.
+var folderListTypes = map[string]string{
+	"Dashboard":     "DashboardFolder",
+	"Document":      "DocumentFolder",
+	"EmailTemplate": "EmailFolder",
+	"Report":        "ReportFolder",
+}
+
+// ComponentComparison compares a component in two orgs.  Source and Target
+// are nil if the component isn't in that org.  Files are unified diffs from
+// the target's version to the source's version.
+type ComponentComparison struct {
+	Type     string
+	FullName string
+	Status   string
+	Source   *MDFileProperties
+	Target   *MDFileProperties
+	Files    []FileDiff
+}
+
+// OrgComparison is the result of comparing the metadata in two orgs.
+type OrgComparison struct {
+	Components []ComponentComparison
+	// Problems reported while retrieving, prefixed with source: or target:
+	Problems []string
+}
+
+// Different reports whether the orgs' metadata differs.
+func (c OrgComparison) Different() bool {
+	for _, component := range c.Components {
+		if component.Status != DiffUnchanged {
+			return true
+		}
+	}
+	return false
+}
+
+// ListMetadataInventory lists the components of each type.  Components of
+// foldered types, such as reports, are listed folder by folder, along with
+// the folders.  Components installed from managed packages are left out.
+func (fm *ForceMetadata) ListMetadataInventory(types []string) ([]MDFileProperties, error) {
+	var queries, folderQueries []listQuery
+	for _, t := range types {
+		if folderType, ok := folderListTypes[t]; ok {
+			folderQueries = append(folderQueries, listQuery{Type: folderType})
+		} else {
+			queries = append(queries, listQuery{Type: t})
+		}
+	}
+	folders, err := fm.listMetadataQueries(folderQueries)
+	if err != nil {
+		return nil, err
+	}
+	var inventory []MDFileProperties
+	for _, folder := range folders {
+		for t, folderType := range folderListTypes {
+			if folder.Type == folderType {
+				// Folders are members of the type they hold.
+				folder.Type = t
+				queries = append(queries, listQuery{Type: t, Folder: folder.FullName})
+			}
+		}
+		inventory = append(inventory, folder)
+	}
+	components, err := fm.listMetadataQueries(queries)
+	if err != nil {
+		return nil, err
+	}
+	inventory = append(inventory, components...)
+
+	var unmanaged []MDFileProperties
+	for _, p := range inventory {
+		if !strings.HasPrefix(p.ManageableState, "installed") {
+			unmanaged = append(unmanaged, p)
+		}
+	}
+	return unmanaged, nil
+}
+
+// listMetadataQueries runs the queries, several per listMetadata call.
+func (fm *ForceMetadata) listMetadataQueries(queries []listQuery) ([]MDFileProperties, error) {
+	var properties []MDFileProperties
+	for start := 0; start < len(queries); start += listMetadataBatchSize {
+		end := min(start+listMetadataBatchSize, len(queries))
+		batch, err := fm.listMetadataBatch(queries[start:end])
+		if err != nil {
+			return nil, err
+		}
+		properties = append(properties, batch...)
+	}
+	return properties, nil
+}
+
+type metadataSnapshot struct {
+	inventory []MDFileProperties
+	files     ForceMetadataFiles
+	problems  []string
+}
+
+// metadataSnapshot lists the components of each type and retrieves them.
+func (f *Force) metadataSnapshot(types []string) (snapshot metadataSnapshot, err error) {
+	snapshot.inventory, err = f.Metadata.ListMetadataInventory(types)
+	if err != nil {
+		return
+	}
+	snapshot.files = make(ForceMetadataFiles)
+	if len(snapshot.inventory) == 0 {
+		return
+	}
+	members := make(map[string][]string)
+	for _, p := range snapshot.inventory {
+		members[p.Type] = append(members[p.Type], p.FullName)
+	}
+	var query ForceMetadataQuery
+	for _, t := range sortedKeys(members) {
+		query = append(query, ForceMetadataQueryElement{Name: []string{t}, Members: members[t]})
+	}
+	snapshot.problems, err = f.Metadata.RetrieveTo(query, collectRetrievedFiles(snapshot.files), RetrieveOptions{})
+	return
+}
+
+// CompareOrgs compares the components of the given types in the source and
+// target orgs, listing and retrieving them from both orgs concurrently.
+// Components are compared by the contents of their files.  Components that
+// share a file, such as the fields of an object, are compared together.
+func CompareOrgs(source *Force, target *Force, types []string) (comparison OrgComparison, err error) {
+	var sourceSnapshot, targetSnapshot metadataSnapshot
+	var sourceErr, targetErr error
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		sourceSnapshot, sourceErr = source.metadataSnapshot(types)
+	}()
+	go func() {
+		defer wg.Done()
+		targetSnapshot, targetErr = target.metadataSnapshot(types)
+	}()
+	wg.Wait()
+	if sourceErr != nil {
+		return comparison, fmt.Errorf("Could not get metadata from source org: %w", sourceErr)
+	}
+	if targetErr != nil {
+		return comparison, fmt.Errorf("Could not get metadata from target org: %w", targetErr)
+	}
+	for _, problem := range sourceSnapshot.problems {
+		comparison.Problems = append(comparison.Problems, "source: "+problem)
+	}
+	for _, problem := range targetSnapshot.problems {
+		comparison.Problems = append(comparison.Problems, "target: "+problem)
+	}
+	comparison.Components = compareSnapshots(sourceSnapshot, targetSnapshot)
+	return comparison, nil
+}
+
+func compareSnapshots(source metadataSnapshot, target metadataSnapshot) []ComponentComparison {
+	components := make(map[string]*ComponentComparison)
+	var keys []string
+	add := func(p MDFileProperties, isSource bool) {
+		key := p.Type + "/" + p.FullName
+		c, ok := components[key]
+		if !ok {
+			c = &ComponentComparison{Type: p.Type, FullName: p.FullName}
+			components[key] = c
+			keys = append(keys, key)
+		}
+		if isSource {
+			c.Source = &p
+		} else {
+			c.Target = &p
+		}
+	}
+	for _, p := range source.inventory {
+		add(p, true)
+	}
+	for _, p := range target.inventory {
+		add(p, false)
+	}
+
+	sourceFiles := componentFileIndex(source.files)
+	targetFiles := componentFileIndex(target.files)
+	sort.Strings(keys)
+	var comparisons []ComponentComparison
+	for _, key := range keys {
+		c := components[key]
+		switch {
+		case c.Target == nil:
+			c.Status = DiffAdded
+		case c.Source == nil:
+			c.Status = DiffTargetOnly
+		default:
+			c.Status = DiffUnchanged
+			paths := make(map[string]bool)
+			for _, path := range sourceFiles[c.Source.FileName] {
+				paths[path] = true
+			}
+			for _, path := range targetFiles[c.Target.FileName] {
+				paths[path] = true
+			}
+			for _, path := range sortedKeys(paths) {
+				sourceData, inSource := source.files[path]
+				targetData, inTarget := target.files[path]
+				diff := diffFileVersions("target/"+path, targetData, inTarget, "source/"+path, sourceData, inSource)
+				if diff == "" {
+					continue
+				}
+				c.Status = DiffChanged
+				f := FileDiff{Path: path, Status: DiffChanged, Diff: diff}
+				if !inTarget {
+					f.Status = DiffAdded
+				} else if !inSource {
+					f.Status = DiffTargetOnly
+				}
+				c.Files = append(c.Files, f)
+			}
+		}
+		comparisons = append(comparisons, *c)
+	}
+	return comparisons
+}
+
+// componentFileIndex maps the file names listMetadata reports for
+// components to the retrieved files that make them up: the file itself, its
+// -meta.xml file, and, for bundles, the files in its directory.
+func componentFileIndex(files ForceMetadataFiles) map[string][]string {
+	index := make(map[string][]string)
+	for path := range files {
+		keys := map[string]bool{path: true, strings.TrimSuffix(path, "-meta.xml"): true}
+		if parts := strings.SplitN(path, "/", 3); len(parts) == 3 {
+			keys[parts[0]+"/"+parts[1]] = true
+		}
+		for key := range keys {
+			index[key] = append(index[key], path)
+		}
+	}
+	return index
+}
+
+// PromotionManifests returns a package.xml of the components to deploy from
+// the source org, and a destructiveChanges.xml of the components to delete
+// from the target org, which together would make the target match the
+// source.  destructiveChanges is nil if nothing needs to be deleted.
+func (c OrgComparison) PromotionManifests() (packageXml []byte, destructiveChanges []byte, err error) {
+	deploy := make(map[string][]string)
+	destroy := make(map[string][]string)
+	for _, component := range c.Components {
+		switch component.Status {
+		case DiffAdded, DiffChanged:
+			deploy[component.Type] = append(deploy[component.Type], component.FullName)
+		case DiffTargetOnly:
+			destroy[component.Type] = append(destroy[component.Type], component.FullName)
+		}
+	}
+	packageXml, err = manifestXml(deploy)
+	if err != nil || len(destroy) == 0 {
+		return
+	}
+	destructiveChanges, err = manifestXml(destroy)
+	return
+}
+
+func manifestXml(members map[string][]string) ([]byte, error) {
+	p := createPackage()
+	for _, name := range sortedKeys(members) {
+		sort.Strings(members[name])
+		p.Types = append(p.Types, MetaType{Name: name, Members: members[name]})
+	}
+	data, err := xml.MarshalIndent(p, "", "    ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,138 @@
+package lib
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestCompareSnapshots(t *testing.T) {
+	source := metadataSnapshot{
+		inventory: []MDFileProperties{
+			{Type: "ApexClass", FullName: "A", FileName: "classes/A.cls", LastModifiedByName: "Jane"},
+			{Type: "ApexClass", FullName: "B", FileName: "classes/B.cls"},
+			{Type: "ApexClass", FullName: "C", FileName: "classes/C.cls"},
+			{Type: "AuraDefinitionBundle", FullName: "Cmp", FileName: "aura/Cmp"},
+		},
+		files: ForceMetadataFiles{
+			"classes/A.cls":          []byte("public class A { }"),
+			"classes/A.cls-meta.xml": []byte("<ApexClass/>"),
+			"classes/B.cls":          []byte("public class B {}"),
+			"classes/C.cls":          []byte("public class C {}"),
+			"aura/Cmp/Cmp.cmp":       []byte("<aura:component/>"),
+		},
+	}
+	target := metadataSnapshot{
+		inventory: []MDFileProperties{
+			{Type: "ApexClass", FullName: "A", FileName: "classes/A.cls", LastModifiedByName: "Bob"},
+			{Type: "ApexClass", FullName: "B", FileName: "classes/B.cls"},
+			{Type: "ApexClass", FullName: "D", FileName: "classes/D.cls"},
+			{Type: "AuraDefinitionBundle", FullName: "Cmp", FileName: "aura/Cmp"},
+		},
+		files: ForceMetadataFiles{
+			"classes/A.cls":          []byte("public class A {}"),
+			"classes/A.cls-meta.xml": []byte("<ApexClass/>"),
+			"classes/B.cls":          []byte("public class B {}"),
+			"classes/D.cls":          []byte("public class D {}"),
+			"aura/Cmp/Cmp.cmp":       []byte("<aura:component/>"),
+			"aura/Cmp/CmpHelper.js":  []byte("({})"),
+		},
+	}
+	comparisons := compareSnapshots(source, target)
+	statuses := make(map[string]string)
+	for _, c := range comparisons {
+		statuses[c.Type+" "+c.FullName] = c.Status
+	}
+	expected := map[string]string{
+		"ApexClass A":              DiffChanged,
+		"ApexClass B":              DiffUnchanged,
+		"ApexClass C":              DiffAdded,
+		"ApexClass D":              DiffTargetOnly,
+		"AuraDefinitionBundle Cmp": DiffChanged,
+	}
+	if !reflect.DeepEqual(statuses, expected) {
+		t.Errorf("Expected %v, got %v", expected, statuses)
+	}
+	a := comparisons[0]
+	if a.Source.LastModifiedByName != "Jane" || a.Target.LastModifiedByName != "Bob" {
+		t.Errorf("Expected file properties from both orgs, got %+v", a)
+	}
+	if len(a.Files) != 1 || a.Files[0].Path != "classes/A.cls" {
+		t.Errorf("Expected only A.cls to differ, got %+v", a.Files)
+	}
+	cmp := comparisons[4]
+	if len(cmp.Files) != 1 || cmp.Files[0].Status != DiffTargetOnly {
+		t.Errorf("Expected the helper to be target-only, got %+v", cmp.Files)
+	}
+}
+
+func TestPromotionManifests(t *testing.T) {
+	comparison := OrgComparison{Components: []ComponentComparison{
+		{Type: "ApexClass", FullName: "B", Status: DiffChanged},
+		{Type: "ApexClass", FullName: "A", Status: DiffAdded},
+		{Type: "ApexClass", FullName: "C", Status: DiffUnchanged},
+		{Type: "CustomObject", FullName: "Old__c", Status: DiffTargetOnly},
+	}}
+	packageXml, destructiveChanges, err := comparison.PromotionManifests()
+	if err != nil {
+		t.Fatalf("PromotionManifests returned error: %v", err)
+	}
+	var p, d Package
+	if err := xml.Unmarshal(packageXml, &p); err != nil {
+		t.Fatalf("Invalid package.xml: %v", err)
+	}
+	if err := xml.Unmarshal(destructiveChanges, &d); err != nil {
+		t.Fatalf("Invalid destructiveChanges.xml: %v", err)
+	}
+	if expected := []MetaType{{Name: "ApexClass", Members: []string{"A", "B"}}}; !reflect.DeepEqual(p.Types, expected) {
+		t.Errorf("Expected package types %v, got %v", expected, p.Types)
+	}
+	if expected := []MetaType{{Name: "CustomObject", Members: []string{"Old__c"}}}; !reflect.DeepEqual(d.Types, expected) {
+		t.Errorf("Expected destructive types %v, got %v", expected, d.Types)
+	}
+
+	comparison.Components = comparison.Components[:3]
+	if _, destructiveChanges, _ := comparison.PromotionManifests(); destructiveChanges != nil {
+		t.Errorf("Expected no destructiveChanges.xml when nothing is deleted")
+	}
+}
+
+func TestListMetadataInventory(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		// Several queries are batched in each call.
+		var results string
+		if strings.Contains(string(body), "<type>ReportFolder</type>") {
+			results += `<result><fullName>Sales</fullName><fileName>reports/Sales</fileName><type>ReportFolder</type></result>`
+		}
+		if strings.Contains(string(body), "<folder>Sales</folder>") {
+			results += `<result><fullName>Sales/Pipeline</fullName><fileName>reports/Sales/Pipeline.report</fileName><type>Report</type></result>`
+		}
+		if strings.Contains(string(body), "<type>ApexClass</type>") {
+			results += `<result><fullName>A</fullName><fileName>classes/A.cls</fileName><type>ApexClass</type><manageableState>unmanaged</manageableState></result>` +
+				`<result><fullName>pkg__B</fullName><fileName>classes/pkg__B.cls</fileName><type>ApexClass</type><manageableState>installed</manageableState></result>`
+		}
+		w.Write([]byte(`<Envelope><Body><listMetadataResponse>` + results + `</listMetadataResponse></Body></Envelope>`))
+	}))
+	defer server.Close()
+
+	force := NewForce(&ForceSession{InstanceUrl: server.URL, AccessToken: "test-token"})
+	inventory, err := force.Metadata.ListMetadataInventory([]string{"ApexClass", "Report"})
+	if err != nil {
+		t.Fatalf("ListMetadataInventory returned error: %v", err)
+	}
+	var names []string
+	for _, p := range inventory {
+		names = append(names, p.Type+" "+p.FullName)
+	}
+	sort.Strings(names)
+	expected := []string{"ApexClass A", "Report Sales", "Report Sales/Pipeline"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected %v, got %v", expected, names)
+	}
+}
@@ -0,0 +1,415 @@
+package lib
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/ForceCLI/force/config"
+	"gopkg.in/yaml.v3"
+)
+
+var OrgSetupPlanEmptyError = errors.New("Plan has no steps")
+
+// Org setup step types
+const (
+	OrgSetupPackage        = "package"
+	OrgSetupDeploy         = "deploy"
+	OrgSetupPermissionSets = "permissionsets"
+	OrgSetupData           = "data"
+	OrgSetupApex           = "apex"
+)
+
+// OrgSetupPlan is a declarative list of steps used to prepare an org, read
+// from a YAML file, e.g.
+//
+//	name: dev-org
+//	steps:
+//	  - name: base-package
+//	    type: package
+//	    namespace: acme
+//	    version: "2.1"
+//	  - name: source
+//	    type: deploy
+//	    path: src
+//	    dependsOn: [base-package]
+//	  - name: perms
+//	    type: permissionsets
+//	    permissionSets: [Acme_Admin]
+//	    dependsOn: [source]
+//	  - name: accounts
+//	    type: data
+//	    object: Account
+//	    operation: upsert
+//	    externalId: Acme_Id__c
+//	    file: data/accounts.csv
+//	    dependsOn: [source]
+//	  - name: init
+//	    type: apex
+//	    file: scripts/init.apex
+//	    dependsOn: [accounts, perms]
+//
+// Paths are relative to the plan file.
+type OrgSetupPlan struct {
+	Name  string         `yaml:"name" json:"name,omitempty"`
+	Steps []OrgSetupStep `yaml:"steps" json:"steps"`
+
+	dir string
+}
+
+type OrgSetupStep struct {
+	Name      string   `yaml:"name" json:"name"`
+	Type      string   `yaml:"type" json:"type"`
+	DependsOn []string `yaml:"dependsOn" json:"dependsOn,omitempty"`
+
+	// package
+	Namespace   string `yaml:"namespace" json:"namespace,omitempty"`
+	Version     string `yaml:"version" json:"version,omitempty"`
+	Id          string `yaml:"id" json:"id,omitempty"`
+	Password    string `yaml:"password" json:"password,omitempty"`
+	ActivateRSS bool   `yaml:"activateRSS" json:"activateRSS,omitempty"`
+
+	// deploy
+	Path string `yaml:"path" json:"path,omitempty"`
+
+	// permissionsets
+	PermissionSets []string `yaml:"permissionSets" json:"permissionSets,omitempty"`
+	Username       string   `yaml:"username" json:"username,omitempty"`
+
+	// data
+	Object     string `yaml:"object" json:"object,omitempty"`
+	Operation  string `yaml:"operation" json:"operation,omitempty"`
+	ExternalId string `yaml:"externalId" json:"externalId,omitempty"`
+
+	// data and apex
+	File string `yaml:"file" json:"file,omitempty"`
+	Code string `yaml:"code" json:"code,omitempty"`
+}
+
+// LoadOrgSetupPlan reads and validates a plan file.  If the plan has no name,
+// the file name is used.
+func LoadOrgSetupPlan(path string) (plan OrgSetupPlan, err error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+	if err = yaml.Unmarshal(data, &plan); err != nil {
+		err = fmt.Errorf("Could not parse plan %s: %w", path, err)
+		return
+	}
+	plan.dir = filepath.Dir(path)
+	if plan.Name == "" {
+		plan.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	if err = plan.validate(); err != nil {
+		return
+	}
+	_, err = plan.Order()
+	return
+}
+
+func (p OrgSetupPlan) path(name string) string {
+	if filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(p.dir, name)
+}
+
+func (p OrgSetupPlan) validate() error {
+	if len(p.Steps) == 0 {
+		return OrgSetupPlanEmptyError
+	}
+	seen := make(map[string]bool)
+	for i, s := range p.Steps {
+		if s.Name == "" {
+			return fmt.Errorf("Step %d has no name", i+1)
+		}
+		if seen[s.Name] {
+			return fmt.Errorf("Duplicate step name: %s", s.Name)
+		}
+		seen[s.Name] = true
+		var missing string
+		switch s.Type {
+		case OrgSetupPackage:
+			if s.Id == "" && (s.Namespace == "" || s.Version == "") {
+				missing = "id, or namespace and version"
+			}
+		case OrgSetupDeploy:
+			if s.Path == "" {
+				missing = "path"
+			}
+		case OrgSetupPermissionSets:
+			if len(s.PermissionSets) == 0 {
+				missing = "permissionSets"
+			}
+		case OrgSetupData:
+			if s.Object == "" || s.File == "" {
+				missing = "object and file"
+			}
+		case OrgSetupApex:
+			if s.File == "" && s.Code == "" {
+				missing = "file or code"
+			}
+		default:
+			return fmt.Errorf("Step %s has unknown type %q", s.Name, s.Type)
+		}
+		if missing != "" {
+			return fmt.Errorf("Step %s requires %s", s.Name, missing)
+		}
+	}
+	return nil
+}
+
+// Order returns the steps in the order they will run: each step runs after
+// the steps it depends on, and otherwise in the order they're listed.
+func (p OrgSetupPlan) Order() ([]OrgSetupStep, error) {
+	index := make(map[string]int)
+	for i, s := range p.Steps {
+		index[s.Name] = i
+	}
+	for _, s := range p.Steps {
+		for _, dep := range s.DependsOn {
+			if _, ok := index[dep]; !ok {
+				return nil, fmt.Errorf("Step %s depends on unknown step %s", s.Name, dep)
+			}
+		}
+	}
+	var ordered []OrgSetupStep
+	placed := make(map[string]bool)
+	for len(ordered) < len(p.Steps) {
+		progress := false
+		for _, s := range p.Steps {
+			if placed[s.Name] {
+				continue
+			}
+			ready := true
+			for _, dep := range s.DependsOn {
+				if !placed[dep] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				ordered = append(ordered, s)
+				placed[s.Name] = true
+				progress = true
+				break
+			}
+		}
+		if !progress {
+			var cycle []string
+			for _, s := range p.Steps {
+				if !placed[s.Name] {
+					cycle = append(cycle, s.Name)
+				}
+			}
+			return nil, fmt.Errorf("Steps have circular dependencies: %s", strings.Join(cycle, ", "))
+		}
+	}
+	return ordered, nil
+}
+
+// Fingerprint identifies the step's configuration, so a completed step is
+// run again if it changes.
+func (s OrgSetupStep) Fingerprint() string {
+	data, _ := json.Marshal(s)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// OrgSetupState records the steps of a plan that have finished in an org.
+type OrgSetupState struct {
+	Completed map[string]OrgSetupStepState `json:"completed"`
+}
+
+type OrgSetupStepState struct {
+	Fingerprint string        `json:"fingerprint"`
+	FinishedAt  time.Time     `json:"finishedAt"`
+	Duration    time.Duration `json:"duration"`
+}
+
+func (f *Force) orgSetupStateDir() string {
+	key := f.describeCacheOrgId()
+	if key == "" {
+		if u, err := url.Parse(f.InstanceUrl()); err == nil {
+			key = u.Host
+		}
+	}
+	return filepath.Join("org-setup", key)
+}
+
+// LoadOrgSetupState returns the saved state of plan in the org.
+func (f *Force) LoadOrgSetupState(plan OrgSetupPlan) (state OrgSetupState) {
+	if data, err := Config.Load(f.orgSetupStateDir(), plan.Name+".json"); err == nil {
+		json.Unmarshal([]byte(data), &state)
+	}
+	if state.Completed == nil {
+		state.Completed = make(map[string]OrgSetupStepState)
+	}
+	return
+}
+
+func (f *Force) saveOrgSetupState(plan OrgSetupPlan, state OrgSetupState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return Config.Save(f.orgSetupStateDir(), plan.Name+".json", string(data))
+}
+
+// ClearOrgSetupState removes the saved state of plan in the org, so all
+// steps run again.
+func (f *Force) ClearOrgSetupState(plan OrgSetupPlan) error {
+	return Config.Delete(f.orgSetupStateDir(), plan.Name+".json")
+}
+
+// Org setup step results
+const (
+	OrgSetupStepDone    = "done"
+	OrgSetupStepSkipped = "skipped"
+	OrgSetupStepFailed  = "failed"
+	OrgSetupStepNotRun  = "not run"
+)
+
+type OrgSetupResult struct {
+	Step     string
+	Type     string
+	Status   string
+	Duration time.Duration
+	Error    error
+}
+
+// RunOrgSetupPlan runs the steps of plan that haven't already finished in the
+// org, saving progress after each step so a failed run can be resumed.
+// started, if not nil, is called before each step is run.  A result is
+// returned for every step, in the order they ran.
+func (f *Force) RunOrgSetupPlan(plan OrgSetupPlan, started func(OrgSetupStep)) ([]OrgSetupResult, error) {
+	steps, err := plan.Order()
+	if err != nil {
+		return nil, err
+	}
+	state := f.LoadOrgSetupState(plan)
+	var results []OrgSetupResult
+	var failure error
+	for _, step := range steps {
+		result := OrgSetupResult{Step: step.Name, Type: step.Type}
+		if failure != nil {
+			result.Status = OrgSetupStepNotRun
+			results = append(results, result)
+			continue
+		}
+		if done, ok := state.Completed[step.Name]; ok && done.Fingerprint == step.Fingerprint() {
+			result.Status = OrgSetupStepSkipped
+			results = append(results, result)
+			continue
+		}
+		if started != nil {
+			started(step)
+		}
+		start := time.Now()
+		err := f.runOrgSetupStep(plan, step)
+		result.Duration = time.Since(start)
+		if err != nil {
+			result.Status = OrgSetupStepFailed
+			result.Error = err
+			failure = fmt.Errorf("Step %s failed: %w", step.Name, err)
+		} else {
+			result.Status = OrgSetupStepDone
+			state.Completed[step.Name] = OrgSetupStepState{
+				Fingerprint: step.Fingerprint(),
+				FinishedAt:  time.Now(),
+				Duration:    result.Duration,
+			}
+			if err := f.saveOrgSetupState(plan, state); err != nil {
+				Log.Info("Failed to save org setup state: " + err.Error())
+			}
+		}
+		results = append(results, result)
+	}
+	return results, failure
+}
+
+var orgSetupPollInterval = 2 * time.Second
+
+func (f *Force) runOrgSetupStep(plan OrgSetupPlan, step OrgSetupStep) error {
+	switch step.Type {
+	case OrgSetupPackage:
+		if step.Id != "" {
+			return f.InstallPackageVersion(step.Id, step.Password, step.ActivateRSS, nil)
+		}
+		return f.Metadata.InstallPackageWithRSS(step.Namespace, step.Version, step.Password, step.ActivateRSS)
+	case OrgSetupDeploy:
+		files, err := ReadMetadataDirectory(plan.path(step.Path))
+		if err != nil {
+			return err
+		}
+		return f.DeployMetadataFiles(files)
+	case OrgSetupPermissionSets:
+		_, err := f.AssignPermissionSets(step.Username, step.PermissionSets)
+		return err
+	case OrgSetupData:
+		return f.runOrgSetupDataLoad(plan, step)
+	case OrgSetupApex:
+		code := step.Code
+		if step.File != "" {
+			data, err := ioutil.ReadFile(plan.path(step.File))
+			if err != nil {
+				return err
+			}
+			code = string(data)
+		}
+		_, err := f.Partner.ExecuteAnonymous(code)
+		return err
+	}
+	return fmt.Errorf("Unknown step type %q", step.Type)
+}
+
+func (f *Force) runOrgSetupDataLoad(plan OrgSetupPlan, step OrgSetupStep) error {
+	file, err := os.Open(plan.path(step.File))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	operation := Bulk2OperationInsert
+	if step.Operation != "" {
+		operation = Bulk2Operation(step.Operation)
+	}
+	job, err := f.CreateBulk2IngestJob(Bulk2IngestJobRequest{
+		Object:              step.Object,
+		Operation:           operation,
+		ExternalIdFieldName: step.ExternalId,
+	})
+	if err != nil {
+		return err
+	}
+	if err = f.UploadBulk2JobData(job.Id, file); err != nil {
+		f.AbortBulk2IngestJob(job.Id)
+		return err
+	}
+	if _, err = f.CloseBulk2IngestJob(job.Id); err != nil {
+		f.AbortBulk2IngestJob(job.Id)
+		return err
+	}
+	job, err = f.WaitForBulk2IngestJob(job.Id, orgSetupPollInterval, nil)
+	if err != nil {
+		return err
+	}
+	if job.State != Bulk2JobStateJobComplete {
+		if job.ErrorMessage != "" {
+			return fmt.Errorf("Bulk job %s %s: %s", job.Id, strings.ToLower(string(job.State)), job.ErrorMessage)
+		}
+		return fmt.Errorf("Bulk job %s %s", job.Id, strings.ToLower(string(job.State)))
+	}
+	if job.NumberRecordsFailed > 0 {
+		return fmt.Errorf("%d of %d records failed; see force bulk2 results %s", job.NumberRecordsFailed, job.NumberRecordsProcessed, job.Id)
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package lib
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeOrgSetupPlan(t *testing.T, plan string) string {
+	path := filepath.Join(t.TempDir(), "setup.yaml")
+	if err := ioutil.WriteFile(path, []byte(plan), 0644); err != nil {
+		t.Fatalf("failed to write plan: %v", err)
+	}
+	return path
+}
+
+func TestLoadOrgSetupPlanOrdersByDependencies(t *testing.T) {
+	path := writeOrgSetupPlan(t, `
+steps:
+  - name: init
+    type: apex
+    code: "System.debug(1);"
+    dependsOn: [source, perms]
+  - name: perms
+    type: permissionsets
+    permissionSets: [Admin]
+    dependsOn: [source]
+  - name: source
+    type: deploy
+    path: src
+  - name: pkg
+    type: package
+    id: 04t000000000001
+`)
+	plan, err := LoadOrgSetupPlan(path)
+	if err != nil {
+		t.Fatalf("LoadOrgSetupPlan returned error: %v", err)
+	}
+	if plan.Name != "setup" {
+		t.Errorf("Expected plan name from file name, got %q", plan.Name)
+	}
+	if plan.path("src") != filepath.Join(filepath.Dir(path), "src") {
+		t.Errorf("Expected path relative to plan, got %s", plan.path("src"))
+	}
+	steps, err := plan.Order()
+	if err != nil {
+		t.Fatalf("Order returned error: %v", err)
+	}
+	var names []string
+	for _, s := range steps {
+		names = append(names, s.Name)
+	}
+	if got := strings.Join(names, ","); got != "source,perms,init,pkg" {
+		t.Errorf("Unexpected order: %s", got)
+	}
+}
+
+func TestLoadOrgSetupPlanErrors(t *testing.T) {
+	cases := map[string]string{
+		"circular dependencies": `
+steps:
+  - {name: a, type: apex, code: x, dependsOn: [b]}
+  - {name: b, type: apex, code: x, dependsOn: [a]}
+`,
+		"unknown step": `
+steps:
+  - {name: a, type: apex, code: x, dependsOn: [missing]}
+`,
+		"Duplicate step": `
+steps:
+  - {name: a, type: apex, code: x}
+  - {name: a, type: apex, code: y}
+`,
+		"unknown type": `
+steps:
+  - {name: a, type: shell}
+`,
+		"requires object and file": `
+steps:
+  - {name: a, type: data, object: Account}
+`,
+		"no steps": `name: empty`,
+	}
+	for expected, plan := range cases {
+		_, err := LoadOrgSetupPlan(writeOrgSetupPlan(t, plan))
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected error containing %q, got %v", expected, err)
+		}
+	}
+}
+
+func TestRunOrgSetupPlanResumesAfterFailure(t *testing.T) {
+	cleanup := setupTestConfig(t)
+	defer cleanup()
+
+	var executed []string
+	failSecond := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		compiled := "true"
+		for _, step := range []string{"first", "second", "third"} {
+			if strings.Contains(string(body), step) {
+				executed = append(executed, step)
+				if step == "second" && failSecond {
+					compiled = "false"
+				}
+			}
+		}
+		w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/"><soapenv:Body><executeAnonymousResponse><result>
+<compiled>` + compiled + `</compiled><compileProblem>Unexpected token</compileProblem><success>` + compiled + `</success>
+</result></executeAnonymousResponse></soapenv:Body></soapenv:Envelope>`))
+	}))
+	defer server.Close()
+
+	force := NewForce(&ForceSession{
+		InstanceUrl:    server.URL,
+		AccessToken:    "test-token",
+		UserInfo:       &UserInfo{OrgId: "00D000000000001"},
+		SessionOptions: &SessionOptions{ApiVersion: "60.0"},
+	})
+	plan, err := LoadOrgSetupPlan(writeOrgSetupPlan(t, `
+name: dev
+steps:
+  - {name: first, type: apex, code: "System.debug('first');"}
+  - {name: second, type: apex, code: "System.debug('second');", dependsOn: [first]}
+  - {name: third, type: apex, code: "System.debug('third');", dependsOn: [second]}
+`))
+	if err != nil {
+		t.Fatalf("LoadOrgSetupPlan returned error: %v", err)
+	}
+
+	results, err := force.RunOrgSetupPlan(plan, nil)
+	if err == nil {
+		t.Fatalf("Expected second step to fail")
+	}
+	statuses := func() string {
+		var s []string
+		for _, r := range results {
+			s = append(s, r.Status)
+		}
+		return strings.Join(s, ",")
+	}
+	if got := statuses(); got != "done,failed,not run" {
+		t.Errorf("Unexpected statuses: %s", got)
+	}
+
+	failSecond = false
+	executed = nil
+	results, err = force.RunOrgSetupPlan(plan, nil)
+	if err != nil {
+		t.Fatalf("RunOrgSetupPlan returned error: %v", err)
+	}
+	if got := statuses(); got != "skipped,done,done" {
+		t.Errorf("Unexpected statuses on resume: %s", got)
+	}
+	if got := strings.Join(executed, ","); got != "second,third" {
+		t.Errorf("Unexpected steps executed on resume: %s", got)
+	}
+
+	if err := force.ClearOrgSetupState(plan); err != nil {
+		t.Fatalf("ClearOrgSetupState returned error: %v", err)
+	}
+	if state := force.LoadOrgSetupState(plan); len(state.Completed) != 0 {
+		t.Errorf("Expected cleared state, got %+v", state.Completed)
+	}
+}
@@ -0,0 +1,165 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var packageInstallPollInterval = 5 * time.Second
+var packageInstallMaxWait = 20 * time.Minute
+
+// ErrPackageRequestNotFound is reported when a status check doesn't find the
+// package install or uninstall request.
+var ErrPackageRequestNotFound = errors.New("No records found")
+
+// PackageRequestProgress reports a step of a package install or uninstall
+// request: its submission, a failed status check (Err), or its current
+// Status.
+type PackageRequestProgress struct {
+	RequestId string
+	Submitted bool
+	Status    string
+	Err       error
+}
+
+// reportPackageRequest calls progress, if not nil, with p.
+func reportPackageRequest(progress func(PackageRequestProgress), p PackageRequestProgress) {
+	if progress != nil {
+		progress(p)
+	}
+}
+
+// InstallPackageVersion installs a package version by its subscriber package
+// version id (04t) and waits for the installation to finish.  progress, if
+// not nil, is called when the request is submitted and each time its status
+// is checked.
+func (f *Force) InstallPackageVersion(packageVersionId string, password string, activateRSS bool, progress func(PackageRequestProgress)) error {
+	if !strings.HasPrefix(packageVersionId, "04t") {
+		return errors.New("Invalid package version ID. Must be a Subscriber Package Version ID (04t)")
+	}
+	attrs := map[string]string{
+		"SubscriberPackageVersionKey": packageVersionId,
+		"EnableRss":                   fmt.Sprintf("%t", activateRSS),
+		"NameConflictResolution":      "Block",
+		"SecurityType":                "None",
+	}
+	if password != "" {
+		attrs["Password"] = password
+	}
+	result, err := f.CreateToolingRecord("PackageInstallRequest", attrs)
+	if err != nil {
+		return fmt.Errorf("Failed to create package install request: %w", err)
+	}
+	if result.Id == "" {
+		return errors.New("Failed to get request ID from response")
+	}
+	reportPackageRequest(progress, PackageRequestProgress{RequestId: result.Id, Submitted: true})
+	return f.WaitForPackageInstall(result.Id, progress)
+}
+
+// WaitForPackageInstall polls a PackageInstallRequest until it succeeds or
+// fails.
+func (f *Force) WaitForPackageInstall(requestId string, progress func(PackageRequestProgress)) error {
+	soql := fmt.Sprintf("SELECT Id, Status, Errors FROM PackageInstallRequest WHERE Id = '%s'", requestId)
+	start := time.Now()
+	for time.Since(start) < packageInstallMaxWait {
+		time.Sleep(packageInstallPollInterval)
+		result, err := f.Query(soql, func(options *QueryOptions) {
+			options.IsTooling = true
+		})
+		if err == nil && len(result.Records) == 0 {
+			err = ErrPackageRequestNotFound
+		}
+		if err != nil {
+			reportPackageRequest(progress, PackageRequestProgress{RequestId: requestId, Err: err})
+			continue
+		}
+		record := result.Records[0]
+		status, _ := record["Status"].(string)
+		reportPackageRequest(progress, PackageRequestProgress{RequestId: requestId, Status: status})
+		switch status {
+		case "SUCCESS":
+			return nil
+		case "ERROR":
+			messages := packageInstallErrors(record["Errors"])
+			if len(messages) > 0 {
+				return fmt.Errorf("Package installation failed with errors:\n%s", strings.Join(messages, "\n"))
+			}
+			return errors.New("Package installation failed with status: ERROR")
+		}
+	}
+	return fmt.Errorf("Package installation timed out after %s", packageInstallMaxWait)
+}
+
+// packageInstallErrors extracts the messages from the Errors field of a
+// PackageInstallRequest, which may be an object with an errors array or an
+// array.
+func packageInstallErrors(errs interface{}) (messages []string) {
+	var list []interface{}
+	switch e := errs.(type) {
+	case map[string]interface{}:
+		list, _ = e["errors"].([]interface{})
+	case []interface{}:
+		list = e
+	}
+	for _, item := range list {
+		if m, ok := item.(map[string]interface{}); ok {
+			if message, ok := m["message"].(string); ok {
+				messages = append(messages, message)
+			}
+		}
+	}
+	return
+}
+
+// UninstallPackageVersion uninstalls a package version by its subscriber
+// package version id (04t) and waits for the uninstall to finish.  progress,
+// if not nil, is called when the request is submitted and each time its
+// status is checked.
+func (f *Force) UninstallPackageVersion(packageVersionId string, progress func(PackageRequestProgress)) error {
+	if !strings.HasPrefix(packageVersionId, "04t") {
+		return errors.New("Invalid package version ID. Must be a Subscriber Package Version ID (04t)")
+	}
+	result, err := f.CreateToolingRecord("SubscriberPackageVersionUninstallRequest", map[string]string{
+		"SubscriberPackageVersionId": packageVersionId,
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to create package uninstall request: %w", err)
+	}
+	if result.Id == "" {
+		return errors.New("Failed to get request ID from response")
+	}
+	reportPackageRequest(progress, PackageRequestProgress{RequestId: result.Id, Submitted: true})
+	return f.WaitForPackageUninstall(result.Id, progress)
+}
+
+// WaitForPackageUninstall polls a SubscriberPackageVersionUninstallRequest
+// until it succeeds or fails.
+func (f *Force) WaitForPackageUninstall(requestId string, progress func(PackageRequestProgress)) error {
+	soql := fmt.Sprintf("SELECT Id, Status FROM SubscriberPackageVersionUninstallRequest WHERE Id = '%s'", requestId)
+	start := time.Now()
+	for time.Since(start) < packageInstallMaxWait {
+		time.Sleep(packageInstallPollInterval)
+		result, err := f.Query(soql, func(options *QueryOptions) {
+			options.IsTooling = true
+		})
+		if err == nil && len(result.Records) == 0 {
+			err = ErrPackageRequestNotFound
+		}
+		if err != nil {
+			reportPackageRequest(progress, PackageRequestProgress{RequestId: requestId, Err: err})
+			continue
+		}
+		status, _ := result.Records[0]["Status"].(string)
+		reportPackageRequest(progress, PackageRequestProgress{RequestId: requestId, Status: status})
+		switch status {
+		case "Success":
+			return nil
+		case "Error":
+			return errors.New("Package uninstall failed with status: Error")
+		}
+	}
+	return fmt.Errorf("Package uninstall timed out after %s", packageInstallMaxWait)
+}
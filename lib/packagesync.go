@@ -0,0 +1,414 @@
+package lib
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// InstalledPackage is a package installed in the org.
+type InstalledPackage struct {
+	Id           string
+	PackageId    string
+	Name         string
+	Namespace    string
+	VersionId    string
+	VersionName  string
+	VersionParts [4]int
+}
+
+func (p InstalledPackage) VersionNumber() string {
+	return fmt.Sprintf("%d.%d.%d.%d", p.VersionParts[0], p.VersionParts[1], p.VersionParts[2], p.VersionParts[3])
+}
+
+// ListInstalledPackages returns the packages installed in the org, ordered by
+// name.
+func (f *Force) ListInstalledPackages() (packages []InstalledPackage, err error) {
+	soql := "SELECT Id, SubscriberPackageId, SubscriberPackage.Name, SubscriberPackage.NamespacePrefix, " +
+		"SubscriberPackageVersion.Id, SubscriberPackageVersion.Name, " +
+		"SubscriberPackageVersion.MajorVersion, SubscriberPackageVersion.MinorVersion, " +
+		"SubscriberPackageVersion.PatchVersion, SubscriberPackageVersion.BuildNumber " +
+		"FROM InstalledSubscriberPackage " +
+		"ORDER BY SubscriberPackage.Name"
+	result, err := f.toolingQuery(soql)
+	if err != nil {
+		return
+	}
+	for _, r := range result.Records {
+		p := InstalledPackage{}
+		p.Id, _ = r["Id"].(string)
+		p.PackageId, _ = r["SubscriberPackageId"].(string)
+		if pkg, ok := r["SubscriberPackage"].(map[string]interface{}); ok {
+			p.Name, _ = pkg["Name"].(string)
+			p.Namespace, _ = pkg["NamespacePrefix"].(string)
+		}
+		if version, ok := r["SubscriberPackageVersion"].(map[string]interface{}); ok {
+			p.VersionId, _ = version["Id"].(string)
+			p.VersionName, _ = version["Name"].(string)
+			p.VersionParts = subscriberVersionParts(version)
+		}
+		packages = append(packages, p)
+	}
+	return
+}
+
+func subscriberVersionParts(r map[string]interface{}) [4]int {
+	return [4]int{recordInt(r, "MajorVersion"), recordInt(r, "MinorVersion"), recordInt(r, "PatchVersion"), recordInt(r, "BuildNumber")}
+}
+
+// PackageManifest lists the packages an org should have installed, read
+// from a YAML or JSON file, e.g.
+//
+//	packages:
+//	  - name: Core
+//	    id: 04t000000000001
+//	  - name: Sales
+//	    id: 04t000000000002
+//	    password: secret
+//	    activateRSS: true
+//	  - namespace: legacy
+//	    version: "3.2"
+//	    dependsOn: [Core]
+//
+// Packages given by 04t id are installed with the Tooling API, and their
+// dependencies on other packages in the manifest are found automatically.
+// Packages given by namespace and version are installed with the Metadata
+// API, and dependencies on them must be declared with dependsOn.
+type PackageManifest struct {
+	Packages []PackageManifestEntry `yaml:"packages"`
+}
+
+type PackageManifestEntry struct {
+	Name        string   `yaml:"name"`
+	Namespace   string   `yaml:"namespace"`
+	Version     string   `yaml:"version"`
+	Id          string   `yaml:"id"`
+	Password    string   `yaml:"password"`
+	ActivateRSS bool     `yaml:"activateRSS"`
+	DependsOn   []string `yaml:"dependsOn"`
+}
+
+// Label returns the name used to refer to the entry.
+func (e PackageManifestEntry) Label() string {
+	switch {
+	case e.Name != "":
+		return e.Name
+	case e.Namespace != "":
+		return e.Namespace
+	}
+	return e.Id
+}
+
+func LoadPackageManifest(path string) (manifest PackageManifest, err error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+	if err = yaml.Unmarshal(data, &manifest); err != nil {
+		err = fmt.Errorf("Could not parse package manifest %s: %w", path, err)
+		return
+	}
+	for i, e := range manifest.Packages {
+		switch {
+		case e.Id != "" && !strings.HasPrefix(e.Id, "04t"):
+			return manifest, fmt.Errorf("Package %d: id must be a Subscriber Package Version ID (04t)", i+1)
+		case e.Id == "" && (e.Namespace == "" || e.Version == ""):
+			return manifest, fmt.Errorf("Package %d: requires id, or namespace and version", i+1)
+		case e.Id == "":
+			if _, err = ParsePackageVersionSpec(e.Version); err != nil {
+				return manifest, fmt.Errorf("Package %s: %w", e.Label(), err)
+			}
+			if !literalPackageVersion(e.Version) {
+				return manifest, fmt.Errorf("Package %s: version %q must be a version number such as 3.2, not LATEST or RELEASED", e.Label(), e.Version)
+			}
+		}
+	}
+	return
+}
+
+// literalPackageVersion reports whether version is a version number, such
+// as 3.2, rather than a spec with LATEST or RELEASED.  The Metadata API only
+// installs version numbers.
+func literalPackageVersion(version string) bool {
+	spec, err := ParsePackageVersionSpec(version)
+	if err != nil || spec.ReleasedOnly {
+		return false
+	}
+	for i := range strings.Split(version, ".") {
+		if spec.Parts[i] < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Package sync actions
+const (
+	PackageSyncNone      = "none"
+	PackageSyncInstall   = "install"
+	PackageSyncUpgrade   = "upgrade"
+	PackageSyncNewer     = "newer installed"
+	PackageSyncUninstall = "uninstall"
+)
+
+// PackageSyncAction is a change needed to bring an org in line with a
+// package manifest.
+type PackageSyncAction struct {
+	Action string
+	// Entry is the manifest entry; it is empty for uninstalls.
+	Entry     PackageManifestEntry
+	Installed *InstalledPackage
+	// Required is the version number required by the manifest.
+	Required string
+
+	// packageId and dependencies are SubscriberPackage ids (033), used to
+	// order the actions.
+	packageId    string
+	dependencies []string
+}
+
+// Label returns the name used to refer to the package.
+func (a PackageSyncAction) Label() string {
+	if a.Action == PackageSyncUninstall {
+		return a.Installed.Name
+	}
+	return a.Entry.Label()
+}
+
+// comparePackageVersion compares an installed version with a required
+// version, in which -1 matches any number.
+func comparePackageVersion(installed [4]int, required [4]int) int {
+	for i := range installed {
+		if required[i] < 0 {
+			continue
+		}
+		if installed[i] != required[i] {
+			if installed[i] < required[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+func formatPackageVersionParts(parts [4]int) string {
+	var s []string
+	for _, p := range parts {
+		if p < 0 {
+			break
+		}
+		s = append(s, fmt.Sprintf("%d", p))
+	}
+	return strings.Join(s, ".")
+}
+
+// PlanPackageSync compares the packages installed in the org with the
+// manifest.  The returned actions are in the order they should be applied:
+// packages are installed after the packages they depend on.  If uninstall
+// is true, installed packages not in the manifest are uninstalled first,
+// dependent packages before their dependencies.
+func (f *Force) PlanPackageSync(manifest PackageManifest, uninstall bool) ([]PackageSyncAction, error) {
+	installed, err := f.ListInstalledPackages()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to query installed packages: %w", err)
+	}
+	packageIds := make(map[string]string)
+	dependencyPackages := func(version ForceRecord) (ids []string, err error) {
+		for _, versionId := range subscriberPackageDependencyIds(version["Dependencies"]) {
+			if _, ok := packageIds[versionId]; !ok {
+				dependency, err := f.subscriberPackageVersion(versionId)
+				if err != nil {
+					return nil, err
+				}
+				packageIds[versionId], _ = dependency["SubscriberPackageId"].(string)
+			}
+			ids = append(ids, packageIds[versionId])
+		}
+		return
+	}
+
+	var actions []PackageSyncAction
+	matched := make(map[string]bool)
+	for _, entry := range manifest.Packages {
+		action := PackageSyncAction{Entry: entry}
+		var required [4]int
+		if entry.Id != "" {
+			version, err := f.subscriberPackageVersion(entry.Id)
+			if err != nil {
+				return nil, fmt.Errorf("Package %s: %w", entry.Label(), err)
+			}
+			action.packageId, _ = version["SubscriberPackageId"].(string)
+			if action.dependencies, err = dependencyPackages(version); err != nil {
+				return nil, fmt.Errorf("Package %s: %w", entry.Label(), err)
+			}
+			required = subscriberVersionParts(version)
+		} else {
+			spec, _ := ParsePackageVersionSpec(entry.Version)
+			required = spec.Parts
+		}
+		action.Required = formatPackageVersionParts(required)
+		for i := range installed {
+			p := &installed[i]
+			if (action.packageId != "" && p.PackageId == action.packageId) ||
+				(entry.Namespace != "" && strings.EqualFold(p.Namespace, entry.Namespace)) {
+				action.Installed = p
+				action.packageId = p.PackageId
+				matched[p.Id] = true
+				break
+			}
+		}
+		switch {
+		case action.Installed == nil:
+			action.Action = PackageSyncInstall
+		case entry.Id != "" && action.Installed.VersionId == entry.Id:
+			action.Action = PackageSyncNone
+		default:
+			switch comparePackageVersion(action.Installed.VersionParts, required) {
+			case 0:
+				action.Action = PackageSyncNone
+			case -1:
+				action.Action = PackageSyncUpgrade
+			default:
+				action.Action = PackageSyncNewer
+			}
+		}
+		actions = append(actions, action)
+	}
+	actions, err = orderPackageSyncActions(actions)
+	if err != nil {
+		return nil, err
+	}
+	if !uninstall {
+		return actions, nil
+	}
+
+	var removals []PackageSyncAction
+	for i := range installed {
+		p := &installed[i]
+		if matched[p.Id] {
+			continue
+		}
+		action := PackageSyncAction{Action: PackageSyncUninstall, Installed: p, packageId: p.PackageId}
+		if version, err := f.subscriberPackageVersion(p.VersionId); err == nil {
+			action.dependencies, _ = dependencyPackages(version)
+		}
+		removals = append(removals, action)
+	}
+	removals, err = orderPackageSyncActions(removals)
+	if err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(removals)-1; i < j; i, j = i+1, j-1 {
+		removals[i], removals[j] = removals[j], removals[i]
+	}
+	return append(removals, actions...), nil
+}
+
+func (f *Force) subscriberPackageVersion(id string) (ForceRecord, error) {
+	result, err := f.toolingQuery(fmt.Sprintf("SELECT Id, SubscriberPackageId, MajorVersion, MinorVersion, PatchVersion, BuildNumber, Dependencies FROM SubscriberPackageVersion WHERE Id = '%s'", escapeSoqlLiteral(id)))
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Records) == 0 {
+		return nil, fmt.Errorf("No package version found with id %s", id)
+	}
+	return result.Records[0], nil
+}
+
+// dependsOn reports whether a depends on b, either as declared in the
+// manifest or through the dependencies of a's package version.
+func (a PackageSyncAction) dependsOn(b PackageSyncAction) bool {
+	for _, name := range a.Entry.DependsOn {
+		if (b.Entry.Name != "" && name == b.Entry.Name) || (b.Entry.Namespace != "" && strings.EqualFold(name, b.Entry.Namespace)) {
+			return true
+		}
+	}
+	if b.packageId == "" {
+		return false
+	}
+	for _, id := range a.dependencies {
+		if id == b.packageId {
+			return true
+		}
+	}
+	return false
+}
+
+// orderPackageSyncActions sorts actions so each comes after the actions it
+// depends on, keeping the original order otherwise.
+func orderPackageSyncActions(actions []PackageSyncAction) ([]PackageSyncAction, error) {
+	var ordered []PackageSyncAction
+	placed := make([]bool, len(actions))
+	for len(ordered) < len(actions) {
+		progress := false
+		for i, a := range actions {
+			if placed[i] {
+				continue
+			}
+			ready := true
+			for j, b := range actions {
+				if i != j && !placed[j] && a.dependsOn(b) {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				ordered = append(ordered, a)
+				placed[i] = true
+				progress = true
+				break
+			}
+		}
+		if !progress {
+			var cycle []string
+			for i, a := range actions {
+				if !placed[i] {
+					cycle = append(cycle, a.Label())
+				}
+			}
+			return nil, fmt.Errorf("Packages have circular dependencies: %s", strings.Join(cycle, ", "))
+		}
+	}
+	return ordered, nil
+}
+
+// ApplyPackageSync installs, upgrades, and uninstalls packages as planned by
+// PlanPackageSync, stopping at the first failure.  progress, if not nil, is
+// called before each change and with the status of each running request.
+func (f *Force) ApplyPackageSync(actions []PackageSyncAction, progress func(action PackageSyncAction, status string)) error {
+	for _, action := range actions {
+		report := func(status string) {
+			if progress != nil {
+				progress(action, status)
+			}
+		}
+		reportStatus := func(p PackageRequestProgress) {
+			if p.Status != "" {
+				report(p.Status)
+			}
+		}
+		var err error
+		switch action.Action {
+		case PackageSyncInstall, PackageSyncUpgrade:
+			report("Started")
+			entry := action.Entry
+			if entry.Id != "" {
+				err = f.InstallPackageVersion(entry.Id, entry.Password, entry.ActivateRSS, reportStatus)
+			} else {
+				err = f.Metadata.InstallPackageWithRSS(entry.Namespace, entry.Version, entry.Password, entry.ActivateRSS)
+			}
+		case PackageSyncUninstall:
+			report("Started")
+			err = f.UninstallPackageVersion(action.Installed.VersionId, reportStatus)
+		default:
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("Failed to %s %s: %w", action.Action, action.Label(), err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package lib
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadPackageManifest(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "packages.yaml")
+	ioutil.WriteFile(path, []byte(`
+packages:
+  - name: Core
+    id: 04tCORE
+  - namespace: legacy
+    version: "3.2"
+`), 0644)
+	manifest, err := LoadPackageManifest(path)
+	if err != nil {
+		t.Fatalf("LoadPackageManifest returned error: %v", err)
+	}
+	if len(manifest.Packages) != 2 || manifest.Packages[1].Label() != "legacy" {
+		t.Errorf("Unexpected manifest: %+v", manifest)
+	}
+
+	ioutil.WriteFile(path, []byte(`{"packages": [{"name": "Core", "id": "0Ho000000000001"}]}`), 0644)
+	if _, err := LoadPackageManifest(path); err == nil || !strings.Contains(err.Error(), "04t") {
+		t.Errorf("Expected error for non-04t id, got %v", err)
+	}
+	ioutil.WriteFile(path, []byte(`{"packages": [{"namespace": "legacy"}]}`), 0644)
+	if _, err := LoadPackageManifest(path); err == nil {
+		t.Errorf("Expected error for namespace without version")
+	}
+	for _, version := range []string{"3.LATEST", "3.2.RELEASED"} {
+		ioutil.WriteFile(path, []byte(`{"packages": [{"namespace": "legacy", "version": "`+version+`"}]}`), 0644)
+		if _, err := LoadPackageManifest(path); err == nil || !strings.Contains(err.Error(), "version number") {
+			t.Errorf("Expected error for namespace with version %s, got %v", version, err)
+		}
+	}
+}
+
+func TestComparePackageVersion(t *testing.T) {
+	cases := []struct {
+		installed [4]int
+		required  [4]int
+		expected  int
+	}{
+		{[4]int{1, 2, 0, 3}, [4]int{1, 2, 0, 3}, 0},
+		{[4]int{1, 2, 0, 3}, [4]int{1, 2, -1, -1}, 0},
+		{[4]int{1, 2, 0, 3}, [4]int{1, 3, -1, -1}, -1},
+		{[4]int{1, 2, 0, 3}, [4]int{1, 2, 0, 2}, 1},
+	}
+	for _, c := range cases {
+		if got := comparePackageVersion(c.installed, c.required); got != c.expected {
+			t.Errorf("comparePackageVersion(%v, %v) = %d, expected %d", c.installed, c.required, got, c.expected)
+		}
+	}
+}
+
+func TestPlanPackageSync(t *testing.T) {
+	server, _ := packageVersionTestServer(t, map[string]string{
+		"FROM InstalledSubscriberPackage": `{"done":true,"totalSize":3,"records":[
+			{"Id":"0A3CORE","SubscriberPackageId":"033CORE","SubscriberPackage":{"Name":"Core","NamespacePrefix":"core"},
+			 "SubscriberPackageVersion":{"Id":"04tCORE1","Name":"v1","MajorVersion":1,"MinorVersion":0,"PatchVersion":0,"BuildNumber":1}},
+			{"Id":"0A3LEG","SubscriberPackageId":"033LEG","SubscriberPackage":{"Name":"Legacy","NamespacePrefix":"legacy"},
+			 "SubscriberPackageVersion":{"Id":"04tLEG","Name":"v3","MajorVersion":3,"MinorVersion":2,"PatchVersion":1,"BuildNumber":0}},
+			{"Id":"0A3OLD","SubscriberPackageId":"033OLD","SubscriberPackage":{"Name":"Old","NamespacePrefix":null},
+			 "SubscriberPackageVersion":{"Id":"04tOLD","Name":"v1","MajorVersion":1,"MinorVersion":0,"PatchVersion":0,"BuildNumber":1}}]}`,
+		"WHERE Id = '04tSALES'": `{"done":true,"totalSize":1,"records":[{"Id":"04tSALES","SubscriberPackageId":"033SALES","MajorVersion":2,"MinorVersion":0,"PatchVersion":0,"BuildNumber":4,"Dependencies":{"ids":[{"subscriberPackageVersionId":"04tCORE1"}]}}]}`,
+		"WHERE Id = '04tCORE2'": `{"done":true,"totalSize":1,"records":[{"Id":"04tCORE2","SubscriberPackageId":"033CORE","MajorVersion":1,"MinorVersion":1,"PatchVersion":0,"BuildNumber":2,"Dependencies":null}]}`,
+		"WHERE Id = '04tCORE1'": `{"done":true,"totalSize":1,"records":[{"Id":"04tCORE1","SubscriberPackageId":"033CORE","MajorVersion":1,"MinorVersion":0,"PatchVersion":0,"BuildNumber":1,"Dependencies":null}]}`,
+		"WHERE Id = '04tOLD'":   `{"done":true,"totalSize":1,"records":[{"Id":"04tOLD","SubscriberPackageId":"033OLD","MajorVersion":1,"MinorVersion":0,"PatchVersion":0,"BuildNumber":1,"Dependencies":null}]}`,
+	})
+	defer server.Close()
+	force := &Force{Credentials: &ForceSession{InstanceUrl: server.URL, AccessToken: "test-token"}}
+
+	manifest := PackageManifest{Packages: []PackageManifestEntry{
+		{Name: "Sales", Id: "04tSALES"},
+		{Namespace: "legacy", Version: "3.2"},
+		{Name: "Core", Id: "04tCORE2"},
+	}}
+	actions, err := force.PlanPackageSync(manifest, true)
+	if err != nil {
+		t.Fatalf("PlanPackageSync returned error: %v", err)
+	}
+	var got []string
+	for _, a := range actions {
+		got = append(got, a.Label()+":"+a.Action)
+	}
+	expected := "Old:uninstall,legacy:none,Core:upgrade,Sales:install"
+	if strings.Join(got, ",") != expected {
+		t.Errorf("Expected %s, got %s", expected, strings.Join(got, ","))
+	}
+	if actions[2].Required != "1.1.0.2" {
+		t.Errorf("Unexpected required version: %s", actions[2].Required)
+	}
+}
@@ -0,0 +1,359 @@
+package lib
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PackageVersion is a second-generation package version from the Dev Hub.
+type PackageVersion struct {
+	Id                         string
+	Package2Id                 string
+	PackageName                string
+	Namespace                  string
+	SubscriberPackageVersionId string
+	AncestorId                 string
+	Name                       string
+	Tag                        string
+	MajorVersion               int
+	MinorVersion               int
+	PatchVersion               int
+	BuildNumber                int
+	IsReleased                 bool
+	ValidationSkipped          bool
+	HasPassedCodeCoverageCheck bool
+	CodeCoverage               *float64
+	ReleaseVersion             string
+	BuildDurationInSeconds     int
+	CreatedDate                string
+}
+
+func (v PackageVersion) VersionNumber() string {
+	return fmt.Sprintf("%d.%d.%d.%d", v.MajorVersion, v.MinorVersion, v.PatchVersion, v.BuildNumber)
+}
+
+const packageVersionFields = "Id, Package2Id, Package2.Name, Package2.NamespacePrefix, SubscriberPackageVersionId, AncestorId, Name, Tag, MajorVersion, MinorVersion, PatchVersion, BuildNumber, IsReleased, ValidationSkipped, HasPassedCodeCoverageCheck, CodeCoverage, ReleaseVersion, BuildDurationInSeconds, CreatedDate"
+
+func recordInt(r map[string]interface{}, field string) int {
+	switch v := r[field].(type) {
+	case float64:
+		return int(v)
+	case string:
+		i, _ := strconv.Atoi(v)
+		return i
+	}
+	return 0
+}
+
+func packageVersionFromRecord(r ForceRecord) PackageVersion {
+	str := func(field string) string {
+		s, _ := r[field].(string)
+		return s
+	}
+	boolean := func(field string) bool {
+		b, _ := r[field].(bool)
+		return b
+	}
+	v := PackageVersion{
+		Id:                         str("Id"),
+		Package2Id:                 str("Package2Id"),
+		SubscriberPackageVersionId: str("SubscriberPackageVersionId"),
+		AncestorId:                 str("AncestorId"),
+		Name:                       str("Name"),
+		Tag:                        str("Tag"),
+		MajorVersion:               recordInt(r, "MajorVersion"),
+		MinorVersion:               recordInt(r, "MinorVersion"),
+		PatchVersion:               recordInt(r, "PatchVersion"),
+		BuildNumber:                recordInt(r, "BuildNumber"),
+		IsReleased:                 boolean("IsReleased"),
+		ValidationSkipped:          boolean("ValidationSkipped"),
+		HasPassedCodeCoverageCheck: boolean("HasPassedCodeCoverageCheck"),
+		BuildDurationInSeconds:     recordInt(r, "BuildDurationInSeconds"),
+		CreatedDate:                str("CreatedDate"),
+	}
+	if release := r["ReleaseVersion"]; release != nil {
+		v.ReleaseVersion = fmt.Sprint(release)
+	}
+	if pkg, ok := r["Package2"].(map[string]interface{}); ok {
+		v.PackageName, _ = pkg["Name"].(string)
+		v.Namespace, _ = pkg["NamespacePrefix"].(string)
+	}
+	if coverage, ok := r["CodeCoverage"].(map[string]interface{}); ok {
+		if percent, ok := coverage["apexCodeCoveragePercentage"].(float64); ok {
+			v.CodeCoverage = &percent
+		}
+	}
+	return v
+}
+
+func (f *Force) toolingQuery(soql string) (ForceQueryResult, error) {
+	return f.Query(soql, func(options *QueryOptions) {
+		options.IsTooling = true
+	})
+}
+
+// GetPackageVersion returns a package version by its Package2Version id
+// (05i) or its subscriber package version id (04t).
+func (f *Force) GetPackageVersion(id string) (version PackageVersion, err error) {
+	field := "Id"
+	if strings.HasPrefix(id, "04t") {
+		field = "SubscriberPackageVersionId"
+	}
+	result, err := f.toolingQuery(fmt.Sprintf("SELECT %s FROM Package2Version WHERE %s = '%s'", packageVersionFields, field, escapeSoqlLiteral(id)))
+	if err != nil {
+		return
+	}
+	if len(result.Records) == 0 {
+		err = fmt.Errorf("No package version found with id %s", id)
+		return
+	}
+	return packageVersionFromRecord(result.Records[0]), nil
+}
+
+// PackageVersionSpec selects package versions by version number.  Any of
+// the four parts of the number may be a wildcard, written as LATEST, and
+// trailing parts may be omitted, e.g. 1.4.LATEST or 1.4.  A final RELEASED
+// part, e.g. 1.4.0.RELEASED, only matches released versions.
+type PackageVersionSpec struct {
+	// Parts holds the major, minor, patch, and build numbers; -1 matches any
+	// number.
+	Parts        [4]int
+	ReleasedOnly bool
+}
+
+func ParsePackageVersionSpec(spec string) (PackageVersionSpec, error) {
+	s := PackageVersionSpec{Parts: [4]int{-1, -1, -1, -1}}
+	parts := strings.Split(spec, ".")
+	if spec == "" || len(parts) > 4 {
+		return s, fmt.Errorf("Invalid version %q: expected major.minor.patch.build", spec)
+	}
+	for i, part := range parts {
+		switch strings.ToUpper(part) {
+		case "LATEST":
+		case "RELEASED":
+			if i != len(parts)-1 {
+				return s, fmt.Errorf("Invalid version %q: RELEASED must be the last part", spec)
+			}
+			s.ReleasedOnly = true
+		default:
+			n, err := strconv.Atoi(part)
+			if err != nil || n < 0 {
+				return s, fmt.Errorf("Invalid version %q: %q is not a number, LATEST, or RELEASED", spec, part)
+			}
+			s.Parts[i] = n
+		}
+	}
+	return s, nil
+}
+
+func (s PackageVersionSpec) soqlConditions() []string {
+	var conditions []string
+	for i, field := range []string{"MajorVersion", "MinorVersion", "PatchVersion", "BuildNumber"} {
+		if s.Parts[i] >= 0 {
+			conditions = append(conditions, fmt.Sprintf("%s = %d", field, s.Parts[i]))
+		}
+	}
+	if s.ReleasedOnly {
+		conditions = append(conditions, "IsReleased = true")
+	}
+	return conditions
+}
+
+// FindPackage2Id returns the id of the package in the Dev Hub with the given
+// name or namespace.
+func (f *Force) FindPackage2Id(nameOrNamespace string) (string, error) {
+	literal := escapeSoqlLiteral(nameOrNamespace)
+	result, err := f.toolingQuery(fmt.Sprintf("SELECT Id, Name, NamespacePrefix FROM Package2 WHERE Name = '%s' OR NamespacePrefix = '%s'", literal, literal))
+	if err != nil {
+		return "", err
+	}
+	switch len(result.Records) {
+	case 0:
+		return "", fmt.Errorf("No package found with name or namespace %s", nameOrNamespace)
+	case 1:
+		id, _ := result.Records[0]["Id"].(string)
+		return id, nil
+	}
+	return "", fmt.Errorf("Multiple packages found with name or namespace %s", nameOrNamespace)
+}
+
+// ResolvePackageDependency returns the subscriber package version id (04t)
+// for a dependency.  The dependency is either a 04t id, which is returned
+// as is, or a package name or namespace and a version spec separated by @,
+// e.g. MyPackage@1.4.LATEST, which is resolved to the highest matching
+// version of the package in the Dev Hub.
+func (f *Force) ResolvePackageDependency(dependency string) (string, error) {
+	if strings.HasPrefix(dependency, "04t") && !strings.Contains(dependency, "@") {
+		return dependency, nil
+	}
+	at := strings.LastIndex(dependency, "@")
+	if at <= 0 {
+		return "", fmt.Errorf("Invalid dependency %q: expected a 04t id or <package>@<version>", dependency)
+	}
+	spec, err := ParsePackageVersionSpec(dependency[at+1:])
+	if err != nil {
+		return "", err
+	}
+	packageId, err := f.FindPackage2Id(dependency[:at])
+	if err != nil {
+		return "", err
+	}
+	conditions := append([]string{fmt.Sprintf("Package2Id = '%s'", packageId)}, spec.soqlConditions()...)
+	soql := fmt.Sprintf("SELECT SubscriberPackageVersionId FROM Package2Version WHERE %s ORDER BY MajorVersion DESC, MinorVersion DESC, PatchVersion DESC, BuildNumber DESC LIMIT 1", strings.Join(conditions, " AND "))
+	result, err := f.toolingQuery(soql)
+	if err != nil {
+		return "", err
+	}
+	if len(result.Records) == 0 {
+		return "", fmt.Errorf("No version of %s matches %s", dependency[:at], dependency[at+1:])
+	}
+	id, _ := result.Records[0]["SubscriberPackageVersionId"].(string)
+	return id, nil
+}
+
+// PackageVersionPromotionProblems returns the reasons a package version
+// can't be promoted to released.
+func PackageVersionPromotionProblems(v PackageVersion) []string {
+	var problems []string
+	if v.IsReleased {
+		problems = append(problems, "version is already released")
+	}
+	if v.ValidationSkipped {
+		problems = append(problems, "version was created with validation skipped")
+	}
+	if v.CodeCoverage != nil && !v.HasPassedCodeCoverageCheck {
+		problems = append(problems, fmt.Sprintf("code coverage of %.0f%% is below the required 75%%", *v.CodeCoverage))
+	}
+	return problems
+}
+
+// PromotePackageVersion marks a package version as released so it can be
+// installed in production orgs.
+func (f *Force) PromotePackageVersion(id string) (version PackageVersion, err error) {
+	version, err = f.GetPackageVersion(id)
+	if err != nil {
+		return
+	}
+	problems := PackageVersionPromotionProblems(version)
+	if version.SubscriberPackageVersionId != "" && !version.IsReleased {
+		tree, err := f.PackageDependencyTree(version.SubscriberPackageVersionId)
+		if err != nil {
+			return version, err
+		}
+		for _, beta := range tree.BetaDependencies() {
+			problems = append(problems, fmt.Sprintf("depends on beta version %s %s", beta.PackageName, beta.VersionNumber))
+		}
+	}
+	if len(problems) > 0 {
+		err = fmt.Errorf("Cannot promote %s %s: %s", version.PackageName, version.VersionNumber(), strings.Join(problems, "; "))
+		return
+	}
+	if err = f.UpdateToolingRecord("Package2Version", version.Id, map[string]string{"IsReleased": "true"}); err != nil {
+		return
+	}
+	version.IsReleased = true
+	return
+}
+
+// PackageDependencyNode is a subscriber package version and the versions it
+// depends on.
+type PackageDependencyNode struct {
+	SubscriberPackageVersionId string
+	PackageName                string
+	Namespace                  string
+	VersionNumber              string
+	IsBeta                     bool
+	Dependencies               []*PackageDependencyNode
+}
+
+// PackageDependencyTree returns the dependencies of a subscriber package
+// version, and their dependencies in turn.
+func (f *Force) PackageDependencyTree(subscriberPackageVersionId string) (*PackageDependencyNode, error) {
+	return f.packageDependencyNode(subscriberPackageVersionId, make(map[string]*PackageDependencyNode), nil)
+}
+
+func (f *Force) packageDependencyNode(id string, seen map[string]*PackageDependencyNode, path []string) (*PackageDependencyNode, error) {
+	for _, p := range path {
+		if p == id {
+			return nil, fmt.Errorf("Circular package dependency: %s", strings.Join(append(path, id), " -> "))
+		}
+	}
+	if node, ok := seen[id]; ok {
+		return node, nil
+	}
+	result, err := f.toolingQuery(fmt.Sprintf("SELECT Id, SubscriberPackageId, MajorVersion, MinorVersion, PatchVersion, BuildNumber, IsBeta, Dependencies FROM SubscriberPackageVersion WHERE Id = '%s'", escapeSoqlLiteral(id)))
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Records) == 0 {
+		return nil, fmt.Errorf("No subscriber package version found with id %s", id)
+	}
+	r := result.Records[0]
+	node := &PackageDependencyNode{
+		SubscriberPackageVersionId: id,
+		VersionNumber:              fmt.Sprintf("%d.%d.%d.%d", recordInt(r, "MajorVersion"), recordInt(r, "MinorVersion"), recordInt(r, "PatchVersion"), recordInt(r, "BuildNumber")),
+	}
+	node.IsBeta, _ = r["IsBeta"].(bool)
+	if packageId, ok := r["SubscriberPackageId"].(string); ok {
+		pkg, err := f.toolingQuery(fmt.Sprintf("SELECT Name, NamespacePrefix FROM SubscriberPackage WHERE Id = '%s'", packageId))
+		if err == nil && len(pkg.Records) > 0 {
+			node.PackageName, _ = pkg.Records[0]["Name"].(string)
+			node.Namespace, _ = pkg.Records[0]["NamespacePrefix"].(string)
+		}
+	}
+	for _, dependencyId := range subscriberPackageDependencyIds(r["Dependencies"]) {
+		dependency, err := f.packageDependencyNode(dependencyId, seen, append(path, id))
+		if err != nil {
+			return nil, err
+		}
+		node.Dependencies = append(node.Dependencies, dependency)
+	}
+	seen[id] = node
+	return node, nil
+}
+
+// subscriberPackageDependencyIds extracts the version ids from the
+// Dependencies field of a SubscriberPackageVersion, which looks like
+// {"ids": [{"subscriberPackageVersionId": "04t..."}]}.
+func subscriberPackageDependencyIds(dependencies interface{}) []string {
+	d, ok := dependencies.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	list, _ := d["ids"].([]interface{})
+	var ids []string
+	for _, item := range list {
+		if m, ok := item.(map[string]interface{}); ok {
+			if id, ok := m["subscriberPackageVersionId"].(string); ok {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
+
+// BetaDependencies returns the dependencies anywhere in the tree that are
+// beta versions, which prevent a version from being promoted.
+func (n *PackageDependencyNode) BetaDependencies() []*PackageDependencyNode {
+	found := make(map[string]*PackageDependencyNode)
+	var walk func(*PackageDependencyNode)
+	walk = func(node *PackageDependencyNode) {
+		for _, d := range node.Dependencies {
+			if d.IsBeta {
+				found[d.SubscriberPackageVersionId] = d
+			}
+			walk(d)
+		}
+	}
+	walk(n)
+	var betas []*PackageDependencyNode
+	for _, d := range found {
+		betas = append(betas, d)
+	}
+	sort.Slice(betas, func(i, j int) bool {
+		return betas[i].SubscriberPackageVersionId < betas[j].SubscriberPackageVersionId
+	})
+	return betas
+}
@@ -0,0 +1,121 @@
+package lib
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParsePackageVersionSpec(t *testing.T) {
+	spec, err := ParsePackageVersionSpec("1.4.LATEST")
+	if err != nil {
+		t.Fatalf("ParsePackageVersionSpec returned error: %v", err)
+	}
+	if spec.Parts != [4]int{1, 4, -1, -1} || spec.ReleasedOnly {
+		t.Errorf("Unexpected spec: %+v", spec)
+	}
+	if got := strings.Join(spec.soqlConditions(), " AND "); got != "MajorVersion = 1 AND MinorVersion = 4" {
+		t.Errorf("Unexpected conditions: %s", got)
+	}
+
+	spec, err = ParsePackageVersionSpec("2.0.1.RELEASED")
+	if err != nil {
+		t.Fatalf("ParsePackageVersionSpec returned error: %v", err)
+	}
+	if spec.Parts != [4]int{2, 0, 1, -1} || !spec.ReleasedOnly {
+		t.Errorf("Unexpected spec: %+v", spec)
+	}
+
+	for _, invalid := range []string{"", "1.x", "1.RELEASED.0", "1.2.3.4.5", "-1.0"} {
+		if _, err := ParsePackageVersionSpec(invalid); err == nil {
+			t.Errorf("Expected error for %q", invalid)
+		}
+	}
+}
+
+func packageVersionTestServer(t *testing.T, responses map[string]string) (*httptest.Server, *[]string) {
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query().Get("q")
+		queries = append(queries, q)
+		w.Header().Set("Content-Type", "application/json")
+		for match, response := range responses {
+			if strings.Contains(q, match) {
+				w.Write([]byte(response))
+				return
+			}
+		}
+		t.Errorf("Unexpected query: %s", q)
+		w.Write([]byte(`{"records":[]}`))
+	}))
+	return server, &queries
+}
+
+func TestResolvePackageDependency(t *testing.T) {
+	server, queries := packageVersionTestServer(t, map[string]string{
+		"FROM Package2 WHERE":        `{"done":true,"totalSize":1,"records":[{"Id":"0Ho000000000001","Name":"Core","NamespacePrefix":"core"}]}`,
+		"FROM Package2Version WHERE": `{"done":true,"totalSize":1,"records":[{"SubscriberPackageVersionId":"04t000000000002"}]}`,
+	})
+	defer server.Close()
+	force := &Force{Credentials: &ForceSession{InstanceUrl: server.URL, AccessToken: "test-token"}}
+
+	id, err := force.ResolvePackageDependency("04t000000000009")
+	if err != nil || id != "04t000000000009" || len(*queries) != 0 {
+		t.Errorf("Expected 04t id to be used as is, got %s %v", id, err)
+	}
+
+	id, err = force.ResolvePackageDependency("core@1.4.LATEST")
+	if err != nil {
+		t.Fatalf("ResolvePackageDependency returned error: %v", err)
+	}
+	if id != "04t000000000002" {
+		t.Errorf("Unexpected id: %s", id)
+	}
+	versionQuery := (*queries)[1]
+	if !strings.Contains(versionQuery, "Package2Id = '0Ho000000000001' AND MajorVersion = 1 AND MinorVersion = 4 ORDER BY") {
+		t.Errorf("Unexpected version query: %s", versionQuery)
+	}
+
+	if _, err := force.ResolvePackageDependency("core"); err == nil {
+		t.Errorf("Expected error for dependency without version")
+	}
+}
+
+func TestPackageDependencyTreeFindsBetaDependencies(t *testing.T) {
+	server, _ := packageVersionTestServer(t, map[string]string{
+		"WHERE Id = '04tAPP'":  `{"done":true,"totalSize":1,"records":[{"Id":"04tAPP","SubscriberPackageId":"033APP","MajorVersion":3,"MinorVersion":0,"PatchVersion":0,"BuildNumber":1,"IsBeta":true,"Dependencies":{"ids":[{"subscriberPackageVersionId":"04tBASE"},{"subscriberPackageVersionId":"04tUTIL"}]}}]}`,
+		"WHERE Id = '04tUTIL'": `{"done":true,"totalSize":1,"records":[{"Id":"04tUTIL","SubscriberPackageId":"033UTIL","MajorVersion":1,"MinorVersion":2,"PatchVersion":0,"BuildNumber":5,"IsBeta":true,"Dependencies":{"ids":[{"subscriberPackageVersionId":"04tBASE"}]}}]}`,
+		"WHERE Id = '04tBASE'": `{"done":true,"totalSize":1,"records":[{"Id":"04tBASE","SubscriberPackageId":"033BASE","MajorVersion":1,"MinorVersion":0,"PatchVersion":0,"BuildNumber":2,"IsBeta":false,"Dependencies":null}]}`,
+		"WHERE Id = '033":      `{"done":true,"totalSize":1,"records":[{"Name":"Pkg","NamespacePrefix":"pkg"}]}`,
+	})
+	defer server.Close()
+	force := &Force{Credentials: &ForceSession{InstanceUrl: server.URL, AccessToken: "test-token"}}
+
+	tree, err := force.PackageDependencyTree("04tAPP")
+	if err != nil {
+		t.Fatalf("PackageDependencyTree returned error: %v", err)
+	}
+	if len(tree.Dependencies) != 2 || tree.Dependencies[1].VersionNumber != "1.2.0.5" {
+		t.Fatalf("Unexpected tree: %+v", tree)
+	}
+	if tree.Dependencies[0] != tree.Dependencies[1].Dependencies[0] {
+		t.Errorf("Expected shared dependency to be looked up once")
+	}
+	betas := tree.BetaDependencies()
+	if len(betas) != 1 || betas[0].SubscriberPackageVersionId != "04tUTIL" {
+		t.Errorf("Unexpected beta dependencies: %+v", betas)
+	}
+}
+
+func TestPackageVersionPromotionProblems(t *testing.T) {
+	coverage := 62.0
+	problems := PackageVersionPromotionProblems(PackageVersion{ValidationSkipped: true, CodeCoverage: &coverage})
+	if len(problems) != 2 {
+		t.Errorf("Unexpected problems: %v", problems)
+	}
+	coverage = 90
+	if problems := PackageVersionPromotionProblems(PackageVersion{CodeCoverage: &coverage, HasPassedCodeCoverageCheck: true}); len(problems) != 0 {
+		t.Errorf("Unexpected problems: %v", problems)
+	}
+}
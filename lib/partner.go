@@ -109,8 +109,8 @@ func (partner *ForcePartner) ExecuteAnonymousTest(apex string) (output string, e
 }
 
 func (partner *ForcePartner) SoapExecuteCore(action, query string) (response []byte, err error) {
-	url := fmt.Sprintf("%s/services/Soap/u/%s/%s", partner.Force.Credentials.InstanceUrl, partner.Force.Credentials.SessionOptions.ApiVersion, partner.Force.Credentials.UserInfo.OrgId)
-	soap := NewSoap(url, "urn:partner.soap.sforce.com", partner.Force.Credentials.AccessToken)
+	url := fmt.Sprintf("%s/services/Soap/u/%s/%s", partner.Force.InstanceUrl(), partner.Force.Credentials.SessionOptions.ApiVersion, partner.Force.Credentials.UserInfo.OrgId)
+	soap := NewSoap(url, "urn:partner.soap.sforce.com", partner.Force.AccessToken())
 	soap.Header = "<apex:DebuggingHeader><apex:debugLevel>DEBUGONLY</apex:debugLevel></apex:DebuggingHeader>"
 	response, err = soap.Execute(action, query)
 	if err == SessionExpiredError {
@@ -121,8 +121,9 @@ func (partner *ForcePartner) SoapExecuteCore(action, query string) (response []b
 }
 
 func (partner *ForcePartner) soapExecute(action, query string) (response []byte, err error) {
-	url := fmt.Sprintf("%s/services/Soap/s/%s/%s", partner.Force.Credentials.InstanceUrl, partner.Force.Credentials.SessionOptions.ApiVersion, partner.Force.Credentials.UserInfo.OrgId)
-	soap := NewSoap(url, "http://soap.sforce.com/2006/08/apex", partner.Force.Credentials.AccessToken)
+	partner.Force.refreshExpiringSession()
+	url := fmt.Sprintf("%s/services/Soap/s/%s/%s", partner.Force.InstanceUrl(), partner.Force.Credentials.SessionOptions.ApiVersion, partner.Force.Credentials.UserInfo.OrgId)
+	soap := NewSoap(url, "http://soap.sforce.com/2006/08/apex", partner.Force.AccessToken())
 	soap.Header = "<apex:DebuggingHeader><apex:debugLevel>DEBUGONLY</apex:debugLevel></apex:DebuggingHeader>"
 	response, err = soap.Execute(action, query)
 	if err == SessionExpiredError {
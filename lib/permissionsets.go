@@ -0,0 +1,87 @@
+package lib
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+func soqlStringList(values []string) string {
+	var quoted []string
+	for _, v := range values {
+		quoted = append(quoted, "'"+escapeSoqlLiteral(v)+"'")
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// AssignPermissionSets assigns the named permission sets to a user, or to the
+// current user if username is empty.  Permission sets that are already
+// assigned are skipped.
+func (f *Force) AssignPermissionSets(username string, names []string) (assigned []string, err error) {
+	var userId string
+	if username == "" {
+		me, err := f.Whoami()
+		if err != nil {
+			return nil, err
+		}
+		userId, _ = me["Id"].(string)
+	} else {
+		users, err := f.Query(fmt.Sprintf("SELECT Id FROM User WHERE Username = %s", soqlStringList([]string{username})))
+		if err != nil {
+			return nil, err
+		}
+		if len(users.Records) == 0 {
+			return nil, fmt.Errorf("User not found: %s", username)
+		}
+		userId, _ = users.Records[0]["Id"].(string)
+	}
+
+	permsets, err := f.Query(fmt.Sprintf("SELECT Id, Name FROM PermissionSet WHERE Name IN (%s)", soqlStringList(names)))
+	if err != nil {
+		return
+	}
+	ids := make(map[string]string)
+	for _, r := range permsets.Records {
+		name, _ := r["Name"].(string)
+		ids[strings.ToLower(name)], _ = r["Id"].(string)
+	}
+	var missing []string
+	for _, name := range names {
+		if ids[strings.ToLower(name)] == "" {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return nil, fmt.Errorf("Permission sets not found: %s", strings.Join(missing, ", "))
+	}
+
+	existing, err := f.Query(fmt.Sprintf("SELECT PermissionSetId FROM PermissionSetAssignment WHERE AssigneeId = '%s'", userId))
+	if err != nil {
+		return
+	}
+	alreadyAssigned := make(map[string]bool)
+	for _, r := range existing.Records {
+		id, _ := r["PermissionSetId"].(string)
+		alreadyAssigned[id] = true
+	}
+	for _, name := range names {
+		id := ids[strings.ToLower(name)]
+		if alreadyAssigned[id] {
+			continue
+		}
+		_, err, messages := f.CreateRecord("PermissionSetAssignment", map[string]string{
+			"AssigneeId":      userId,
+			"PermissionSetId": id,
+		})
+		if err != nil {
+			if len(messages) > 0 {
+				return assigned, fmt.Errorf("Failed to assign %s: %s", name, messages[0].Message)
+			}
+			return assigned, fmt.Errorf("Failed to assign %s: %w", name, err)
+		}
+		alreadyAssigned[id] = true
+		assigned = append(assigned, name)
+	}
+	return
+}
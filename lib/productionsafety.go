@@ -0,0 +1,189 @@
+package lib
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Production safety policies, which control what happens before a
+// destructive operation in a production org.
+const (
+	ProductionPolicyConfirm  = "confirm"
+	ProductionPolicyTypeName = "type-name"
+	ProductionPolicyRefuse   = "refuse"
+	ProductionPolicyAllow    = "allow"
+)
+
+var ProductionPolicies = []string{ProductionPolicyConfirm, ProductionPolicyTypeName, ProductionPolicyRefuse, ProductionPolicyAllow}
+
+// Prompt asks the user a question and returns the answer.  It is nil when
+// no one is available to answer, in which case destructive operations in
+// production orgs are refused unless the login's policy allows them.
+var Prompt func(question string) (string, error)
+
+// ConfirmDestructiveOperations confirms destructive operations in production
+// orgs without prompting, for example with --yes in scripts.  Operations are
+// still refused if the login's policy is refuse.
+var ConfirmDestructiveOperations bool
+
+// DestructiveOperationRefusedError is returned when the production safety
+// policy prevents a destructive operation.
+type DestructiveOperationRefusedError struct {
+	Operation string
+	Org       string
+	Reason    string
+}
+
+func (e DestructiveOperationRefusedError) Error() string {
+	return fmt.Sprintf("Refusing to %s in production org %s: %s", e.Operation, e.Org, e.Reason)
+}
+
+type safetyOrg struct {
+	Name       string
+	Production bool
+}
+
+// LoginProductionPolicy returns the production safety policy of a saved
+// login.  Unless one has been set, it's confirm.
+func LoginProductionPolicy(account string) string {
+	m := LoadLoginMetadata(account)
+	if m.ProductionPolicy != "" {
+		return m.ProductionPolicy
+	}
+	return ProductionPolicyConfirm
+}
+
+// SetLoginProductionPolicy sets the production safety policy of a saved
+// login.  An empty policy restores the default.
+func SetLoginProductionPolicy(account string, policy string) error {
+	policy = strings.ToLower(policy)
+	valid := policy == ""
+	for _, p := range ProductionPolicies {
+		valid = valid || p == policy
+	}
+	if !valid {
+		return fmt.Errorf("Invalid production policy %q: must be one of %s", policy, strings.Join(ProductionPolicies, ", "))
+	}
+	return updateLoginMetadata(account, func(m *LoginMetadata) error {
+		m.ProductionPolicy = policy
+		return nil
+	})
+}
+
+func (f *Force) loginName() string {
+	if f.Credentials == nil || f.Credentials.UserInfo == nil {
+		return ""
+	}
+	return f.Credentials.UserInfo.UserName
+}
+
+// safetyOrg returns the name of the org and whether it's a production org,
+// i.e. neither a sandbox nor a scratch or trial org.
+func (f *Force) safetyOrg() (safetyOrg, error) {
+	if f.org != nil {
+		return *f.org, nil
+	}
+	result, err := f.Query("SELECT Name, IsSandbox, TrialExpirationDate FROM Organization")
+	if err != nil {
+		return safetyOrg{}, err
+	}
+	if len(result.Records) == 0 {
+		return safetyOrg{}, fmt.Errorf("Organization not found")
+	}
+	record := result.Records[0]
+	name, _ := record["Name"].(string)
+	isSandbox, _ := record["IsSandbox"].(bool)
+	f.org = &safetyOrg{
+		Name:       name,
+		Production: !isSandbox && record["TrialExpirationDate"] == nil,
+	}
+	return *f.org, nil
+}
+
+// CheckDestructiveOperation applies the production safety policy before a
+// destructive operation, described by operation, e.g. "delete Account
+// 001000000000001".  It applies to logins labeled production, and to
+// unlabeled logins if the org isn't a sandbox or scratch org.  Depending on
+// the login's policy, the operation is allowed, refused, or requires the user
+// to confirm it or to type the org name.
+func (f *Force) CheckDestructiveOperation(operation string) error {
+	login := f.loginName()
+	if login == "" {
+		return nil
+	}
+	env := LoadLoginMetadata(login).Environment
+	if env != "" && env != EnvironmentProduction {
+		return nil
+	}
+	labeled := env == EnvironmentProduction
+	policy := LoginProductionPolicy(login)
+	if policy == ProductionPolicyAllow {
+		return nil
+	}
+	org, err := f.safetyOrg()
+	if err != nil {
+		return fmt.Errorf("Unable to check whether org is production: %w", err)
+	}
+	if !labeled && !org.Production {
+		return nil
+	}
+
+	display := fmt.Sprintf("%s (%s)", org.Name, login)
+	refuse := func(reason string) error {
+		return DestructiveOperationRefusedError{Operation: operation, Org: display, Reason: reason}
+	}
+	if policy == ProductionPolicyRefuse {
+		return refuse("the production policy for this login is refuse")
+	}
+	if ConfirmDestructiveOperations {
+		return nil
+	}
+	if Prompt == nil {
+		return refuse("confirmation is required")
+	}
+	if policy == ProductionPolicyTypeName {
+		answer, err := Prompt(fmt.Sprintf("About to %s in production org %s.\nType the org name to continue: ", operation, display))
+		if err != nil {
+			return refuse(err.Error())
+		}
+		if strings.TrimSpace(answer) != org.Name {
+			return refuse("the org name did not match")
+		}
+		return nil
+	}
+	answer, err := Prompt(fmt.Sprintf("About to %s in production org %s. Continue? [y/N] ", operation, display))
+	if err != nil {
+		return refuse(err.Error())
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return nil
+	}
+	return refuse("the operation was not confirmed")
+}
+
+// destructiveDeployOperation describes the destructive changes in a deploy,
+// or returns an empty string if there are none.
+func destructiveDeployOperation(zipfile []byte, options ForceDeployOptions) string {
+	if options.CheckOnly {
+		return ""
+	}
+	var files []string
+	if r, err := zip.NewReader(bytes.NewReader(zipfile), int64(len(zipfile))); err == nil {
+		for _, f := range r.File {
+			if strings.HasPrefix(path.Base(f.Name), "destructiveChanges") {
+				files = append(files, path.Base(f.Name))
+			}
+		}
+	}
+	switch {
+	case len(files) > 0 && options.PurgeOnDelete:
+		return fmt.Sprintf("deploy %s with purgeOnDelete", strings.Join(files, ", "))
+	case len(files) > 0:
+		return fmt.Sprintf("deploy %s", strings.Join(files, ", "))
+	}
+	return ""
+}
@@ -0,0 +1,238 @@
+package lib
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	forceConfig "github.com/ForceCLI/force/config"
+)
+
+func productionSafetyTestForce(t *testing.T, isSandbox bool) *Force {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if isSandbox {
+			w.Write([]byte(`{"done": true, "totalSize": 1, "records": [{"Name": "Acme", "IsSandbox": true, "TrialExpirationDate": null}]}`))
+		} else {
+			w.Write([]byte(`{"done": true, "totalSize": 1, "records": [{"Name": "Acme", "IsSandbox": false, "TrialExpirationDate": null}]}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return &Force{
+		Credentials: &ForceSession{
+			InstanceUrl: server.URL,
+			AccessToken: "test-token",
+			UserInfo:    &UserInfo{UserName: "admin@acme.com"},
+		},
+	}
+}
+
+func withPrompt(t *testing.T, answer string) *[]string {
+	var questions []string
+	saved := Prompt
+	Prompt = func(question string) (string, error) {
+		questions = append(questions, question)
+		return answer + "\n", nil
+	}
+	t.Cleanup(func() { Prompt = saved })
+	return &questions
+}
+
+func TestCheckDestructiveOperationConfirm(t *testing.T) {
+	cleanup := setupTestConfig(t)
+	defer cleanup()
+	forceConfig.Config.Save("accounts", "admin@acme.com", "{}")
+	SetLoginEnvironment("admin@acme.com", EnvironmentProduction)
+
+	questions := withPrompt(t, "y")
+	if err := productionSafetyTestForce(t, false).CheckDestructiveOperation("delete Account 001"); err != nil {
+		t.Fatalf("Expected confirmed operation to be allowed, got %v", err)
+	}
+	if len(*questions) != 1 {
+		t.Fatalf("Expected one prompt, got %v", *questions)
+	}
+
+	withPrompt(t, "n")
+	err := productionSafetyTestForce(t, false).CheckDestructiveOperation("delete Account 001")
+	var refused DestructiveOperationRefusedError
+	if !errors.As(err, &refused) || refused.Org != "Acme (admin@acme.com)" {
+		t.Errorf("Expected refusal, got %v", err)
+	}
+}
+
+func TestCheckDestructiveOperationChecksUnlabeledLogins(t *testing.T) {
+	cleanup := setupTestConfig(t)
+	defer cleanup()
+	forceConfig.Config.Save("accounts", "admin@acme.com", "{}")
+
+	Prompt = nil
+	if policy := LoginProductionPolicy("admin@acme.com"); policy != ProductionPolicyConfirm {
+		t.Errorf("Expected default policy confirm for unlabeled login, got %s", policy)
+	}
+	if err := productionSafetyTestForce(t, false).CheckDestructiveOperation("delete Account 001"); err == nil {
+		t.Errorf("Expected unlabeled login to production org to require confirmation")
+	}
+	if err := productionSafetyTestForce(t, true).CheckDestructiveOperation("delete Account 001"); err != nil {
+		t.Errorf("Expected sandbox to be allowed, got %v", err)
+	}
+	SetLoginEnvironment("admin@acme.com", EnvironmentSandbox)
+	if err := productionSafetyTestForce(t, false).CheckDestructiveOperation("delete Account 001"); err != nil {
+		t.Errorf("Expected login labeled sandbox to be allowed, got %v", err)
+	}
+}
+
+func TestCheckDestructiveOperationConfirmedWithoutPrompt(t *testing.T) {
+	cleanup := setupTestConfig(t)
+	defer cleanup()
+	forceConfig.Config.Save("accounts", "admin@acme.com", "{}")
+	SetLoginEnvironment("admin@acme.com", EnvironmentProduction)
+	defer func(confirm bool) { ConfirmDestructiveOperations = confirm }(ConfirmDestructiveOperations)
+	Prompt = nil
+
+	ConfirmDestructiveOperations = true
+	if err := productionSafetyTestForce(t, false).CheckDestructiveOperation("delete Account 001"); err != nil {
+		t.Errorf("Expected confirmed operation to be allowed without a prompt, got %v", err)
+	}
+	SetLoginProductionPolicy("admin@acme.com", ProductionPolicyRefuse)
+	if err := productionSafetyTestForce(t, false).CheckDestructiveOperation("delete Account 001"); err == nil {
+		t.Errorf("Expected refuse policy to refuse even when confirmed")
+	}
+}
+
+func TestCheckDestructiveOperationSkipsSandboxes(t *testing.T) {
+	cleanup := setupTestConfig(t)
+	defer cleanup()
+	forceConfig.Config.Save("accounts", "admin@acme.com", "{}")
+	SetLoginProductionPolicy("admin@acme.com", ProductionPolicyConfirm)
+
+	questions := withPrompt(t, "n")
+	if err := productionSafetyTestForce(t, true).CheckDestructiveOperation("delete Account 001"); err != nil {
+		t.Errorf("Expected operation in sandbox to be allowed, got %v", err)
+	}
+
+	SetLoginEnvironment("admin@acme.com", EnvironmentProduction)
+	if err := productionSafetyTestForce(t, true).CheckDestructiveOperation("delete Account 001"); err == nil {
+		t.Errorf("Expected login labeled production to require confirmation")
+	}
+	if len(*questions) != 1 {
+		t.Errorf("Expected one prompt, got %v", *questions)
+	}
+}
+
+func TestCheckDestructiveOperationPolicies(t *testing.T) {
+	cleanup := setupTestConfig(t)
+	defer cleanup()
+	forceConfig.Config.Save("accounts", "admin@acme.com", "{}")
+	SetLoginEnvironment("admin@acme.com", EnvironmentProduction)
+
+	if err := SetLoginProductionPolicy("admin@acme.com", "sometimes"); err == nil {
+		t.Errorf("Expected error for invalid policy")
+	}
+
+	SetLoginProductionPolicy("admin@acme.com", ProductionPolicyTypeName)
+	withPrompt(t, "y")
+	if err := productionSafetyTestForce(t, false).CheckDestructiveOperation("delete Account 001"); err == nil {
+		t.Errorf("Expected type-name policy to reject y")
+	}
+	withPrompt(t, "Acme")
+	if err := productionSafetyTestForce(t, false).CheckDestructiveOperation("delete Account 001"); err != nil {
+		t.Errorf("Expected type-name policy to accept org name, got %v", err)
+	}
+
+	SetLoginProductionPolicy("admin@acme.com", ProductionPolicyRefuse)
+	if err := productionSafetyTestForce(t, false).CheckDestructiveOperation("delete Account 001"); err == nil {
+		t.Errorf("Expected refuse policy to refuse")
+	}
+
+	SetLoginProductionPolicy("admin@acme.com", ProductionPolicyAllow)
+	Prompt = nil
+	if err := productionSafetyTestForce(t, false).CheckDestructiveOperation("delete Account 001"); err != nil {
+		t.Errorf("Expected allow policy to allow, got %v", err)
+	}
+
+	SetLoginProductionPolicy("admin@acme.com", "")
+	if err := productionSafetyTestForce(t, false).CheckDestructiveOperation("delete Account 001"); err == nil {
+		t.Errorf("Expected refusal without a prompt")
+	}
+}
+
+func TestDestructiveDeletesAreChecked(t *testing.T) {
+	cleanup := setupTestConfig(t)
+	defer cleanup()
+	forceConfig.Config.Save("accounts", "admin@acme.com", "{}")
+	SetLoginEnvironment("admin@acme.com", EnvironmentProduction)
+	SetLoginProductionPolicy("admin@acme.com", ProductionPolicyRefuse)
+
+	f := productionSafetyTestForce(t, false)
+	var refused DestructiveOperationRefusedError
+	if err := f.DeleteToolingRecord("ApexClass", "01p000000000001"); !errors.As(err, &refused) {
+		t.Errorf("Expected tooling delete to be refused, got %v", err)
+	}
+	if err := f.DeleteDataPipeline("0Hx000000000001"); !errors.As(err, &refused) {
+		t.Errorf("Expected data pipeline delete to be refused, got %v", err)
+	}
+	_, err := f.ExecuteRecordOperations([]RecordOperation{
+		{Operation: RecordOperationCreate, SObject: "Account"},
+		{Operation: RecordOperationDelete, Id: "001000000000001AAA"},
+	}, false, nil)
+	if !errors.As(err, &refused) || refused.Operation != "delete 1 records" {
+		t.Errorf("Expected record batch with deletes to be refused, got %v", err)
+	}
+}
+
+func TestDestructiveDeployOperation(t *testing.T) {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	w.Create("unpackaged/package.xml")
+	w.Create("unpackaged/destructiveChangesPost.xml")
+	w.Close()
+
+	if op := destructiveDeployOperation(buf.Bytes(), ForceDeployOptions{PurgeOnDelete: true}); op != "deploy destructiveChangesPost.xml with purgeOnDelete" {
+		t.Errorf("Unexpected operation %q", op)
+	}
+	if op := destructiveDeployOperation(buf.Bytes(), ForceDeployOptions{CheckOnly: true}); op != "" {
+		t.Errorf("Expected validation not to be destructive, got %q", op)
+	}
+
+	buf.Reset()
+	w = zip.NewWriter(&buf)
+	w.Create("package.xml")
+	w.Close()
+	if op := destructiveDeployOperation(buf.Bytes(), ForceDeployOptions{PurgeOnDelete: true}); op != "" {
+		t.Errorf("Expected deploy without destructive changes not to be destructive, got %q", op)
+	}
+}
+
+func TestDiagnosticToolingDeletesAreNotChecked(t *testing.T) {
+	cleanup := setupTestConfig(t)
+	defer cleanup()
+	forceConfig.Config.Save("accounts", "admin@acme.com", "{}")
+	SetLoginEnvironment("admin@acme.com", EnvironmentProduction)
+	SetLoginProductionPolicy("admin@acme.com", ProductionPolicyRefuse)
+
+	var deleted []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deleted = append(deleted, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	f := &Force{
+		Credentials: &ForceSession{
+			InstanceUrl: server.URL,
+			AccessToken: "test-token",
+			UserInfo:    &UserInfo{UserName: "admin@acme.com"},
+		},
+	}
+	if err := f.DeleteToolingRecord("ApexLog", "07L000000000001"); err != nil {
+		t.Errorf("Expected debug log delete to be allowed, got %v", err)
+	}
+	if err := f.DeleteToolingRecord("TraceFlag", "7tf000000000001"); err != nil {
+		t.Errorf("Expected trace flag delete to be allowed, got %v", err)
+	}
+	if len(deleted) != 2 {
+		t.Errorf("Expected two deletes, got %v", deleted)
+	}
+}
@@ -39,7 +39,8 @@ var GRPCEndpoint = DefaultGRPCEndpoint
 var InvalidReplayIdError = errors.New("Invalid Replay Id")
 
 type PubSubClient struct {
-	session *ForceSession
+	force    *Force
+	userInfo *UserInfo
 
 	conn         *grpc.ClientConn
 	pubSubClient proto.PubSubClient
@@ -347,7 +348,7 @@ func (c *PubSubClient) Publish(channel string, message map[string]any) error {
 		message["CreatedDate"] = time.Now().Unix()
 	}
 	if message["CreatedById"] == nil {
-		message["CreatedById"] = c.session.UserInfo.UserId
+		message["CreatedById"] = c.userInfo.UserId
 	}
 	switch message["CreatedDate"].(type) {
 	case int:
@@ -406,9 +407,9 @@ func (c *PubSubClient) Publish(channel string, message map[string]any) error {
 // Returns a new context with the necessary authentication parameters for the gRPC server
 func (c *PubSubClient) getAuthContext() context.Context {
 	return metadata.NewOutgoingContext(context.Background(), metadata.Pairs(
-		tokenHeader, c.session.AccessToken,
-		instanceHeader, c.session.InstanceUrl,
-		tenantHeader, c.session.UserInfo.OrgId,
+		tokenHeader, c.force.AccessToken(),
+		instanceHeader, c.force.InstanceUrl(),
+		tenantHeader, c.userInfo.OrgId,
 	))
 }
 
@@ -446,7 +447,8 @@ func NewGRPCClient(f *Force) (*PubSubClient, error) {
 
 	return &PubSubClient{
 		conn:         conn,
-		session:      f.Credentials,
+		force:        f,
+		userInfo:     f.Credentials.UserInfo,
 		pubSubClient: proto.NewPubSubClient(conn),
 		codecCache:   make(map[string]*goavro.Codec),
 		schemaCache:  make(map[string]map[string]any),
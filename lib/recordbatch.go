@@ -0,0 +1,335 @@
+package lib
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// The sObject Collections API accepts at most 200 records per request, and a
+// composite request may contain at most 5 sObject Collections subrequests.
+const (
+	SObjectCollectionMaxRecords       = 200
+	CompositeMaxCollectionSubrequests = 5
+)
+
+type RecordOperationType string
+
+const (
+	RecordOperationCreate RecordOperationType = "create"
+	RecordOperationUpdate RecordOperationType = "update"
+	RecordOperationUpsert RecordOperationType = "upsert"
+	RecordOperationDelete RecordOperationType = "delete"
+)
+
+// RecordOperation is a single create, update, upsert, or delete, as read from
+// one line of an NDJSON batch file, e.g.
+//
+//	{"operation": "create", "sobject": "Account", "fields": {"Name": "Acme"}}
+//	{"operation": "update", "sobject": "Account", "id": "001...", "fields": {"Phone": "555-0100"}}
+//	{"operation": "upsert", "sobject": "Account", "externalIdField": "Ext_Id__c", "fields": {"Ext_Id__c": "A1", "Name": "Acme"}}
+//	{"operation": "delete", "id": "001..."}
+type RecordOperation struct {
+	Operation       RecordOperationType    `json:"operation"`
+	SObject         string                 `json:"sobject,omitempty"`
+	Id              string                 `json:"id,omitempty"`
+	ExternalIdField string                 `json:"externalIdField,omitempty"`
+	Fields          map[string]interface{} `json:"fields,omitempty"`
+}
+
+// RecordOperationResult is the result of a RecordOperation.  Line is the
+// position of the operation in the input, starting at 1.
+type RecordOperationResult struct {
+	Line      int                 `json:"line"`
+	Operation RecordOperationType `json:"operation"`
+	Result
+}
+
+func (op RecordOperation) validate() error {
+	switch op.Operation {
+	case RecordOperationCreate:
+		if op.SObject == "" {
+			return fmt.Errorf("create requires sobject")
+		}
+	case RecordOperationUpdate:
+		if op.SObject == "" || op.Id == "" {
+			return fmt.Errorf("update requires sobject and id")
+		}
+	case RecordOperationUpsert:
+		if op.SObject == "" || op.ExternalIdField == "" {
+			return fmt.Errorf("upsert requires sobject and externalIdField")
+		}
+		if _, ok := op.Fields[op.ExternalIdField]; !ok {
+			return fmt.Errorf("upsert requires a value for %s", op.ExternalIdField)
+		}
+	case RecordOperationDelete:
+		if op.Id == "" {
+			return fmt.Errorf("delete requires id")
+		}
+	default:
+		return fmt.Errorf("unknown operation %q", op.Operation)
+	}
+	return nil
+}
+
+// ReadRecordOperations reads NDJSON record operations, one per line.  Blank
+// lines are ignored.
+func ReadRecordOperations(r io.Reader) (ops []RecordOperation, err error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var op RecordOperation
+		if err = json.Unmarshal([]byte(text), &op); err != nil {
+			return nil, fmt.Errorf("Line %d: %w", line, err)
+		}
+		op.Operation = RecordOperationType(strings.ToLower(string(op.Operation)))
+		if err = op.validate(); err != nil {
+			return nil, fmt.Errorf("Line %d: %w", line, err)
+		}
+		ops = append(ops, op)
+	}
+	err = scanner.Err()
+	return
+}
+
+// recordBatch is a group of operations that can be sent in a single sObject
+// Collections request.  indexes holds the position of each operation in the
+// input.
+type recordBatch struct {
+	operation       RecordOperationType
+	sobject         string
+	externalIdField string
+	ops             []RecordOperation
+	indexes         []int
+}
+
+func (b recordBatch) accepts(op RecordOperation) bool {
+	if b.operation != op.Operation || len(b.ops) >= SObjectCollectionMaxRecords {
+		return false
+	}
+	if op.Operation == RecordOperationUpsert {
+		return b.sobject == op.SObject && b.externalIdField == op.ExternalIdField
+	}
+	return true
+}
+
+// recordBatches groups consecutive operations of the same type into batches
+// of up to 200 records.  Upserts are also grouped by sobject and external id
+// field, since the upsert resource is specific to both.  Operations are never
+// reordered.
+func recordBatches(ops []RecordOperation) (batches []recordBatch) {
+	for i, op := range ops {
+		if len(batches) == 0 || !batches[len(batches)-1].accepts(op) {
+			batches = append(batches, recordBatch{
+				operation:       op.Operation,
+				sobject:         op.SObject,
+				externalIdField: op.ExternalIdField,
+			})
+		}
+		b := &batches[len(batches)-1]
+		b.ops = append(b.ops, op)
+		b.indexes = append(b.indexes, i)
+	}
+	return
+}
+
+type compositeSubrequest struct {
+	Method      string      `json:"method"`
+	Url         string      `json:"url"`
+	ReferenceId string      `json:"referenceId"`
+	Body        interface{} `json:"body,omitempty"`
+}
+
+type compositeRequest struct {
+	AllOrNone        bool                  `json:"allOrNone"`
+	CompositeRequest []compositeSubrequest `json:"compositeRequest"`
+}
+
+type compositeSubresponse struct {
+	Body           json.RawMessage `json:"body"`
+	HttpStatusCode int             `json:"httpStatusCode"`
+	ReferenceId    string          `json:"referenceId"`
+}
+
+type compositeResponse struct {
+	CompositeResponse []compositeSubresponse `json:"compositeResponse"`
+}
+
+type sobjectCollection struct {
+	AllOrNone bool                     `json:"allOrNone"`
+	Records   []map[string]interface{} `json:"records"`
+}
+
+func (b recordBatch) subrequest(referenceId string, allOrNone bool) compositeSubrequest {
+	base := fmt.Sprintf("/services/data/%s/composite/sobjects", apiVersion)
+	if b.operation == RecordOperationDelete {
+		var ids []string
+		for _, op := range b.ops {
+			ids = append(ids, op.Id)
+		}
+		q := url.Values{}
+		q.Set("ids", strings.Join(ids, ","))
+		q.Set("allOrNone", fmt.Sprintf("%t", allOrNone))
+		return compositeSubrequest{
+			Method:      "DELETE",
+			Url:         base + "?" + q.Encode(),
+			ReferenceId: referenceId,
+		}
+	}
+	collection := sobjectCollection{AllOrNone: allOrNone}
+	for _, op := range b.ops {
+		record := make(map[string]interface{}, len(op.Fields)+2)
+		for k, v := range op.Fields {
+			record[k] = v
+		}
+		record["attributes"] = map[string]string{"type": op.SObject}
+		if op.Operation == RecordOperationUpdate {
+			record["Id"] = op.Id
+		}
+		collection.Records = append(collection.Records, record)
+	}
+	sub := compositeSubrequest{
+		Method:      "POST",
+		Url:         base,
+		ReferenceId: referenceId,
+		Body:        collection,
+	}
+	switch b.operation {
+	case RecordOperationUpdate:
+		sub.Method = "PATCH"
+	case RecordOperationUpsert:
+		sub.Method = "PATCH"
+		sub.Url = fmt.Sprintf("%s/%s/%s", base, url.PathEscape(b.sobject), url.PathEscape(b.externalIdField))
+	}
+	return sub
+}
+
+// results converts the response to a batch's subrequest into a result for
+// each operation.  If the whole subrequest failed, the error is reported for
+// every operation in the batch.
+func (b recordBatch) results(res compositeSubresponse) ([]Result, error) {
+	var results []Result
+	if res.HttpStatusCode/100 == 2 {
+		if err := json.Unmarshal(res.Body, &results); err != nil {
+			return nil, fmt.Errorf("Could not parse results: %w", err)
+		}
+		if len(results) != len(b.ops) {
+			return nil, fmt.Errorf("Expected %d results, got %d", len(b.ops), len(results))
+		}
+		return results, nil
+	}
+	var errs []ForceError
+	if err := json.Unmarshal(res.Body, &errs); err != nil || len(errs) == 0 {
+		errs = []ForceError{{ErrorCode: fmt.Sprintf("HTTP %d", res.HttpStatusCode), Message: string(res.Body)}}
+	}
+	var resultErrors []ResultError
+	for _, e := range errs {
+		resultErrors = append(resultErrors, ResultError{StatusCode: e.ErrorCode, Message: e.Message})
+	}
+	for _, op := range b.ops {
+		results = append(results, Result{Id: op.Id, Errors: resultErrors})
+	}
+	return results, nil
+}
+
+// ExecuteRecordOperations sends ops using the sObject Collections API, with up
+// to 5 collection requests of 200 records per composite request.  Results are
+// returned in the same order as ops.
+//
+// If allOrNone is true, all of the operations in a composite request are
+// rolled back if any of them fail; otherwise, records are saved or fail
+// independently.  progress, if not nil, is called after each composite
+// request with the number of operations processed so far.
+func (f *Force) ExecuteRecordOperations(ops []RecordOperation, allOrNone bool, progress func(done int)) ([]RecordOperationResult, error) {
+	for i, op := range ops {
+		if err := op.validate(); err != nil {
+			return nil, fmt.Errorf("Operation %d: %w", i+1, err)
+		}
+	}
+	deletes := 0
+	for _, op := range ops {
+		if op.Operation == RecordOperationDelete {
+			deletes++
+		}
+	}
+	if deletes > 0 {
+		if err := f.CheckDestructiveOperation(fmt.Sprintf("delete %d records", deletes)); err != nil {
+			return nil, err
+		}
+	}
+	results := make([]RecordOperationResult, len(ops))
+	batches := recordBatches(ops)
+	done := 0
+	for start := 0; start < len(batches); start += CompositeMaxCollectionSubrequests {
+		end := start + CompositeMaxCollectionSubrequests
+		if end > len(batches) {
+			end = len(batches)
+		}
+		group := batches[start:end]
+		responses, err := f.compositeRecordBatches(group, allOrNone)
+		if err != nil {
+			return nil, err
+		}
+		for i, b := range group {
+			batchResults, err := b.results(responses[i])
+			if err != nil {
+				return nil, err
+			}
+			for j, r := range batchResults {
+				index := b.indexes[j]
+				results[index] = RecordOperationResult{
+					Line:      index + 1,
+					Operation: b.operation,
+					Result:    r,
+				}
+			}
+			done += len(b.ops)
+		}
+		if progress != nil {
+			progress(done)
+		}
+	}
+	return results, nil
+}
+
+func (f *Force) compositeRecordBatches(batches []recordBatch, allOrNone bool) ([]compositeSubresponse, error) {
+	req := compositeRequest{AllOrNone: allOrNone}
+	for i, b := range batches {
+		req.CompositeRequest = append(req.CompositeRequest, b.subrequest(fmt.Sprintf("batch%d", i), allOrNone))
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	endpoint := fmt.Sprintf("%s/services/data/%s/composite", f.InstanceUrl(), apiVersion)
+	resBody, err := f.httpPostPatchWithRetry(endpoint, string(body), ContentTypeJson, HttpMethodPost)
+	if err != nil {
+		return nil, err
+	}
+	var res compositeResponse
+	if err := json.Unmarshal(resBody, &res); err != nil {
+		return nil, fmt.Errorf("Could not parse composite response: %w", err)
+	}
+	byReference := make(map[string]compositeSubresponse)
+	for _, sub := range res.CompositeResponse {
+		byReference[sub.ReferenceId] = sub
+	}
+	responses := make([]compositeSubresponse, len(batches))
+	for i := range batches {
+		sub, ok := byReference[fmt.Sprintf("batch%d", i)]
+		if !ok {
+			return nil, fmt.Errorf("Missing response for batch %d", i)
+		}
+		responses[i] = sub
+	}
+	return responses, nil
+}
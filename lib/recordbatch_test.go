@@ -0,0 +1,147 @@
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestReadRecordOperations(t *testing.T) {
+	input := `{"operation": "create", "sobject": "Account", "fields": {"Name": "Acme"}}
+
+{"operation": "DELETE", "id": "001000000000001AAA"}
+`
+	ops, err := ReadRecordOperations(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ReadRecordOperations returned error: %v", err)
+	}
+	if len(ops) != 2 || ops[1].Operation != RecordOperationDelete {
+		t.Errorf("Unexpected operations: %+v", ops)
+	}
+
+	_, err = ReadRecordOperations(strings.NewReader(`{"operation": "upsert", "sobject": "Account", "externalIdField": "Ext__c", "fields": {"Name": "Acme"}}`))
+	if err == nil || !strings.Contains(err.Error(), "Line 1") {
+		t.Errorf("Expected error for upsert without external id value, got %v", err)
+	}
+}
+
+func TestRecordBatches_groups_consecutive_operations(t *testing.T) {
+	var ops []RecordOperation
+	for i := 0; i < 250; i++ {
+		ops = append(ops, RecordOperation{Operation: RecordOperationCreate, SObject: "Account"})
+	}
+	ops = append(ops,
+		RecordOperation{Operation: RecordOperationUpsert, SObject: "Account", ExternalIdField: "Ext__c"},
+		RecordOperation{Operation: RecordOperationUpsert, SObject: "Contact", ExternalIdField: "Ext__c"},
+		RecordOperation{Operation: RecordOperationCreate, SObject: "Contact"},
+	)
+	batches := recordBatches(ops)
+	var sizes []int
+	for _, b := range batches {
+		sizes = append(sizes, len(b.ops))
+	}
+	if fmt.Sprint(sizes) != "[200 50 1 1 1]" {
+		t.Errorf("Unexpected batch sizes: %v", sizes)
+	}
+	if batches[4].indexes[0] != 252 {
+		t.Errorf("Expected last batch to hold operation 252, got %d", batches[4].indexes[0])
+	}
+}
+
+func TestExecuteRecordOperations_returns_results_in_input_order(t *testing.T) {
+	var requests []compositeRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		var req compositeRequest
+		json.Unmarshal(body, &req)
+		requests = append(requests, req)
+		res := compositeResponse{}
+		for _, sub := range req.CompositeRequest {
+			var out interface{}
+			status := 200
+			switch {
+			case sub.Method == "DELETE":
+				status = 400
+				out = []ForceError{{ErrorCode: "INVALID_ID", Message: "bad id"}}
+			case strings.HasSuffix(sub.Url, "/Account/Ext__c"):
+				out = []Result{{Id: "001UPSERT", Success: true, Created: true}}
+			default:
+				out = []Result{{Id: "001NEW1", Success: true}, {Success: false, Errors: []ResultError{{StatusCode: "REQUIRED_FIELD_MISSING", Message: "Name"}}}}
+			}
+			data, _ := json.Marshal(out)
+			res.CompositeResponse = append(res.CompositeResponse, compositeSubresponse{Body: data, HttpStatusCode: status, ReferenceId: sub.ReferenceId})
+		}
+		json.NewEncoder(w).Encode(res)
+	}))
+	defer server.Close()
+
+	force := &Force{Credentials: &ForceSession{InstanceUrl: server.URL, AccessToken: "token"}}
+	ops := []RecordOperation{
+		{Operation: RecordOperationCreate, SObject: "Account", Fields: map[string]interface{}{"Name": "Acme"}},
+		{Operation: RecordOperationCreate, SObject: "Account"},
+		{Operation: RecordOperationUpsert, SObject: "Account", ExternalIdField: "Ext__c", Fields: map[string]interface{}{"Ext__c": "A1"}},
+		{Operation: RecordOperationDelete, Id: "001000000000001AAA"},
+	}
+	results, err := force.ExecuteRecordOperations(ops, true, nil)
+	if err != nil {
+		t.Fatalf("ExecuteRecordOperations returned error: %v", err)
+	}
+	if len(requests) != 1 || !requests[0].AllOrNone || len(requests[0].CompositeRequest) != 3 {
+		t.Fatalf("Expected a single all-or-none composite request with 3 subrequests, got %+v", requests)
+	}
+	if results[0].Id != "001NEW1" || !results[0].Success {
+		t.Errorf("Unexpected result for line 1: %+v", results[0])
+	}
+	if results[1].Success || results[1].Errors[0].StatusCode != "REQUIRED_FIELD_MISSING" {
+		t.Errorf("Unexpected result for line 2: %+v", results[1])
+	}
+	if results[2].Line != 3 || !results[2].Created {
+		t.Errorf("Unexpected result for line 3: %+v", results[2])
+	}
+	if results[3].Success || results[3].Id != "001000000000001AAA" || results[3].Errors[0].StatusCode != "INVALID_ID" {
+		t.Errorf("Unexpected result for line 4: %+v", results[3])
+	}
+}
+
+func TestExecuteRecordOperations_limits_collection_subrequests(t *testing.T) {
+	var sizes []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req compositeRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		sizes = append(sizes, len(req.CompositeRequest))
+		if len(req.CompositeRequest) > 5 {
+			t.Errorf("Composite request has %d collection subrequests", len(req.CompositeRequest))
+		}
+		res := compositeResponse{}
+		for _, sub := range req.CompositeRequest {
+			var out []Result
+			for range sub.Body.(map[string]interface{})["records"].([]interface{}) {
+				out = append(out, Result{Success: true})
+			}
+			data, _ := json.Marshal(out)
+			res.CompositeResponse = append(res.CompositeResponse, compositeSubresponse{Body: data, HttpStatusCode: 200, ReferenceId: sub.ReferenceId})
+		}
+		json.NewEncoder(w).Encode(res)
+	}))
+	defer server.Close()
+
+	force := &Force{Credentials: &ForceSession{InstanceUrl: server.URL, AccessToken: "token"}}
+	var ops []RecordOperation
+	for i := 0; i < 2500; i++ {
+		ops = append(ops, RecordOperation{Operation: RecordOperationCreate, SObject: "Account"})
+	}
+	results, err := force.ExecuteRecordOperations(ops, false, nil)
+	if err != nil {
+		t.Fatalf("ExecuteRecordOperations returned error: %v", err)
+	}
+	if len(results) != 2500 || !results[2499].Success {
+		t.Errorf("Expected 2500 successful results, got %d", len(results))
+	}
+	if fmt.Sprint(sizes) != "[5 5 3]" {
+		t.Errorf("Expected composite requests of 5, 5 and 3 subrequests, got %v", sizes)
+	}
+}
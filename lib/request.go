@@ -147,6 +147,9 @@ func (r *Request) toHttpCallback(forceResponse *Response) HttpCallback {
 // processes the HTTP response in the configured way,
 // and returns the Response.
 func (f *Force) ExecuteRequest(r *Request) (*Response, error) {
+	if !r.unauthed {
+		f.refreshExpiringSession()
+	}
 	var absUrl string
 	if r.absoluteUrl != "" {
 		absUrl = r.absoluteUrl
@@ -0,0 +1,355 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// RetrieveChunkSize is the most components requested in a single retrieve.
+// The Metadata API limits a retrieve to 10,000 files, and most components
+// are retrieved as one or two files.
+var RetrieveChunkSize = 5000
+
+// RetrieveConcurrency is how many chunks of a large retrieve run at once.
+// Orgs limit the number of concurrent asynchronous Metadata API calls.
+var RetrieveConcurrency = 3
+
+// componentDependentTypes only contain entries, such as field permissions or
+// translations, for the other components retrieved in the same request, so
+// they can't be split across chunks.
+var componentDependentTypes = map[string]bool{
+	"Profile":                     true,
+	"PermissionSet":               true,
+	"MutingPermissionSet":         true,
+	"CustomObjectTranslation":     true,
+	"Translations":                true,
+	"StandardValueSetTranslation": true,
+	"GlobalValueSetTranslation":   true,
+}
+
+// listMetadataBatchSize is the most queries the Metadata API accepts in a
+// single listMetadata call.
+const listMetadataBatchSize = 3
+
+type retrieveType struct {
+	Name    string   `xml:"name"`
+	Members []string `xml:"members"`
+}
+
+func (t retrieveType) isWildcard() bool {
+	for _, m := range t.Members {
+		if m == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// xml returns the type as a package.xml types element.
+func (t retrieveType) xml() ([]byte, error) {
+	return xml.MarshalIndent(struct {
+		retrieveType
+		XMLName xml.Name `xml:"types"`
+	}{retrieveType: t}, " ", "    ")
+}
+
+// retrieveTypes combines the elements of a query into one entry per
+// metadata type, keeping the order in which types first appear.
+func retrieveTypes(query ForceMetadataQuery) []retrieveType {
+	var types []retrieveType
+	index := make(map[string]int)
+	for _, element := range query {
+		for _, name := range element.Name {
+			i, ok := index[name]
+			if !ok {
+				i = len(types)
+				index[name] = i
+				types = append(types, retrieveType{Name: name})
+			}
+			types[i].Members = append(types[i].Members, element.Members...)
+		}
+	}
+	return types
+}
+
+// listQuery is a listMetadata query for the components of a type, or of a
+// foldered type in a folder.
+type listQuery struct {
+	Type   string
+	Folder string
+}
+
+func (q listQuery) xml() string {
+	if q.Folder == "" {
+		return fmt.Sprintf("<queries><type>%s</type></queries>", q.Type)
+	}
+	return fmt.Sprintf("<queries><type>%s</type><folder>%s</folder></queries>", q.Type, q.Folder)
+}
+
+// listMetadataBatch runs up to listMetadataBatchSize queries in a single
+// listMetadata call.
+func (fm *ForceMetadata) listMetadataBatch(queries []listQuery) ([]MDFileProperties, error) {
+	xmlQueries := ""
+	for _, q := range queries {
+		xmlQueries += q.xml()
+	}
+	body, err := fm.soapExecute("listMetadata", xmlQueries)
+	if err != nil {
+		return nil, err
+	}
+	var res struct {
+		Response ListMetadataResponse `xml:"Body>listMetadataResponse"`
+	}
+	if err = xml.Unmarshal(body, &res); err != nil {
+		return nil, err
+	}
+	return res.Response.Result, nil
+}
+
+// listWildcardMembers returns the members of each type requested with a
+// wildcard, as reported by listMetadata.  Components installed from managed
+// packages aren't included in wildcard retrieves, so they aren't counted.
+// Types that can't be listed are left out.
+func (fm *ForceMetadata) listWildcardMembers(types []retrieveType) map[string][]string {
+	var queries []listQuery
+	for _, t := range types {
+		if t.isWildcard() {
+			queries = append(queries, listQuery{Type: t.Name})
+		}
+	}
+	listed := make(map[string][]string)
+	for start := 0; start < len(queries); start += listMetadataBatchSize {
+		end := min(start+listMetadataBatchSize, len(queries))
+		properties, err := fm.listMetadataBatch(queries[start:end])
+		if err != nil {
+			continue
+		}
+		for _, q := range queries[start:end] {
+			listed[q.Type] = []string{}
+		}
+		for _, p := range properties {
+			if strings.HasPrefix(p.ManageableState, "installed") {
+				continue
+			}
+			listed[p.Type] = append(listed[p.Type], p.FullName)
+		}
+	}
+	return listed
+}
+
+// planRetrieveChunks splits the types to retrieve into chunks of at most
+// size components.  Wildcard types are kept as wildcards unless they have
+// more than size members, in which case their listed members are split
+// across chunks.
+func planRetrieveChunks(types []retrieveType, listed map[string][]string, size int) [][]retrieveType {
+	type item struct {
+		retrieveType
+		count int
+	}
+	var items []item
+	split := func(name string, members []string) {
+		for start := 0; start < len(members); start += size {
+			end := start + size
+			if end > len(members) {
+				end = len(members)
+			}
+			items = append(items, item{retrieveType{Name: name, Members: members[start:end]}, end - start})
+		}
+	}
+	for _, t := range types {
+		if !t.isWildcard() {
+			split(t.Name, t.Members)
+			continue
+		}
+		members := listed[t.Name]
+		if len(members) <= size {
+			count := len(members)
+			if count == 0 {
+				count = 1
+			}
+			items = append(items, item{t, count})
+			continue
+		}
+		// Explicit members are usually among the listed members, but
+		// installed components aren't listed.
+		seen := make(map[string]bool, len(members))
+		for _, m := range members {
+			seen[m] = true
+		}
+		members = append([]string{}, members...)
+		for _, m := range t.Members {
+			if m != "*" && !seen[m] {
+				seen[m] = true
+				members = append(members, m)
+			}
+		}
+		split(t.Name, members)
+	}
+
+	var chunks [][]retrieveType
+	var chunk []retrieveType
+	count := 0
+	for _, i := range items {
+		if count > 0 && count+i.count > size {
+			chunks = append(chunks, chunk)
+			chunk, count = nil, 0
+		}
+		chunk = append(chunk, i.retrieveType)
+		count += i.count
+	}
+	if len(chunk) > 0 {
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+// componentDependentTypeNames returns the types to retrieve whose contents
+// depend on the other components retrieved.
+func componentDependentTypeNames(types []retrieveType) []string {
+	var names []string
+	for _, t := range types {
+		if componentDependentTypes[t.Name] {
+			names = append(names, t.Name)
+		}
+	}
+	return names
+}
+
+// retrieveQuery retrieves the types, splitting them into several
+// concurrent retrieves if there are too many components for one.
+func (fm *ForceMetadata) retrieveQuery(types []retrieveType, handler RetrieveFileHandler, options RetrieveOptions) (problems []string, err error) {
+	chunks := planRetrieveChunks(types, fm.listWildcardMembers(types), RetrieveChunkSize)
+	if dependent := componentDependentTypeNames(types); len(chunks) > 1 && len(dependent) > 0 {
+		Log.Info(fmt.Sprintf("Not splitting the retrieve into chunks because %s only contain entries for the components retrieved with them.  It may exceed the Metadata API's retrieve limit; retrieve them separately if it does.", strings.Join(dependent, ", ")))
+		chunks = [][]retrieveType{types}
+	}
+	if len(chunks) <= 1 {
+		problems, _, err = fm.retrieveUnpackaged(types, "", options, handler)
+		return
+	}
+	Log.Info(fmt.Sprintf("Retrieving in %d chunks", len(chunks)))
+
+	// Chunks are extracted concurrently, but handler is called with one file
+	// at a time.  Each chunk has its own package.xml, so they're merged once
+	// all the chunks have been retrieved.
+	var mu sync.Mutex
+	var packageXmls [][]byte
+	chunkHandler := func(name string, r io.Reader) error {
+		if name == "package.xml" {
+			data, err := io.ReadAll(r)
+			if err != nil {
+				return err
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			packageXmls = append(packageXmls, data)
+			return nil
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		return handler(name, r)
+	}
+	chunkOptions := options
+	if options.FileProperties != nil {
+		chunkOptions.FileProperties = func(properties []MDFileProperties) {
+			mu.Lock()
+			defer mu.Unlock()
+			options.FileProperties(properties)
+		}
+	}
+
+	type result struct {
+		problems []string
+		err      error
+	}
+	results := make([]result, len(chunks))
+	sem := make(chan struct{}, RetrieveConcurrency)
+	var wg sync.WaitGroup
+	for i, chunk := range chunks {
+		wg.Add(1)
+		go func(i int, chunk []retrieveType) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			prefix := fmt.Sprintf("Chunk %d/%d: ", i+1, len(chunks))
+			r := &results[i]
+			var count int
+			chunkOptions := chunkOptions
+			chunkOptions.PreserveZip = chunkZipPath(options.PreserveZip, i+1)
+			r.problems, count, r.err = fm.retrieveUnpackaged(chunk, prefix, chunkOptions, chunkHandler)
+			if r.err == nil {
+				Log.Info(fmt.Sprintf("%sRetrieved %d files", prefix, count))
+			}
+		}(i, chunk)
+	}
+	wg.Wait()
+
+	for i, r := range results {
+		if r.err != nil {
+			return nil, fmt.Errorf("Chunk %d/%d failed: %w", i+1, len(chunks), r.err)
+		}
+		problems = append(problems, r.problems...)
+	}
+	if len(packageXmls) == 0 {
+		return
+	}
+	data, err := mergePackageXml(packageXmls)
+	if err != nil {
+		return nil, err
+	}
+	err = handler("package.xml", bytes.NewReader(data))
+	return
+}
+
+// chunkZipPath returns where to keep the zip file of the nth chunk of a
+// retrieve whose zip file is kept at path.
+func chunkZipPath(path string, n int) string {
+	if path == "" {
+		return ""
+	}
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s-%d%s", strings.TrimSuffix(path, ext), n, ext)
+}
+
+// mergePackageXml combines the package.xml files from several retrieves.
+func mergePackageXml(packageXmls [][]byte) ([]byte, error) {
+	merged := createPackage()
+	members := make(map[string]map[string]bool)
+	for _, data := range packageXmls {
+		var p Package
+		if err := xml.Unmarshal(data, &p); err != nil {
+			return nil, fmt.Errorf("Could not parse retrieved package.xml: %w", err)
+		}
+		if p.Version != "" {
+			merged.Version = p.Version
+		}
+		for _, t := range p.Types {
+			if members[t.Name] == nil {
+				members[t.Name] = make(map[string]bool)
+			}
+			for _, m := range t.Members {
+				members[t.Name][m] = true
+			}
+		}
+	}
+	for name, set := range members {
+		t := MetaType{Name: name}
+		for m := range set {
+			t.Members = append(t.Members, m)
+		}
+		sort.Strings(t.Members)
+		merged.Types = append(merged.Types, t)
+	}
+	sort.Slice(merged.Types, func(i, j int) bool { return merged.Types[i].Name < merged.Types[j].Name })
+	data, err := xml.MarshalIndent(merged, "", "    ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
@@ -0,0 +1,110 @@
+package lib
+
+import (
+	"encoding/xml"
+	"reflect"
+	"testing"
+)
+
+func TestRetrieveTypesCombinesElements(t *testing.T) {
+	query := ForceMetadataQuery{
+		{Name: []string{"ApexClass", "ApexTrigger"}, Members: []string{"*"}},
+		{Name: []string{"ApexClass"}, Members: []string{"Util"}},
+	}
+	expected := []retrieveType{
+		{Name: "ApexClass", Members: []string{"*", "Util"}},
+		{Name: "ApexTrigger", Members: []string{"*"}},
+	}
+	if types := retrieveTypes(query); !reflect.DeepEqual(types, expected) {
+		t.Errorf("Expected %v, got %v", expected, types)
+	}
+}
+
+func TestPlanRetrieveChunks(t *testing.T) {
+	types := []retrieveType{
+		{Name: "ApexClass", Members: []string{"*"}},
+		{Name: "CustomObject", Members: []string{"*"}},
+		{Name: "Layout", Members: []string{"Account-Layout", "Contact-Layout", "Lead-Layout"}},
+		{Name: "Settings", Members: []string{"*"}},
+	}
+	listed := map[string][]string{
+		"ApexClass":    {"A", "B"},
+		"CustomObject": {"Account", "Contact", "Lead", "Case", "Opportunity"},
+	}
+	chunks := planRetrieveChunks(types, listed, 4)
+	expected := [][]retrieveType{
+		{{Name: "ApexClass", Members: []string{"*"}}},
+		{{Name: "CustomObject", Members: []string{"Account", "Contact", "Lead", "Case"}}},
+		{{Name: "CustomObject", Members: []string{"Opportunity"}}, {Name: "Layout", Members: []string{"Account-Layout", "Contact-Layout", "Lead-Layout"}}},
+		{{Name: "Settings", Members: []string{"*"}}},
+	}
+	if !reflect.DeepEqual(chunks, expected) {
+		t.Errorf("Expected %v, got %v", expected, chunks)
+	}
+
+	if chunks := planRetrieveChunks(types, listed, 5000); len(chunks) != 1 || !reflect.DeepEqual(chunks[0], types) {
+		t.Errorf("Expected a single unchanged chunk, got %v", chunks)
+	}
+}
+
+func TestPlanRetrieveChunksKeepsUnlistedExplicitMembers(t *testing.T) {
+	types := []retrieveType{{Name: "ApexClass", Members: []string{"*", "B", "pkg__Installed"}}}
+	listed := map[string][]string{"ApexClass": {"A", "B", "C"}}
+	chunks := planRetrieveChunks(types, listed, 2)
+	expected := [][]retrieveType{
+		{{Name: "ApexClass", Members: []string{"A", "B"}}},
+		{{Name: "ApexClass", Members: []string{"C", "pkg__Installed"}}},
+	}
+	if !reflect.DeepEqual(chunks, expected) {
+		t.Errorf("Expected %v, got %v", expected, chunks)
+	}
+}
+
+func TestComponentDependentTypeNames(t *testing.T) {
+	types := []retrieveType{{Name: "ApexClass"}, {Name: "Profile"}, {Name: "CustomObjectTranslation"}}
+	if names := componentDependentTypeNames(types); !reflect.DeepEqual(names, []string{"Profile", "CustomObjectTranslation"}) {
+		t.Errorf("Unexpected dependent types %v", names)
+	}
+}
+
+func TestMergePackageXml(t *testing.T) {
+	packageXmls := [][]byte{
+		[]byte(`<Package xmlns="http://soap.sforce.com/2006/04/metadata"><types><members>A</members><name>ApexClass</name></types><version>60.0</version></Package>`),
+		[]byte(`<Package xmlns="http://soap.sforce.com/2006/04/metadata"><types><members>B</members><name>ApexClass</name></types><types><members>Account</members><name>CustomObject</name></types><version>60.0</version></Package>`),
+	}
+	data, err := mergePackageXml(packageXmls)
+	if err != nil {
+		t.Fatalf("mergePackageXml returned error: %v", err)
+	}
+	var p Package
+	if err := xml.Unmarshal(data, &p); err != nil {
+		t.Fatalf("Invalid merged package.xml: %v", err)
+	}
+	expected := []MetaType{
+		{Name: "ApexClass", Members: []string{"A", "B"}},
+		{Name: "CustomObject", Members: []string{"Account"}},
+	}
+	if !reflect.DeepEqual(p.Types, expected) || p.Version != "60.0" {
+		t.Errorf("Unexpected merged package: %+v", p)
+	}
+}
+
+func TestChunkZipPath(t *testing.T) {
+	if path := chunkZipPath("inbound.zip", 2); path != "inbound-2.zip" {
+		t.Errorf("Expected inbound-2.zip, got %s", path)
+	}
+	if path := chunkZipPath("", 2); path != "" {
+		t.Errorf("Expected no path, got %s", path)
+	}
+}
+
+func TestRetrieveTypeXml(t *testing.T) {
+	typeXml, err := retrieveType{Name: "Report", Members: []string{"Sales & Marketing/Pipeline"}}.xml()
+	if err != nil {
+		t.Fatalf("xml returned error: %v", err)
+	}
+	expected := " <types>\n     <name>Report</name>\n     <members>Sales &amp; Marketing/Pipeline</members>\n </types>"
+	if string(typeXml) != expected {
+		t.Errorf("Expected %s, got %s", expected, typeXml)
+	}
+}
@@ -0,0 +1,232 @@
+package lib
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// RetrieveFileHandler is called with each file in a retrieve's zip file as
+// it's extracted.  name is the file's slash-separated path within the zip
+// file.  r is only valid until the handler returns.
+type RetrieveFileHandler func(name string, r io.Reader) error
+
+type RetrieveOptions struct {
+	// PreserveZip is where to keep the retrieved zip file.  When a retrieve
+	// is split into chunks, each chunk's zip file is kept, numbered from 1,
+	// e.g. inbound-1.zip.  By default, the zip file is removed once it has
+	// been extracted.
+	PreserveZip string
+	// FileProperties, if set, is called with the properties of the retrieved
+	// components, as reported by each retrieve.
+	FileProperties func([]MDFileProperties)
+}
+
+// RetrieveToDirectory returns a RetrieveFileHandler that writes each
+// retrieved file to its path under dir.
+func RetrieveToDirectory(dir string) RetrieveFileHandler {
+	return func(name string, r io.Reader) error {
+		return WriteRetrievedFile(dir, name, r)
+	}
+}
+
+// WriteRetrievedFile writes a retrieved file to its path under dir, creating
+// any missing directories.
+func WriteRetrievedFile(dir string, name string, r io.Reader) error {
+	name = filepath.FromSlash(name)
+	if !filepath.IsLocal(name) {
+		return fmt.Errorf("Invalid file name in retrieved zip file: %s", name)
+	}
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// collectRetrievedFiles returns a RetrieveFileHandler that reads each
+// retrieved file into files.
+func collectRetrievedFiles(files ForceMetadataFiles) RetrieveFileHandler {
+	return func(name string, r io.Reader) error {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		files[name] = data
+		return nil
+	}
+}
+
+// stripUnpackaged returns a RetrieveFileHandler that removes the unpackaged/
+// directory from the names of retrieved files.
+func stripUnpackaged(handler RetrieveFileHandler) RetrieveFileHandler {
+	return func(name string, r io.Reader) error {
+		return handler(strings.TrimPrefix(name, "unpackaged/"), r)
+	}
+}
+
+// extractRetrieve downloads the zip file of a completed retrieve, to
+// options.PreserveZip or a temporary file, and calls handler with each file
+// in it.  It returns the number of files extracted.
+func (fm *ForceMetadata) extractRetrieve(id string, options RetrieveOptions, handler RetrieveFileHandler) (problems []string, count int, err error) {
+	var zipFile *os.File
+	if options.PreserveZip != "" {
+		zipFile, err = os.Create(options.PreserveZip)
+	} else {
+		zipFile, err = os.CreateTemp("", "force-retrieve-*.zip")
+		if err == nil {
+			defer os.Remove(zipFile.Name())
+		}
+	}
+	if err != nil {
+		return
+	}
+	defer zipFile.Close()
+	problems, properties, err := fm.downloadRetrieveZip(id, zipFile)
+	if err != nil {
+		return
+	}
+	if options.FileProperties != nil {
+		options.FileProperties(properties)
+	}
+	count, err = extractZip(zipFile, handler)
+	return
+}
+
+// downloadRetrieveZip writes the zip file of a completed retrieve to w
+// without holding it in memory.
+func (fm *ForceMetadata) downloadRetrieveZip(id string, w io.Writer) (problems []string, properties []MDFileProperties, err error) {
+	fm.Force.refreshExpiringSession()
+	url := fmt.Sprintf("%s/services/Soap/m/%s", fm.Force.InstanceUrl(), fm.ApiVersion)
+	soap := NewSoap(url, "http://soap.sforce.com/2006/04/metadata", fm.Force.AccessToken())
+	body, err := soap.ExecuteStream("checkRetrieveStatus", fmt.Sprintf("<id>%s</id>", id))
+	if err != nil {
+		return
+	}
+	defer body.Close()
+	response, err := decodeZipFile(body, w)
+	if err != nil {
+		return
+	}
+	if isSoapInvalidSessionError(response) {
+		if err = fm.Force.RefreshSession(); err != nil {
+			return
+		}
+		return fm.downloadRetrieveZip(id, w)
+	}
+	if err = processError(response); err != nil {
+		return
+	}
+	var status struct {
+		Problems   []string           `xml:"Body>checkRetrieveStatusResponse>result>messages>problem"`
+		Properties []MDFileProperties `xml:"Body>checkRetrieveStatusResponse>result>fileProperties"`
+	}
+	if err = xml.Unmarshal(response, &status); err != nil {
+		return
+	}
+	return status.Problems, status.Properties, nil
+}
+
+// decodeZipFile copies the base64-decoded contents of the zipFile element
+// in a checkRetrieveStatus response to w.  It returns the rest of the
+// response, with the zipFile element left empty.
+func decodeZipFile(r io.Reader, w io.Writer) ([]byte, error) {
+	start := []byte("<zipFile>")
+	br := bufio.NewReader(r)
+	var response bytes.Buffer
+	for !bytes.HasSuffix(response.Bytes(), start) {
+		tag, err := br.ReadSlice('>')
+		response.Write(tag)
+		switch {
+		case err == bufio.ErrBufferFull:
+			continue
+		case err == io.EOF:
+			// There's no zip file, e.g. because the response is a fault.
+			return response.Bytes(), nil
+		case err != nil:
+			return nil, err
+		}
+	}
+	if _, err := io.Copy(w, base64.NewDecoder(base64.StdEncoding, &xmlText{r: br})); err != nil {
+		return nil, fmt.Errorf("Could not decode retrieved zip file: %w", err)
+	}
+	if _, err := response.ReadFrom(br); err != nil {
+		return nil, err
+	}
+	return response.Bytes(), nil
+}
+
+// xmlText reads character data up to the start of the next tag.
+type xmlText struct {
+	r    *bufio.Reader
+	done bool
+}
+
+func (t *xmlText) Read(p []byte) (int, error) {
+	if t.done {
+		return 0, io.EOF
+	}
+	if t.r.Buffered() == 0 {
+		if _, err := t.r.Peek(1); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return 0, err
+		}
+	}
+	text, _ := t.r.Peek(min(t.r.Buffered(), len(p)))
+	if i := bytes.IndexByte(text, '<'); i >= 0 {
+		text = text[:i]
+		t.done = true
+	}
+	n := copy(p, text)
+	t.r.Discard(n)
+	if n == 0 && t.done {
+		return 0, io.EOF
+	}
+	return n, nil
+}
+
+// extractZip calls handler with each file in the zip file f, returning the
+// number of files.
+func extractZip(f *os.File, handler RetrieveFileHandler) (count int, err error) {
+	info, err := f.Stat()
+	if err != nil {
+		return
+	}
+	zipfiles, err := zip.NewReader(f, info.Size())
+	if err != nil {
+		return
+	}
+	for _, file := range zipfiles.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		fd, err := file.Open()
+		if err != nil {
+			return count, err
+		}
+		err = handler(file.Name, fd)
+		fd.Close()
+		if err != nil {
+			return count, err
+		}
+		count++
+	}
+	return
+}
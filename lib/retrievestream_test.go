@@ -0,0 +1,155 @@
+package lib
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testRetrieveZip(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(content))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func checkRetrieveStatusResponse(zipData []byte) string {
+	encoded := base64.StdEncoding.EncodeToString(zipData)
+	// Break the encoded zip file into lines, as Salesforce does.
+	var lines []string
+	for len(encoded) > 76 {
+		lines = append(lines, encoded[:76])
+		encoded = encoded[76:]
+	}
+	lines = append(lines, encoded)
+	return `<?xml version="1.0" encoding="UTF-8"?>
+<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/" xmlns="http://soap.sforce.com/2006/04/metadata">
+<soapenv:Body><checkRetrieveStatusResponse><result>
+<done>true</done>
+<fileProperties><fileName>unpackaged/classes/A.cls</fileName><fullName>A</fullName><lastModifiedByName>Jane</lastModifiedByName><lastModifiedDate>2026-03-04T05:06:07.000Z</lastModifiedDate><type>ApexClass</type></fileProperties>
+<messages><fileName>unpackaged/package.xml</fileName><problem>Entity type: 'Foo' is unknown</problem></messages>
+<status>Succeeded</status><success>true</success>
+<zipFile>` + strings.Join(lines, "\r\n") + `</zipFile>
+</result></checkRetrieveStatusResponse></soapenv:Body></soapenv:Envelope>`
+}
+
+func TestDecodeZipFile(t *testing.T) {
+	zipData := testRetrieveZip(t, map[string]string{"unpackaged/classes/A.cls": strings.Repeat("a", 10000)})
+	var decoded bytes.Buffer
+	response, err := decodeZipFile(strings.NewReader(checkRetrieveStatusResponse(zipData)), &decoded)
+	if err != nil {
+		t.Fatalf("decodeZipFile returned error: %v", err)
+	}
+	if !bytes.Equal(decoded.Bytes(), zipData) {
+		t.Errorf("Decoded zip file doesn't match")
+	}
+	if !strings.Contains(string(response), "<zipFile></zipFile>") || !strings.Contains(string(response), "<problem>") {
+		t.Errorf("Unexpected response: %s", response)
+	}
+}
+
+func TestDecodeZipFileWithoutZipFile(t *testing.T) {
+	fault := `<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/"><soapenv:Body><soapenv:Fault><faultcode>sf:INVALID_SESSION_ID</faultcode><faultstring>Invalid Session ID</faultstring></soapenv:Fault></soapenv:Body></soapenv:Envelope>`
+	var decoded bytes.Buffer
+	response, err := decodeZipFile(strings.NewReader(fault), &decoded)
+	if err != nil {
+		t.Fatalf("decodeZipFile returned error: %v", err)
+	}
+	if string(response) != fault || decoded.Len() != 0 {
+		t.Errorf("Expected the fault to be returned unchanged, got %s", response)
+	}
+	if !isSoapInvalidSessionError(response) {
+		t.Errorf("Expected an invalid session error")
+	}
+}
+
+func TestDecodeZipFileTruncated(t *testing.T) {
+	response := checkRetrieveStatusResponse(testRetrieveZip(t, map[string]string{"a": "a"}))
+	truncated := response[:strings.Index(response, "<zipFile>")+20]
+	if _, err := decodeZipFile(strings.NewReader(truncated), &bytes.Buffer{}); err == nil {
+		t.Errorf("Expected an error for a truncated zip file")
+	}
+}
+
+func TestRetrievePackageToDirectory(t *testing.T) {
+	zipData := testRetrieveZip(t, map[string]string{
+		"unpackaged/package.xml":      "<Package/>",
+		"unpackaged/classes/A.cls":    "public class A {}",
+		"unpackaged/objects/B.object": "<CustomObject/>",
+	})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Header.Get("SOAPAction") {
+		case "retrieve":
+			w.Write([]byte(`<Envelope><Body><retrieveResponse><result><id>09S000000000001</id></result></retrieveResponse></Body></Envelope>`))
+		case "checkStatus":
+			w.Write([]byte(`<Envelope><Body><checkStatusResponse><result><done>true</done><state>Completed</state></result></checkStatusResponse></Body></Envelope>`))
+		case "checkRetrieveStatus":
+			w.Write([]byte(checkRetrieveStatusResponse(zipData)))
+		default:
+			t.Errorf("Unexpected action %s", r.Header.Get("SOAPAction"))
+		}
+	}))
+	defer server.Close()
+
+	force := &Force{
+		Credentials: &ForceSession{
+			InstanceUrl: server.URL,
+			AccessToken: "test-token",
+		},
+	}
+	fm := NewForceMetadata(force)
+	dir := t.TempDir()
+	zipPath := filepath.Join(dir, "MyPackage.zip")
+	var properties []MDFileProperties
+	options := RetrieveOptions{
+		PreserveZip:    zipPath,
+		FileProperties: func(p []MDFileProperties) { properties = append(properties, p...) },
+	}
+	problems, err := fm.RetrievePackageTo("MyPackage", RetrieveToDirectory(filepath.Join(dir, "src")), options)
+	if err != nil {
+		t.Fatalf("RetrievePackageTo returned error: %v", err)
+	}
+	if len(problems) != 1 || problems[0] != "Entity type: 'Foo' is unknown" {
+		t.Errorf("Unexpected problems: %v", problems)
+	}
+	if len(properties) != 1 || properties[0].FullName != "A" || properties[0].LastModifiedByName != "Jane" {
+		t.Errorf("Unexpected file properties: %+v", properties)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "src", "classes", "A.cls"))
+	if err != nil || string(data) != "public class A {}" {
+		t.Errorf("Expected classes/A.cls to be written, got %q, %v", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "src", "package.xml")); err != nil {
+		t.Errorf("Expected package.xml to be written: %v", err)
+	}
+	preserved, err := os.ReadFile(zipPath)
+	if err != nil || !bytes.Equal(preserved, zipData) {
+		t.Errorf("Expected the zip file to be preserved: %v", err)
+	}
+}
+
+func TestWriteRetrievedFileRejectsEscapingPaths(t *testing.T) {
+	dir := t.TempDir()
+	if err := WriteRetrievedFile(dir, "../outside.cls", strings.NewReader("x")); err == nil {
+		t.Errorf("Expected an error for a path outside the directory")
+	}
+	if err := WriteRetrievedFile(dir, "/abs.cls", strings.NewReader("x")); err == nil {
+		t.Errorf("Expected an error for an absolute path")
+	}
+}
@@ -161,18 +161,31 @@ func (s *ScratchOrg) getSession() (session ForceSession, err error) {
 
 // DeploySettings deploys settings metadata to a scratch org
 func (f *Force) DeploySettings(settings []string) error {
-	files := buildSettingsMetadata(settings)
+	return f.DeploySettingsFiles(buildSettingsMetadata(settings))
+}
 
+// DeploySettingsFiles deploys settings metadata files, such as those built
+// from a scratch org definition, to a scratch org
+func (f *Force) DeploySettingsFiles(files ForceMetadataFiles) error {
+	if err := f.DeployMetadataFiles(files); err != nil {
+		return fmt.Errorf("settings deployment failed: %w", err)
+	}
+	return nil
+}
+
+// DeployMetadataFiles deploys metadata and waits for the deployment to
+// finish, returning an error describing any component failures.
+func (f *Force) DeployMetadataFiles(files ForceMetadataFiles) error {
 	// Deploy the metadata
 	options := ForceDeployOptions{}
 	result, err := f.Metadata.Deploy(files, options)
 	if err != nil {
-		return fmt.Errorf("settings deployment failed: %w", err)
+		return err
 	}
 
 	// Check if deployment succeeded
 	if !result.Success {
-		errorMsg := fmt.Sprintf("Settings deployment failed with status: %s", result.Status)
+		errorMsg := fmt.Sprintf("Deployment failed with status: %s", result.Status)
 		if result.ErrorMessage != "" {
 			errorMsg += fmt.Sprintf(": %s", result.ErrorMessage)
 		}
@@ -219,6 +232,18 @@ func settingsFile(rootElement string, flags []settingsFlag) []byte {
 	return buf.Bytes()
 }
 
+// settingsPackageXml returns a package.xml that deploys all Settings.
+func settingsPackageXml() []byte {
+	return []byte(fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<Package xmlns="http://soap.sforce.com/2006/04/metadata">
+    <types>
+        <members>*</members>
+        <name>Settings</name>
+    </types>
+    <version>%s</version>
+</Package>`, ApiVersionNumber()))
+}
+
 type settingsFlag struct {
 	name    string
 	enabled bool
@@ -228,15 +253,7 @@ func buildSettingsMetadata(settings []string) ForceMetadataFiles {
 	files := make(ForceMetadataFiles)
 
 	// Create package.xml at root of zip
-	packageXml := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
-<Package xmlns="http://soap.sforce.com/2006/04/metadata">
-    <types>
-        <members>*</members>
-        <name>Settings</name>
-    </types>
-    <version>%s</version>
-</Package>`, ApiVersionNumber())
-	files["unpackaged/package.xml"] = []byte(packageXml)
+	files["unpackaged/package.xml"] = settingsPackageXml()
 
 	// Track each preference individually so multiple flags can share one file.
 	var (
@@ -608,44 +625,12 @@ func (f *Force) CreateScratchOrgWithRelease(username string, features []string,
 }
 
 func (f *Force) CreateScratchOrgWithDuration(username string, features []string, edition string, settings []string, namespace string, release string, duration int) (id string, err error) {
-	params := make(map[string]string)
-	params["ConnectedAppCallbackUrl"] = "http://localhost:1717/OauthRedirect"
-	params["ConnectedAppConsumerKey"] = "PlatformCLI"
-	params["Country"] = "US"
-	params["DurationDays"] = fmt.Sprintf("%d", duration)
-
-	if edition != "" {
-		params["Edition"] = edition
-	} else {
-		params["Edition"] = "Developer"
-	}
-
-	baseFeatures := "AuthorApex;API;AddCustomApps:30;AddCustomTabs:30;ForceComPlatform;Sites;CustomerSelfService"
-	if len(features) > 0 {
-		for _, feature := range features {
-			baseFeatures += ";" + feature
-		}
-	}
-	params["Features"] = baseFeatures
-
 	// Note: settings parameter is kept for later deployment after org creation
-
-	params["OrgName"] = "Force CLI Scratch"
-	if namespace != "" {
-		params["Namespace"] = namespace
-	}
-	if username != "" {
-		params["Username"] = username
-	}
-	if release != "" {
-		params["Release"] = release
-	}
-	id, err, messages := f.CreateRecord("ScratchOrgInfo", params)
-	if err != nil {
-		if len(messages) == 1 && messages[0].ErrorCode == "NOT_FOUND" {
-			return "", DevHubOrgRequiredError
-		}
-		return
+	def := ScratchOrgDefinition{
+		Username: username,
+		Features: features,
+		Edition:  edition,
+		Release:  release,
 	}
-	return
+	return f.CreateScratchOrgFromDefinition(def, namespace, duration)
 }
@@ -0,0 +1,192 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+// ScratchOrgDefinition is a scratch org shape in the project-scratch-def.json
+// format used by the Salesforce CLI.
+type ScratchOrgDefinition struct {
+	OrgName       string                 `json:"orgName,omitempty"`
+	Edition       string                 `json:"edition,omitempty"`
+	Country       string                 `json:"country,omitempty"`
+	Language      string                 `json:"language,omitempty"`
+	Username      string                 `json:"username,omitempty"`
+	AdminEmail    string                 `json:"adminEmail,omitempty"`
+	Description   string                 `json:"description,omitempty"`
+	HasSampleData bool                   `json:"hasSampleData,omitempty"`
+	Release       string                 `json:"release,omitempty"`
+	Instance      string                 `json:"instance,omitempty"`
+	Snapshot      string                 `json:"snapshot,omitempty"`
+	SourceOrg     string                 `json:"sourceOrg,omitempty"`
+	Features      ScratchDefFeatures     `json:"features,omitempty"`
+	Settings      map[string]interface{} `json:"settings,omitempty"`
+}
+
+// ScratchDefFeatures is the list of features in a scratch org definition,
+// which may be given as either an array or a semicolon-separated string.
+type ScratchDefFeatures []string
+
+func (f *ScratchDefFeatures) UnmarshalJSON(data []byte) error {
+	var list []string
+	if err := json.Unmarshal(data, &list); err == nil {
+		*f = list
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("features must be an array or a semicolon-separated string")
+	}
+	*f = nil
+	for _, feature := range strings.Split(s, ";") {
+		if feature = strings.TrimSpace(feature); feature != "" {
+			*f = append(*f, feature)
+		}
+	}
+	return nil
+}
+
+// LoadScratchOrgDefinition reads a project-scratch-def.json file.
+func LoadScratchOrgDefinition(path string) (def ScratchOrgDefinition, err error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+	if err = json.Unmarshal(data, &def); err != nil {
+		err = fmt.Errorf("Could not parse scratch org definition %s: %w", path, err)
+	}
+	return
+}
+
+// SettingsMetadata converts the settings in the definition into Settings
+// metadata files that can be deployed with DeploySettingsFiles.  Each key,
+// e.g. lightningExperienceSettings, becomes a settings file, e.g.
+// LightningExperience.settings, with nested objects and arrays converted to
+// nested and repeated elements.
+func (d ScratchOrgDefinition) SettingsMetadata() (ForceMetadataFiles, error) {
+	files := make(ForceMetadataFiles)
+	if len(d.Settings) == 0 {
+		return files, nil
+	}
+	files["unpackaged/package.xml"] = settingsPackageXml()
+	for key, value := range d.Settings {
+		fields, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("settings.%s must be an object", key)
+		}
+		name := strings.TrimSuffix(key, "Settings")
+		if name == "" {
+			return nil, fmt.Errorf("Invalid settings name: %s", key)
+		}
+		name = strings.ToUpper(name[:1]) + name[1:]
+		root := name + "Settings"
+		var buf bytes.Buffer
+		buf.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+		fmt.Fprintf(&buf, "<%s xmlns=\"http://soap.sforce.com/2006/04/metadata\">\n", root)
+		writeSettingsElements(&buf, fields, 1)
+		fmt.Fprintf(&buf, "</%s>", root)
+		files["unpackaged/settings/"+name+".settings"] = buf.Bytes()
+	}
+	return files, nil
+}
+
+func writeSettingsElements(buf *bytes.Buffer, fields map[string]interface{}, depth int) {
+	var names []string
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		writeSettingsElement(buf, name, fields[name], depth)
+	}
+}
+
+func writeSettingsElement(buf *bytes.Buffer, name string, value interface{}, depth int) {
+	indent := strings.Repeat("    ", depth)
+	switch v := value.(type) {
+	case map[string]interface{}:
+		fmt.Fprintf(buf, "%s<%s>\n", indent, name)
+		writeSettingsElements(buf, v, depth+1)
+		fmt.Fprintf(buf, "%s</%s>\n", indent, name)
+	case []interface{}:
+		for _, item := range v {
+			writeSettingsElement(buf, name, item, depth)
+		}
+	case nil:
+	default:
+		var text bytes.Buffer
+		xml.EscapeText(&text, []byte(fmt.Sprint(v)))
+		fmt.Fprintf(buf, "%s<%s>%s</%s>\n", indent, name, text.String(), name)
+	}
+}
+
+// createParams returns the ScratchOrgInfo fields for the definition.
+func (d ScratchOrgDefinition) createParams() map[string]string {
+	params := make(map[string]string)
+	params["ConnectedAppCallbackUrl"] = "http://localhost:1717/OauthRedirect"
+	params["ConnectedAppConsumerKey"] = "PlatformCLI"
+
+	params["Country"] = "US"
+	if d.Country != "" {
+		params["Country"] = d.Country
+	}
+	params["Edition"] = "Developer"
+	if d.Edition != "" {
+		params["Edition"] = d.Edition
+	}
+	params["OrgName"] = "Force CLI Scratch"
+	if d.OrgName != "" {
+		params["OrgName"] = d.OrgName
+	}
+
+	baseFeatures := "AuthorApex;API;AddCustomApps:30;AddCustomTabs:30;ForceComPlatform;Sites;CustomerSelfService"
+	for _, feature := range d.Features {
+		baseFeatures += ";" + feature
+	}
+	params["Features"] = baseFeatures
+
+	optional := map[string]string{
+		"Username":    d.Username,
+		"AdminEmail":  d.AdminEmail,
+		"Description": d.Description,
+		"Language":    d.Language,
+		"Release":     d.Release,
+		"Instance":    d.Instance,
+		"Snapshot":    d.Snapshot,
+		"SourceOrg":   d.SourceOrg,
+	}
+	for field, value := range optional {
+		if value != "" {
+			params[field] = value
+		}
+	}
+	if d.HasSampleData {
+		params["HasSampleData"] = "true"
+	}
+	return params
+}
+
+// CreateScratchOrgFromDefinition creates a new scratch org from a Dev Hub org
+// using the shape in def.  Settings are not part of the ScratchOrgInfo record;
+// deploy them once the org is ready.
+func (f *Force) CreateScratchOrgFromDefinition(def ScratchOrgDefinition, namespace string, duration int) (id string, err error) {
+	params := def.createParams()
+	params["DurationDays"] = fmt.Sprintf("%d", duration)
+	if namespace != "" {
+		params["Namespace"] = namespace
+	}
+	id, err, messages := f.CreateRecord("ScratchOrgInfo", params)
+	if err != nil {
+		if len(messages) == 1 && messages[0].ErrorCode == "NOT_FOUND" {
+			return "", DevHubOrgRequiredError
+		}
+		return
+	}
+	return
+}
@@ -0,0 +1,98 @@
+package lib
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const testScratchDef = `{
+	"orgName": "Acme",
+	"edition": "Enterprise",
+	"features": "PersonAccounts; StateAndCountryPicklist",
+	"hasSampleData": true,
+	"settings": {
+		"lightningExperienceSettings": {"enableS1DesktopEnabled": true},
+		"securitySettings": {
+			"passwordPolicies": {"enableSetPasswordInApi": true, "minimumPasswordLength": 8}
+		},
+		"languageSettings": {"enableTranslationWorkbench": true, "enableEndUserLanguages": false}
+	}
+}`
+
+func TestScratchOrgDefinition_features(t *testing.T) {
+	var def ScratchOrgDefinition
+	if err := json.Unmarshal([]byte(testScratchDef), &def); err != nil {
+		t.Fatalf("Failed to parse definition: %v", err)
+	}
+	if len(def.Features) != 2 || def.Features[1] != "StateAndCountryPicklist" {
+		t.Errorf("Unexpected features: %v", def.Features)
+	}
+	if err := json.Unmarshal([]byte(`{"features": ["Communities"]}`), &def); err != nil || def.Features[0] != "Communities" {
+		t.Errorf("Expected features array to be parsed, got %v, %v", def.Features, err)
+	}
+}
+
+func TestScratchOrgDefinition_createParams(t *testing.T) {
+	var def ScratchOrgDefinition
+	json.Unmarshal([]byte(testScratchDef), &def)
+	params := def.createParams()
+	if params["Edition"] != "Enterprise" || params["OrgName"] != "Acme" || params["Country"] != "US" {
+		t.Errorf("Unexpected params: %v", params)
+	}
+	if !strings.HasSuffix(params["Features"], ";PersonAccounts;StateAndCountryPicklist") {
+		t.Errorf("Expected definition features to be added, got %s", params["Features"])
+	}
+	if params["HasSampleData"] != "true" {
+		t.Errorf("Expected HasSampleData to be set")
+	}
+	if _, ok := params["Username"]; ok {
+		t.Errorf("Expected empty Username to be omitted")
+	}
+}
+
+func TestScratchOrgDefinition_SettingsMetadata(t *testing.T) {
+	var def ScratchOrgDefinition
+	json.Unmarshal([]byte(testScratchDef), &def)
+	files, err := def.SettingsMetadata()
+	if err != nil {
+		t.Fatalf("SettingsMetadata returned error: %v", err)
+	}
+	if _, ok := files["unpackaged/package.xml"]; !ok {
+		t.Errorf("Expected package.xml")
+	}
+	security := string(files["unpackaged/settings/Security.settings"])
+	expected := `<SecuritySettings xmlns="http://soap.sforce.com/2006/04/metadata">
+    <passwordPolicies>
+        <enableSetPasswordInApi>true</enableSetPasswordInApi>
+        <minimumPasswordLength>8</minimumPasswordLength>
+    </passwordPolicies>
+</SecuritySettings>`
+	if !strings.Contains(security, expected) {
+		t.Errorf("Unexpected Security.settings:\n%s", security)
+	}
+	language := string(files["unpackaged/settings/Language.settings"])
+	if !strings.Contains(language, "<enableEndUserLanguages>false</enableEndUserLanguages>") {
+		t.Errorf("Expected false preferences to be kept:\n%s", language)
+	}
+	if _, ok := files["unpackaged/settings/LightningExperience.settings"]; !ok {
+		t.Errorf("Expected LightningExperience.settings")
+	}
+
+	def.Settings = map[string]interface{}{"securitySettings": true}
+	if _, err := def.SettingsMetadata(); err == nil {
+		t.Errorf("Expected error for settings that aren't an object")
+	}
+}
+
+func TestScratchOrgInfo_Matches(t *testing.T) {
+	org := ScratchOrgInfo{Id: "2SR000000000001AAA", OrgId: "00D000000000001", SignupUsername: "test@example.com"}
+	for _, identifier := range []string{"Test@Example.com", "00D000000000001AAA", "00D000000000001", "2SR000000000001AAA"} {
+		if !org.Matches(identifier) {
+			t.Errorf("Expected %s to match", identifier)
+		}
+	}
+	if org.Matches("other@example.com") {
+		t.Errorf("Expected other username not to match")
+	}
+}
@@ -0,0 +1,107 @@
+package lib
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ScratchOrgInfo describes an active scratch org created from a Dev Hub.
+type ScratchOrgInfo struct {
+	Id             string
+	OrgId          string
+	SignupUsername string
+	OrgName        string
+	Edition        string
+	Status         string
+	ExpirationDate string
+	CreatedDate    string
+	CreatedBy      string
+	LoginUrl       string
+}
+
+// Matches returns whether identifier is the scratch org's username, org id,
+// or ScratchOrgInfo id.
+func (s ScratchOrgInfo) Matches(identifier string) bool {
+	switch {
+	case strings.EqualFold(identifier, s.SignupUsername):
+		return true
+	case len(identifier) >= 15 && len(s.OrgId) >= 15 && strings.EqualFold(identifier[:15], s.OrgId[:15]):
+		return true
+	case len(identifier) >= 15 && len(s.Id) >= 15 && strings.EqualFold(identifier[:15], s.Id[:15]):
+		return true
+	}
+	return false
+}
+
+func scratchOrgInfoFromRecord(r ForceRecord) ScratchOrgInfo {
+	str := func(field string) string {
+		s, _ := r[field].(string)
+		return s
+	}
+	info := ScratchOrgInfo{
+		Id:             str("Id"),
+		OrgId:          str("ScratchOrg"),
+		SignupUsername: str("SignupUsername"),
+		OrgName:        str("OrgName"),
+		Edition:        str("Edition"),
+		Status:         str("Status"),
+		ExpirationDate: str("ExpirationDate"),
+		CreatedDate:    str("CreatedDate"),
+		LoginUrl:       str("LoginUrl"),
+	}
+	if createdBy, ok := r["CreatedBy"].(map[string]interface{}); ok {
+		info.CreatedBy, _ = createdBy["Username"].(string)
+	}
+	return info
+}
+
+// ListScratchOrgs returns the active scratch orgs in the Dev Hub.  Unless all
+// is true, only scratch orgs created by the current user are returned.
+func (f *Force) ListScratchOrgs(all bool) (orgs []ScratchOrgInfo, err error) {
+	soql := "SELECT Id, ScratchOrg, SignupUsername, OrgName, Edition, Status, ExpirationDate, CreatedDate, CreatedBy.Username, LoginUrl FROM ScratchOrgInfo WHERE Status = 'Active'"
+	if !all && f.Credentials.UserInfo != nil && f.Credentials.UserInfo.UserId != "" {
+		soql += fmt.Sprintf(" AND CreatedById = '%s'", f.Credentials.UserInfo.UserId)
+	}
+	soql += " ORDER BY CreatedDate"
+	result, err := f.Query(soql)
+	if err != nil {
+		if strings.Contains(err.Error(), "ScratchOrgInfo") {
+			err = DevHubOrgRequiredError
+		}
+		return
+	}
+	for _, r := range result.Records {
+		orgs = append(orgs, scratchOrgInfoFromRecord(r))
+	}
+	return
+}
+
+// FindScratchOrg returns the active scratch org with the given username, org
+// id, or ScratchOrgInfo id.
+func (f *Force) FindScratchOrg(identifier string) (org ScratchOrgInfo, err error) {
+	orgs, err := f.ListScratchOrgs(true)
+	if err != nil {
+		return
+	}
+	for _, o := range orgs {
+		if o.Matches(identifier) {
+			return o, nil
+		}
+	}
+	err = fmt.Errorf("No active scratch org found for %s", identifier)
+	return
+}
+
+// DeleteScratchOrg deletes a scratch org by deleting its ActiveScratchOrg
+// record from the Dev Hub.
+func (f *Force) DeleteScratchOrg(org ScratchOrgInfo) error {
+	result, err := f.Query(fmt.Sprintf("SELECT Id FROM ActiveScratchOrg WHERE ScratchOrgInfoId = '%s'", org.Id))
+	if err != nil {
+		return err
+	}
+	if len(result.Records) == 0 {
+		return fmt.Errorf("No active scratch org found for %s", org.SignupUsername)
+	}
+	id, _ := result.Records[0]["Id"].(string)
+	return f.DeleteRecord("ActiveScratchOrg", id)
+}
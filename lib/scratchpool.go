@@ -0,0 +1,462 @@
+package lib
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Scratch org pools keep a number of ready-to-use scratch orgs so CI jobs
+// don't have to wait for a new org.  The pool and state of each org are
+// stored in the Description of its ScratchOrgInfo record in the Dev Hub, as
+// force-pool:<pool>:<state>, so any job logged into the Dev Hub can see them.
+// The credentials used to log into a pool org are stored in a custom text
+// field on ScratchOrgInfo, since the org's AuthCode can only be used once.
+const scratchPoolTagPrefix = "force-pool:"
+
+const salesforceDateTimeLayout = "2006-01-02T15:04:05.000-0700"
+
+const (
+	ScratchPoolProvisioning = "provisioning"
+	ScratchPoolAvailable    = "available"
+	ScratchPoolClaimed      = "claimed"
+)
+
+// DefaultScratchPoolAuthField is the ScratchOrgInfo field used to store the
+// credentials for pool orgs if the pool doesn't specify one.
+const DefaultScratchPoolAuthField = "Pool_Auth__c"
+
+var ScratchPoolEmptyError = errors.New("No available scratch orgs in pool")
+
+// ScratchPool is the configuration of a scratch org pool, read from a JSON
+// file.  Paths are relative to the file.
+type ScratchPool struct {
+	Name           string           `json:"name"`
+	DefinitionFile string           `json:"definitionFile"`
+	Size           int              `json:"size"`
+	Duration       int              `json:"duration,omitempty"`
+	Namespace      string           `json:"namespace,omitempty"`
+	AuthField      string           `json:"authField,omitempty"`
+	Setup          ScratchPoolSetup `json:"setup,omitempty"`
+
+	dir string
+}
+
+// ScratchPoolSetup lists the steps run against each new pool org, in order:
+// metadata directories to deploy, packages to install, and anonymous Apex
+// files to execute.
+type ScratchPoolSetup struct {
+	Deploy   []string             `json:"deploy,omitempty"`
+	Packages []ScratchPoolPackage `json:"packages,omitempty"`
+	Apex     []string             `json:"apex,omitempty"`
+}
+
+type ScratchPoolPackage struct {
+	Id       string `json:"id"`
+	Password string `json:"password,omitempty"`
+}
+
+// LoadScratchPool reads a pool configuration file.
+func LoadScratchPool(path string) (pool ScratchPool, err error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+	if err = json.Unmarshal(data, &pool); err != nil {
+		err = fmt.Errorf("Could not parse pool %s: %w", path, err)
+		return
+	}
+	pool.dir = filepath.Dir(path)
+	if pool.Name == "" || strings.Contains(pool.Name, ":") {
+		err = fmt.Errorf("Pool %s must have a name without colons", path)
+		return
+	}
+	if pool.Size < 1 {
+		err = fmt.Errorf("Pool %s must have a size of at least 1", path)
+		return
+	}
+	if pool.Duration == 0 {
+		pool.Duration = 7
+	}
+	if pool.AuthField == "" {
+		pool.AuthField = DefaultScratchPoolAuthField
+	}
+	return
+}
+
+func (p ScratchPool) path(name string) string {
+	if filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(p.dir, name)
+}
+
+func (p ScratchPool) definition() (def ScratchOrgDefinition, err error) {
+	if p.DefinitionFile != "" {
+		def, err = LoadScratchOrgDefinition(p.path(p.DefinitionFile))
+	}
+	return
+}
+
+func scratchPoolTag(pool, state string) string {
+	return scratchPoolTagPrefix + pool + ":" + state
+}
+
+// parseScratchPoolTag splits a pool tag into the pool name, state, and, for
+// claimed orgs, the claim token.
+func parseScratchPoolTag(description string) (pool, state, token string, ok bool) {
+	if !strings.HasPrefix(description, scratchPoolTagPrefix) {
+		return
+	}
+	parts := strings.SplitN(strings.TrimPrefix(description, scratchPoolTagPrefix), ":", 3)
+	if len(parts) < 2 {
+		return
+	}
+	pool, state = parts[0], parts[1]
+	if len(parts) == 3 {
+		token = parts[2]
+	}
+	return pool, state, token, true
+}
+
+// ScratchPoolOrg is a scratch org in a pool.
+type ScratchPoolOrg struct {
+	ScratchOrgInfo
+	Pool             string
+	State            string
+	Auth             string
+	LastModifiedDate string
+}
+
+// ListScratchPoolOrgs returns the active scratch orgs in pool, or in all
+// pools if pool is empty.  authField is the field holding the credentials for
+// the org; if empty, credentials aren't returned.
+func (f *Force) ListScratchPoolOrgs(pool string, authField string) (orgs []ScratchPoolOrg, err error) {
+	fields := "Id, ScratchOrg, SignupUsername, OrgName, Edition, Status, ExpirationDate, CreatedDate, CreatedBy.Username, LoginUrl, Description, LastModifiedDate"
+	if authField != "" {
+		fields += ", " + authField
+	}
+	result, err := f.Query(fmt.Sprintf("SELECT %s FROM ScratchOrgInfo WHERE Status = 'Active' ORDER BY ExpirationDate, CreatedDate", fields))
+	if err != nil {
+		return
+	}
+	for _, r := range result.Records {
+		description, _ := r["Description"].(string)
+		orgPool, state, _, ok := parseScratchPoolTag(description)
+		if !ok || (pool != "" && orgPool != pool) {
+			continue
+		}
+		org := ScratchPoolOrg{
+			ScratchOrgInfo: scratchOrgInfoFromRecord(r),
+			Pool:           orgPool,
+			State:          state,
+		}
+		org.LastModifiedDate, _ = r["LastModifiedDate"].(string)
+		if authField != "" {
+			org.Auth, _ = r[authField].(string)
+		}
+		orgs = append(orgs, org)
+	}
+	return
+}
+
+// ScratchPoolStatus counts the orgs in each state, by pool.
+func ScratchPoolStatus(orgs []ScratchPoolOrg) map[string]map[string]int {
+	status := make(map[string]map[string]int)
+	for _, org := range orgs {
+		if status[org.Pool] == nil {
+			status[org.Pool] = make(map[string]int)
+		}
+		status[org.Pool][org.State]++
+	}
+	return status
+}
+
+// Orgs that have been provisioning for longer than this are assumed to have
+// been abandoned by the job creating them.
+var ScratchPoolProvisioningTimeout = time.Hour
+
+// Shortfall returns the number of orgs that need to be created to bring the
+// pool up to its size, counting available orgs and orgs still being
+// provisioned.
+func (p ScratchPool) Shortfall(orgs []ScratchPoolOrg) int {
+	ready := 0
+	for _, org := range orgs {
+		if org.Pool != p.Name {
+			continue
+		}
+		switch org.State {
+		case ScratchPoolAvailable:
+			ready++
+		case ScratchPoolProvisioning:
+			created, err := time.Parse(salesforceDateTimeLayout, org.CreatedDate)
+			if err == nil && time.Since(created) < ScratchPoolProvisioningTimeout {
+				ready++
+			}
+		}
+	}
+	if ready >= p.Size {
+		return 0
+	}
+	return p.Size - ready
+}
+
+// CreateScratchPoolOrgs adds count new orgs to a pool.  The orgs are created
+// concurrently; each is logged into, set up, and then marked available.  If
+// setup fails, the org is deleted.  progress, if not nil, is called with
+// status messages.  The number of orgs added to the pool is returned along
+// with any errors.
+func (f *Force) CreateScratchPoolOrgs(pool ScratchPool, count int, progress func(string)) (int, error) {
+	def, err := pool.definition()
+	if err != nil {
+		return 0, err
+	}
+	settings, err := def.SettingsMetadata()
+	if err != nil {
+		return 0, err
+	}
+	if progress == nil {
+		progress = func(string) {}
+	}
+	var ids []string
+	var errs []string
+	for i := 0; i < count; i++ {
+		def.Description = scratchPoolTag(pool.Name, ScratchPoolProvisioning)
+		id, err := f.CreateScratchOrgFromDefinition(def, pool.Namespace, pool.Duration)
+		if err != nil {
+			errs = append(errs, err.Error())
+			break
+		}
+		ids = append(ids, id)
+	}
+	progress(fmt.Sprintf("Requested %d scratch orgs for pool %s", len(ids), pool.Name))
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	added := 0
+	for _, id := range ids {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			username, err := f.provisionScratchPoolOrg(pool, id, settings)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("%s: %s", id, err.Error()))
+				progress(fmt.Sprintf("Failed to set up %s: %s", id, err.Error()))
+				return
+			}
+			added++
+			progress(fmt.Sprintf("Added %s to pool %s", username, pool.Name))
+		}(id)
+	}
+	wg.Wait()
+	if len(errs) > 0 {
+		return added, errors.New(strings.Join(errs, "\n"))
+	}
+	return added, nil
+}
+
+func (f *Force) provisionScratchPoolOrg(pool ScratchPool, scratchOrgInfoId string, settings ForceMetadataFiles) (username string, err error) {
+	session, err := f.ForceLoginNewScratch(scratchOrgInfoId)
+	if err == nil && len(settings) > 0 {
+		err = NewForce(&session).DeploySettingsFiles(settings)
+	}
+	if err == nil {
+		err = runScratchPoolSetup(NewForce(&session), pool)
+	}
+	if err == nil {
+		err = f.UpdateRecord("ScratchOrgInfo", scratchOrgInfoId, map[string]string{
+			"Description":  scratchPoolTag(pool.Name, ScratchPoolAvailable),
+			pool.AuthField: scratchPoolAuthUrl(session),
+		})
+	}
+	if err != nil {
+		if org, findErr := f.FindScratchOrg(scratchOrgInfoId); findErr == nil {
+			f.DeleteScratchOrg(org)
+		}
+		return
+	}
+	username = scratchOrgInfoId
+	if record, err := f.GetRecord("ScratchOrgInfo", scratchOrgInfoId); err == nil {
+		username, _ = record["SignupUsername"].(string)
+	}
+	return
+}
+
+func runScratchPoolSetup(f *Force, pool ScratchPool) error {
+	for _, dir := range pool.Setup.Deploy {
+		files, err := ReadMetadataDirectory(pool.path(dir))
+		if err != nil {
+			return err
+		}
+		if err := f.DeployMetadataFiles(files); err != nil {
+			return fmt.Errorf("Deploy of %s failed: %w", dir, err)
+		}
+	}
+	for _, p := range pool.Setup.Packages {
+		if err := f.InstallPackageVersion(p.Id, p.Password, false, nil); err != nil {
+			return fmt.Errorf("Install of %s failed: %w", p.Id, err)
+		}
+	}
+	for _, file := range pool.Setup.Apex {
+		code, err := ioutil.ReadFile(pool.path(file))
+		if err != nil {
+			return err
+		}
+		if _, err := f.Partner.ExecuteAnonymous(string(code)); err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+	}
+	return nil
+}
+
+// scratchPoolAuthUrl returns the credentials for a pool org in the same
+// format as an SFDX auth URL.
+func scratchPoolAuthUrl(session ForceSession) string {
+	clientId := session.ClientId
+	if clientId == "" {
+		clientId = "PlatformCLI"
+	}
+	host := strings.TrimPrefix(session.InstanceUrl, "https://")
+	return fmt.Sprintf("force://%s::%s@%s", clientId, session.RefreshToken, host)
+}
+
+func parseScratchPoolAuthUrl(authUrl string) (session ForceSession, err error) {
+	invalid := errors.New("Invalid pool credentials")
+	if !strings.HasPrefix(authUrl, "force://") {
+		return session, invalid
+	}
+	rest := strings.TrimPrefix(authUrl, "force://")
+	at := strings.LastIndex(rest, "@")
+	if at < 0 {
+		return session, invalid
+	}
+	credentials, host := rest[:at], rest[at+1:]
+	parts := strings.SplitN(credentials, "::", 2)
+	if len(parts) != 2 || parts[1] == "" || host == "" {
+		return session, invalid
+	}
+	clientId, refreshToken := parts[0], parts[1]
+	session = ForceSession{
+		InstanceUrl:   "https://" + host,
+		EndpointUrl:   "https://" + host,
+		ClientId:      clientId,
+		RefreshToken:  refreshToken,
+		ForceEndpoint: EndpointTest,
+		SessionOptions: &SessionOptions{
+			RefreshMethod: RefreshOauth,
+		},
+	}
+	return
+}
+
+// newScratchPoolClaimToken returns a token identifying a claim, including the
+// host name so claimed orgs can be traced back to the job that claimed them.
+func newScratchPoolClaimToken() string {
+	b := make([]byte, 6)
+	rand.Read(b)
+	host, _ := os.Hostname()
+	token := hex.EncodeToString(b)
+	if host != "" {
+		token += "@" + host
+	}
+	return token
+}
+
+// ClaimScratchPoolOrg claims an available org from a pool and returns a
+// session for it.  Orgs that expire soonest are claimed first.
+//
+// Claims are safe to make concurrently: the org is marked claimed using a
+// conditional update that fails if the ScratchOrgInfo record changed since it
+// was read, and the claim token is then read back to confirm that this job
+// won.  If another job claims the org first, the next org is tried.
+func (f *Force) ClaimScratchPoolOrg(pool string, authField string) (session ForceSession, org ScratchPoolOrg, err error) {
+	if authField == "" {
+		authField = DefaultScratchPoolAuthField
+	}
+	orgs, err := f.ListScratchPoolOrgs(pool, authField)
+	if err != nil {
+		return
+	}
+	sort.SliceStable(orgs, func(i, j int) bool {
+		return orgs[i].ExpirationDate < orgs[j].ExpirationDate
+	})
+	for _, candidate := range orgs {
+		if candidate.State != ScratchPoolAvailable {
+			continue
+		}
+		token := newScratchPoolClaimToken()
+		claimed, claimErr := f.claimScratchOrgInfo(candidate, scratchPoolTag(pool, ScratchPoolClaimed)+":"+token)
+		if claimErr != nil {
+			err = claimErr
+			return
+		}
+		if !claimed {
+			continue
+		}
+		session, err = parseScratchPoolAuthUrl(candidate.Auth)
+		if err == nil {
+			scratchForce := NewForce(&session)
+			if err = scratchForce.RefreshSession(); err == nil {
+				session = *scratchForce.Credentials
+			}
+		}
+		if err != nil {
+			err = fmt.Errorf("Claimed %s, but could not log in: %w", candidate.SignupUsername, err)
+		}
+		org = candidate
+		org.State = ScratchPoolClaimed
+		return
+	}
+	err = ScratchPoolEmptyError
+	return
+}
+
+// claimScratchOrgInfo sets the Description of org to claim unless it has been
+// modified since it was listed, then confirms that the claim stuck.
+func (f *Force) claimScratchOrgInfo(org ScratchPoolOrg, claim string) (bool, error) {
+	lastModified, err := time.Parse(salesforceDateTimeLayout, org.LastModifiedDate)
+	if err != nil {
+		return false, fmt.Errorf("Invalid LastModifiedDate for %s: %w", org.SignupUsername, err)
+	}
+	body, _ := json.Marshal(map[string]string{"Description": claim})
+	endpoint := fmt.Sprintf("%s/services/data/%s/sobjects/ScratchOrgInfo/%s", f.InstanceUrl(), apiVersion, org.Id)
+	ifUnmodifiedSince := func(req *http.Request) {
+		req.Header.Set("If-Unmodified-Since", lastModified.UTC().Format(http.TimeFormat))
+	}
+	res, err := f.httpPostPatch(endpoint, string(body), ContentTypeJson, HttpMethodPatch, ifUnmodifiedSince)
+	if err == SessionExpiredError {
+		if err = f.RefreshSession(); err != nil {
+			return false, err
+		}
+		res, err = f.httpPostPatch(endpoint, string(body), ContentTypeJson, HttpMethodPatch, ifUnmodifiedSince)
+	}
+	if err != nil {
+		return false, err
+	}
+	resBody, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	switch {
+	case res.StatusCode == http.StatusPreconditionFailed:
+		return false, nil
+	case res.StatusCode/100 != 2:
+		return false, f._coerceHttpError(res, resBody)
+	}
+	record, err := f.GetRecord("ScratchOrgInfo", org.Id)
+	if err != nil {
+		return false, err
+	}
+	description, _ := record["Description"].(string)
+	return description == claim, nil
+}
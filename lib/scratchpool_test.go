@@ -0,0 +1,98 @@
+package lib
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseScratchPoolTag(t *testing.T) {
+	pool, state, token, ok := parseScratchPoolTag("force-pool:ci:claimed:abc123@runner")
+	if !ok || pool != "ci" || state != ScratchPoolClaimed || token != "abc123@runner" {
+		t.Errorf("Unexpected tag: %s %s %s %v", pool, state, token, ok)
+	}
+	if _, _, _, ok := parseScratchPoolTag("My scratch org"); ok {
+		t.Errorf("Expected description without tag not to parse")
+	}
+}
+
+func TestScratchPoolAuthUrl_round_trip(t *testing.T) {
+	url := scratchPoolAuthUrl(ForceSession{
+		InstanceUrl:  "https://power-dream-1234.scratch.my.salesforce.com",
+		RefreshToken: "5Aep861.token",
+	})
+	if url != "force://PlatformCLI::5Aep861.token@power-dream-1234.scratch.my.salesforce.com" {
+		t.Errorf("Unexpected auth url: %s", url)
+	}
+	session, err := parseScratchPoolAuthUrl(url)
+	if err != nil {
+		t.Fatalf("parseScratchPoolAuthUrl returned error: %v", err)
+	}
+	if session.RefreshToken != "5Aep861.token" || session.ClientId != "PlatformCLI" || session.InstanceUrl != "https://power-dream-1234.scratch.my.salesforce.com" {
+		t.Errorf("Unexpected session: %+v", session)
+	}
+	if _, err := parseScratchPoolAuthUrl(""); err == nil {
+		t.Errorf("Expected error for missing credentials")
+	}
+}
+
+func TestScratchPool_Shortfall(t *testing.T) {
+	pool := ScratchPool{Name: "ci", Size: 4}
+	recent := time.Now().Add(-10 * time.Minute).Format(salesforceDateTimeLayout)
+	stale := time.Now().Add(-2 * time.Hour).Format(salesforceDateTimeLayout)
+	orgs := []ScratchPoolOrg{
+		{Pool: "ci", State: ScratchPoolAvailable},
+		{Pool: "ci", State: ScratchPoolClaimed},
+		{Pool: "ci", State: ScratchPoolProvisioning, ScratchOrgInfo: ScratchOrgInfo{CreatedDate: recent}},
+		{Pool: "ci", State: ScratchPoolProvisioning, ScratchOrgInfo: ScratchOrgInfo{CreatedDate: stale}},
+		{Pool: "other", State: ScratchPoolAvailable},
+	}
+	if shortfall := pool.Shortfall(orgs); shortfall != 2 {
+		t.Errorf("Expected shortfall of 2, got %d", shortfall)
+	}
+}
+
+func TestClaimScratchOrgInfo(t *testing.T) {
+	lastModified := "2024-05-01T12:00:00.000+0000"
+	description := scratchPoolTag("ci", ScratchPoolAvailable)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPatch:
+			if r.Header.Get("If-Unmodified-Since") != "Wed, 01 May 2024 12:00:00 GMT" {
+				t.Errorf("Unexpected If-Unmodified-Since: %s", r.Header.Get("If-Unmodified-Since"))
+			}
+			if strings.HasSuffix(r.URL.Path, "/2SR000000000002AAA") {
+				w.WriteHeader(http.StatusPreconditionFailed)
+				return
+			}
+			body, _ := ioutil.ReadAll(r.Body)
+			var fields map[string]string
+			json.Unmarshal(body, &fields)
+			description = fields["Description"]
+			w.WriteHeader(http.StatusNoContent)
+		case http.MethodGet:
+			json.NewEncoder(w).Encode(map[string]string{"Id": "2SR000000000001AAA", "Description": description})
+		}
+	}))
+	defer server.Close()
+
+	force := &Force{Credentials: &ForceSession{InstanceUrl: server.URL, AccessToken: "token"}}
+	org := ScratchPoolOrg{ScratchOrgInfo: ScratchOrgInfo{Id: "2SR000000000001AAA"}, LastModifiedDate: lastModified}
+	claimed, err := force.claimScratchOrgInfo(org, "force-pool:ci:claimed:me")
+	if err != nil || !claimed {
+		t.Errorf("Expected claim to succeed, got %v, %v", claimed, err)
+	}
+	if description != "force-pool:ci:claimed:me" {
+		t.Errorf("Unexpected description: %s", description)
+	}
+
+	org.Id = "2SR000000000002AAA"
+	claimed, err = force.claimScratchOrgInfo(org, "force-pool:ci:claimed:me")
+	if err != nil || claimed {
+		t.Errorf("Expected claim of modified org to fail, got %v, %v", claimed, err)
+	}
+}
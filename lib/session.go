@@ -99,9 +99,9 @@ func (f *Force) refreshSFDX() (err error) {
 		f.Credentials.ClientId = refreshed.ClientId
 	}
 
-	currentToken := strings.TrimSpace(f.Credentials.AccessToken)
+	currentToken := strings.TrimSpace(f.AccessToken())
 	newToken := strings.TrimSpace(refreshed.AccessToken)
-	currentInstance := strings.TrimSpace(f.Credentials.InstanceUrl)
+	currentInstance := strings.TrimSpace(f.InstanceUrl())
 	newInstance := strings.TrimSpace(refreshed.InstanceUrl)
 
 	if currentToken == newToken && currentInstance == newInstance {
@@ -125,9 +125,49 @@ func (f *Force) refreshSFDX() (err error) {
 	return SaveLogin(*f.Credentials)
 }
 
+func (f *Force) refreshJWT() (err error) {
+	if f.Credentials.UserInfo == nil || f.Credentials.SessionOptions.JwtKeyFile == "" {
+		return SessionRefreshUnavailable
+	}
+	clientId := ClientId
+	if f.Credentials.ClientId != "" {
+		clientId = f.Credentials.ClientId
+	}
+	Log.Info("Refreshing Session Token Using JWT")
+	assertion, err := JwtAssertionForEndpoint(f.Credentials.EndpointUrl, f.Credentials.UserInfo.UserName, f.Credentials.SessionOptions.JwtKeyFile, clientId)
+	if err != nil {
+		return
+	}
+	result, err := JWTLoginAtEndpoint(f.Credentials.EndpointUrl, assertion)
+	if err != nil {
+		return
+	}
+	f.UpdateCredentials(result)
+	return
+}
+
+func (f *Force) refreshClientCredentials() (err error) {
+	if f.Credentials.SessionOptions.ClientSecretRef == "" {
+		return SessionRefreshUnavailable
+	}
+	secret, err := ResolveSecretRef(f.Credentials.SessionOptions.ClientSecretRef)
+	if err != nil {
+		return
+	}
+	Log.Info("Refreshing Session Token Using Client Credentials")
+	result, err := ClientCredentialsLoginAtEndpoint(f.Credentials.EndpointUrl, f.Credentials.ClientId, secret)
+	if err != nil {
+		return
+	}
+	f.UpdateCredentials(result)
+	return
+}
+
 func updateFromSFDXSession(f *Force, refreshed ForceSession) {
 	f.CopyCredentialAuthFields(&refreshed)
 
+	credentialsMutex.Lock()
+	defer credentialsMutex.Unlock()
 	if trimmed := strings.TrimSpace(refreshed.RefreshToken); trimmed != "" {
 		f.Credentials.RefreshToken = trimmed
 	}
@@ -168,6 +208,12 @@ func (f *Force) RefreshSessionOrExit() {
 }
 
 func (f *Force) RefreshSession() error {
+	sessionRefreshMutex.Lock()
+	defer sessionRefreshMutex.Unlock()
+	return f.refreshSession()
+}
+
+func (f *Force) refreshSession() error {
 	if f.Credentials.SessionOptions == nil {
 		return SessionRefreshUnavailable
 	}
@@ -177,6 +223,10 @@ func (f *Force) RefreshSession() error {
 		return f.refreshOauth()
 	} else if f.Credentials.SessionOptions.RefreshMethod == RefreshSFDX {
 		return f.refreshSFDX()
+	} else if f.Credentials.SessionOptions.RefreshMethod == RefreshJWT {
+		return f.refreshJWT()
+	} else if f.Credentials.SessionOptions.RefreshMethod == RefreshClientCredentials {
+		return f.refreshClientCredentials()
 	}
 	return SessionRefreshUnavailable
 }
@@ -0,0 +1,192 @@
+package lib
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	. "github.com/ForceCLI/force/config"
+)
+
+// SessionRefreshMargin is how long before its estimated expiry an access
+// token is refreshed.
+var SessionRefreshMargin = 10 * time.Minute
+
+// sessionCheckInterval is how often KeepSessionFresh checks the session.
+var sessionCheckInterval = time.Minute
+
+// sessionRefreshMutex serializes session refreshes.
+var sessionRefreshMutex sync.Mutex
+
+// credentialsMutex guards the fields of the credentials that a refresh
+// replaces, which other goroutines, such as streaming clients, read while
+// KeepSessionFresh refreshes the session.
+var credentialsMutex sync.RWMutex
+
+// Refresh capabilities
+const (
+	RefreshCapabilityNone              = "none"
+	RefreshCapabilityOauth             = "refresh token"
+	RefreshCapabilitySFDX              = "sfdx"
+	RefreshCapabilityJWT               = "jwt"
+	RefreshCapabilityClientCredentials = "client credentials"
+	RefreshCapabilityCustom            = "custom"
+)
+
+// RefreshCapability describes how the session can be refreshed when its
+// access token expires.
+func (creds ForceSession) RefreshCapability() string {
+	options := creds.SessionOptions
+	if options == nil {
+		return RefreshCapabilityNone
+	}
+	switch {
+	case options.RefreshFunc != nil:
+		return RefreshCapabilityCustom
+	case options.RefreshMethod == RefreshOauth && creds.RefreshToken != "":
+		return RefreshCapabilityOauth
+	case options.RefreshMethod == RefreshSFDX:
+		return RefreshCapabilitySFDX
+	case options.RefreshMethod == RefreshJWT && options.JwtKeyFile != "":
+		return RefreshCapabilityJWT
+	case options.RefreshMethod == RefreshClientCredentials && options.ClientSecretRef != "":
+		return RefreshCapabilityClientCredentials
+	}
+	return RefreshCapabilityNone
+}
+
+// CanRefresh reports whether the session can be refreshed.
+func (creds ForceSession) CanRefresh() bool {
+	return creds.RefreshCapability() != RefreshCapabilityNone
+}
+
+// AccessToken returns the session's current access token.
+func (f *Force) AccessToken() string {
+	credentialsMutex.RLock()
+	defer credentialsMutex.RUnlock()
+	return f.Credentials.AccessToken
+}
+
+// InstanceUrl returns the session's current instance url.
+func (f *Force) InstanceUrl() string {
+	credentialsMutex.RLock()
+	defer credentialsMutex.RUnlock()
+	return f.Credentials.InstanceUrl
+}
+
+// session returns a copy of the session's credentials.
+func (f *Force) session() ForceSession {
+	credentialsMutex.RLock()
+	defer credentialsMutex.RUnlock()
+	return *f.Credentials
+}
+
+// expiresWithin reports whether the access token is estimated to expire
+// within d.  Tokens without an issue time are assumed not to expire.
+func (creds ForceSession) expiresWithin(d time.Duration) bool {
+	expiry, ok := creds.TokenExpiry()
+	return ok && time.Until(expiry) < d
+}
+
+// refreshExpiringSession refreshes the session if its access token is about
+// to expire and the session can be refreshed, reporting whether it was
+// refreshed.  Failures are left for the request to surface as a
+// SessionExpiredError.
+func (f *Force) refreshExpiringSession() bool {
+	if f.Credentials == nil || !f.session().expiresWithin(SessionRefreshMargin) {
+		return false
+	}
+	sessionRefreshMutex.Lock()
+	defer sessionRefreshMutex.Unlock()
+	creds := f.session()
+	// Another request may have refreshed the session while we waited.
+	if !creds.expiresWithin(SessionRefreshMargin) {
+		return true
+	}
+	// Don't try again before every request after a failed refresh.
+	if !creds.CanRefresh() || time.Since(f.refreshFailedAt) < sessionCheckInterval {
+		return false
+	}
+	if err := f.refreshSession(); err != nil {
+		Log.Info(fmt.Sprintf("Unable to refresh session before it expires: %s", err.Error()))
+		f.refreshFailedAt = time.Now()
+		return false
+	}
+	f.refreshFailedAt = time.Time{}
+	credentialsMutex.Lock()
+	defer credentialsMutex.Unlock()
+	if f.Credentials.IssuedAt == creds.IssuedAt || f.Credentials.IssuedAt == "" {
+		// The refresh didn't report when the new token was issued.
+		f.Credentials.IssuedAt = strconv.FormatInt(time.Now().UnixMilli(), 10)
+	}
+	return true
+}
+
+// KeepSessionFresh refreshes the session before its access token expires
+// until ctx is done.  Long-running commands, such as those that stream
+// events, use it to keep their connections authenticated.
+func (f *Force) KeepSessionFresh(ctx context.Context) {
+	if f.Credentials == nil {
+		return
+	}
+	sessionRefreshMutex.Lock()
+	creds := f.session()
+	sessionRefreshMutex.Unlock()
+	if !creds.CanRefresh() {
+		if expiry, ok := creds.TokenExpiry(); ok {
+			Log.Info(fmt.Sprintf("Session cannot be refreshed and may expire around %s", expiry.Local().Format(time.Kitchen)))
+		}
+		return
+	}
+	ticker := time.NewTicker(sessionCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			f.refreshExpiringSession()
+		}
+	}
+}
+
+// SessionStatus describes a saved login's session.
+type SessionStatus struct {
+	Login             string
+	RefreshCapability string
+	IssuedAt          time.Time
+	Expiry            time.Time
+}
+
+// Expired reports whether the access token is estimated to have expired.
+func (s SessionStatus) Expired() bool {
+	return !s.Expiry.IsZero() && s.Expiry.Before(time.Now())
+}
+
+// SavedSessionStatuses returns the status of each saved login's session
+// without refreshing any of them.
+func SavedSessionStatuses() ([]SessionStatus, error) {
+	logins, err := SavedLogins()
+	if err != nil {
+		return nil, err
+	}
+	var statuses []SessionStatus
+	for _, login := range logins {
+		data, err := Config.Load("accounts", login)
+		if err != nil {
+			return nil, err
+		}
+		var creds ForceSession
+		if err := json.Unmarshal([]byte(data), &creds); err != nil {
+			return nil, fmt.Errorf("Invalid saved login %s: %w", login, err)
+		}
+		status := SessionStatus{Login: login, RefreshCapability: creds.RefreshCapability()}
+		status.IssuedAt, _ = creds.TokenIssuedAt()
+		status.Expiry, _ = creds.TokenExpiry()
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
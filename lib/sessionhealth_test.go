@@ -0,0 +1,185 @@
+package lib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	forceConfig "github.com/ForceCLI/force/config"
+)
+
+func issuedAgo(d time.Duration) string {
+	return strconv.FormatInt(time.Now().Add(-d).UnixMilli(), 10)
+}
+
+func TestRefreshCapability(t *testing.T) {
+	cases := []struct {
+		creds    ForceSession
+		expected string
+	}{
+		{ForceSession{}, RefreshCapabilityNone},
+		{ForceSession{SessionOptions: &SessionOptions{RefreshMethod: RefreshOauth}}, RefreshCapabilityNone},
+		{ForceSession{RefreshToken: "token", SessionOptions: &SessionOptions{RefreshMethod: RefreshOauth}}, RefreshCapabilityOauth},
+		{ForceSession{SessionOptions: &SessionOptions{RefreshMethod: RefreshSFDX}}, RefreshCapabilitySFDX},
+		{ForceSession{SessionOptions: &SessionOptions{RefreshMethod: RefreshJWT, JwtKeyFile: "/keys/jwt.key"}}, RefreshCapabilityJWT},
+		{ForceSession{SessionOptions: &SessionOptions{RefreshMethod: RefreshClientCredentials}}, RefreshCapabilityNone},
+		{ForceSession{SessionOptions: &SessionOptions{RefreshMethod: RefreshClientCredentials, ClientSecretRef: "env:SECRET"}}, RefreshCapabilityClientCredentials},
+	}
+	for _, c := range cases {
+		if capability := c.creds.RefreshCapability(); capability != c.expected {
+			t.Errorf("Expected %q, got %q for %+v", c.expected, capability, c.creds.SessionOptions)
+		}
+	}
+}
+
+func TestRefreshExpiringSession(t *testing.T) {
+	refreshes := 0
+	creds := &ForceSession{
+		AccessToken: "old",
+		IssuedAt:    issuedAgo(DefaultSessionLifetime - time.Minute),
+		SessionOptions: &SessionOptions{
+			RefreshFunc: func(f *Force) error {
+				refreshes++
+				f.Credentials.AccessToken = "new"
+				return nil
+			},
+		},
+	}
+	f := NewForce(creds)
+
+	if !f.refreshExpiringSession() {
+		t.Fatalf("Expected expiring session to be refreshed")
+	}
+	if refreshes != 1 || creds.AccessToken != "new" {
+		t.Errorf("Expected one refresh, got %d", refreshes)
+	}
+	if f.refreshExpiringSession() || refreshes != 1 {
+		t.Errorf("Expected refreshed session not to be refreshed again")
+	}
+
+	creds.IssuedAt = issuedAgo(time.Minute)
+	if f.refreshExpiringSession() {
+		t.Errorf("Expected new token not to be refreshed")
+	}
+}
+
+func TestRefreshExpiringSessionFailureKeepsIssuedAt(t *testing.T) {
+	refreshes := 0
+	issuedAt := issuedAgo(DefaultSessionLifetime - time.Minute)
+	creds := &ForceSession{
+		AccessToken: "old",
+		IssuedAt:    issuedAt,
+		SessionOptions: &SessionOptions{
+			RefreshFunc: func(f *Force) error {
+				refreshes++
+				return errors.New("refresh failed")
+			},
+		},
+	}
+	f := NewForce(creds)
+
+	if f.refreshExpiringSession() {
+		t.Fatalf("Expected failed refresh to be reported")
+	}
+	if creds.IssuedAt != issuedAt {
+		t.Errorf("Expected issue time to be kept, got %q", creds.IssuedAt)
+	}
+	if f.refreshExpiringSession() || refreshes != 1 {
+		t.Errorf("Expected failed refresh not to be retried before every request, got %d refreshes", refreshes)
+	}
+}
+
+// Run with -race to check that requests read the credentials safely while
+// they're refreshed in the background.
+func TestKeepSessionFreshWhileRequesting(t *testing.T) {
+	defer func(interval time.Duration) { sessionCheckInterval = interval }(sessionCheckInterval)
+	sessionCheckInterval = time.Millisecond
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer token") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+
+	var refreshes int64
+	f := NewForce(&ForceSession{
+		AccessToken: "token0",
+		InstanceUrl: server.URL,
+		IssuedAt:    issuedAgo(DefaultSessionLifetime),
+		SessionOptions: &SessionOptions{
+			RefreshFunc: func(f *Force) error {
+				n := atomic.AddInt64(&refreshes, 1)
+				// Keep the token expiring so it's refreshed again.
+				f.CopyCredentialAuthFields(&ForceSession{
+					AccessToken: fmt.Sprintf("token%d", n),
+					InstanceUrl: server.URL,
+					IssuedAt:    issuedAgo(DefaultSessionLifetime),
+				})
+				return nil
+			},
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		f.KeepSessionFresh(ctx)
+		close(done)
+	}()
+	for i := 0; i < 50; i++ {
+		if _, err := f.GetAbsolute("/services/data"); err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+	if atomic.LoadInt64(&refreshes) == 0 {
+		t.Errorf("Expected session to be refreshed")
+	}
+}
+
+func TestSavedSessionStatuses(t *testing.T) {
+	cleanup := setupTestConfig(t)
+	defer cleanup()
+
+	forceConfig.Config.Save("accounts", "jwt@acme.com", `{"issued_at": "`+issuedAgo(3*time.Hour)+`", "SessionOptions": {"RefreshMethod": 3, "JwtKeyFile": "/keys/jwt.key"}}`)
+	forceConfig.Config.Save("accounts", "soap@acme.com", `{"SessionOptions": {}}`)
+
+	statuses, err := SavedSessionStatuses()
+	if err != nil {
+		t.Fatalf("SavedSessionStatuses returned error: %v", err)
+	}
+	if len(statuses) != 2 {
+		t.Fatalf("Expected 2 statuses, got %v", statuses)
+	}
+	if statuses[0].Login != "jwt@acme.com" || statuses[0].RefreshCapability != RefreshCapabilityJWT || !statuses[0].Expired() {
+		t.Errorf("Unexpected JWT status: %+v", statuses[0])
+	}
+	if statuses[1].RefreshCapability != RefreshCapabilityNone || statuses[1].Expired() {
+		t.Errorf("Unexpected SOAP status: %+v", statuses[1])
+	}
+}
+
+func TestResolveSecretRef(t *testing.T) {
+	t.Setenv("FORCE_TEST_SECRET", "s3cret")
+	if secret, err := ResolveSecretRef("env:FORCE_TEST_SECRET"); err != nil || secret != "s3cret" {
+		t.Errorf("Unexpected secret %q, %v", secret, err)
+	}
+	if _, err := ResolveSecretRef("env:FORCE_TEST_MISSING"); err == nil {
+		t.Errorf("Expected error for unset variable")
+	}
+	if _, err := ResolveSecretRef("s3cret"); err == nil {
+		t.Errorf("Expected error for invalid reference")
+	}
+}
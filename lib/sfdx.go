@@ -360,42 +360,60 @@ func decryptWithCandidates(value string, preferred int, keys []sfdxKey) (string,
 }
 
 func loadSFDXKeyFromKeychain() ([]byte, sfdxCryptoVersion, error) {
+	secret, err := lookupKeychainSecret(sfdxKeyService, sfdxKeyAccount, "user", sfdxKeyAccount, "domain", sfdxKeyService)
+	if errors.Is(err, errKeychainSecretNotFound) {
+		return nil, cryptoUnknown, errSFDXKeyNotFound
+	}
+	if err != nil {
+		return nil, cryptoUnknown, err
+	}
+	return parseSFDXKey(secret)
+}
+
+// errKeychainSecretNotFound is returned when there's no matching secret, or
+// no keychain to look in.
+var errKeychainSecretNotFound = errors.New("secret not found in keychain")
+
+// lookupKeychainSecret looks up a password by service and account in the
+// macOS keychain or, on Linux, by secretToolAttributes in the Secret Service
+// keyring.
+func lookupKeychainSecret(service string, account string, secretToolAttributes ...string) (string, error) {
 	switch runtime.GOOS {
 	case "darwin":
-		return loadSFDXKeyFromSecurity()
+		return lookupSecurityPassword(service, account)
 	case "linux":
-		return loadSFDXKeyFromSecretTool()
+		return lookupSecretTool(secretToolAttributes...)
 	default:
-		return nil, cryptoUnknown, errSFDXKeyNotFound
+		return "", errKeychainSecretNotFound
 	}
 }
 
-func loadSFDXKeyFromSecurity() ([]byte, sfdxCryptoVersion, error) {
-	cmd := exec.Command("security", "find-generic-password", "-a", sfdxKeyAccount, "-s", sfdxKeyService, "-w")
+func lookupSecurityPassword(service string, account string) (string, error) {
+	cmd := exec.Command("security", "find-generic-password", "-a", account, "-s", service, "-w")
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		var execErr *exec.Error
 		if errors.As(err, &execErr) && errors.Is(execErr.Err, exec.ErrNotFound) {
-			return nil, cryptoUnknown, errSFDXKeyNotFound
+			return "", errKeychainSecretNotFound
 		}
 		message := strings.TrimSpace(string(output))
 		if message != "" {
 			if strings.Contains(strings.ToLower(message), "could not be found") {
-				return nil, cryptoUnknown, errSFDXKeyNotFound
+				return "", errKeychainSecretNotFound
 			}
-			return nil, cryptoUnknown, fmt.Errorf("%s: %w", message, err)
+			return "", fmt.Errorf("%s: %w", message, err)
 		}
-		return nil, cryptoUnknown, err
+		return "", err
 	}
-	return parseSFDXKey(string(output))
+	return strings.TrimRight(string(output), "\r\n"), nil
 }
 
-func loadSFDXKeyFromSecretTool() ([]byte, sfdxCryptoVersion, error) {
+func lookupSecretTool(attributes ...string) (string, error) {
 	cmdName := os.Getenv(secretToolEnvVar)
 	if strings.TrimSpace(cmdName) == "" {
 		cmdName = "secret-tool"
 	}
-	args := []string{"lookup", "user", sfdxKeyAccount, "domain", sfdxKeyService}
+	args := append([]string{"lookup"}, attributes...)
 
 	var lastMsg string
 	for attempts := 0; attempts < 3; attempts++ {
@@ -405,15 +423,15 @@ func loadSFDXKeyFromSecretTool() ([]byte, sfdxCryptoVersion, error) {
 		if err != nil {
 			var execErr *exec.Error
 			if errors.As(err, &execErr) && errors.Is(execErr.Err, exec.ErrNotFound) {
-				return nil, cryptoUnknown, errSFDXKeyNotFound
+				return "", errKeychainSecretNotFound
 			}
 			exitErr, ok := err.(*exec.ExitError)
 			message := strings.TrimSpace(string(output))
 			if !ok {
 				if message != "" {
-					return nil, cryptoUnknown, fmt.Errorf("secret-tool lookup failed: %s: %w", message, err)
+					return "", fmt.Errorf("secret-tool lookup failed: %s: %w", message, err)
 				}
-				return nil, cryptoUnknown, fmt.Errorf("secret-tool lookup failed: %w", err)
+				return "", fmt.Errorf("secret-tool lookup failed: %w", err)
 			}
 			if message == "" {
 				message = exitErr.Error()
@@ -423,16 +441,16 @@ func loadSFDXKeyFromSecretTool() ([]byte, sfdxCryptoVersion, error) {
 				if strings.Contains(message, "invalid or unencryptable secret") {
 					continue
 				}
-				return nil, cryptoUnknown, errSFDXKeyNotFound
+				return "", errKeychainSecretNotFound
 			}
-			return nil, cryptoUnknown, fmt.Errorf("secret-tool lookup failed: %s: %w", message, err)
+			return "", fmt.Errorf("secret-tool lookup failed: %s: %w", message, err)
 		}
-		return parseSFDXKey(string(output))
+		return strings.TrimRight(string(output), "\r\n"), nil
 	}
 	if lastMsg != "" {
-		return nil, cryptoUnknown, fmt.Errorf("secret-tool lookup failed: %s", lastMsg)
+		return "", fmt.Errorf("secret-tool lookup failed: %s", lastMsg)
 	}
-	return nil, cryptoUnknown, fmt.Errorf("secret-tool lookup failed after retries")
+	return "", fmt.Errorf("secret-tool lookup failed after retries")
 }
 
 func parseSFDXKey(raw string) ([]byte, sfdxCryptoVersion, error) {
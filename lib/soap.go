@@ -5,6 +5,7 @@ import (
 	"encoding/xml"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"strings"
@@ -76,6 +77,35 @@ func (s *Soap) ExecuteLogin(username, password string) (response []byte, err err
 }
 
 func (s *Soap) Execute(action, query string) (response []byte, err error) {
+	res, err := s.post(action, query)
+	if err != nil {
+		return
+	}
+	defer res.Body.Close()
+	response, err = ioutil.ReadAll(res.Body)
+	if err != nil {
+		return
+	}
+	if isSoapInvalidSessionError(response) {
+		err = SessionExpiredError
+		return
+	}
+	err = processError(response)
+	return
+}
+
+// ExecuteStream executes the action, returning the response body for the
+// caller to read and close.  Unlike Execute, SOAP faults in the response
+// aren't checked.
+func (s *Soap) ExecuteStream(action, query string) (io.ReadCloser, error) {
+	res, err := s.post(action, query)
+	if err != nil {
+		return nil, err
+	}
+	return res.Body, nil
+}
+
+func (s *Soap) post(action, query string) (res *http.Response, err error) {
 	soap := `
 		<env:Envelope xmlns:xsd="http://www.w3.org/2001/XMLSchema" 
 		xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" 
@@ -103,28 +133,18 @@ func (s *Soap) Execute(action, query string) (response []byte, err error) {
 	}
 	req.Header.Add("Content-Type", "text/xml")
 	req.Header.Add("SOAPACtion", action)
-	res, err := doRequest(req)
+	res, err = doRequest(req)
 	if err != nil {
 		return
 	}
-	defer res.Body.Close()
 	if res.Header.Get(http.CanonicalHeaderKey("x-sfdc-edge-err")) == "true" {
-		err = errors.New("Unexpected error from Salesforce Edge")
-		return
+		res.Body.Close()
+		return nil, errors.New("Unexpected error from Salesforce Edge")
 	}
 	if res.StatusCode == 401 {
-		err = errors.New("authorization expired, please run `force login`")
-		return
+		res.Body.Close()
+		return nil, errors.New("authorization expired, please run `force login`")
 	}
-	response, err = ioutil.ReadAll(res.Body)
-	if err != nil {
-		return
-	}
-	if isSoapInvalidSessionError(response) {
-		err = SessionExpiredError
-		return
-	}
-	err = processError(response)
 	return
 }
 
@@ -0,0 +1,394 @@
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+)
+
+// ToolingPollInterval is how often the status of a Tooling API deploy is
+// checked.
+var ToolingPollInterval = time.Second
+
+// containerMemberTypes maps the metadata directories of components that can
+// be saved through a MetadataContainer to their types and file extensions.
+var containerMemberTypes = map[string]struct {
+	Type      string
+	Extension string
+}{
+	"classes":    {"ApexClass", ".cls"},
+	"triggers":   {"ApexTrigger", ".trigger"},
+	"pages":      {"ApexPage", ".page"},
+	"components": {"ApexComponent", ".component"},
+}
+
+// auraDefTypes maps the suffixes of the files in an Aura bundle, after the
+// bundle name, to their AuraDefinition DefTypes.
+var auraDefTypes = map[string]string{
+	".cmp":          "COMPONENT",
+	".app":          "APPLICATION",
+	".evt":          "EVENT",
+	".intf":         "INTERFACE",
+	".tokens":       "TOKENS",
+	".design":       "DESIGN",
+	".svg":          "SVG",
+	".css":          "STYLE",
+	".auradoc":      "DOCUMENTATION",
+	"Controller.js": "CONTROLLER",
+	"Helper.js":     "HELPER",
+	"Renderer.js":   "RENDERER",
+}
+
+// ToolingFile is a changed file to save through the Tooling API.  Path is
+// relative to the metadata directory, e.g. classes/MyClass.cls.
+type ToolingFile struct {
+	Path string
+	Body []byte
+}
+
+// ToolingDeployOptions configures a Tooling API deploy.
+type ToolingDeployOptions struct {
+	// CheckOnly compiles Apex and Visualforce without saving it.  Aura and
+	// Lightning web component files can't be checked without saving them.
+	CheckOnly bool
+}
+
+// ToolingDeployResult is the result of saving files through the Tooling
+// API.  Failures have FileName set to the file's Path.  Files whose
+// components aren't in the org yet can't be saved through the Tooling API,
+// and are returned in NotFound to be deployed through the Metadata API.
+type ToolingDeployResult struct {
+	Failures []ComponentFailure
+	NotFound []string
+}
+
+type toolingComponent struct {
+	kind   string // MetadataContainer member type, AuraDefinition, or LightningComponentResource
+	name   string // component or bundle name
+	detail string // AuraDefinition DefType, or LightningComponentResource FilePath
+}
+
+// toolingComponentForPath returns the component that a file can be saved
+// as through the Tooling API, if any.
+func toolingComponentForPath(filePath string) (toolingComponent, bool) {
+	parts := strings.Split(filePath, "/")
+	if strings.HasSuffix(filePath, "-meta.xml") {
+		return toolingComponent{}, false
+	}
+	switch {
+	case len(parts) == 2:
+		member, ok := containerMemberTypes[parts[0]]
+		if !ok || !strings.HasSuffix(parts[1], member.Extension) {
+			return toolingComponent{}, false
+		}
+		return toolingComponent{kind: member.Type, name: strings.TrimSuffix(parts[1], member.Extension)}, true
+	case len(parts) == 3 && parts[0] == "aura":
+		bundle := parts[1]
+		defType, ok := auraDefTypes[strings.TrimPrefix(parts[2], bundle)]
+		if !ok || !strings.HasPrefix(parts[2], bundle) {
+			return toolingComponent{}, false
+		}
+		return toolingComponent{kind: "AuraDefinition", name: bundle, detail: defType}, true
+	case len(parts) >= 3 && parts[0] == "lwc":
+		return toolingComponent{kind: "LightningComponentResource", name: parts[1], detail: filePath}, true
+	}
+	return toolingComponent{}, false
+}
+
+// IsToolingPath reports whether a file can be saved through the Tooling API
+// rather than deployed through the Metadata API.
+func IsToolingPath(filePath string) bool {
+	_, ok := toolingComponentForPath(filePath)
+	return ok
+}
+
+// IsContainerPath reports whether a file is an Apex class or trigger, or a
+// Visualforce page or component, which can be compiled through a
+// MetadataContainer.
+func IsContainerPath(filePath string) bool {
+	c, ok := toolingComponentForPath(filePath)
+	return ok && c.kind != "AuraDefinition" && c.kind != "LightningComponentResource"
+}
+
+// ToolingDeploy saves existing Apex classes and triggers, Visualforce pages
+// and components, and the files of Aura and Lightning web component bundles,
+// through the Tooling API, which is faster than a Metadata API deploy.  Apex
+// and Visualforce are compiled together in a MetadataContainer; bundle files
+// are updated individually.
+func (f *Force) ToolingDeploy(files []ToolingFile, options ToolingDeployOptions) (result ToolingDeployResult, err error) {
+	byKind := make(map[string][]ToolingFile)
+	components := make(map[string]toolingComponent)
+	for _, file := range files {
+		c, ok := toolingComponentForPath(file.Path)
+		if !ok {
+			return result, fmt.Errorf("%s can't be saved through the Tooling API", file.Path)
+		}
+		if options.CheckOnly && !IsContainerPath(file.Path) {
+			return result, fmt.Errorf("%s can't be checked without saving it", file.Path)
+		}
+		byKind[c.kind] = append(byKind[c.kind], file)
+		components[file.Path] = c
+	}
+
+	var members []containerMember
+	for _, kind := range sortedKeys(byKind) {
+		var ids map[string]string
+		switch kind {
+		case "AuraDefinition":
+			ids, err = f.auraDefinitionIds(byKind[kind], components)
+		case "LightningComponentResource":
+			ids, err = f.lightningResourceIds(byKind[kind], components)
+		default:
+			ids, err = f.toolingComponentIds(kind, byKind[kind], components)
+		}
+		if err != nil {
+			return
+		}
+		for _, file := range byKind[kind] {
+			id, ok := ids[file.Path]
+			if !ok {
+				result.NotFound = append(result.NotFound, file.Path)
+				continue
+			}
+			switch kind {
+			case "AuraDefinition", "LightningComponentResource":
+				if err := f.UpdateToolingRecord(kind, id, map[string]string{"Source": string(file.Body)}); err != nil {
+					result.Failures = append(result.Failures, ComponentFailure{
+						ComponentType: kind,
+						FileName:      file.Path,
+						FullName:      components[file.Path].name,
+						Problem:       err.Error(),
+					})
+				}
+			default:
+				members = append(members, containerMember{file: file, component: components[file.Path], id: id})
+			}
+		}
+	}
+	if len(members) == 0 {
+		return
+	}
+	failures, err := f.deployContainer(members, options.CheckOnly)
+	result.Failures = append(result.Failures, failures...)
+	return
+}
+
+type containerMember struct {
+	file      ToolingFile
+	component toolingComponent
+	id        string
+}
+
+// deployContainer saves, or with checkOnly only compiles, Apex and
+// Visualforce components through a MetadataContainer, which is deleted
+// afterwards.
+func (f *Force) deployContainer(members []containerMember, checkOnly bool) (failures []ComponentFailure, err error) {
+	container, err := f.CreateToolingRecord("MetadataContainer", map[string]string{
+		"Name": fmt.Sprintf("force-%d", time.Now().UnixNano()),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Could not create MetadataContainer: %w", err)
+	}
+	defer f.deleteToolingRecord("MetadataContainer", container.Id)
+
+	for _, m := range members {
+		_, err = f.CreateToolingRecord(m.component.kind+"Member", map[string]string{
+			"MetadataContainerId": container.Id,
+			"ContentEntityId":     m.id,
+			"Body":                string(m.file.Body),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("Could not add %s to MetadataContainer: %w", m.file.Path, err)
+		}
+	}
+	request, err := f.createContainerAsyncRequest(container.Id, checkOnly)
+	if err != nil {
+		return nil, fmt.Errorf("Could not create ContainerAsyncRequest: %w", err)
+	}
+
+	status, err := f.waitForContainerAsyncRequest(request.Id)
+	if err != nil {
+		return
+	}
+	if status.State == "Completed" {
+		return nil, nil
+	}
+	if status.State != "Failed" {
+		if status.ErrorMsg != "" {
+			return nil, fmt.Errorf("ContainerAsyncRequest %s: %s", strings.ToLower(status.State), status.ErrorMsg)
+		}
+		return nil, fmt.Errorf("ContainerAsyncRequest %s", strings.ToLower(status.State))
+	}
+
+	paths := make(map[string]string)
+	for _, m := range members {
+		paths[strings.ToLower(m.component.kind+"/"+m.component.name)] = m.file.Path
+	}
+	for _, failure := range status.DeployDetails.ComponentFailures {
+		if p, ok := paths[strings.ToLower(failure.ComponentType+"/"+failure.FullName)]; ok {
+			failure.FileName = p
+		}
+		failures = append(failures, failure)
+	}
+	if len(failures) == 0 && status.ErrorMsg != "" {
+		failures = append(failures, ComponentFailure{Problem: status.ErrorMsg})
+	}
+	return failures, nil
+}
+
+// createContainerAsyncRequest is posted as JSON, rather than through
+// CreateToolingRecord, because IsCheckOnly is a boolean.
+func (f *Force) createContainerAsyncRequest(containerId string, checkOnly bool) (result ForceCreateRecordResult, err error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"MetadataContainerId": containerId,
+		"IsCheckOnly":         checkOnly,
+	})
+	if err != nil {
+		return
+	}
+	response, err := f.PostAbsolute(fmt.Sprintf("/services/data/%s/tooling/sobjects/ContainerAsyncRequest", apiVersion), string(body))
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal([]byte(response), &result)
+	return
+}
+
+type containerAsyncRequest struct {
+	State         string
+	ErrorMsg      string
+	DeployDetails struct {
+		ComponentFailures []ComponentFailure `json:"componentFailures"`
+	}
+}
+
+func (f *Force) waitForContainerAsyncRequest(id string) (status containerAsyncRequest, err error) {
+	url := fmt.Sprintf("%s/services/data/%s/tooling/sobjects/ContainerAsyncRequest/%s", f.InstanceUrl(), apiVersion, id)
+	for {
+		body, err := f.makeHttpRequestSync(NewRequest("GET").AbsoluteUrl(url))
+		if err != nil {
+			return status, err
+		}
+		if err = json.Unmarshal(body, &status); err != nil {
+			return status, err
+		}
+		if status.State != "Queued" {
+			return status, nil
+		}
+		time.Sleep(ToolingPollInterval)
+	}
+}
+
+// toolingComponentIds returns the ids of the org's components of a
+// MetadataContainer member type, keyed by file path.
+func (f *Force) toolingComponentIds(kind string, files []ToolingFile, components map[string]toolingComponent) (map[string]string, error) {
+	var names []string
+	for _, file := range files {
+		names = append(names, components[file.Path].name)
+	}
+	records, err := f.toolingQuery(fmt.Sprintf("SELECT Id, Name FROM %s WHERE NamespacePrefix = null AND Name IN (%s)", kind, soqlStringList(names)))
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]string)
+	for _, r := range records.Records {
+		name, _ := r["Name"].(string)
+		id, _ := r["Id"].(string)
+		byName[strings.ToLower(name)] = id
+	}
+	ids := make(map[string]string)
+	for _, file := range files {
+		if id, ok := byName[strings.ToLower(components[file.Path].name)]; ok {
+			ids[file.Path] = id
+		}
+	}
+	return ids, nil
+}
+
+// auraDefinitionIds returns the ids of the org's AuraDefinitions for the
+// files, keyed by file path.
+func (f *Force) auraDefinitionIds(files []ToolingFile, components map[string]toolingComponent) (map[string]string, error) {
+	records, err := f.toolingQuery(fmt.Sprintf("SELECT Id, DefType, AuraDefinitionBundle.DeveloperName FROM AuraDefinition WHERE AuraDefinitionBundle.NamespacePrefix = null AND AuraDefinitionBundle.DeveloperName IN (%s)", bundleNames(files, components)))
+	if err != nil {
+		return nil, err
+	}
+	byDef := make(map[string]string)
+	for _, r := range records.Records {
+		bundle, _ := r["AuraDefinitionBundle"].(map[string]interface{})
+		name, _ := bundle["DeveloperName"].(string)
+		defType, _ := r["DefType"].(string)
+		id, _ := r["Id"].(string)
+		byDef[strings.ToLower(name)+"/"+defType] = id
+	}
+	ids := make(map[string]string)
+	for _, file := range files {
+		c := components[file.Path]
+		if id, ok := byDef[strings.ToLower(c.name)+"/"+c.detail]; ok {
+			ids[file.Path] = id
+		}
+	}
+	return ids, nil
+}
+
+// lightningResourceIds returns the ids of the org's
+// LightningComponentResources for the files, keyed by file path.
+func (f *Force) lightningResourceIds(files []ToolingFile, components map[string]toolingComponent) (map[string]string, error) {
+	records, err := f.toolingQuery(fmt.Sprintf("SELECT Id, FilePath FROM LightningComponentResource WHERE LightningComponentBundle.NamespacePrefix = null AND LightningComponentBundle.DeveloperName IN (%s)", bundleNames(files, components)))
+	if err != nil {
+		return nil, err
+	}
+	byPath := make(map[string]string)
+	for _, r := range records.Records {
+		filePath, _ := r["FilePath"].(string)
+		id, _ := r["Id"].(string)
+		byPath[path.Clean(filePath)] = id
+	}
+	ids := make(map[string]string)
+	for _, file := range files {
+		if id, ok := byPath[components[file.Path].detail]; ok {
+			ids[file.Path] = id
+		}
+	}
+	return ids, nil
+}
+
+func bundleNames(files []ToolingFile, components map[string]toolingComponent) string {
+	names := make(map[string]bool)
+	for _, file := range files {
+		names[components[file.Path].name] = true
+	}
+	return soqlStringList(sortedKeys(names))
+}
+
+// CompileError is a problem found compiling a file, in the form editors
+// consume.
+type CompileError struct {
+	File    string `json:"file"`
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+// NewCompileError converts a deploy's component failure.  The failure's
+// file name is used as given, or its component name if it has none.
+func NewCompileError(failure ComponentFailure) CompileError {
+	file := failure.FileName
+	if file == "" {
+		file = failure.FullName
+	}
+	return CompileError{File: file, Line: failure.LineNumber, Column: failure.ColumnNumber, Message: failure.Problem}
+}
+
+// String formats the error as file:line:column: message.
+func (e CompileError) String() string {
+	location := e.File
+	switch {
+	case e.Line > 0 && e.Column > 0:
+		location = fmt.Sprintf("%s:%d:%d", location, e.Line, e.Column)
+	case e.Line > 0:
+		location = fmt.Sprintf("%s:%d", location, e.Line)
+	}
+	return fmt.Sprintf("%s: %s", location, e.Message)
+}
@@ -0,0 +1,132 @@
+package lib
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestToolingComponentForPath(t *testing.T) {
+	cases := map[string]toolingComponent{
+		"classes/A.cls":                   {kind: "ApexClass", name: "A"},
+		"triggers/T.trigger":              {kind: "ApexTrigger", name: "T"},
+		"pages/P.page":                    {kind: "ApexPage", name: "P"},
+		"aura/Cmp/CmpController.js":       {kind: "AuraDefinition", name: "Cmp", detail: "CONTROLLER"},
+		"aura/Cmp/Cmp.cmp":                {kind: "AuraDefinition", name: "Cmp", detail: "COMPONENT"},
+		"lwc/myCmp/myCmp.js":              {kind: "LightningComponentResource", name: "myCmp", detail: "lwc/myCmp/myCmp.js"},
+		"lwc/myCmp/templates/a.html":      {kind: "LightningComponentResource", name: "myCmp", detail: "lwc/myCmp/templates/a.html"},
+		"classes/A.cls-meta.xml":          {},
+		"objects/Account.object":          {},
+		"aura/Cmp/Other.js":               {},
+		"staticresources/R.resource":      {},
+		"lwc/myCmp/myCmp.js-meta.xml":     {},
+		"components/Banner.component-foo": {},
+	}
+	for path, expected := range cases {
+		c, ok := toolingComponentForPath(path)
+		if ok != (expected.kind != "") || c != expected {
+			t.Errorf("%s: expected %+v, got %+v (%v)", path, expected, c, ok)
+		}
+	}
+}
+
+func TestToolingDeployContainer(t *testing.T) {
+	defer func(interval time.Duration) { ToolingPollInterval = interval }(ToolingPollInterval)
+	ToolingPollInterval = 0
+	var mu sync.Mutex
+	var created []string
+	var deleted []string
+	var checkOnly interface{}
+	polls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/tooling/query") || strings.HasSuffix(r.URL.Path, "/tooling/query/"):
+			q := r.URL.Query().Get("q")
+			if !strings.Contains(q, "FROM ApexClass") || !strings.Contains(q, "'A'") {
+				t.Errorf("Unexpected query %s", q)
+			}
+			w.Write([]byte(`{"done": true, "totalSize": 1, "records": [{"Id": "01p000000000001AAA", "Name": "A"}]}`))
+		case r.Method == "POST":
+			var attrs map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&attrs)
+			kind := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+			created = append(created, kind)
+			if kind == "ApexClassMember" && (attrs["ContentEntityId"] != "01p000000000001AAA" || attrs["MetadataContainerId"] != "1dc000000000001AAA") {
+				t.Errorf("Unexpected member %v", attrs)
+			}
+			if kind == "ContainerAsyncRequest" {
+				checkOnly = attrs["IsCheckOnly"]
+			}
+			id := map[string]string{"MetadataContainer": "1dc000000000001AAA", "ApexClassMember": "400000000000001AAA", "ContainerAsyncRequest": "1dr000000000001AAA"}[kind]
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id": "` + id + `", "success": true, "errors": []}`))
+		case r.Method == "GET" && strings.Contains(r.URL.Path, "/ContainerAsyncRequest/"):
+			polls++
+			if polls == 1 {
+				w.Write([]byte(`{"State": "Queued"}`))
+				return
+			}
+			w.Write([]byte(`{"State": "Failed", "DeployDetails": {"componentFailures": [
+				{"componentType": "ApexClass", "fullName": "A", "lineNumber": 3, "columnNumber": 7, "problem": "Missing ';'"}
+			]}}`))
+		case r.Method == "DELETE":
+			deleted = append(deleted, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	force := &Force{Credentials: &ForceSession{InstanceUrl: server.URL, AccessToken: "test-token"}}
+	result, err := force.ToolingDeploy([]ToolingFile{
+		{Path: "classes/A.cls", Body: []byte("public class A { Integer x }")},
+		{Path: "classes/B.cls", Body: []byte("public class B {}")},
+	}, ToolingDeployOptions{CheckOnly: true})
+	if err != nil {
+		t.Fatalf("ToolingDeploy returned error: %v", err)
+	}
+	if expected := []string{"classes/B.cls"}; !reflect.DeepEqual(result.NotFound, expected) {
+		t.Errorf("Expected %v not to be found, got %v", expected, result.NotFound)
+	}
+	if len(result.Failures) != 1 || result.Failures[0].FileName != "classes/A.cls" || result.Failures[0].LineNumber != 3 {
+		t.Errorf("Expected a failure in classes/A.cls on line 3, got %+v", result.Failures)
+	}
+	if expected := []string{"MetadataContainer", "ApexClassMember", "ContainerAsyncRequest"}; !reflect.DeepEqual(created, expected) {
+		t.Errorf("Expected %v to be created, got %v", expected, created)
+	}
+	if checkOnly != true {
+		t.Errorf("Expected IsCheckOnly to be true, got %v", checkOnly)
+	}
+	if len(deleted) != 1 || !strings.HasSuffix(deleted[0], "/MetadataContainer/1dc000000000001AAA") {
+		t.Errorf("Expected the MetadataContainer to be deleted, got %v", deleted)
+	}
+}
+
+func TestToolingDeployCheckOnlyRejectsBundles(t *testing.T) {
+	force := &Force{Credentials: &ForceSession{InstanceUrl: "http://localhost", AccessToken: "test-token"}}
+	_, err := force.ToolingDeploy([]ToolingFile{{Path: "lwc/c/c.js"}}, ToolingDeployOptions{CheckOnly: true})
+	if err == nil || !strings.Contains(err.Error(), "lwc/c/c.js") {
+		t.Errorf("Expected an error checking an LWC file, got %v", err)
+	}
+}
+
+func TestCompileErrorString(t *testing.T) {
+	cases := map[string]CompileError{
+		"classes/A.cls:3:7: Missing ';'": NewCompileError(ComponentFailure{FileName: "classes/A.cls", LineNumber: 3, ColumnNumber: 7, Problem: "Missing ';'"}),
+		"classes/A.cls:3: Bad":           {File: "classes/A.cls", Line: 3, Message: "Bad"},
+		"A: Invalid":                     NewCompileError(ComponentFailure{FullName: "A", Problem: "Invalid"}),
+	}
+	for expected, e := range cases {
+		if e.String() != expected {
+			t.Errorf("Expected %q, got %q", expected, e.String())
+		}
+	}
+}
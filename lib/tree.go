@@ -0,0 +1,652 @@
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ForceCLI/force/lib/query"
+)
+
+// The sObject Tree API accepts at most 200 records per request, including
+// nested child records.
+const SObjectTreeMaxRecords = 200
+
+// SObjectTree is a set of records in the sObject Tree format.  Each record
+// has an attributes field with its type and reference id.  Child
+// relationships contain nested SObjectTrees, and lookups to other records in
+// the export are replaced with "@<referenceId>" placeholders.
+type SObjectTree struct {
+	Records []SObjectTreeRecord `json:"records"`
+}
+
+type SObjectTreeRecord map[string]interface{}
+
+type SObjectTreeResult struct {
+	HasErrors bool                    `json:"hasErrors"`
+	Results   []SObjectTreeResultItem `json:"results"`
+}
+
+type SObjectTreeResultItem struct {
+	ReferenceId string        `json:"referenceId"`
+	Id          string        `json:"id"`
+	Errors      []ResultError `json:"errors"`
+}
+
+// RecordTreePlanEntry lists the data files for an sobject in a record tree
+// export.  Plans list sobjects in the order in which they should be
+// imported.
+type RecordTreePlanEntry struct {
+	SObject     string   `json:"sobject"`
+	SaveRefs    bool     `json:"saveRefs"`
+	ResolveRefs bool     `json:"resolveRefs"`
+	Files       []string `json:"files"`
+}
+
+const RecordTreePlanFile = "plan.json"
+
+var referencePlaceholder = regexp.MustCompile(`^@([A-Za-z0-9_]+)$`)
+
+func (r SObjectTreeRecord) attributes() map[string]interface{} {
+	attrs, _ := r["attributes"].(map[string]interface{})
+	return attrs
+}
+
+func (r SObjectTreeRecord) Type() string {
+	t, _ := r.attributes()["type"].(string)
+	return t
+}
+
+func (r SObjectTreeRecord) ReferenceId() string {
+	ref, _ := r.attributes()["referenceId"].(string)
+	return ref
+}
+
+// children returns the nested child records of r, keyed by relationship name.
+func (r SObjectTreeRecord) children() map[string][]SObjectTreeRecord {
+	children := make(map[string][]SObjectTreeRecord)
+	for k, v := range r {
+		switch tree := v.(type) {
+		case SObjectTree:
+			children[k] = tree.Records
+		case map[string]interface{}:
+			records, ok := tree["records"].([]interface{})
+			if k == "attributes" || !ok {
+				continue
+			}
+			children[k] = nil
+			for _, rec := range records {
+				if m, ok := rec.(map[string]interface{}); ok {
+					children[k] = append(children[k], SObjectTreeRecord(m))
+				}
+			}
+		}
+	}
+	return children
+}
+
+// Count returns the number of records in the tree rooted at r.
+func (r SObjectTreeRecord) Count() int {
+	count := 1
+	for _, records := range r.children() {
+		for _, child := range records {
+			count += child.Count()
+		}
+	}
+	return count
+}
+
+// References returns the reference ids of other records that the tree
+// rooted at r looks up.
+func (r SObjectTreeRecord) References() []string {
+	var refs []string
+	for k, v := range r {
+		if k == "attributes" {
+			continue
+		}
+		if s, ok := v.(string); ok {
+			if m := referencePlaceholder.FindStringSubmatch(s); m != nil {
+				refs = append(refs, m[1])
+			}
+		}
+	}
+	for _, records := range r.children() {
+		for _, child := range records {
+			refs = append(refs, child.References()...)
+		}
+	}
+	return refs
+}
+
+// treeReferenceIds returns the reference ids of the records in the tree
+// rooted at r.
+func (r SObjectTreeRecord) treeReferenceIds() map[string]bool {
+	refs := make(map[string]bool)
+	walkTreeRecords(r, func(rec SObjectTreeRecord) {
+		refs[rec.ReferenceId()] = true
+	})
+	return refs
+}
+
+// treeReferenceUpdate is a lookup between records in the same tree.  The
+// sObject Tree API can't set these, so they're set after the tree is
+// inserted.
+type treeReferenceUpdate struct {
+	SObject     string
+	ReferenceId string
+	Field       string
+	Target      string
+}
+
+// resolve returns a copy of the tree rooted at r with reference placeholders
+// replaced by record ids.  Fields with references to records that haven't
+// been inserted are omitted and added to deferred.
+func (r SObjectTreeRecord) resolve(ids map[string]string, deferred *[]treeReferenceUpdate) SObjectTreeRecord {
+	resolved := make(SObjectTreeRecord, len(r))
+	children := r.children()
+	for k, v := range r {
+		if records, ok := children[k]; ok {
+			tree := SObjectTree{}
+			for _, child := range records {
+				tree.Records = append(tree.Records, child.resolve(ids, deferred))
+			}
+			resolved[k] = tree
+			continue
+		}
+		if s, ok := v.(string); ok {
+			if m := referencePlaceholder.FindStringSubmatch(s); m != nil {
+				id, found := ids[m[1]]
+				if !found {
+					*deferred = append(*deferred, treeReferenceUpdate{
+						SObject:     r.Type(),
+						ReferenceId: r.ReferenceId(),
+						Field:       k,
+						Target:      m[1],
+					})
+					continue
+				}
+				v = id
+			}
+		}
+		resolved[k] = v
+	}
+	return resolved
+}
+
+// RecordTreeExport converts query results into record trees.  Lookups to
+// records that are part of the export are replaced with reference
+// placeholders so the records can be inserted into another org.
+type RecordTreeExport struct {
+	Trees    map[string]*SObjectTree
+	refs     map[string]string
+	counters map[string]int
+	exported map[string]bool
+	order    []string
+	// parentFields maps "<sobject>.<child relationship>" to the field on
+	// the child records that looks up the parent.
+	parentFields map[string]string
+}
+
+func NewRecordTreeExport() *RecordTreeExport {
+	return &RecordTreeExport{
+		Trees:        make(map[string]*SObjectTree),
+		refs:         make(map[string]string),
+		counters:     make(map[string]int),
+		exported:     make(map[string]bool),
+		parentFields: make(map[string]string),
+	}
+}
+
+// ExportRecordTrees runs each query and returns the results as record trees.
+// Queries should include the Id field so that lookups between exported
+// records can be replaced with references.
+func (f *Force) ExportRecordTrees(queries []string) (*RecordTreeExport, error) {
+	var results [][]query.Record
+	for _, qs := range queries {
+		records, err := query.Eager(append(f.QueryOptions(), query.QS(qs))...)
+		if err != nil {
+			return nil, fmt.Errorf("Error querying records: %w", err)
+		}
+		results = append(results, records)
+	}
+	export := NewRecordTreeExport()
+	for _, records := range results {
+		export.assignReferences(records)
+	}
+	if err := f.describeChildRelationships(export, results); err != nil {
+		return nil, err
+	}
+	for _, records := range results {
+		export.add(records)
+	}
+	return export, nil
+}
+
+// describeChildRelationships records the parent lookup field of each child
+// relationship queried in results.
+func (f *Force) describeChildRelationships(e *RecordTreeExport, results [][]query.Record) error {
+	parents := make(map[string]bool)
+	var walk func(records []query.Record)
+	walk = func(records []query.Record) {
+		for _, r := range records {
+			for _, v := range r.Fields {
+				if children, ok := v.([]query.Record); ok {
+					parents[r.Attributes.Type] = true
+					walk(children)
+				}
+			}
+		}
+	}
+	for _, records := range results {
+		walk(records)
+	}
+	for sobject := range parents {
+		body, err := f.DescribeSObjectCached(sobject)
+		if err != nil {
+			return fmt.Errorf("Error describing %s: %w", sobject, err)
+		}
+		var describe SObjectDescribe
+		if err := json.Unmarshal([]byte(body), &describe); err != nil {
+			return fmt.Errorf("Error parsing describe of %s: %w", sobject, err)
+		}
+		for _, c := range describe.ChildRelationships {
+			if c.RelationshipName != "" {
+				e.parentFields[sobject+"."+c.RelationshipName] = c.Field
+			}
+		}
+	}
+	return nil
+}
+
+func recordId(r query.Record) string {
+	id, _ := r.Fields["Id"].(string)
+	return id
+}
+
+func (e *RecordTreeExport) assignReferences(records []query.Record) {
+	for _, r := range records {
+		if id := recordId(r); id != "" && e.refs[id] == "" {
+			e.refs[id] = e.nextReference(r.Attributes.Type)
+		}
+		for _, v := range r.Fields {
+			if children, ok := v.([]query.Record); ok {
+				e.assignReferences(children)
+			}
+		}
+	}
+}
+
+// add adds query results to the export.  Records that have already been
+// exported are skipped.
+func (e *RecordTreeExport) add(records []query.Record) {
+	for _, r := range records {
+		id := recordId(r)
+		if id != "" && e.exported[id] {
+			continue
+		}
+		sobject := r.Attributes.Type
+		if _, ok := e.Trees[sobject]; !ok {
+			e.Trees[sobject] = &SObjectTree{}
+			e.order = append(e.order, sobject)
+		}
+		e.Trees[sobject].Records = append(e.Trees[sobject].Records, e.convert(r, ""))
+	}
+}
+
+func (e *RecordTreeExport) nextReference(sobject string) string {
+	e.counters[sobject]++
+	return fmt.Sprintf("%sRef%d", sobject, e.counters[sobject])
+}
+
+// reference returns the reference id for r.  Records queried without an Id
+// get a new reference id each time they're exported.
+func (e *RecordTreeExport) reference(r query.Record) string {
+	if ref, ok := e.refs[recordId(r)]; ok {
+		return ref
+	}
+	return e.nextReference(r.Attributes.Type)
+}
+
+// convert converts r to a tree record.  parentField, if set, is the lookup to
+// the record r is nested under, which is set by the nested insert.
+func (e *RecordTreeExport) convert(r query.Record, parentField string) SObjectTreeRecord {
+	id := recordId(r)
+	if id != "" {
+		e.exported[id] = true
+	}
+	tree := SObjectTreeRecord{
+		"attributes": map[string]interface{}{
+			"type":        r.Attributes.Type,
+			"referenceId": e.reference(r),
+		},
+	}
+	for k, v := range r.Fields {
+		switch value := v.(type) {
+		case nil:
+		case query.Record, map[string]interface{}:
+			// Parent relationship and compound fields can't be inserted.
+		case []query.Record:
+			child := SObjectTree{}
+			for _, c := range value {
+				if childId := recordId(c); childId != "" && e.exported[childId] {
+					// Already exported by an earlier query.
+					continue
+				}
+				child.Records = append(child.Records, e.convert(c, e.parentFields[r.Attributes.Type+"."+k]))
+			}
+			if len(child.Records) > 0 {
+				tree[k] = child
+			}
+		case string:
+			switch {
+			case k == "Id", k == parentField:
+			case e.refs[value] != "":
+				tree[k] = "@" + e.refs[value]
+			default:
+				tree[k] = value
+			}
+		default:
+			tree[k] = value
+		}
+	}
+	return tree
+}
+
+// Plan returns the plan for importing the export, with sobjects ordered so
+// that lookups are inserted before the records that reference them.
+func (e *RecordTreeExport) Plan(prefix string) []RecordTreePlanEntry {
+	typeOfRef := make(map[string]string)
+	dependencies := make(map[string]map[string]bool)
+	for _, sobject := range e.order {
+		dependencies[sobject] = make(map[string]bool)
+		for _, r := range e.Trees[sobject].Records {
+			walkTreeRecords(r, func(rec SObjectTreeRecord) {
+				typeOfRef[rec.ReferenceId()] = sobject
+			})
+		}
+	}
+	for _, sobject := range e.order {
+		for _, r := range e.Trees[sobject].Records {
+			for _, ref := range r.References() {
+				if dep := typeOfRef[ref]; dep != "" && dep != sobject {
+					dependencies[sobject][dep] = true
+				}
+			}
+		}
+	}
+
+	var plan []RecordTreePlanEntry
+	added := make(map[string]bool)
+	var visit func(sobject string, visiting map[string]bool)
+	visit = func(sobject string, visiting map[string]bool) {
+		if added[sobject] || visiting[sobject] {
+			return
+		}
+		visiting[sobject] = true
+		var deps []string
+		for dep := range dependencies[sobject] {
+			deps = append(deps, dep)
+		}
+		sort.Strings(deps)
+		for _, dep := range deps {
+			visit(dep, visiting)
+		}
+		added[sobject] = true
+		plan = append(plan, RecordTreePlanEntry{
+			SObject:     sobject,
+			SaveRefs:    true,
+			ResolveRefs: len(deps) > 0,
+			Files:       []string{prefix + sobject + ".json"},
+		})
+	}
+	for _, sobject := range e.order {
+		visit(sobject, make(map[string]bool))
+	}
+	return plan
+}
+
+func walkTreeRecords(r SObjectTreeRecord, fn func(SObjectTreeRecord)) {
+	fn(r)
+	for _, records := range r.children() {
+		for _, child := range records {
+			walkTreeRecords(child, fn)
+		}
+	}
+}
+
+// Write writes a data file for each sobject and a plan file to dir.
+func (e *RecordTreeExport) Write(dir string, prefix string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	plan := e.Plan(prefix)
+	for _, entry := range plan {
+		if err := writeJsonFile(filepath.Join(dir, entry.Files[0]), e.Trees[entry.SObject]); err != nil {
+			return err
+		}
+	}
+	return writeJsonFile(filepath.Join(dir, prefix+RecordTreePlanFile), plan)
+}
+
+func writeJsonFile(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// LoadRecordTrees reads records from data files or plan files.  Files listed
+// in a plan are read relative to the plan's directory.
+func LoadRecordTrees(paths []string) (records []SObjectTreeRecord, err error) {
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(strings.TrimSpace(string(data)), "[") {
+			var plan []RecordTreePlanEntry
+			if err := json.Unmarshal(data, &plan); err != nil {
+				return nil, fmt.Errorf("Could not parse plan %s: %w", path, err)
+			}
+			for _, entry := range plan {
+				var files []string
+				for _, file := range entry.Files {
+					files = append(files, filepath.Join(filepath.Dir(path), file))
+				}
+				planRecords, err := LoadRecordTrees(files)
+				if err != nil {
+					return nil, err
+				}
+				records = append(records, planRecords...)
+			}
+			continue
+		}
+		var tree SObjectTree
+		if err := json.Unmarshal(data, &tree); err != nil {
+			return nil, fmt.Errorf("Could not parse %s: %w", path, err)
+		}
+		for _, r := range tree.Records {
+			if r.Type() == "" || r.ReferenceId() == "" {
+				return nil, fmt.Errorf("Record in %s is missing attributes.type or attributes.referenceId", path)
+			}
+		}
+		records = append(records, tree.Records...)
+	}
+	return
+}
+
+// CreateSObjectTree inserts records of type sobject, along with their nested
+// children, using the sObject Tree API.
+func (f *Force) CreateSObjectTree(sobject string, tree SObjectTree) (result SObjectTreeResult, err error) {
+	url := fmt.Sprintf("%s/services/data/%s/composite/tree/%s", f.InstanceUrl(), apiVersion, sobject)
+	body, err := f.httpPostJsonWithResults(url, tree)
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(body, &result)
+	return
+}
+
+// httpPostJsonWithResults posts payload as JSON.  Unlike most requests, the
+// body of a 400 response is returned rather than an error, since composite
+// resources use it to report per-record errors.
+func (f *Force) httpPostJsonWithResults(url string, payload interface{}) ([]byte, error) {
+	rbody, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	res, err := f.httpPostPatch(url, string(rbody), ContentTypeJson, HttpMethodPost)
+	if err == SessionExpiredError {
+		if refreshErr := f.RefreshSession(); refreshErr != nil {
+			return nil, err
+		}
+		res, err = f.httpPostPatch(url, string(rbody), ContentTypeJson, HttpMethodPost)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode/100 == 2 || res.StatusCode == 400 {
+		return body, nil
+	}
+	return nil, f._coerceHttpError(res, body)
+}
+
+// SObjectTreeError describes the records that failed in a tree insert.
+type SObjectTreeError struct {
+	SObject string
+	Results []SObjectTreeResultItem
+}
+
+func (e SObjectTreeError) Error() string {
+	var msgs []string
+	for _, r := range e.Results {
+		for _, re := range r.Errors {
+			msgs = append(msgs, fmt.Sprintf("%s: %s: %s", r.ReferenceId, re.StatusCode, re.Message))
+		}
+	}
+	return fmt.Sprintf("Failed to insert %s records: %s", e.SObject, strings.Join(msgs, "; "))
+}
+
+// ImportRecordTrees inserts records using the sObject Tree API.  Records are
+// inserted once all of the records outside their tree that they reference
+// have been inserted, and reference placeholders are replaced with the new
+// record ids.  Lookups between records in the same tree are set by updating
+// the records once all of the trees have been inserted.  progress is called
+// after each insert request.  The returned map contains the id of each
+// inserted record by reference id.
+func (f *Force) ImportRecordTrees(records []SObjectTreeRecord, progress func(sobject string, inserted int)) (map[string]string, error) {
+	ids := make(map[string]string)
+	var deferred []treeReferenceUpdate
+	pending := records
+	for len(pending) > 0 {
+		var ready, waiting []SObjectTreeRecord
+		for _, r := range pending {
+			if referencesResolved(r, ids) {
+				ready = append(ready, r)
+			} else {
+				waiting = append(waiting, r)
+			}
+		}
+		if len(ready) == 0 {
+			return ids, fmt.Errorf("Unable to resolve references: %s", strings.Join(unresolvedReferences(waiting, ids), ", "))
+		}
+		for _, batch := range treeBatches(ready) {
+			sobject := batch[0].Type()
+			tree := SObjectTree{}
+			for _, r := range batch {
+				tree.Records = append(tree.Records, r.resolve(ids, &deferred))
+			}
+			result, err := f.CreateSObjectTree(sobject, tree)
+			if err != nil {
+				return ids, err
+			}
+			if result.HasErrors {
+				return ids, SObjectTreeError{SObject: sobject, Results: result.Results}
+			}
+			for _, item := range result.Results {
+				ids[item.ReferenceId] = item.Id
+			}
+			if progress != nil {
+				progress(sobject, len(result.Results))
+			}
+		}
+		pending = waiting
+	}
+	for _, u := range deferred {
+		err := f.UpdateRecord(u.SObject, ids[u.ReferenceId], map[string]string{u.Field: ids[u.Target]})
+		if err != nil {
+			return ids, fmt.Errorf("Failed to set %s on %s: %w", u.Field, u.ReferenceId, err)
+		}
+	}
+	return ids, nil
+}
+
+// referencesResolved reports whether the records outside r's tree that it
+// references have been inserted.
+func referencesResolved(r SObjectTreeRecord, ids map[string]string) bool {
+	internal := r.treeReferenceIds()
+	for _, ref := range r.References() {
+		if _, ok := ids[ref]; !ok && !internal[ref] {
+			return false
+		}
+	}
+	return true
+}
+
+func unresolvedReferences(records []SObjectTreeRecord, ids map[string]string) []string {
+	seen := make(map[string]bool)
+	var refs []string
+	for _, r := range records {
+		internal := r.treeReferenceIds()
+		for _, ref := range r.References() {
+			if _, ok := ids[ref]; !ok && !internal[ref] && !seen[ref] {
+				seen[ref] = true
+				refs = append(refs, "@"+ref)
+			}
+		}
+	}
+	sort.Strings(refs)
+	return refs
+}
+
+// treeBatches groups records by type, in the order in which each type first
+// appears, into batches that fit in a single sObject Tree request.
+func treeBatches(records []SObjectTreeRecord) [][]SObjectTreeRecord {
+	var types []string
+	byType := make(map[string][]SObjectTreeRecord)
+	for _, r := range records {
+		if _, ok := byType[r.Type()]; !ok {
+			types = append(types, r.Type())
+		}
+		byType[r.Type()] = append(byType[r.Type()], r)
+	}
+	var batches [][]SObjectTreeRecord
+	for _, t := range types {
+		var batch []SObjectTreeRecord
+		count := 0
+		for _, r := range byType[t] {
+			n := r.Count()
+			if count+n > SObjectTreeMaxRecords && len(batch) > 0 {
+				batches = append(batches, batch)
+				batch, count = nil, 0
+			}
+			batch = append(batch, r)
+			count += n
+		}
+		if len(batch) > 0 {
+			batches = append(batches, batch)
+		}
+	}
+	return batches
+}
@@ -0,0 +1,246 @@
+package lib
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ForceCLI/force/lib/query"
+)
+
+func testQueryRecord(sobject string, fields map[string]interface{}) query.Record {
+	r := query.Record{Fields: fields}
+	r.Attributes.Type = sobject
+	return r
+}
+
+func TestRecordTreeExport_replaces_lookups_with_references(t *testing.T) {
+	contact := testQueryRecord("Contact", map[string]interface{}{
+		"Id":         "003000000000001AAA",
+		"LastName":   "Doe",
+		"AccountId":  "001000000000001AAA",
+		"Partner__c": "001000000000001AAA",
+	})
+	account := testQueryRecord("Account", map[string]interface{}{
+		"Id":       "001000000000001AAA",
+		"Name":     "Acme",
+		"Owner":    testQueryRecord("User", map[string]interface{}{"Name": "Admin"}),
+		"Contacts": []query.Record{contact},
+	})
+	opportunity := testQueryRecord("Opportunity", map[string]interface{}{
+		"Id":          "006000000000001AAA",
+		"Name":        "Big Deal",
+		"AccountId":   "001000000000001AAA",
+		"CloseDate":   nil,
+		"OwnerId":     "005000000000001AAA",
+		"Description": "",
+	})
+
+	export := NewRecordTreeExport()
+	export.parentFields["Account.Contacts"] = "AccountId"
+	results := [][]query.Record{{opportunity}, {account}}
+	for _, records := range results {
+		export.assignReferences(records)
+	}
+	for _, records := range results {
+		export.add(records)
+	}
+
+	acct := export.Trees["Account"].Records[0]
+	if acct.ReferenceId() != "AccountRef1" {
+		t.Errorf("Expected AccountRef1, got %s", acct.ReferenceId())
+	}
+	if _, ok := acct["Owner"]; ok {
+		t.Errorf("Expected parent relationship to be omitted")
+	}
+	contacts := acct["Contacts"].(SObjectTree).Records
+	if _, ok := contacts[0]["AccountId"]; ok {
+		t.Errorf("Expected lookup to parent to be omitted from nested record")
+	}
+	if contacts[0]["Partner__c"] != "@AccountRef1" {
+		t.Errorf("Expected other lookups to parent to be kept, got %v", contacts[0]["Partner__c"])
+	}
+
+	opp := export.Trees["Opportunity"].Records[0]
+	if opp["AccountId"] != "@AccountRef1" {
+		t.Errorf("Expected AccountId reference, got %v", opp["AccountId"])
+	}
+	if opp["OwnerId"] != "005000000000001AAA" {
+		t.Errorf("Expected lookup to record outside export to be kept, got %v", opp["OwnerId"])
+	}
+	if _, ok := opp["CloseDate"]; ok {
+		t.Errorf("Expected null field to be omitted")
+	}
+	if _, ok := opp["Id"]; ok {
+		t.Errorf("Expected Id to be omitted")
+	}
+
+	plan := export.Plan("")
+	if len(plan) != 2 || plan[0].SObject != "Account" || plan[1].SObject != "Opportunity" {
+		t.Errorf("Expected Account to be planned before Opportunity, got %+v", plan)
+	}
+	if !plan[1].ResolveRefs {
+		t.Errorf("Expected Opportunity to resolve references")
+	}
+}
+
+func TestRecordTreeExport_exports_records_once(t *testing.T) {
+	contact := testQueryRecord("Contact", map[string]interface{}{
+		"Id":        "003000000000001AAA",
+		"LastName":  "Doe",
+		"AccountId": "001000000000001AAA",
+	})
+	account := testQueryRecord("Account", map[string]interface{}{
+		"Id":       "001000000000001AAA",
+		"Name":     "Acme",
+		"Contacts": []query.Record{contact},
+	})
+
+	export := NewRecordTreeExport()
+	export.parentFields["Account.Contacts"] = "AccountId"
+	results := [][]query.Record{{contact}, {account}}
+	for _, records := range results {
+		export.assignReferences(records)
+	}
+	for _, records := range results {
+		export.add(records)
+	}
+
+	contacts := export.Trees["Contact"].Records
+	if len(contacts) != 1 || contacts[0].ReferenceId() != "ContactRef1" {
+		t.Fatalf("Expected the contact to be exported once at the top level, got %v", contacts)
+	}
+	if contacts[0]["AccountId"] != "@AccountRef1" {
+		t.Errorf("Expected AccountId reference, got %v", contacts[0]["AccountId"])
+	}
+	if _, ok := export.Trees["Account"].Records[0]["Contacts"]; ok {
+		t.Errorf("Expected the exported contact not to be nested again")
+	}
+}
+
+func TestImportRecordTrees_inserts_in_dependency_order(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests = append(requests, r.URL.Path)
+		var tree SObjectTree
+		json.Unmarshal(body, &tree)
+		result := SObjectTreeResult{}
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/composite/tree/Account"):
+			result.Results = []SObjectTreeResultItem{
+				{ReferenceId: "AccountRef1", Id: "001NEW"},
+				{ReferenceId: "ContactRef1", Id: "003NEW"},
+			}
+		case strings.HasSuffix(r.URL.Path, "/composite/tree/Opportunity"):
+			if tree.Records[0]["AccountId"] != "001NEW" {
+				t.Errorf("Expected AccountId to be resolved, got %v", tree.Records[0]["AccountId"])
+			}
+			result.Results = []SObjectTreeResultItem{{ReferenceId: "OpportunityRef1", Id: "006NEW"}}
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(result)
+	}))
+	defer server.Close()
+
+	var records []SObjectTreeRecord
+	data := `{"records": [
+		{"attributes": {"type": "Opportunity", "referenceId": "OpportunityRef1"}, "Name": "Big Deal", "AccountId": "@AccountRef1"},
+		{"attributes": {"type": "Account", "referenceId": "AccountRef1"}, "Name": "Acme",
+			"Contacts": {"records": [{"attributes": {"type": "Contact", "referenceId": "ContactRef1"}, "LastName": "Doe"}]}}
+	]}`
+	var tree SObjectTree
+	if err := json.Unmarshal([]byte(data), &tree); err != nil {
+		t.Fatal(err)
+	}
+	records = tree.Records
+
+	force := &Force{
+		Credentials: &ForceSession{
+			InstanceUrl: server.URL,
+			AccessToken: "test-token",
+		},
+	}
+	ids, err := force.ImportRecordTrees(records, nil)
+	if err != nil {
+		t.Fatalf("ImportRecordTrees returned error: %v", err)
+	}
+	if len(requests) != 2 || !strings.HasSuffix(requests[0], "/Account") {
+		t.Errorf("Expected Account to be inserted first, got %v", requests)
+	}
+	if ids["OpportunityRef1"] != "006NEW" || ids["ContactRef1"] != "003NEW" {
+		t.Errorf("Unexpected ids: %v", ids)
+	}
+}
+
+func TestImportRecordTrees_updates_references_within_a_tree(t *testing.T) {
+	var updates []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.Method == http.MethodPatch {
+			updates = append(updates, r.URL.Path+" "+string(body))
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		if strings.Contains(string(body), "ReportsToId") {
+			t.Errorf("Expected reference within the tree to be omitted from insert, got %s", body)
+		}
+		result := SObjectTreeResult{Results: []SObjectTreeResultItem{
+			{ReferenceId: "AccountRef1", Id: "001NEW"},
+			{ReferenceId: "ContactRef1", Id: "003NEW1"},
+			{ReferenceId: "ContactRef2", Id: "003NEW2"},
+		}}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(result)
+	}))
+	defer server.Close()
+
+	data := `{"records": [
+		{"attributes": {"type": "Account", "referenceId": "AccountRef1"}, "Name": "Acme",
+			"Contacts": {"records": [
+				{"attributes": {"type": "Contact", "referenceId": "ContactRef1"}, "LastName": "Doe", "ReportsToId": "@ContactRef2"},
+				{"attributes": {"type": "Contact", "referenceId": "ContactRef2"}, "LastName": "Roe"}
+			]}}
+	]}`
+	var tree SObjectTree
+	if err := json.Unmarshal([]byte(data), &tree); err != nil {
+		t.Fatal(err)
+	}
+	force := &Force{
+		Credentials: &ForceSession{
+			InstanceUrl: server.URL,
+			AccessToken: "test-token",
+		},
+	}
+	if _, err := force.ImportRecordTrees(tree.Records, nil); err != nil {
+		t.Fatalf("ImportRecordTrees returned error: %v", err)
+	}
+	if len(updates) != 1 || !strings.HasSuffix(updates[0], `/sobjects/Contact/003NEW1 {"ReportsToId":"003NEW2"}`) {
+		t.Errorf("Expected ReportsToId to be set after insert, got %v", updates)
+	}
+}
+
+func TestImportRecordTrees_reports_unresolved_references(t *testing.T) {
+	records := []SObjectTreeRecord{
+		{"attributes": map[string]interface{}{"type": "Contact", "referenceId": "ContactRef1"}, "AccountId": "@AccountRef9"},
+	}
+	force := &Force{Credentials: &ForceSession{}}
+	_, err := force.ImportRecordTrees(records, nil)
+	if err == nil || !strings.Contains(err.Error(), "@AccountRef9") {
+		t.Errorf("Expected unresolved reference error, got %v", err)
+	}
+}
+
+func TestTreeBatches_limits_records_per_request(t *testing.T) {
+	var records []SObjectTreeRecord
+	for i := 0; i < 250; i++ {
+		records = append(records, SObjectTreeRecord{"attributes": map[string]interface{}{"type": "Account"}})
+	}
+	batches := treeBatches(records)
+	if len(batches) != 2 || len(batches[0]) != 200 || len(batches[1]) != 50 {
+		t.Errorf("Unexpected batch sizes")
+	}
+}